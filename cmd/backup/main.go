@@ -0,0 +1,70 @@
+// Command backup snapshots the SQLite database of the server, also while the server is running.
+//
+//	go run ./cmd/backup                      write data/algosim-<time>.db next to the database
+//	go run ./cmd/backup -out backups/x.db    write the snapshot to backups/x.db
+//
+// The database is database.path of the config unless -database-path is given. The snapshot is a
+// complete database file, restore it by stopping the server and putting it in place of the
+// database file.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/algosim/backend/configs"
+	"github.com/algosim/backend/pkg/db"
+)
+
+func main() {
+	databasePath := flag.String("database-path", "", "SQLite database file, defaults to database.path of the config")
+	out := flag.String("out", "", "file to write the snapshot to, defaults to the database name with the time appended")
+	flag.Parse()
+
+	path := *databasePath
+	if path == "" {
+		cfg, err := configs.Load()
+		if err != nil {
+			log.Fatalf("Failed to load config: %v", err)
+		}
+		if cfg.Database.Driver != configs.DatabaseDriverSQLite {
+			log.Fatalf("Backups are for the %s driver, database.driver is %s", configs.DatabaseDriverSQLite, cfg.Database.Driver)
+		}
+		path = cfg.Database.Path
+	}
+
+	// Opening a missing file would create an empty database and back that up
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		log.Fatalf("Database %s does not exist", path)
+	}
+
+	if *out == "" {
+		ext := filepath.Ext(path)
+		*out = fmt.Sprintf("%s-%s%s", strings.TrimSuffix(path, ext), time.Now().UTC().Format("20060102T150405Z"), ext)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	conn, err := db.OpenSQLite(ctx, db.SQLiteConfig{Path: path})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer conn.Close()
+
+	start := time.Now()
+	if err := db.BackupSQLite(ctx, conn, *out); err != nil {
+		conn.Close()
+		log.Fatal(err)
+	}
+	fmt.Printf("Backed up %s to %s in %s\n", path, *out, time.Since(start).Round(time.Millisecond))
+}
@@ -0,0 +1,186 @@
+// Command migrate manages the schema of the server database.
+//
+//	go run ./cmd/migrate up              apply every pending migration
+//	go run ./cmd/migrate down [n]        roll back the last n migrations, 1 by default
+//	go run ./cmd/migrate goto <version>  migrate up or down to version, 0 rolls back everything
+//	go run ./cmd/migrate status          list migrations and whether they are applied
+//	go run ./cmd/migrate create <name>   add an empty migration for every driver to migrations
+//
+// The database is the one of the config, database.url for postgres and database.path for
+// sqlite, unless -database-url or -database-path is given.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/algosim/backend/configs"
+	"github.com/algosim/backend/migrations"
+	"github.com/algosim/backend/pkg/db"
+	"github.com/algosim/backend/pkg/migrate"
+)
+
+func main() {
+	databaseURL := flag.String("database-url", "", "PostgreSQL connection string, defaults to database.url of the config")
+	databasePath := flag.String("database-path", "", "SQLite database file, defaults to database.path of the config")
+	dir := flag.String("dir", "migrations", "directory create adds migrations to, one subdirectory per driver")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: migrate [flags] up | down [n] | goto <version> | status | create <name>")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// create only writes files, it needs no database
+	if args[0] == "create" {
+		if len(args) != 2 {
+			log.Fatal("usage: migrate create <name>")
+		}
+		// Every driver gets the migration so their versions stay the same
+		for _, driver := range []string{configs.DatabaseDriverPostgres, configs.DatabaseDriverSQLite} {
+			upPath, downPath, err := migrate.Create(filepath.Join(*dir, driver), args[1])
+			if err != nil {
+				log.Fatalf("Failed to create migration: %v", err)
+			}
+			fmt.Printf("Created %s\nCreated %s\n", upPath, downPath)
+		}
+		return
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	migrator, closeDB, err := openMigrator(ctx, *databaseURL, *databasePath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer closeDB()
+
+	if err := run(ctx, migrator, args); err != nil {
+		closeDB()
+		log.Fatal(err)
+	}
+}
+
+// openMigrator connects to the database and loads the embedded migrations of its driver
+func openMigrator(ctx context.Context, databaseURL, databasePath string) (*migrate.Migrator, func(), error) {
+	driver := configs.DatabaseDriverPostgres
+	switch {
+	case databaseURL != "":
+	case databasePath != "":
+		driver = configs.DatabaseDriverSQLite
+	default:
+		cfg, err := configs.Load()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load config: %w", err)
+		}
+		driver, databaseURL, databasePath = cfg.Database.Driver, cfg.Database.URL, cfg.Database.Path
+	}
+
+	switch driver {
+	case configs.DatabaseDriverPostgres:
+		pool, err := db.OpenPostgres(ctx, db.PostgresConfig{URL: databaseURL, MaxConns: 2, ConnectTimeout: 10 * time.Second})
+		if err != nil {
+			return nil, nil, err
+		}
+		migrator, err := migrate.New(migrate.NewPostgresDriver(pool), migrations.Postgres())
+		if err != nil {
+			pool.Close()
+			return nil, nil, err
+		}
+		return migrator, pool.Close, nil
+
+	case configs.DatabaseDriverSQLite:
+		conn, err := db.OpenSQLite(ctx, db.SQLiteConfig{Path: databasePath})
+		if err != nil {
+			return nil, nil, err
+		}
+		closeDB := func() { conn.Close() }
+		migrator, err := migrate.New(migrate.NewSQLiteDriver(conn), migrations.SQLite())
+		if err != nil {
+			closeDB()
+			return nil, nil, err
+		}
+		return migrator, closeDB, nil
+
+	default:
+		return nil, nil, fmt.Errorf("the %s driver has no migrations, set database.driver or -database-url", driver)
+	}
+}
+
+// run executes a subcommand
+func run(ctx context.Context, migrator *migrate.Migrator, args []string) error {
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		report("Applied", applied)
+		if err == nil && len(applied) == 0 {
+			fmt.Println("Nothing to migrate")
+		}
+		return err
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid number of migrations %q", args[1])
+			}
+			steps = n
+		}
+		rolledBack, err := migrator.Down(ctx, steps)
+		report("Rolled back", rolledBack)
+		return err
+
+	case "goto":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: migrate goto <version>")
+		}
+		version, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil || version < 0 {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+		changed, err := migrator.Goto(ctx, version)
+		report("Migrated", changed)
+		return err
+
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.Applied != nil {
+				state = "applied " + status.Applied.AppliedAt.Format(time.RFC3339)
+			}
+			if status.Modified {
+				state += ", MODIFIED since"
+			}
+			fmt.Printf("%04d_%s\t%s\n", status.Migration.Version, status.Migration.Name, state)
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
+
+// report prints the migrations a command went through
+func report(verb string, done []migrate.Migration) {
+	for _, migration := range done {
+		fmt.Printf("%s %04d_%s\n", verb, migration.Version, migration.Name)
+	}
+}
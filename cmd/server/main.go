@@ -35,7 +35,9 @@ func main() {
 
 	// Create and setup server
 	srv := server.NewServer(cfg)
-	srv.SetupRoutes()
+	if err := srv.SetupRoutes(); err != nil {
+		log.Fatalf("Failed to setup routes: %v", err)
+	}
 
 	// Run server
 	if err := srv.Run(); err != nil {
@@ -0,0 +1,211 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/algosim/backend/internal/auth/usecase"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// AdminHandler handles HTTP requests for user moderation and role management
+type AdminHandler struct {
+	adminUseCase *usecase.AdminUseCase
+}
+
+// NewAdminHandler creates a new AdminHandler instance
+func NewAdminHandler(adminUseCase *usecase.AdminUseCase) *AdminHandler {
+	return &AdminHandler{
+		adminUseCase: adminUseCase,
+	}
+}
+
+// GrantRole handles granting a role
+// @Summary Grant Role
+// @Description Grants a role to a user, it shows up in their next access token
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Param role body RoleRequest true "Role to grant (user, coach or admin)"
+// @Success 200 {object} UserResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /admin/users/{id}/roles [post]
+func (h *AdminHandler) GrantRole(c *gin.Context) {
+	actor, _ := CurrentUser(c)
+
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req RoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.adminUseCase.GrantRole(actor.ID, userID, req.Role)
+	if err != nil {
+		c.JSON(adminErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, newUserResponse(user))
+}
+
+// RevokeRole handles revoking a role
+// @Summary Revoke Role
+// @Description Revokes a role from a user
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Param role path string true "Role to revoke"
+// @Success 200 {object} UserResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /admin/users/{id}/roles/{role} [delete]
+func (h *AdminHandler) RevokeRole(c *gin.Context) {
+	actor, _ := CurrentUser(c)
+
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	user, err := h.adminUseCase.RevokeRole(actor.ID, userID, c.Param("role"))
+	if err != nil {
+		c.JSON(adminErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, newUserResponse(user))
+}
+
+// BanUser handles banning a user
+// @Summary Ban User
+// @Description Bans a user and revokes all of their sessions and access tokens
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 200 {object} UserResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /admin/users/{id}/ban [post]
+func (h *AdminHandler) BanUser(c *gin.Context) {
+	actor, _ := CurrentUser(c)
+
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	user, err := h.adminUseCase.BanUser(actor.ID, userID)
+	if err != nil {
+		c.JSON(adminErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, newUserResponse(user))
+}
+
+// UnbanUser handles lifting a ban
+// @Summary Unban User
+// @Description Lifts the ban of a user
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 200 {object} UserResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /admin/users/{id}/ban [delete]
+func (h *AdminHandler) UnbanUser(c *gin.Context) {
+	actor, _ := CurrentUser(c)
+
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	user, err := h.adminUseCase.UnbanUser(actor.ID, userID)
+	if err != nil {
+		c.JSON(adminErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, newUserResponse(user))
+}
+
+// ResetMFA handles removing the authenticator of a user
+// @Summary Reset MFA
+// @Description Disables MFA of a user who lost their authenticator and recovery codes
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 200 {object} UserResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /admin/users/{id}/mfa [delete]
+func (h *AdminHandler) ResetMFA(c *gin.Context) {
+	actor, _ := CurrentUser(c)
+
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	user, err := h.adminUseCase.ResetMFA(actor.ID, userID)
+	if err != nil {
+		c.JSON(adminErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, newUserResponse(user))
+}
+
+// userIDParam parses the user ID path parameter and writes a 400 when it is malformed
+func userIDParam(c *gin.Context) (uuid.UUID, bool) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+// adminErrorStatus maps admin errors to HTTP status codes
+func adminErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, domain.ErrInvalidRole):
+		return http.StatusBadRequest
+	case errors.Is(err, domain.ErrSelfModification):
+		return http.StatusForbidden
+	case errors.Is(err, domain.ErrUserNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// RoleRequest represents a role to grant
+type RoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
@@ -0,0 +1,20 @@
+package http
+
+import (
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/gin-gonic/gin"
+)
+
+// SetupAdminRoutes configures the user moderation and role management routes
+func SetupAdminRoutes(r *gin.Engine, h *AdminHandler, m *AuthMiddleware) {
+	users := r.Group("/api/v1/admin/users", m.RequireAuth())
+	{
+		users.POST("/:id/roles", m.RequirePermission(domain.PermissionRolesWrite), h.GrantRole)
+		users.DELETE("/:id/roles/:role", m.RequirePermission(domain.PermissionRolesWrite), h.RevokeRole)
+
+		users.POST("/:id/ban", m.RequirePermission(domain.PermissionUsersWrite), h.BanUser)
+		users.DELETE("/:id/ban", m.RequirePermission(domain.PermissionUsersWrite), h.UnbanUser)
+
+		users.DELETE("/:id/mfa", m.RequirePermission(domain.PermissionUsersWrite), h.ResetMFA)
+	}
+}
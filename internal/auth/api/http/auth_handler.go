@@ -1,63 +1,99 @@
 package http
 
 import (
+	"errors"
 	"net/http"
 	"time"
 
+	"github.com/algosim/backend/configs"
+	"github.com/algosim/backend/internal/auth/domain"
 	"github.com/algosim/backend/internal/auth/usecase"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
+const (
+	// oauthStateCookie holds the signed OAuth state binding between login and callback
+	oauthStateCookie = "oauth_state"
+	oauthCookiePath  = "/api/v1/auth/oauth"
+)
+
 // AuthHandler handles HTTP requests for authentication
 type AuthHandler struct {
 	authUseCase *usecase.AuthUseCase
+	config      *configs.Config
 }
 
 // NewAuthHandler creates a new AuthHandler instance
-func NewAuthHandler(authUseCase *usecase.AuthUseCase) *AuthHandler {
+func NewAuthHandler(authUseCase *usecase.AuthUseCase, config *configs.Config) *AuthHandler {
 	return &AuthHandler{
 		authUseCase: authUseCase,
+		config:      config,
 	}
 }
 
 // InitiateOAuthLogin handles the OAuth login initiation
 // @Summary Initiate OAuth Login
-// @Description Redirects to Google OAuth login page
+// @Description Returns the login URL of the requested OAuth provider
 // @Tags auth
 // @Accept json
 // @Produce json
-// @Param provider query string true "OAuth provider (e.g., google)"
-// @Success 302 {string} string "Redirect to OAuth provider"
+// @Param provider query string true "OAuth provider (e.g., google, github)"
+// @Success 200 {object} map[string]string
 // @Failure 400 {object} map[string]string
 // @Router /auth/oauth/login [get]
 func (h *AuthHandler) InitiateOAuthLogin(c *gin.Context) {
-	provider := c.Query("provider")
-	if provider != "google" {
+	// Generate state for CSRF protection and bind it to this browser
+	login, err := h.authUseCase.InitiateOAuthLogin(c.Request.Context(), c.Query("provider"))
+	if errors.Is(err, domain.ErrOAuthProviderNotSupported) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported provider"})
 		return
 	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 
-	// Generate state for CSRF protection
-	state := "random-state" // TODO: Generate proper random state
-	authURL := h.authUseCase.InitiateOAuthLogin(state)
+	h.setOAuthStateCookie(c, login)
 
 	// Instead of redirecting, return the URL to the frontend
 	c.JSON(http.StatusOK, gin.H{
-		"auth_url": authURL,
+		"auth_url": login.AuthURL,
+	})
+}
+
+// setOAuthStateCookie binds the OAuth state of login to the browser
+func (h *AuthHandler) setOAuthStateCookie(c *gin.Context, login *usecase.OAuthLogin) {
+	// Lax so the cookie survives the top-level redirect back from the provider
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     oauthStateCookie,
+		Value:    login.Binding,
+		Path:     oauthCookiePath,
+		Domain:   h.config.Cookie.Domain,
+		Expires:  login.ExpiresAt,
+		MaxAge:   int(time.Until(login.ExpiresAt).Seconds()),
+		Secure:   h.config.Cookie.Secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
 	})
 }
 
 // OAuthCallback handles the OAuth callback
 // @Summary OAuth Callback
-// @Description Handles the callback from OAuth provider
+// @Description Handles the callback from OAuth provider. Signs in, or returns the linked identity when the login was started through /auth/oauth/link.
+// @Description Users with MFA enabled get 202 with an mfa_token to complete the sign-in at /auth/mfa/verify.
 // @Tags auth
 // @Accept json
 // @Produce json
+// @Param provider path string true "OAuth provider (e.g., google, github)"
 // @Param code query string true "Authorization code from OAuth provider"
+// @Param state query string true "State issued by the login request"
 // @Success 200 {object} TokenResponse
+// @Success 202 {object} MFARequiredResponse
 // @Failure 400 {object} map[string]string
-// @Router /auth/oauth/callback [get]
+// @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /auth/oauth/{provider}/callback [get]
 func (h *AuthHandler) OAuthCallback(c *gin.Context) {
 	code := c.Query("code")
 	if code == "" {
@@ -65,68 +101,188 @@ func (h *AuthHandler) OAuthCallback(c *gin.Context) {
 		return
 	}
 
-	token, err := h.authUseCase.HandleOAuthCallback(code)
+	// The state cookie is single-use, drop it whatever the outcome
+	binding, _ := c.Cookie(oauthStateCookie)
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     oauthStateCookie,
+		Path:     oauthCookiePath,
+		Domain:   h.config.Cookie.Domain,
+		MaxAge:   -1,
+		Secure:   h.config.Cookie.Secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	result, err := h.authUseCase.HandleOAuthCallback(c.Request.Context(), c.Param("provider"), code, c.Query("state"), binding, clientInfo(c))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.JSON(oauthCallbackStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	if result.LinkedIdentity != nil {
+		c.JSON(http.StatusOK, newIdentityResponse(result.LinkedIdentity))
+		return
+	}
+	if result.MFA != nil {
+		respondWithMFAPending(c, result.MFA)
 		return
 	}
 
 	// Return tokens to the frontend
-	c.JSON(http.StatusOK, TokenResponse{
-		AccessToken:  token.AccessToken,
-		RefreshToken: token.RefreshToken,
-	})
+	h.respondWithToken(c, http.StatusOK, result.Token)
+}
+
+// oauthCallbackStatus maps OAuth callback errors to HTTP status codes
+func oauthCallbackStatus(err error) int {
+	switch {
+	case errors.Is(err, domain.ErrOAuthStateMissing),
+		errors.Is(err, domain.ErrOAuthStateMismatch),
+		errors.Is(err, domain.ErrOAuthStateExpired),
+		errors.Is(err, domain.ErrOAuthStateReplayed),
+		errors.Is(err, domain.ErrUserBanned):
+		return http.StatusForbidden
+	case errors.Is(err, domain.ErrIdentityAlreadyLinked),
+		errors.Is(err, domain.ErrEmailNotVerified),
+		errors.Is(err, domain.ErrAccountEmailNotVerified):
+		return http.StatusConflict
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+// Register handles email/password registration
+// @Summary Register
+// @Description Creates a password account to sign in with at /auth/login. A taken email gets the same answer, so registering does not reveal which emails have accounts.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param user body RegisterRequest true "Registration data"
+// @Success 202 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Router /auth/register [post]
+func (h *AuthHandler) Register(c *gin.Context) {
+	var req RegisterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.authUseCase.Register(req.Email, req.Password); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, domain.ErrInvalidEmail) || errors.Is(err, domain.ErrWeakPassword) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Sign in with your email and password"})
+}
+
+// Login handles email/password login
+// @Summary Login
+// @Description Signs in a password account. Users with MFA enabled get 202 with an mfa_token to complete the sign-in at /auth/mfa/verify.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param credentials body LoginRequest true "Login credentials"
+// @Success 200 {object} TokenResponse
+// @Success 202 {object} MFARequiredResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /auth/login [post]
+func (h *AuthHandler) Login(c *gin.Context) {
+	var req LoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.authUseCase.Login(req.Email, req.Password, clientInfo(c))
+	if errors.Is(err, domain.ErrInvalidCredentials) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, domain.ErrUserBanned) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if result.MFA != nil {
+		respondWithMFAPending(c, result.MFA)
+		return
+	}
+	h.respondWithToken(c, http.StatusOK, result.Token)
 }
 
 // RefreshToken handles token refresh
 // @Summary Refresh Token
-// @Description Generates a new access token using refresh token
+// @Description Generates a new access token using refresh token.
+// @Description In cookie mode the refresh token is read from the refresh_token cookie
+// @Description and the X-CSRF-Token header must echo the csrf_token cookie.
 // @Tags auth
 // @Accept json
 // @Produce json
-// @Security BearerAuth
-// @Param token body RefreshTokenRequest true "Refresh token data"
+// @Param token body RefreshTokenRequest false "Refresh token data (json mode)"
+// @Param X-CSRF-Token header string false "CSRF token (cookie mode)"
 // @Success 200 {object} TokenResponse
 // @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
 // @Router /auth/refresh [post]
 func (h *AuthHandler) RefreshToken(c *gin.Context) {
-	var req RefreshTokenRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	refreshToken, ok := h.refreshTokenFromRequest(c)
+	if !ok {
 		return
 	}
 
-	token, err := h.authUseCase.RefreshToken(req.RefreshToken)
+	token, err := h.authUseCase.RefreshToken(refreshToken, clientInfo(c))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		// The cookie will not work again, stop the browser from sending it
+		h.clearTokenCookies(c)
+		status := http.StatusBadRequest
+		switch {
+		case errors.Is(err, domain.ErrRefreshTokenReused):
+			status = http.StatusUnauthorized
+		case errors.Is(err, domain.ErrUserBanned):
+			status = http.StatusForbidden
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, TokenResponse{
-		AccessToken:  token.AccessToken,
-		RefreshToken: token.RefreshToken,
-	})
+	h.respondWithToken(c, http.StatusOK, token)
 }
 
 // Logout handles user logout
 // @Summary Logout
-// @Description Invalidates the refresh token
+// @Description Invalidates the refresh token.
+// @Description In cookie mode the refresh token is read from the refresh_token cookie
+// @Description and the X-CSRF-Token header must echo the csrf_token cookie.
 // @Tags auth
 // @Accept json
 // @Produce json
-// @Security BearerAuth
-// @Param token body LogoutRequest true "Logout data"
+// @Param token body LogoutRequest false "Logout data (json mode)"
+// @Param X-CSRF-Token header string false "CSRF token (cookie mode)"
 // @Success 200 {object} map[string]string
 // @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
 // @Router /auth/logout [post]
 func (h *AuthHandler) Logout(c *gin.Context) {
-	var req LogoutRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	refreshToken, ok := h.refreshTokenFromRequest(c)
+	if !ok {
 		return
 	}
 
-	if err := h.authUseCase.Logout(req.RefreshToken); err != nil {
+	err := h.authUseCase.Logout(refreshToken)
+	h.clearTokenCookies(c)
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -145,44 +301,57 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 // @Failure 401 {object} map[string]string
 // @Router /auth/validate [get]
 func (h *AuthHandler) ValidateToken(c *gin.Context) {
-	// Get token from Authorization header
-	tokenString := c.GetHeader("Authorization")
-	if tokenString == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "no token provided"})
-		return
-	}
+	user, _ := CurrentUser(c)
+	c.JSON(http.StatusOK, newUserResponse(user))
+}
 
-	// Remove "Bearer " prefix if present
-	if len(tokenString) > 7 && tokenString[:7] == "Bearer " {
-		tokenString = tokenString[7:]
-	}
+// JWKS publishes the public keys access tokens are signed with, empty when tokens use HS256.
+// It is served at /.well-known/jwks.json, outside the documented /api/v1 base path.
+func (h *AuthHandler) JWKS(c *gin.Context) {
+	// Short enough that verifiers pick up a newly published key well before it starts signing
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.authUseCase.JWKS())
+}
 
-	user, err := h.authUseCase.ValidateToken(tokenString)
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
-		return
-	}
+// clientInfo describes the client of the request for its session
+func clientInfo(c *gin.Context) domain.ClientInfo {
+	return domain.NewClientInfo(c.Request.UserAgent(), c.ClientIP())
+}
 
-	c.JSON(http.StatusOK, UserResponse{
-		ID:               user.ID,
-		Email:            user.Email,
-		CodeforcesHandle: user.CodeforcesHandle,
-		AtcoderHandle:    user.AtcoderHandle,
-		OAuthProvider:    user.OAuthProvider,
-		CreatedAt:        user.CreatedAt,
-		UpdatedAt:        user.UpdatedAt,
-	})
+// newUserResponse converts a domain user to its response representation
+func newUserResponse(user *domain.User) UserResponse {
+	return UserResponse{
+		ID:                  user.ID,
+		Email:               user.Email,
+		Handle:              user.Handle,
+		DisplayName:         user.DisplayName,
+		Timezone:            user.Timezone,
+		PreferredJudge:      user.PreferredJudge,
+		CodeforcesHandle:    user.CodeforcesHandle,
+		AtcoderHandle:       user.AtcoderHandle,
+		Roles:               user.Roles,
+		Banned:              user.IsBanned(),
+		DeletionScheduledAt: user.DeletionScheduledAt,
+		CreatedAt:           user.CreatedAt,
+		UpdatedAt:           user.UpdatedAt,
+	}
 }
 
 // UserResponse represents the user information response
 type UserResponse struct {
-	ID               uuid.UUID `json:"id"`
-	Email            string    `json:"email"`
-	CodeforcesHandle string    `json:"codeforces_handle,omitempty"`
-	AtcoderHandle    string    `json:"atcoder_handle,omitempty"`
-	OAuthProvider    string    `json:"oauth_provider"`
-	CreatedAt        time.Time `json:"created_at"`
-	UpdatedAt        time.Time `json:"updated_at"`
+	ID                  uuid.UUID  `json:"id"`
+	Email               string     `json:"email"`
+	Handle              string     `json:"handle,omitempty"`
+	DisplayName         string     `json:"display_name,omitempty"`
+	Timezone            string     `json:"timezone,omitempty"`
+	PreferredJudge      string     `json:"preferred_judge,omitempty"`
+	CodeforcesHandle    string     `json:"codeforces_handle,omitempty"`
+	AtcoderHandle       string     `json:"atcoder_handle,omitempty"`
+	Roles               []string   `json:"roles"`
+	Banned              bool       `json:"banned,omitempty"`
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
 }
 
 // Request types
@@ -190,6 +359,16 @@ type OAuthCallbackRequest struct {
 	Code string `json:"code" binding:"required"`
 }
 
+type RegisterRequest struct {
+	Email    string `json:"email" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+type LoginRequest struct {
+	Email    string `json:"email" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
 type RefreshTokenRequest struct {
 	RefreshToken string `json:"refresh_token" binding:"required"`
 }
@@ -201,4 +380,6 @@ type LogoutRequest struct {
 type TokenResponse struct {
 	AccessToken  string `json:"access_token"`
 	RefreshToken string `json:"refresh_token,omitempty"`
+	// CSRFToken is set in cookie mode and must be echoed in the X-CSRF-Token header
+	CSRFToken string `json:"csrf_token,omitempty"`
 }
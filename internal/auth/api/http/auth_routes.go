@@ -5,16 +5,40 @@ import (
 )
 
 // SetupAuthRoutes configures all authentication routes
-func SetupAuthRoutes(r *gin.Engine, h *AuthHandler) {
+func SetupAuthRoutes(r *gin.Engine, h *AuthHandler, m *AuthMiddleware) {
 	auth := r.Group("/api/v1/auth")
 	{
+		// Password routes
+		auth.POST("/register", h.Register)
+		auth.POST("/login", h.Login)
+
 		// OAuth routes
 		auth.GET("/oauth/login", h.InitiateOAuthLogin)
-		auth.GET("/oauth/callback", h.OAuthCallback)
+		auth.GET("/oauth/:provider/callback", h.OAuthCallback)
+		auth.GET("/oauth/link", m.RequireAuth(), h.InitiateOAuthLink)
+
+		// Linked identities
+		auth.GET("/identities", m.RequireAuth(), h.ListIdentities)
+		auth.DELETE("/identities/:id", m.RequireAuth(), h.UnlinkIdentity)
+
+		// Multi-factor authentication
+		auth.GET("/mfa", m.RequireAuth(), h.GetMFAStatus)
+		auth.POST("/mfa/enroll", m.RequireAuth(), h.EnrollMFA)
+		auth.POST("/mfa/confirm", m.RequireAuth(), h.ConfirmMFA)
+		auth.DELETE("/mfa", m.RequireAuth(), h.DisableMFA)
+		auth.POST("/mfa/verify", h.VerifyMFA)
 
 		// Token management
 		auth.POST("/refresh", h.RefreshToken)
 		auth.POST("/logout", h.Logout)
-		auth.GET("/validate", h.ValidateToken)
+		auth.GET("/validate", m.RequireAuth(), h.ValidateToken)
+
+		// Session management
+		auth.GET("/sessions", m.RequireAuth(), h.ListSessions)
+		auth.DELETE("/sessions/:id", m.RequireAuth(), h.RevokeSession)
+		auth.POST("/logout-all", m.RequireAuth(), h.LogoutAll)
 	}
+
+	// Public keys for verifying access tokens
+	r.GET("/.well-known/jwks.json", h.JWKS)
 }
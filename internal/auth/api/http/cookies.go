@@ -0,0 +1,132 @@
+package http
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/algosim/backend/configs"
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// refreshTokenCookie carries the refresh token in cookie mode
+	refreshTokenCookie = "refresh_token"
+	// csrfTokenCookie is readable by the frontend, which echoes it in csrfTokenHeader
+	csrfTokenCookie = "csrf_token"
+	csrfTokenHeader = "X-CSRF-Token"
+	authCookiePath  = "/api/v1/auth"
+)
+
+// cookieMode reports whether refresh tokens travel in cookies rather than JSON bodies
+func (h *AuthHandler) cookieMode() bool {
+	return h.config.Cookie.RefreshTokenMode == configs.RefreshTokenModeCookie
+}
+
+// respondWithToken writes an issued token pair, in cookie mode the refresh token only goes into a cookie
+func (h *AuthHandler) respondWithToken(c *gin.Context, status int, token *domain.Token) {
+	if !h.cookieMode() {
+		c.JSON(status, TokenResponse{
+			AccessToken:  token.AccessToken,
+			RefreshToken: token.RefreshToken,
+		})
+		return
+	}
+
+	csrfToken, err := generateCSRFToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.setCookie(c, refreshTokenCookie, token.RefreshToken, authCookiePath, token.ExpiresAt, true)
+	h.setCookie(c, csrfTokenCookie, csrfToken, "/", token.ExpiresAt, false)
+
+	c.JSON(status, TokenResponse{
+		AccessToken: token.AccessToken,
+		CSRFToken:   csrfToken,
+	})
+}
+
+// refreshTokenFromRequest reads the refresh token of a cookie-authenticated or JSON request
+// and writes the error response itself when there is none
+func (h *AuthHandler) refreshTokenFromRequest(c *gin.Context) (string, bool) {
+	if !h.cookieMode() {
+		var req RefreshTokenRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return "", false
+		}
+		return req.RefreshToken, true
+	}
+
+	refreshToken, err := c.Cookie(refreshTokenCookie)
+	if err != nil || refreshToken == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "refresh token cookie is required"})
+		return "", false
+	}
+
+	// Double submit: a cross-site request carries the cookies but cannot read them to set the header
+	csrfToken, err := c.Cookie(csrfTokenCookie)
+	header := c.GetHeader(csrfTokenHeader)
+	if err != nil || csrfToken == "" || subtle.ConstantTimeCompare([]byte(csrfToken), []byte(header)) != 1 {
+		c.JSON(http.StatusForbidden, gin.H{"error": "invalid csrf token"})
+		return "", false
+	}
+
+	return refreshToken, true
+}
+
+// clearTokenCookies removes the refresh and CSRF cookies, it is a no-op in JSON mode
+func (h *AuthHandler) clearTokenCookies(c *gin.Context) {
+	if !h.cookieMode() {
+		return
+	}
+	h.setCookie(c, refreshTokenCookie, "", authCookiePath, time.Time{}, true)
+	h.setCookie(c, csrfTokenCookie, "", "/", time.Time{}, false)
+}
+
+// setCookie writes a cookie that expires at expiresAt, a zero expiry deletes it
+func (h *AuthHandler) setCookie(c *gin.Context, name, value, path string, expiresAt time.Time, httpOnly bool) {
+	cookie := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Domain:   h.config.Cookie.Domain,
+		Secure:   h.config.Cookie.Secure,
+		HttpOnly: httpOnly,
+		SameSite: h.sameSite(),
+	}
+	if expiresAt.IsZero() {
+		cookie.MaxAge = -1
+	} else {
+		cookie.Expires = expiresAt
+		cookie.MaxAge = int(time.Until(expiresAt).Seconds())
+	}
+	http.SetCookie(c.Writer, cookie)
+}
+
+// sameSite returns the configured SameSite mode, strict unless lax or none is asked for
+func (h *AuthHandler) sameSite() http.SameSite {
+	switch strings.ToLower(h.config.Cookie.SameSite) {
+	case "lax":
+		return http.SameSiteLaxMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteStrictMode
+	}
+}
+
+// generateCSRFToken returns a random token for the double-submit cookie
+func generateCSRFToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,286 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/algosim/backend/internal/auth/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+// Grant types of the device token endpoint
+const (
+	grantTypeDeviceCode   = "urn:ietf:params:oauth:grant-type:device_code"
+	grantTypeRefreshToken = "refresh_token"
+)
+
+// DeviceHandler handles HTTP requests of the device authorization grant used by the CLI
+type DeviceHandler struct {
+	deviceUseCase *usecase.DeviceUseCase
+	authUseCase   *usecase.AuthUseCase
+}
+
+// NewDeviceHandler creates a new DeviceHandler instance
+func NewDeviceHandler(deviceUseCase *usecase.DeviceUseCase, authUseCase *usecase.AuthUseCase) *DeviceHandler {
+	return &DeviceHandler{
+		deviceUseCase: deviceUseCase,
+		authUseCase:   authUseCase,
+	}
+}
+
+// StartDeviceAuthorization handles a device asking for its codes
+// @Summary Start Device Authorization
+// @Description Starts the sign-in of a device without a browser (RFC 8628). Show the user_code and verification_uri to the user, then poll /auth/device/token with the device_code every interval seconds.
+// @Tags device
+// @Produce json
+// @Success 200 {object} DeviceCodeResponse
+// @Router /auth/device/code [post]
+func (h *DeviceHandler) StartDeviceAuthorization(c *gin.Context) {
+	codes, err := h.deviceUseCase.StartDeviceAuthorization(clientInfo(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, DeviceCodeResponse{
+		DeviceCode:              codes.DeviceCode,
+		UserCode:                codes.UserCode,
+		VerificationURI:         codes.VerificationURI,
+		VerificationURIComplete: codes.VerificationURIComplete,
+		ExpiresIn:               int(time.Until(codes.ExpiresAt).Seconds()),
+		Interval:                int(codes.Interval.Seconds()),
+	})
+}
+
+// DeviceToken handles the token requests of a device
+// @Summary Device Token
+// @Description Polls for the tokens of a device sign-in with grant_type urn:ietf:params:oauth:grant-type:device_code, or refreshes them with grant_type refresh_token.
+// @Description Tokens are always returned in the body. Errors carry an RFC 8628 code in error: authorization_pending, slow_down, access_denied, expired_token or invalid_grant.
+// @Tags device
+// @Accept x-www-form-urlencoded,json
+// @Produce json
+// @Param request body DeviceTokenRequest true "Token request"
+// @Success 200 {object} DeviceTokenResponse
+// @Failure 400 {object} DeviceTokenError
+// @Failure 403 {object} DeviceTokenError
+// @Router /auth/device/token [post]
+func (h *DeviceHandler) DeviceToken(c *gin.Context) {
+	var req DeviceTokenRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, DeviceTokenError{Error: "invalid_request", Description: err.Error()})
+		return
+	}
+
+	var (
+		token *domain.Token
+		err   error
+	)
+	switch req.GrantType {
+	case grantTypeDeviceCode:
+		if req.DeviceCode == "" {
+			c.JSON(http.StatusBadRequest, DeviceTokenError{Error: "invalid_request", Description: "device_code is required"})
+			return
+		}
+		token, err = h.deviceUseCase.PollDeviceToken(req.DeviceCode, clientInfo(c))
+	case grantTypeRefreshToken:
+		if req.RefreshToken == "" {
+			c.JSON(http.StatusBadRequest, DeviceTokenError{Error: "invalid_request", Description: "refresh_token is required"})
+			return
+		}
+		token, err = h.authUseCase.RefreshToken(req.RefreshToken, clientInfo(c))
+		if err != nil && !errors.Is(err, domain.ErrUserBanned) {
+			// Unknown, expired and reused refresh tokens alike
+			c.JSON(http.StatusBadRequest, DeviceTokenError{Error: "invalid_grant", Description: err.Error()})
+			return
+		}
+	default:
+		c.JSON(http.StatusBadRequest, DeviceTokenError{Error: "unsupported_grant_type", Description: "unsupported grant type"})
+		return
+	}
+	if err != nil {
+		status, code := deviceTokenError(err)
+		c.JSON(status, DeviceTokenError{Error: code, Description: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, DeviceTokenResponse{
+		AccessToken:  token.AccessToken,
+		RefreshToken: token.RefreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(time.Until(token.AccessTokenExpiresAt).Seconds()),
+	})
+}
+
+// GetDeviceAuthorization handles looking up a device before approving it
+// @Summary Get Device Authorization
+// @Description Describes the device waiting for a user code so the user can check it is theirs
+// @Tags device
+// @Produce json
+// @Security BearerAuth
+// @Param user_code query string true "User code shown by the device"
+// @Success 200 {object} DeviceAuthorizationResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /auth/device [get]
+func (h *DeviceHandler) GetDeviceAuthorization(c *gin.Context) {
+	userCode := c.Query("user_code")
+	if userCode == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_code is required"})
+		return
+	}
+
+	authorization, err := h.deviceUseCase.GetDeviceAuthorization(userCode)
+	if err != nil {
+		c.JSON(deviceDecisionStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, DeviceAuthorizationResponse{
+		UserAgent: authorization.Client.UserAgent,
+		IPAddress: authorization.Client.IPAddress,
+		CreatedAt: authorization.CreatedAt,
+		ExpiresAt: authorization.ExpiresAt,
+	})
+}
+
+// ApproveDevice handles a user approving a device
+// @Summary Approve Device
+// @Description Signs the device showing the user code in as the authenticated user. Its session is listed at /auth/sessions.
+// @Tags device
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body UserCodeRequest true "User code shown by the device"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /auth/device/approve [post]
+func (h *DeviceHandler) ApproveDevice(c *gin.Context) {
+	user, _ := CurrentUser(c)
+
+	var req UserCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.deviceUseCase.ApproveDevice(user.ID, req.UserCode); err != nil {
+		c.JSON(deviceDecisionStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Device approved"})
+}
+
+// DenyDevice handles a user refusing a device
+// @Summary Deny Device
+// @Description Refuses the sign-in of the device showing the user code
+// @Tags device
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body UserCodeRequest true "User code shown by the device"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /auth/device/deny [post]
+func (h *DeviceHandler) DenyDevice(c *gin.Context) {
+	user, _ := CurrentUser(c)
+
+	var req UserCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.deviceUseCase.DenyDevice(user.ID, req.UserCode); err != nil {
+		c.JSON(deviceDecisionStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Device denied"})
+}
+
+// deviceTokenError maps token endpoint errors to HTTP status codes and RFC 8628 error codes
+func deviceTokenError(err error) (int, string) {
+	switch {
+	case errors.Is(err, domain.ErrDeviceAuthorizationPending):
+		return http.StatusBadRequest, "authorization_pending"
+	case errors.Is(err, domain.ErrDeviceSlowDown):
+		return http.StatusBadRequest, "slow_down"
+	case errors.Is(err, domain.ErrDeviceAuthorizationDenied):
+		return http.StatusBadRequest, "access_denied"
+	case errors.Is(err, domain.ErrDeviceAuthorizationExpired):
+		return http.StatusBadRequest, "expired_token"
+	case errors.Is(err, domain.ErrUserBanned):
+		return http.StatusForbidden, "access_denied"
+	case errors.Is(err, domain.ErrDeviceAuthorizationNotFound):
+		return http.StatusBadRequest, "invalid_grant"
+	default:
+		return http.StatusInternalServerError, "server_error"
+	}
+}
+
+// deviceDecisionStatus maps errors of looking up, approving and denying devices to HTTP status codes
+func deviceDecisionStatus(err error) int {
+	switch {
+	case errors.Is(err, domain.ErrDeviceAuthorizationNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, domain.ErrDeviceAuthorizationDecided):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// DeviceTokenRequest is a token request of a device, form encoded as in RFC 6749 or JSON
+type DeviceTokenRequest struct {
+	GrantType    string `form:"grant_type" json:"grant_type" binding:"required"`
+	DeviceCode   string `form:"device_code" json:"device_code"`
+	RefreshToken string `form:"refresh_token" json:"refresh_token"`
+}
+
+// DeviceCodeResponse is the device authorization response of RFC 8628
+type DeviceCodeResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+// DeviceTokenResponse carries the tokens of a device, the refresh token is never put in a cookie
+type DeviceTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+// DeviceTokenError is an OAuth 2.0 error response
+type DeviceTokenError struct {
+	Error       string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+// DeviceAuthorizationResponse describes a device waiting for approval
+type DeviceAuthorizationResponse struct {
+	UserAgent string    `json:"user_agent,omitempty"`
+	IPAddress string    `json:"ip_address,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// UserCodeRequest carries the user code a device shows
+type UserCodeRequest struct {
+	UserCode string `json:"user_code" binding:"required"`
+}
@@ -0,0 +1,20 @@
+package http
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// SetupDeviceRoutes configures the device authorization grant routes
+func SetupDeviceRoutes(r *gin.Engine, h *DeviceHandler, m *AuthMiddleware) {
+	device := r.Group("/api/v1/auth/device")
+	{
+		// Called by the device
+		device.POST("/code", h.StartDeviceAuthorization)
+		device.POST("/token", h.DeviceToken)
+
+		// Called by the browser of the signed-in user
+		device.GET("", m.RequireAuth(), h.GetDeviceAuthorization)
+		device.POST("/approve", m.RequireAuth(), h.ApproveDevice)
+		device.POST("/deny", m.RequireAuth(), h.DenyDevice)
+	}
+}
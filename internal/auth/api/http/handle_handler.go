@@ -0,0 +1,136 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/algosim/backend/internal/auth/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+// HandleHandler handles HTTP requests for linking judge handles
+type HandleHandler struct {
+	handleUseCase *usecase.HandleUseCase
+}
+
+// NewHandleHandler creates a new HandleHandler instance
+func NewHandleHandler(handleUseCase *usecase.HandleUseCase) *HandleHandler {
+	return &HandleHandler{
+		handleUseCase: handleUseCase,
+	}
+}
+
+// ClaimHandle handles claiming a judge handle
+// @Summary Claim Handle
+// @Description Starts linking a Codeforces or AtCoder handle and returns the task that proves ownership
+// @Tags handles
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param judge path string true "Judge (codeforces or atcoder)"
+// @Param handle body ClaimHandleRequest true "Handle to link"
+// @Success 201 {object} HandleVerificationResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /auth/handles/{judge} [post]
+func (h *HandleHandler) ClaimHandle(c *gin.Context) {
+	user, _ := CurrentUser(c)
+
+	var req ClaimHandleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	verification, err := h.handleUseCase.ClaimHandle(user.ID, c.Param("judge"), req.Handle)
+	if err != nil {
+		c.JSON(handleErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, newHandleVerificationResponse(verification))
+}
+
+// VerifyHandle handles checking a handle verification task
+// @Summary Verify Handle
+// @Description Checks the pending verification task with the judge and links the handle once it is done
+// @Tags handles
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param judge path string true "Judge (codeforces or atcoder)"
+// @Success 200 {object} UserResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /auth/handles/{judge}/verify [post]
+func (h *HandleHandler) VerifyHandle(c *gin.Context) {
+	user, _ := CurrentUser(c)
+
+	updated, err := h.handleUseCase.VerifyHandle(user.ID, c.Param("judge"))
+	if err != nil {
+		c.JSON(handleErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, newUserResponse(updated))
+}
+
+// handleErrorStatus maps handle linking errors to HTTP status codes
+func handleErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, domain.ErrJudgeNotSupported),
+		errors.Is(err, domain.ErrInvalidHandle),
+		errors.Is(err, domain.ErrHandleNotFound):
+		return http.StatusBadRequest
+	case errors.Is(err, domain.ErrHandleVerificationNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, domain.ErrHandleVerificationExpired):
+		return http.StatusGone
+	case errors.Is(err, domain.ErrHandleVerificationIncomplete):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// newHandleVerificationResponse describes the verification task to the user
+func newHandleVerificationResponse(v *domain.HandleVerification) HandleVerificationResponse {
+	resp := HandleVerificationResponse{
+		Judge:     v.Judge,
+		Handle:    v.Handle,
+		ExpiresAt: v.ExpiresAt,
+	}
+
+	switch v.Judge {
+	case domain.JudgeCodeforces:
+		resp.ProblemURL = fmt.Sprintf("https://codeforces.com/problemset/problem/%d/%s", v.ContestID, v.ProblemIndex)
+		resp.Task = fmt.Sprintf("Submit a solution that fails with Compilation error to problem %d%s before %s",
+			v.ContestID, v.ProblemIndex, v.ExpiresAt.UTC().Format(time.RFC3339))
+	case domain.JudgeAtCoder:
+		resp.Token = v.Token
+		resp.Task = fmt.Sprintf("Put %s in the Affiliation field of your AtCoder profile before %s",
+			v.Token, v.ExpiresAt.UTC().Format(time.RFC3339))
+	}
+
+	return resp
+}
+
+// ClaimHandleRequest represents a request to link a judge handle
+type ClaimHandleRequest struct {
+	Handle string `json:"handle" binding:"required"`
+}
+
+// HandleVerificationResponse represents the task that proves ownership of a handle
+type HandleVerificationResponse struct {
+	Judge      string    `json:"judge"`
+	Handle     string    `json:"handle"`
+	Task       string    `json:"task"`
+	ProblemURL string    `json:"problem_url,omitempty"`
+	Token      string    `json:"token,omitempty"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
@@ -0,0 +1,14 @@
+package http
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// SetupHandleRoutes configures the judge handle linking routes
+func SetupHandleRoutes(r *gin.Engine, h *HandleHandler, m *AuthMiddleware) {
+	handles := r.Group("/api/v1/auth/handles", m.RequireAuth())
+	{
+		handles.POST("/:judge", h.ClaimHandle)
+		handles.POST("/:judge/verify", h.VerifyHandle)
+	}
+}
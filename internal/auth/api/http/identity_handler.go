@@ -0,0 +1,125 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// InitiateOAuthLink handles starting to link a provider account
+// @Summary Initiate OAuth Link
+// @Description Returns the login URL of the requested OAuth provider. Its callback links the provider account to the signed-in user instead of signing in.
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Param provider query string true "OAuth provider (e.g., google, github)"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /auth/oauth/link [get]
+func (h *AuthHandler) InitiateOAuthLink(c *gin.Context) {
+	user, _ := CurrentUser(c)
+
+	login, err := h.authUseCase.InitiateOAuthLink(c.Request.Context(), user.ID, c.Query("provider"))
+	if errors.Is(err, domain.ErrOAuthProviderNotSupported) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported provider"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.setOAuthStateCookie(c, login)
+	c.JSON(http.StatusOK, gin.H{
+		"auth_url": login.AuthURL,
+	})
+}
+
+// ListIdentities handles listing the linked provider accounts
+// @Summary List Identities
+// @Description Lists the provider accounts linked to the authenticated user
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} IdentityResponse
+// @Failure 401 {object} map[string]string
+// @Router /auth/identities [get]
+func (h *AuthHandler) ListIdentities(c *gin.Context) {
+	user, _ := CurrentUser(c)
+
+	identities, err := h.authUseCase.ListIdentities(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp := make([]IdentityResponse, 0, len(identities))
+	for _, identity := range identities {
+		resp = append(resp, newIdentityResponse(identity))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// UnlinkIdentity handles unlinking a provider account
+// @Summary Unlink Identity
+// @Description Unlinks a provider account from the authenticated user. The last way to sign in cannot be unlinked.
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Identity ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /auth/identities/{id} [delete]
+func (h *AuthHandler) UnlinkIdentity(c *gin.Context) {
+	user, _ := CurrentUser(c)
+
+	identityID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid identity id"})
+		return
+	}
+
+	err = h.authUseCase.UnlinkIdentity(user.ID, identityID)
+	switch {
+	case errors.Is(err, domain.ErrIdentityNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, domain.ErrLastSignInMethod):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Identity unlinked"})
+}
+
+func newIdentityResponse(identity *domain.Identity) IdentityResponse {
+	return IdentityResponse{
+		ID:            identity.ID,
+		Provider:      identity.Provider,
+		Email:         identity.Email,
+		EmailVerified: identity.EmailVerified,
+		CreatedAt:     identity.CreatedAt,
+		LastUsedAt:    identity.LastUsedAt,
+	}
+}
+
+// IdentityResponse represents a linked provider account
+type IdentityResponse struct {
+	ID            uuid.UUID `json:"id"`
+	Provider      string    `json:"provider"`
+	Email         string    `json:"email,omitempty"`
+	EmailVerified bool      `json:"email_verified"`
+	CreatedAt     time.Time `json:"created_at"`
+	LastUsedAt    time.Time `json:"last_used_at"`
+}
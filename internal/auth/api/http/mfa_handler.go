@@ -0,0 +1,220 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/algosim/backend/internal/auth/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+// GetMFAStatus handles reading the MFA settings
+// @Summary Get MFA Status
+// @Description Returns whether MFA is enabled for the authenticated user and how many recovery codes are left
+// @Tags mfa
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} MFAStatusResponse
+// @Failure 401 {object} map[string]string
+// @Router /auth/mfa [get]
+func (h *AuthHandler) GetMFAStatus(c *gin.Context) {
+	user, _ := CurrentUser(c)
+
+	status, err := h.authUseCase.GetMFAStatus(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, MFAStatusResponse{
+		Enabled:                status.Enabled,
+		RecoveryCodesRemaining: status.RecoveryCodesRemaining,
+		EnabledAt:              status.EnabledAt,
+	})
+}
+
+// EnrollMFA handles starting the enrollment of an authenticator
+// @Summary Enroll MFA
+// @Description Creates a TOTP secret for the authenticated user. Show the otpauth URI as QR code, MFA is enabled once /auth/mfa/confirm receives a first code.
+// @Tags mfa
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} MFAEnrollmentResponse
+// @Failure 401 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /auth/mfa/enroll [post]
+func (h *AuthHandler) EnrollMFA(c *gin.Context) {
+	user, _ := CurrentUser(c)
+
+	enrollment, err := h.authUseCase.EnrollMFA(user.ID)
+	if err != nil {
+		c.JSON(mfaErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, MFAEnrollmentResponse{
+		Secret:          enrollment.Secret,
+		ProvisioningURI: enrollment.ProvisioningURI,
+	})
+}
+
+// ConfirmMFA handles enabling MFA with a first code
+// @Summary Confirm MFA
+// @Description Enables MFA with a code of the enrolled authenticator and returns the one-time recovery codes. They are not shown again.
+// @Tags mfa
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param code body MFACodeRequest true "TOTP code"
+// @Success 200 {object} RecoveryCodesResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Router /auth/mfa/confirm [post]
+func (h *AuthHandler) ConfirmMFA(c *gin.Context) {
+	user, _ := CurrentUser(c)
+
+	var req MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	codes, err := h.authUseCase.ConfirmMFA(user.ID, req.Code)
+	if err != nil {
+		c.JSON(mfaErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// DisableMFA handles turning MFA off
+// @Summary Disable MFA
+// @Description Disables MFA after checking a TOTP or recovery code
+// @Tags mfa
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param code body MFACodeRequest true "TOTP or recovery code"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Router /auth/mfa [delete]
+func (h *AuthHandler) DisableMFA(c *gin.Context) {
+	user, _ := CurrentUser(c)
+
+	var req MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.authUseCase.DisableMFA(user.ID, req.Code); err != nil {
+		c.JSON(mfaErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "MFA disabled"})
+}
+
+// VerifyMFA handles the second factor of a sign-in
+// @Summary Verify MFA
+// @Description Completes a login that answered with mfa_required by posting its mfa_token with a TOTP or recovery code
+// @Tags mfa
+// @Accept json
+// @Produce json
+// @Param verification body MFAVerifyRequest true "Pending token and code"
+// @Success 200 {object} TokenResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Router /auth/mfa/verify [post]
+func (h *AuthHandler) VerifyMFA(c *gin.Context) {
+	var req MFAVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	token, err := h.authUseCase.VerifyMFA(req.MFAToken, req.Code, clientInfo(c))
+	if err != nil {
+		c.JSON(mfaErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	h.respondWithToken(c, http.StatusOK, token)
+}
+
+// respondWithMFAPending tells the client the sign-in needs its second factor
+func respondWithMFAPending(c *gin.Context, pending *usecase.MFAPending) {
+	c.JSON(http.StatusAccepted, MFARequiredResponse{
+		MFARequired: true,
+		MFAToken:    pending.Token,
+		ExpiresAt:   pending.ExpiresAt,
+	})
+}
+
+// mfaErrorStatus maps MFA errors to HTTP status codes
+func mfaErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, domain.ErrInvalidMFACode),
+		errors.Is(err, domain.ErrMFAChallengeNotFound):
+		return http.StatusUnauthorized
+	case errors.Is(err, domain.ErrMFALocked):
+		return http.StatusTooManyRequests
+	case errors.Is(err, domain.ErrMFANotEnrolled),
+		errors.Is(err, domain.ErrUserNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, domain.ErrMFAAlreadyEnabled),
+		errors.Is(err, domain.ErrMFAChanged):
+		return http.StatusConflict
+	case errors.Is(err, domain.ErrUserBanned):
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// MFACodeRequest carries a TOTP or recovery code
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// MFAVerifyRequest answers the MFA challenge of a sign-in
+type MFAVerifyRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// MFARequiredResponse is returned by logins that still need the second factor
+type MFARequiredResponse struct {
+	MFARequired bool      `json:"mfa_required"`
+	MFAToken    string    `json:"mfa_token"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// MFAStatusResponse represents the MFA settings of a user
+type MFAStatusResponse struct {
+	Enabled                bool       `json:"enabled"`
+	RecoveryCodesRemaining int        `json:"recovery_codes_remaining"`
+	EnabledAt              *time.Time `json:"enabled_at,omitempty"`
+}
+
+// MFAEnrollmentResponse is what an authenticator app needs to enroll
+type MFAEnrollmentResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"otpauth_uri"`
+}
+
+// RecoveryCodesResponse lists one-time recovery codes
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
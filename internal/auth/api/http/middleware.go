@@ -0,0 +1,181 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/algosim/backend/internal/auth/infrastructure/jwt"
+	"github.com/algosim/backend/internal/auth/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// Gin context keys of the authenticated request
+	currentUserKey   = "auth.current_user"
+	currentClaimsKey = "auth.current_claims"
+
+	bearerRealm = "api"
+)
+
+var (
+	errNoBearerToken        = errors.New("no bearer token provided")
+	errMalformedBearerToken = errors.New("malformed authorization header")
+)
+
+// AuthMiddleware authenticates requests carrying a bearer access token
+type AuthMiddleware struct {
+	authUseCase *usecase.AuthUseCase
+}
+
+// NewAuthMiddleware creates a new AuthMiddleware instance
+func NewAuthMiddleware(authUseCase *usecase.AuthUseCase) *AuthMiddleware {
+	return &AuthMiddleware{
+		authUseCase: authUseCase,
+	}
+}
+
+// RequireAuth rejects requests without a valid bearer token
+func (m *AuthMiddleware) RequireAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !m.authenticate(c) {
+			return
+		}
+		c.Next()
+	}
+}
+
+// OptionalAuth lets anonymous requests through but still rejects invalid bearer tokens
+func (m *AuthMiddleware) OptionalAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			c.Next()
+			return
+		}
+		if !m.authenticate(c) {
+			return
+		}
+		c.Next()
+	}
+}
+
+// authenticate validates the bearer token and stores the user and claims in the context,
+// aborting with 401 when the token is missing or invalid
+func (m *AuthMiddleware) authenticate(c *gin.Context) bool {
+	tokenString, err := parseBearerToken(c.GetHeader("Authorization"))
+	if err != nil {
+		abortUnauthorized(c, err)
+		return false
+	}
+
+	user, claims, err := m.authUseCase.Authenticate(tokenString)
+	if err != nil {
+		abortUnauthorized(c, err)
+		return false
+	}
+
+	c.Set(currentUserKey, user)
+	c.Set(currentClaimsKey, claims)
+	return true
+}
+
+// parseBearerToken extracts the token of an RFC 6750 "Bearer <token>" authorization header
+func parseBearerToken(header string) (string, error) {
+	if header == "" {
+		return "", errNoBearerToken
+	}
+
+	scheme, token, found := strings.Cut(header, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", errMalformedBearerToken
+	}
+
+	// token68 characters only, which also rules out extra spaces
+	for i, r := range token {
+		switch {
+		case r >= 'A' && r <= 'Z', r >= 'a' && r <= 'z', r >= '0' && r <= '9',
+			r == '-', r == '.', r == '_', r == '~', r == '+', r == '/':
+		case r == '=' && i > 0:
+			if strings.TrimRight(token[i:], "=") != "" {
+				return "", errMalformedBearerToken
+			}
+			return token, nil
+		default:
+			return "", errMalformedBearerToken
+		}
+	}
+
+	return token, nil
+}
+
+// abortUnauthorized writes the uniform 401 response with its WWW-Authenticate challenge
+func abortUnauthorized(c *gin.Context, err error) {
+	challenge := fmt.Sprintf("Bearer realm=%q", bearerRealm)
+	if !errors.Is(err, errNoBearerToken) {
+		challenge += `, error="invalid_token"`
+	}
+
+	c.Header("WWW-Authenticate", challenge)
+	c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+}
+
+// CurrentUser returns the user authenticated by the middleware
+func CurrentUser(c *gin.Context) (*domain.User, bool) {
+	value, exists := c.Get(currentUserKey)
+	if !exists {
+		return nil, false
+	}
+	user, ok := value.(*domain.User)
+	return user, ok
+}
+
+// CurrentClaims returns the access token claims verified by the middleware
+func CurrentClaims(c *gin.Context) (*jwt.Claims, bool) {
+	value, exists := c.Get(currentClaimsKey)
+	if !exists {
+		return nil, false
+	}
+	claims, ok := value.(*jwt.Claims)
+	return claims, ok
+}
+
+// RequirePermission rejects requests whose user lacks permission, it must run after RequireAuth.
+// The permission has to be in the access token and still granted to the stored user,
+// so a revoked role stops working before the token expires.
+func (m *AuthMiddleware) RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, userOK := CurrentUser(c)
+		claims, claimsOK := CurrentClaims(c)
+		if !userOK || !claimsOK {
+			abortUnauthorized(c, errNoBearerToken)
+			return
+		}
+
+		if !slices.Contains(claims.Permissions, permission) || !user.HasPermission(permission) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "missing permission " + permission})
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireRole rejects requests whose user lacks role, it must run after RequireAuth
+func (m *AuthMiddleware) RequireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, userOK := CurrentUser(c)
+		claims, claimsOK := CurrentClaims(c)
+		if !userOK || !claimsOK {
+			abortUnauthorized(c, errNoBearerToken)
+			return
+		}
+
+		if !slices.Contains(claims.Roles, role) || !user.HasRole(role) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "missing role " + role})
+			return
+		}
+		c.Next()
+	}
+}
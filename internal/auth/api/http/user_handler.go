@@ -0,0 +1,276 @@
+package http
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/algosim/backend/internal/auth/usecase"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// UserHandler handles HTTP requests for user profiles
+type UserHandler struct {
+	userUseCase *usecase.UserUseCase
+	authUseCase *usecase.AuthUseCase
+}
+
+// NewUserHandler creates a new UserHandler instance
+func NewUserHandler(userUseCase *usecase.UserUseCase, authUseCase *usecase.AuthUseCase) *UserHandler {
+	return &UserHandler{
+		userUseCase: userUseCase,
+		authUseCase: authUseCase,
+	}
+}
+
+// GetUser handles reading a public profile
+// @Summary Get User
+// @Description Returns the public profile of a user
+// @Tags users
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} PublicUserResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /users/{id} [get]
+func (h *UserHandler) GetUser(c *gin.Context) {
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	user, err := h.userUseCase.GetUser(userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, newPublicUserResponse(user))
+}
+
+// GetMe handles reading the caller's own profile
+// @Summary Get Own Profile
+// @Description Returns the full profile of the authenticated user
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} UserResponse
+// @Failure 401 {object} map[string]string
+// @Router /users/me [get]
+func (h *UserHandler) GetMe(c *gin.Context) {
+	user, _ := CurrentUser(c)
+	c.JSON(http.StatusOK, newUserResponse(user))
+}
+
+// UpdateMe handles updating the caller's own profile
+// @Summary Update Own Profile
+// @Description Updates the given profile fields, omitted fields are left alone and an empty string clears a field
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param profile body UpdateProfileRequest true "Profile fields to change"
+// @Success 200 {object} UserResponse
+// @Failure 400 {object} FieldErrorResponse
+// @Failure 401 {object} map[string]string
+// @Failure 409 {object} FieldErrorResponse
+// @Router /users/me [patch]
+func (h *UserHandler) UpdateMe(c *gin.Context) {
+	user, _ := CurrentUser(c)
+
+	var req UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updated, err := h.userUseCase.UpdateProfile(user.ID, domain.ProfileUpdate{
+		Handle:         req.Handle,
+		DisplayName:    req.DisplayName,
+		Timezone:       req.Timezone,
+		PreferredJudge: req.PreferredJudge,
+	})
+	if err != nil {
+		var fieldErr *domain.FieldError
+		switch {
+		case errors.As(err, &fieldErr):
+			status := http.StatusBadRequest
+			if errors.Is(err, domain.ErrHandleTaken) {
+				status = http.StatusConflict
+			}
+			c.JSON(status, FieldErrorResponse{Error: fieldErr.Err.Error(), Field: fieldErr.Field})
+		case errors.Is(err, domain.ErrUserNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, newUserResponse(updated))
+}
+
+// DeleteMe handles deleting the caller's own account
+// @Summary Delete Own Account
+// @Description Schedules the account for deletion after a grace period and signs out every session. Signing in again during the grace period cancels the deletion. Password accounts confirm with their password, OAuth-only accounts must have signed in within the last few minutes.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param confirmation body DeleteAccountRequest false "Current password, required for password accounts"
+// @Success 202 {object} DeleteAccountResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /users/me [delete]
+func (h *UserHandler) DeleteMe(c *gin.Context) {
+	user, _ := CurrentUser(c)
+	claims, _ := CurrentClaims(c)
+
+	var req DeleteAccountRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	deleted, err := h.authUseCase.RequestAccountDeletion(user.ID, claims.SessionID, req.Password)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, domain.ErrInvalidCredentials) || errors.Is(err, domain.ErrReauthenticationRequired) {
+			// Not 401, the access token itself is fine
+			status = http.StatusForbidden
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, DeleteAccountResponse{DeletionScheduledAt: *deleted.DeletionScheduledAt})
+}
+
+// ExportMe handles exporting everything stored about the caller
+// @Summary Export Own Data
+// @Description Returns everything stored about the authenticated user as one JSON document, or as a ZIP archive with one JSON file per module
+// @Tags users
+// @Produce json
+// @Produce application/zip
+// @Security BearerAuth
+// @Param format query string false "json (default) or zip"
+// @Success 200 {object} usecase.UserDataExport
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /users/me/export [get]
+func (h *UserHandler) ExportMe(c *gin.Context) {
+	user, _ := CurrentUser(c)
+
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "zip" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or zip"})
+		return
+	}
+
+	export, err := h.userUseCase.ExportUserData(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	filename := "algosim-export-" + export.ExportedAt.UTC().Format("20060102")
+	if format == "json" {
+		c.Header("Content-Disposition", `attachment; filename="`+filename+`.json"`)
+		c.JSON(http.StatusOK, export)
+		return
+	}
+
+	archive, err := zipExport(export)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`.zip"`)
+	c.Data(http.StatusOK, "application/zip", archive)
+}
+
+// zipExport packs the export as user.json plus one <module>.json per module
+func zipExport(export *usecase.UserDataExport) ([]byte, error) {
+	files := map[string]any{"user.json": export.User}
+	for name, data := range export.Modules {
+		files[name+".json"] = data
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range names {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: export.ExportedAt})
+		if err != nil {
+			return nil, err
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(files[name]); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func newPublicUserResponse(user *domain.User) PublicUserResponse {
+	return PublicUserResponse{
+		ID:               user.ID,
+		Handle:           user.Handle,
+		DisplayName:      user.DisplayName,
+		CodeforcesHandle: user.CodeforcesHandle,
+		AtcoderHandle:    user.AtcoderHandle,
+		CreatedAt:        user.CreatedAt,
+	}
+}
+
+// PublicUserResponse represents the profile anyone may see
+type PublicUserResponse struct {
+	ID               uuid.UUID `json:"id"`
+	Handle           string    `json:"handle,omitempty"`
+	DisplayName      string    `json:"display_name,omitempty"`
+	CodeforcesHandle string    `json:"codeforces_handle,omitempty"`
+	AtcoderHandle    string    `json:"atcoder_handle,omitempty"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// UpdateProfileRequest holds the profile fields to change
+type UpdateProfileRequest struct {
+	Handle         *string `json:"handle"`
+	DisplayName    *string `json:"display_name"`
+	Timezone       *string `json:"timezone" example:"Europe/Berlin"`
+	PreferredJudge *string `json:"preferred_judge" example:"codeforces"`
+}
+
+// DeleteAccountRequest confirms an account deletion
+type DeleteAccountRequest struct {
+	Password string `json:"password"`
+}
+
+// DeleteAccountResponse tells when the account will be deleted
+type DeleteAccountResponse struct {
+	DeletionScheduledAt time.Time `json:"deletion_scheduled_at"`
+}
+
+// FieldErrorResponse reports which field of the request was rejected
+type FieldErrorResponse struct {
+	Error string `json:"error"`
+	Field string `json:"field"`
+}
@@ -0,0 +1,17 @@
+package http
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// SetupUserRoutes configures the user profile routes
+func SetupUserRoutes(r *gin.Engine, h *UserHandler, m *AuthMiddleware) {
+	users := r.Group("/api/v1/users")
+	{
+		users.GET("/me", m.RequireAuth(), h.GetMe)
+		users.PATCH("/me", m.RequireAuth(), h.UpdateMe)
+		users.DELETE("/me", m.RequireAuth(), h.DeleteMe)
+		users.GET("/me/export", m.RequireAuth(), h.ExportMe)
+		users.GET("/:id", h.GetUser)
+	}
+}
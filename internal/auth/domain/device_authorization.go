@@ -0,0 +1,60 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DeviceAuthorizationStatus is where a device authorization is in its approval
+type DeviceAuthorizationStatus string
+
+// Device authorization statuses
+const (
+	// DeviceAuthorizationPending waits for the user to enter the user code
+	DeviceAuthorizationPending DeviceAuthorizationStatus = "pending"
+	// DeviceAuthorizationApproved lets the next poll of the device sign the approving user in
+	DeviceAuthorizationApproved DeviceAuthorizationStatus = "approved"
+	// DeviceAuthorizationDenied makes the next poll of the device fail
+	DeviceAuthorizationDenied DeviceAuthorizationStatus = "denied"
+)
+
+// DeviceAuthorization is a sign-in of a device without a browser (RFC 8628). The device polls
+// with its device code while the user approves the user code in a signed-in browser.
+type DeviceAuthorization struct {
+	ID uuid.UUID
+	// DeviceCodeHash is the hash of the device code the device polls with
+	DeviceCodeHash string
+	// UserCode is the short code the user types in the browser, stored normalized
+	UserCode string
+	Status   DeviceAuthorizationStatus
+	// UserID is the user who approved or denied the device
+	UserID uuid.UUID
+	// Interval is how long the device has to wait between polls, raised when it polls too fast
+	Interval   time.Duration
+	LastPollAt *time.Time
+	// Client is the device as seen when it asked for the codes, its session gets this metadata
+	Client    ClientInfo
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+// NewDeviceAuthorization creates a pending device authorization that expires after ttl
+func NewDeviceAuthorization(deviceCodeHash, userCode string, interval, ttl time.Duration, client ClientInfo) *DeviceAuthorization {
+	now := time.Now()
+	return &DeviceAuthorization{
+		ID:             uuid.New(),
+		DeviceCodeHash: deviceCodeHash,
+		UserCode:       userCode,
+		Status:         DeviceAuthorizationPending,
+		Interval:       interval,
+		Client:         client,
+		ExpiresAt:      now.Add(ttl),
+		CreatedAt:      now,
+	}
+}
+
+// IsExpired reports whether the device authorization can no longer be approved or polled at now
+func (a *DeviceAuthorization) IsExpired(now time.Time) bool {
+	return !now.Before(a.ExpiresAt)
+}
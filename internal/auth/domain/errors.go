@@ -6,24 +6,147 @@ var (
 	// ErrUserNotFound is returned when a user cannot be found
 	ErrUserNotFound = errors.New("user not found")
 
+	// ErrUserBanned is returned when a banned user tries to sign in or use a token
+	ErrUserBanned = errors.New("user banned")
+
+	// ErrInvalidRole is returned when a role is unknown
+	ErrInvalidRole = errors.New("invalid role")
+
+	// ErrSelfModification is returned when an admin tries to demote or ban themselves
+	ErrSelfModification = errors.New("cannot change your own admin access")
+
+	// ErrReauthenticationRequired is returned when a sensitive action needs a fresh sign-in
+	ErrReauthenticationRequired = errors.New("re-authentication required")
+
 	// ErrUserAlreadyExists is returned when trying to create a user that already exists
 	ErrUserAlreadyExists = errors.New("user already exists")
 
 	// ErrInvalidCredentials is returned when login credentials are invalid
 	ErrInvalidCredentials = errors.New("invalid credentials")
 
+	// ErrInvalidEmail is returned when an email address is malformed
+	ErrInvalidEmail = errors.New("invalid email")
+
+	// ErrWeakPassword is returned when a password does not meet the strength rules
+	ErrWeakPassword = errors.New("weak password")
+
 	// ErrTokenNotFound is returned when a token cannot be found
 	ErrTokenNotFound = errors.New("token not found")
 
 	// ErrTokenExpired is returned when a token has expired
 	ErrTokenExpired = errors.New("token expired")
 
+	// ErrRefreshTokenReused is returned when a superseded refresh token is presented again
+	ErrRefreshTokenReused = errors.New("refresh token reused")
+
+	// ErrSessionNotFound is returned when a session does not exist or belongs to another user
+	ErrSessionNotFound = errors.New("session not found")
+
+	// ErrTokenRevoked is returned when an access token was revoked before it expired
+	ErrTokenRevoked = errors.New("token revoked")
+
 	// ErrInvalidToken is returned when a token is invalid
 	ErrInvalidToken = errors.New("invalid token")
 
+	// ErrMFANotEnrolled is returned when the user has not enrolled an authenticator
+	ErrMFANotEnrolled = errors.New("mfa not enrolled")
+
+	// ErrMFAAlreadyEnabled is returned when enrolling or confirming while MFA is already enabled
+	ErrMFAAlreadyEnabled = errors.New("mfa already enabled")
+
+	// ErrInvalidMFACode is returned when a TOTP or recovery code is wrong or already used
+	ErrInvalidMFACode = errors.New("invalid mfa code")
+
+	// ErrMFALocked is returned while code checks are locked after too many wrong codes
+	ErrMFALocked = errors.New("too many invalid mfa codes, try again later")
+
+	// ErrMFAChallengeNotFound is returned when an mfa pending token is unknown or has expired
+	ErrMFAChallengeNotFound = errors.New("mfa challenge not found or expired")
+
+	// ErrMFAChanged is returned when the authenticator changed since it was read, e.g. by a
+	// concurrent code check or reset
+	ErrMFAChanged = errors.New("mfa changed concurrently, try again")
+
 	// ErrOAuthProviderNotSupported is returned when the OAuth provider is not supported
 	ErrOAuthProviderNotSupported = errors.New("oauth provider not supported")
 
 	// ErrOAuthCallbackFailed is returned when OAuth callback fails
 	ErrOAuthCallbackFailed = errors.New("oauth callback failed")
+
+	// ErrIdentityNotFound is returned when a linked identity does not exist or belongs to another user
+	ErrIdentityNotFound = errors.New("identity not found")
+
+	// ErrIdentityAlreadyLinked is returned when a provider account is already linked to a user
+	ErrIdentityAlreadyLinked = errors.New("identity already linked")
+
+	// ErrLastSignInMethod is returned when unlinking would leave a user without a way to sign in
+	ErrLastSignInMethod = errors.New("cannot unlink the last sign-in method")
+
+	// ErrEmailNotVerified is returned when a provider account would take over an account by an
+	// email address the provider has not verified
+	ErrEmailNotVerified = errors.New("email not verified by provider, sign in and link the account instead")
+
+	// ErrAccountEmailNotVerified is returned when a provider account would take over an account
+	// whose own email address was never verified, someone else may have registered it
+	ErrAccountEmailNotVerified = errors.New("account email not verified, sign in and link the account instead")
+
+	// ErrOAuthStateMissing is returned when the OAuth callback carries no state or no state cookie
+	ErrOAuthStateMissing = errors.New("oauth state missing")
+
+	// ErrOAuthStateMismatch is returned when the OAuth state does not match the one bound to the browser
+	ErrOAuthStateMismatch = errors.New("oauth state mismatch")
+
+	// ErrOAuthStateExpired is returned when the OAuth state is unknown or has expired
+	ErrOAuthStateExpired = errors.New("oauth state expired")
+
+	// ErrOAuthStateReplayed is returned when the OAuth state has already been used
+	ErrOAuthStateReplayed = errors.New("oauth state already used")
+
+	// ErrInvalidIDToken is returned when the OpenID Connect ID token of a login fails verification
+	ErrInvalidIDToken = errors.New("invalid id token")
+
+	// ErrJudgeNotSupported is returned when the online judge is not supported
+	ErrJudgeNotSupported = errors.New("judge not supported")
+
+	// ErrInvalidHandle is returned when a judge handle is malformed
+	ErrInvalidHandle = errors.New("invalid handle")
+
+	// ErrHandleTaken is returned when another user already goes by a profile handle
+	ErrHandleTaken = errors.New("handle already taken")
+
+	// ErrInvalidDisplayName is returned when a display name is too long or has control characters
+	ErrInvalidDisplayName = errors.New("invalid display name")
+
+	// ErrInvalidTimezone is returned when a timezone is not an IANA time zone name
+	ErrInvalidTimezone = errors.New("invalid timezone")
+
+	// ErrHandleNotFound is returned when the judge does not know the handle
+	ErrHandleNotFound = errors.New("handle not found on judge")
+
+	// ErrHandleVerificationNotFound is returned when the user has no pending handle verification
+	ErrHandleVerificationNotFound = errors.New("handle verification not found")
+
+	// ErrHandleVerificationExpired is returned when the verification task was not completed in time
+	ErrHandleVerificationExpired = errors.New("handle verification expired")
+
+	// ErrHandleVerificationIncomplete is returned when the judge does not show the verification task as done
+	ErrHandleVerificationIncomplete = errors.New("handle verification task not completed")
+
+	// ErrDeviceAuthorizationNotFound is returned when a device or user code is unknown or expired
+	ErrDeviceAuthorizationNotFound = errors.New("device authorization not found")
+
+	// ErrDeviceAuthorizationPending is returned when the device polls before the user decided
+	ErrDeviceAuthorizationPending = errors.New("device authorization pending")
+
+	// ErrDeviceSlowDown is returned when the device polls faster than its interval
+	ErrDeviceSlowDown = errors.New("device polling too fast")
+
+	// ErrDeviceAuthorizationDenied is returned when the user denied the device
+	ErrDeviceAuthorizationDenied = errors.New("device authorization denied")
+
+	// ErrDeviceAuthorizationExpired is returned when the device polls after its codes expired
+	ErrDeviceAuthorizationExpired = errors.New("device authorization expired")
+
+	// ErrDeviceAuthorizationDecided is returned when a user code was approved or denied already
+	ErrDeviceAuthorizationDecided = errors.New("device authorization already decided")
 )
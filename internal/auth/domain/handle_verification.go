@@ -0,0 +1,46 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Online judges a user can link a handle from
+const (
+	JudgeCodeforces = "codeforces"
+	JudgeAtCoder    = "atcoder"
+)
+
+// HandleVerification represents a pending claim of a judge handle and the task that proves ownership
+type HandleVerification struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+	Judge  string
+	Handle string
+	// Codeforces task: submit a compilation error to this problem
+	ContestID    int
+	ProblemIndex string
+	// AtCoder task: put this token in the affiliation field
+	Token     string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+// NewHandleVerification creates a new HandleVerification that expires after ttl
+func NewHandleVerification(userID uuid.UUID, judge, handle string, ttl time.Duration) *HandleVerification {
+	now := time.Now()
+	return &HandleVerification{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Judge:     judge,
+		Handle:    handle,
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	}
+}
+
+// IsExpired reports whether the task can no longer be completed at the given time
+func (v *HandleVerification) IsExpired(now time.Time) bool {
+	return !now.Before(v.ExpiresAt)
+}
@@ -0,0 +1,36 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Identity is an external account a user signs in with, one user can link several
+type Identity struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+	// Provider and Subject identify the account at the provider, the pair is unique
+	Provider string
+	Subject  string
+	// Email is the address the provider reported on the last sign-in
+	Email         string
+	EmailVerified bool
+	CreatedAt     time.Time
+	LastUsedAt    time.Time
+}
+
+// NewIdentity creates a new Identity of userID
+func NewIdentity(userID uuid.UUID, provider, subject, email string, emailVerified bool) *Identity {
+	now := time.Now()
+	return &Identity{
+		ID:            uuid.New(),
+		UserID:        userID,
+		Provider:      provider,
+		Subject:       subject,
+		Email:         email,
+		EmailVerified: emailVerified,
+		CreatedAt:     now,
+		LastUsedAt:    now,
+	}
+}
@@ -0,0 +1,73 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MFA is the TOTP authenticator of a user. It is enrolled unconfirmed and only guards sign-in
+// once the user proved the authenticator works by sending a first code.
+type MFA struct {
+	UserID uuid.UUID
+	// Secret is the base32 TOTP secret shared with the authenticator app
+	Secret string
+	// LastUsedStep is the time step of the last accepted code, codes up to it are refused
+	LastUsedStep int64
+	// RecoveryCodeHashes are the hashes of the unused one-time recovery codes
+	RecoveryCodeHashes []string
+	// FailedAttempts counts wrong codes in a row, reaching the limit locks code checks
+	FailedAttempts int
+	LockedUntil    *time.Time
+	ConfirmedAt    *time.Time
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// NewMFA creates an unconfirmed MFA enrollment with secret
+func NewMFA(userID uuid.UUID, secret string) *MFA {
+	now := time.Now()
+	return &MFA{
+		UserID:    userID,
+		Secret:    secret,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+// IsEnabled reports whether sign-in requires a code
+func (m *MFA) IsEnabled() bool {
+	return m.ConfirmedAt != nil
+}
+
+// IsLocked reports whether code checks are locked after too many wrong codes at now
+func (m *MFA) IsLocked(now time.Time) bool {
+	return m.LockedUntil != nil && now.Before(*m.LockedUntil)
+}
+
+// MFAChallenge is a sign-in that passed the first factor and waits for a code
+type MFAChallenge struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+	// TokenHash is the hash of the pending token the client answers the challenge with
+	TokenHash string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+// NewMFAChallenge creates a challenge of user that expires after ttl
+func NewMFAChallenge(userID uuid.UUID, tokenHash string, ttl time.Duration) *MFAChallenge {
+	now := time.Now()
+	return &MFAChallenge{
+		ID:        uuid.New(),
+		UserID:    userID,
+		TokenHash: tokenHash,
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	}
+}
+
+// IsExpired reports whether the challenge can no longer be answered at now
+func (c *MFAChallenge) IsExpired(now time.Time) bool {
+	return !now.Before(c.ExpiresAt)
+}
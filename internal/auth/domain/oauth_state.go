@@ -0,0 +1,45 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OAuthState represents a pending OAuth login started by a browser
+type OAuthState struct {
+	State    string
+	Provider string
+	// CodeVerifier is the PKCE secret sent on code exchange, never to the browser
+	CodeVerifier string
+	// Nonce is echoed back in the ID token of OpenID Connect providers
+	Nonce string
+	// LinkUserID is set when a signed-in user links the provider account instead of signing in
+	LinkUserID uuid.UUID
+	ExpiresAt  time.Time
+	CreatedAt  time.Time
+	ConsumedAt *time.Time
+}
+
+// NewOAuthState creates a new OAuthState that expires after ttl
+func NewOAuthState(state, provider, codeVerifier, nonce string, ttl time.Duration) *OAuthState {
+	now := time.Now()
+	return &OAuthState{
+		State:        state,
+		Provider:     provider,
+		CodeVerifier: codeVerifier,
+		Nonce:        nonce,
+		ExpiresAt:    now.Add(ttl),
+		CreatedAt:    now,
+	}
+}
+
+// IsLink reports whether the state links an identity to a signed-in user
+func (s *OAuthState) IsLink() bool {
+	return s.LinkUserID != uuid.Nil
+}
+
+// IsExpired reports whether the state is no longer usable at the given time
+func (s *OAuthState) IsExpired(now time.Time) bool {
+	return !now.Before(s.ExpiresAt)
+}
@@ -0,0 +1,29 @@
+package domain
+
+// ProfileUpdate holds the profile fields a user wants to change.
+// Nil fields are left alone and an empty string clears the field.
+type ProfileUpdate struct {
+	Handle         *string
+	DisplayName    *string
+	Timezone       *string
+	PreferredJudge *string
+}
+
+// FieldError ties a validation error to the request field that caused it
+type FieldError struct {
+	Field string
+	Err   error
+}
+
+// NewFieldError creates a new FieldError
+func NewFieldError(field string, err error) *FieldError {
+	return &FieldError{Field: field, Err: err}
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + ": " + e.Err.Error()
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
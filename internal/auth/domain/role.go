@@ -0,0 +1,66 @@
+package domain
+
+import "sort"
+
+// Roles a user can hold
+const (
+	RoleUser  = "user"
+	RoleCoach = "coach"
+	RoleAdmin = "admin"
+)
+
+// Permissions granted through roles, named <resource>:<action>
+const (
+	PermissionProblemsRead  = "problems:read"
+	PermissionProblemsWrite = "problems:write"
+	PermissionGroupsRead    = "groups:read"
+	PermissionGroupsWrite   = "groups:write"
+	PermissionUsersRead     = "users:read"
+	PermissionUsersWrite    = "users:write"
+	PermissionRolesWrite    = "roles:write"
+)
+
+// rolePermissions lists what each role is allowed to do
+var rolePermissions = map[string][]string{
+	RoleUser: {
+		PermissionProblemsRead,
+		PermissionGroupsRead,
+	},
+	RoleCoach: {
+		PermissionProblemsRead,
+		PermissionGroupsRead,
+		PermissionGroupsWrite,
+	},
+	RoleAdmin: {
+		PermissionProblemsRead,
+		PermissionProblemsWrite,
+		PermissionGroupsRead,
+		PermissionGroupsWrite,
+		PermissionUsersRead,
+		PermissionUsersWrite,
+		PermissionRolesWrite,
+	},
+}
+
+// IsValidRole reports whether role is a known role
+func IsValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// PermissionsForRoles returns the sorted union of the permissions of roles
+func PermissionsForRoles(roles []string) []string {
+	set := make(map[string]struct{})
+	for _, role := range roles {
+		for _, permission := range rolePermissions[role] {
+			set[permission] = struct{}{}
+		}
+	}
+
+	permissions := make([]string, 0, len(set))
+	for permission := range set {
+		permissions = append(permissions, permission)
+	}
+	sort.Strings(permissions)
+	return permissions
+}
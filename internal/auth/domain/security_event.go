@@ -0,0 +1,61 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Security event types
+const (
+	// SecurityEventRefreshTokenReuse is emitted when a superseded refresh token is presented again
+	SecurityEventRefreshTokenReuse = "refresh_token_reuse"
+	// SecurityEventAdminBootstrapped is emitted when the configured bootstrap admin is promoted
+	SecurityEventAdminBootstrapped = "admin_bootstrapped"
+	// SecurityEventRoleGranted and SecurityEventRoleRevoked are emitted when an admin changes roles
+	SecurityEventRoleGranted = "role_granted"
+	SecurityEventRoleRevoked = "role_revoked"
+	// SecurityEventUserBanned and SecurityEventUserUnbanned are emitted when an admin changes a ban
+	SecurityEventUserBanned   = "user_banned"
+	SecurityEventUserUnbanned = "user_unbanned"
+	// SecurityEventAccountDeletionRequested and SecurityEventAccountDeletionCancelled are emitted when
+	// a user schedules the deletion of their account or signs in again during the grace period
+	SecurityEventAccountDeletionRequested = "account_deletion_requested"
+	SecurityEventAccountDeletionCancelled = "account_deletion_cancelled"
+	// SecurityEventIdentityLinked and SecurityEventIdentityUnlinked are emitted when a provider
+	// account is linked to or unlinked from a user
+	SecurityEventIdentityLinked   = "identity_linked"
+	SecurityEventIdentityUnlinked = "identity_unlinked"
+	// SecurityEventMFAEnabled and SecurityEventMFADisabled are emitted when a user turns MFA on or off,
+	// SecurityEventMFAReset when an admin removes the authenticator of a user
+	SecurityEventMFAEnabled  = "mfa_enabled"
+	SecurityEventMFADisabled = "mfa_disabled"
+	SecurityEventMFAReset    = "mfa_reset"
+	// SecurityEventMFALocked is emitted when too many wrong codes lock the code checks of a user
+	SecurityEventMFALocked = "mfa_locked"
+	// SecurityEventRecoveryCodeUsed is emitted when a recovery code stands in for a TOTP code
+	SecurityEventRecoveryCodeUsed = "mfa_recovery_code_used"
+	// SecurityEventDeviceApproved and SecurityEventDeviceDenied are emitted when a user decides on
+	// the sign-in of a device
+	SecurityEventDeviceApproved = "device_approved"
+	SecurityEventDeviceDenied   = "device_denied"
+)
+
+// SecurityEvent records a security relevant occurrence for auditing and alerting
+type SecurityEvent struct {
+	Type       string
+	UserID     uuid.UUID
+	FamilyID   uuid.UUID
+	OccurredAt time.Time
+	Details    map[string]string
+}
+
+// NewSecurityEvent creates a new SecurityEvent that occurred now
+func NewSecurityEvent(eventType string, userID uuid.UUID) *SecurityEvent {
+	return &SecurityEvent{
+		Type:       eventType,
+		UserID:     userID,
+		OccurredAt: time.Now(),
+		Details:    make(map[string]string),
+	}
+}
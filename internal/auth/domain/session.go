@@ -0,0 +1,51 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// maxUserAgentLength bounds the user agent stored with a session
+const maxUserAgentLength = 512
+
+// ClientInfo describes the client a token is issued to
+type ClientInfo struct {
+	UserAgent string
+	IPAddress string
+}
+
+// NewClientInfo creates a ClientInfo, truncating overly long user agents
+func NewClientInfo(userAgent, ipAddress string) ClientInfo {
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+	return ClientInfo{
+		UserAgent: userAgent,
+		IPAddress: ipAddress,
+	}
+}
+
+// Session is one signed-in device, backed by the newest token of a token family
+type Session struct {
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	UserAgent  string
+	IPAddress  string
+	CreatedAt  time.Time
+	LastUsedAt time.Time
+	ExpiresAt  time.Time
+}
+
+// NewSessionFromToken describes the session a token belongs to
+func NewSessionFromToken(token *Token) *Session {
+	return &Session{
+		ID:         token.FamilyID,
+		UserID:     token.UserID,
+		UserAgent:  token.UserAgent,
+		IPAddress:  token.IPAddress,
+		CreatedAt:  token.SessionStartedAt,
+		LastUsedAt: token.LastUsedAt,
+		ExpiresAt:  token.ExpiresAt,
+	}
+}
@@ -8,23 +8,54 @@ import (
 
 // Token represents the refresh token entity in the domain
 type Token struct {
-	ID           uuid.UUID
-	UserID       uuid.UUID
-	AccessToken  string
+	ID     uuid.UUID
+	UserID uuid.UUID
+	// FamilyID is shared by every token rotated from the same login
+	FamilyID    uuid.UUID
+	AccessToken string
+	// AccessTokenID is the jti of AccessToken, kept so the access token can be revoked
+	AccessTokenID        string
+	AccessTokenExpiresAt time.Time
+	// RefreshToken is only known when the token is issued, repositories never store it
 	RefreshToken string
-	ExpiresAt    time.Time
-	CreatedAt    time.Time
+	// RefreshTokenHash is the keyed hash repositories store and look tokens up by
+	RefreshTokenHash string
+	ExpiresAt        time.Time
+	CreatedAt        time.Time
+	// RotatedAt is set once the token has been exchanged for its successor
+	RotatedAt *time.Time
+
+	// Client metadata of the session, carried over and refreshed on rotation
+	UserAgent        string
+	IPAddress        string
+	SessionStartedAt time.Time
+	LastUsedAt       time.Time
 }
 
 // NewToken creates a new Token instance with default values
-func NewToken(userID uuid.UUID, accessToken string, refreshToken string, expiresAt time.Time) *Token {
+func NewToken(userID uuid.UUID, accessToken, refreshToken, refreshTokenHash string, expiresAt time.Time) *Token {
 	now := time.Now()
+	id := uuid.New()
 	return &Token{
-		ID:           uuid.New(),
-		UserID:       userID,
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-		ExpiresAt:    expiresAt,
-		CreatedAt:    now,
+		ID:               id,
+		FamilyID:         id,
+		UserID:           userID,
+		AccessToken:      accessToken,
+		RefreshToken:     refreshToken,
+		RefreshTokenHash: refreshTokenHash,
+		ExpiresAt:        expiresAt,
+		CreatedAt:        now,
+		SessionStartedAt: now,
+		LastUsedAt:       now,
 	}
 }
+
+// IsRotated reports whether the token has been superseded by a newer one in its family
+func (t *Token) IsRotated() bool {
+	return t.RotatedAt != nil
+}
+
+// IsActive reports whether the token is the live head of its session at now
+func (t *Token) IsActive(now time.Time) bool {
+	return !t.IsRotated() && now.Before(t.ExpiresAt)
+}
@@ -8,26 +8,104 @@ import (
 
 // User represents the core user entity in the domain
 type User struct {
-	ID               uuid.UUID
-	Email            string
+	ID    uuid.UUID
+	Email string
+	// Handle is the public name of the user on the site, unique ignoring case
+	Handle         string
+	DisplayName    string
+	Timezone       string
+	PreferredJudge string
+	// Judge handles are only set once the user proved they own them
 	CodeforcesHandle string
 	AtcoderHandle    string
-	OAuthProvider    string
-	OAuthProviderID  string
-	CreatedAt        time.Time
-	UpdatedAt        time.Time
+	// PasswordHash is empty for accounts that only sign in through OAuth
+	PasswordHash string
+	Roles        []string
+	// BannedAt is set while an admin has banned the user
+	BannedAt *time.Time
+	// DeletionScheduledAt is when the account will be deleted, signing in before cancels it
+	DeletionScheduledAt *time.Time
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
 }
 
-// NewUser creates a new User instance with default values
-func NewUser(email, oauthProvider, oauthProviderID string) *User {
+// NewUser creates a new User instance with default values.
+// Provider accounts the user signs in with are linked as Identity.
+func NewUser(email string) *User {
 	now := time.Now()
 	return &User{
-		ID:              uuid.New(),
-		Email:           email,
-		OAuthProvider:   oauthProvider,
-		OAuthProviderID: oauthProviderID,
-		CreatedAt:       now,
-		UpdatedAt:       now,
+		ID:        uuid.New(),
+		Email:     email,
+		Roles:     []string{RoleUser},
+		CreatedAt: now,
+		UpdatedAt: now,
 	}
+}
+
+// HasRole reports whether the user holds role
+func (u *User) HasRole(role string) bool {
+	for _, r := range u.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
 
+// AddRole grants role, reporting whether the user did not hold it yet
+func (u *User) AddRole(role string) bool {
+	if u.HasRole(role) {
+		return false
+	}
+	u.Roles = append(u.Roles, role)
+	return true
+}
+
+// RemoveRole revokes role, reporting whether the user held it
+func (u *User) RemoveRole(role string) bool {
+	for i, r := range u.Roles {
+		if r == role {
+			u.Roles = append(u.Roles[:i:i], u.Roles[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Permissions returns what the roles of the user allow
+func (u *User) Permissions() []string {
+	return PermissionsForRoles(u.Roles)
+}
+
+// HasPermission reports whether one of the roles of the user grants permission
+func (u *User) HasPermission(permission string) bool {
+	for _, p := range u.Permissions() {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// IsBanned reports whether the user is banned
+func (u *User) IsBanned() bool {
+	return u.BannedAt != nil
+}
+
+// IsDeletionScheduled reports whether the user asked for their account to be deleted
+func (u *User) IsDeletionScheduled() bool {
+	return u.DeletionScheduledAt != nil
+}
+
+// VerifiedHandles returns the verified judge handles keyed by judge.
+// Submission tracking must only follow handles returned here.
+func (u *User) VerifiedHandles() map[string]string {
+	handles := make(map[string]string)
+	if u.CodeforcesHandle != "" {
+		handles[JudgeCodeforces] = u.CodeforcesHandle
+	}
+	if u.AtcoderHandle != "" {
+		handles[JudgeAtCoder] = u.AtcoderHandle
+	}
+	return handles
 }
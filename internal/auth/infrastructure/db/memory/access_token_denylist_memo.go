@@ -0,0 +1,52 @@
+package memory
+
+import (
+	"sync"
+	"time"
+
+	"github.com/algosim/backend/internal/auth/repository"
+)
+
+// AccessTokenDenylistMemo implements AccessTokenDenylist interface using in-memory storage
+type AccessTokenDenylistMemo struct {
+	entries map[string]time.Time
+	mu      sync.RWMutex
+}
+
+// NewAccessTokenDenylistMemo creates a new in-memory access token denylist
+func NewAccessTokenDenylistMemo() *AccessTokenDenylistMemo {
+	return &AccessTokenDenylistMemo{
+		entries: make(map[string]time.Time),
+	}
+}
+
+// Add stores a revoked jti and drops the entries whose tokens have expired. Revoking a jti again
+// never shortens its revocation.
+func (r *AccessTokenDenylistMemo) Add(jti string, expiresAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for key, exp := range r.entries {
+		if !now.Before(exp) {
+			delete(r.entries, key)
+		}
+	}
+
+	if now.Before(expiresAt) && expiresAt.After(r.entries[jti]) {
+		r.entries[jti] = expiresAt
+	}
+	return nil
+}
+
+// Contains reports whether a jti is revoked and its token not yet expired
+func (r *AccessTokenDenylistMemo) Contains(jti string) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	expiresAt, exists := r.entries[jti]
+	return exists && time.Now().Before(expiresAt), nil
+}
+
+// Ensure AccessTokenDenylistMemo implements AccessTokenDenylist interface
+var _ repository.AccessTokenDenylist = (*AccessTokenDenylistMemo)(nil)
@@ -0,0 +1,204 @@
+package memory
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/algosim/backend/internal/auth/repository"
+	"github.com/google/uuid"
+)
+
+// expiredDeviceAuthorizationRetention is how long expired device authorizations are kept so
+// polling devices are told their codes expired rather than unknown
+const expiredDeviceAuthorizationRetention = time.Hour
+
+// DeviceAuthorizationRepoMemo implements DeviceAuthorizationRepository interface using in-memory storage
+type DeviceAuthorizationRepoMemo struct {
+	authorizations map[uuid.UUID]*domain.DeviceAuthorization
+	mu             sync.RWMutex
+}
+
+// NewDeviceAuthorizationRepoMemo creates a new in-memory device authorization repository
+func NewDeviceAuthorizationRepoMemo() *DeviceAuthorizationRepoMemo {
+	return &DeviceAuthorizationRepoMemo{
+		authorizations: make(map[uuid.UUID]*domain.DeviceAuthorization),
+	}
+}
+
+// Create stores a new device authorization and drops the ones that expired long ago.
+// User codes must be unique among the unexpired authorizations.
+func (r *DeviceAuthorizationRepoMemo) Create(authorization *domain.DeviceAuthorization) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for id, a := range r.authorizations {
+		if a.IsExpired(now.Add(-expiredDeviceAuthorizationRetention)) {
+			delete(r.authorizations, id)
+		}
+	}
+
+	if _, exists := r.authorizations[authorization.ID]; exists {
+		return fmt.Errorf("device authorization already exists")
+	}
+	for _, a := range r.authorizations {
+		if a.DeviceCodeHash == authorization.DeviceCodeHash {
+			return fmt.Errorf("device code hash already exists")
+		}
+		if a.UserCode == authorization.UserCode && !a.IsExpired(now) {
+			return fmt.Errorf("user code already in use")
+		}
+	}
+
+	r.authorizations[authorization.ID] = copyDeviceAuthorization(authorization)
+	return nil
+}
+
+// FindByDeviceCodeHash retrieves a device authorization by the hash of its device code
+func (r *DeviceAuthorizationRepoMemo) FindByDeviceCodeHash(deviceCodeHash string) (*domain.DeviceAuthorization, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, a := range r.authorizations {
+		if a.DeviceCodeHash == deviceCodeHash {
+			return copyDeviceAuthorization(a), nil
+		}
+	}
+
+	return nil, domain.ErrDeviceAuthorizationNotFound
+}
+
+// FindByUserCode retrieves an unexpired device authorization by its user code
+func (r *DeviceAuthorizationRepoMemo) FindByUserCode(userCode string) (*domain.DeviceAuthorization, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	now := time.Now()
+	for _, a := range r.authorizations {
+		if a.UserCode == userCode && !a.IsExpired(now) {
+			return copyDeviceAuthorization(a), nil
+		}
+	}
+
+	return nil, domain.ErrDeviceAuthorizationNotFound
+}
+
+// FindByUserID retrieves the device authorizations a user decided
+func (r *DeviceAuthorizationRepoMemo) FindByUserID(userID uuid.UUID) ([]*domain.DeviceAuthorization, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var authorizations []*domain.DeviceAuthorization
+	for _, a := range r.authorizations {
+		if a.UserID == userID {
+			authorizations = append(authorizations, copyDeviceAuthorization(a))
+		}
+	}
+
+	return authorizations, nil
+}
+
+// UpdatePoll records a poll of a device authorization
+func (r *DeviceAuthorizationRepoMemo) UpdatePoll(id uuid.UUID, lastPollAt time.Time, interval time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	a, exists := r.authorizations[id]
+	if !exists {
+		return domain.ErrDeviceAuthorizationNotFound
+	}
+
+	a.LastPollAt = &lastPollAt
+	a.Interval = interval
+	return nil
+}
+
+// Decide records the decision on a device authorization that is still pending
+func (r *DeviceAuthorizationRepoMemo) Decide(id uuid.UUID, status domain.DeviceAuthorizationStatus, userID uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	a, exists := r.authorizations[id]
+	if !exists || a.Status != domain.DeviceAuthorizationPending {
+		return domain.ErrDeviceAuthorizationNotFound
+	}
+
+	a.Status = status
+	a.UserID = userID
+	return nil
+}
+
+// Delete removes a device authorization
+func (r *DeviceAuthorizationRepoMemo) Delete(id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.authorizations[id]; !exists {
+		return domain.ErrDeviceAuthorizationNotFound
+	}
+
+	delete(r.authorizations, id)
+	return nil
+}
+
+// DeleteByUserID removes every device authorization a user decided
+func (r *DeviceAuthorizationRepoMemo) DeleteByUserID(userID uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, a := range r.authorizations {
+		if a.UserID == userID {
+			delete(r.authorizations, id)
+		}
+	}
+
+	return nil
+}
+
+// deleteUserRecords removes the device authorizations a deleted user decided
+func (r *DeviceAuthorizationRepoMemo) deleteUserRecords(userID uuid.UUID, undo *undoLog) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, a := range r.authorizations {
+		if a.UserID == userID {
+			delete(r.authorizations, id)
+			undo.add(func() {
+				r.mu.Lock()
+				defer r.mu.Unlock()
+
+				if _, exists := r.authorizations[id]; !exists {
+					r.authorizations[id] = a
+				}
+			})
+		}
+	}
+}
+
+// Redeem removes a device authorization that still has status
+func (r *DeviceAuthorizationRepoMemo) Redeem(id uuid.UUID, status domain.DeviceAuthorizationStatus) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if a, exists := r.authorizations[id]; !exists || a.Status != status {
+		return domain.ErrDeviceAuthorizationNotFound
+	}
+
+	delete(r.authorizations, id)
+	return nil
+}
+
+// copyDeviceAuthorization returns a deep copy so callers cannot modify stored authorizations
+func copyDeviceAuthorization(a *domain.DeviceAuthorization) *domain.DeviceAuthorization {
+	c := *a
+	if a.LastPollAt != nil {
+		lastPollAt := *a.LastPollAt
+		c.LastPollAt = &lastPollAt
+	}
+	return &c
+}
+
+// Ensure DeviceAuthorizationRepoMemo implements DeviceAuthorizationRepository interface
+var _ repository.DeviceAuthorizationRepository = (*DeviceAuthorizationRepoMemo)(nil)
@@ -0,0 +1,119 @@
+package memory
+
+import (
+	"sync"
+
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/algosim/backend/internal/auth/repository"
+	"github.com/google/uuid"
+)
+
+// HandleVerificationRepoMemo implements HandleVerificationRepository interface using in-memory storage
+type HandleVerificationRepoMemo struct {
+	verifications map[uuid.UUID]*domain.HandleVerification
+	mu            sync.RWMutex
+}
+
+// NewHandleVerificationRepoMemo creates a new in-memory handle verification repository
+func NewHandleVerificationRepoMemo() *HandleVerificationRepoMemo {
+	return &HandleVerificationRepoMemo{
+		verifications: make(map[uuid.UUID]*domain.HandleVerification),
+	}
+}
+
+// Create stores a new verification and drops the user's previous one for the same judge
+func (r *HandleVerificationRepoMemo) Create(verification *domain.HandleVerification) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, v := range r.verifications {
+		if v.UserID == verification.UserID && v.Judge == verification.Judge {
+			delete(r.verifications, id)
+		}
+	}
+
+	stored := *verification
+	r.verifications[verification.ID] = &stored
+	return nil
+}
+
+// FindByUserAndJudge retrieves the user's pending verification for a judge
+func (r *HandleVerificationRepoMemo) FindByUserAndJudge(userID uuid.UUID, judge string) (*domain.HandleVerification, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, v := range r.verifications {
+		if v.UserID == userID && v.Judge == judge {
+			found := *v
+			return &found, nil
+		}
+	}
+
+	return nil, domain.ErrHandleVerificationNotFound
+}
+
+// FindByUserID retrieves the pending verifications of a user
+func (r *HandleVerificationRepoMemo) FindByUserID(userID uuid.UUID) ([]*domain.HandleVerification, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var verifications []*domain.HandleVerification
+	for _, v := range r.verifications {
+		if v.UserID == userID {
+			found := *v
+			verifications = append(verifications, &found)
+		}
+	}
+
+	return verifications, nil
+}
+
+// Delete removes a verification
+func (r *HandleVerificationRepoMemo) Delete(id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.verifications[id]; !exists {
+		return domain.ErrHandleVerificationNotFound
+	}
+
+	delete(r.verifications, id)
+	return nil
+}
+
+// DeleteByUserID removes every verification of a user
+func (r *HandleVerificationRepoMemo) DeleteByUserID(userID uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, v := range r.verifications {
+		if v.UserID == userID {
+			delete(r.verifications, id)
+		}
+	}
+
+	return nil
+}
+
+// deleteUserRecords removes the verifications of a deleted user
+func (r *HandleVerificationRepoMemo) deleteUserRecords(userID uuid.UUID, undo *undoLog) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, v := range r.verifications {
+		if v.UserID == userID {
+			delete(r.verifications, id)
+			undo.add(func() {
+				r.mu.Lock()
+				defer r.mu.Unlock()
+
+				if _, exists := r.verifications[id]; !exists {
+					r.verifications[id] = v
+				}
+			})
+		}
+	}
+}
+
+// Ensure HandleVerificationRepoMemo implements HandleVerificationRepository interface
+var _ repository.HandleVerificationRepository = (*HandleVerificationRepoMemo)(nil)
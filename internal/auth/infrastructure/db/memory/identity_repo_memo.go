@@ -0,0 +1,179 @@
+package memory
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/algosim/backend/internal/auth/repository"
+	"github.com/google/uuid"
+)
+
+// IdentityRepoMemo implements IdentityRepository interface using in-memory storage
+type IdentityRepoMemo struct {
+	identities map[uuid.UUID]*domain.Identity
+	mu         sync.RWMutex
+	// units is locked by a running unit, writes outside units wait for it
+	units sync.RWMutex
+}
+
+// NewIdentityRepoMemo creates a new in-memory identity repository
+func NewIdentityRepoMemo() *IdentityRepoMemo {
+	return &IdentityRepoMemo{
+		identities: make(map[uuid.UUID]*domain.Identity),
+	}
+}
+
+// Create stores a new identity
+func (r *IdentityRepoMemo) Create(identity *domain.Identity) error {
+	r.units.RLock()
+	defer r.units.RUnlock()
+
+	return r.create(identity)
+}
+
+// create is Create for writes inside a unit
+func (r *IdentityRepoMemo) create(identity *domain.Identity) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.identities {
+		if existing.ID == identity.ID ||
+			(existing.Provider == identity.Provider && existing.Subject == identity.Subject) {
+			return domain.ErrIdentityAlreadyLinked
+		}
+	}
+
+	stored := *identity
+	r.identities[identity.ID] = &stored
+	return nil
+}
+
+// FindByID retrieves an identity by ID
+func (r *IdentityRepoMemo) FindByID(id uuid.UUID) (*domain.Identity, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	identity, exists := r.identities[id]
+	if !exists {
+		return nil, domain.ErrIdentityNotFound
+	}
+
+	found := *identity
+	return &found, nil
+}
+
+// FindByProviderSubject retrieves the identity of an account at a provider
+func (r *IdentityRepoMemo) FindByProviderSubject(provider, subject string) (*domain.Identity, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, identity := range r.identities {
+		if identity.Provider == provider && identity.Subject == subject {
+			found := *identity
+			return &found, nil
+		}
+	}
+
+	return nil, domain.ErrIdentityNotFound
+}
+
+// FindByUserID retrieves the identities of a user, oldest first
+func (r *IdentityRepoMemo) FindByUserID(userID uuid.UUID) ([]*domain.Identity, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var identities []*domain.Identity
+	for _, identity := range r.identities {
+		if identity.UserID == userID {
+			found := *identity
+			identities = append(identities, &found)
+		}
+	}
+	sort.Slice(identities, func(i, j int) bool {
+		return identities[i].CreatedAt.Before(identities[j].CreatedAt)
+	})
+
+	return identities, nil
+}
+
+// Update updates an existing identity
+func (r *IdentityRepoMemo) Update(identity *domain.Identity) error {
+	r.units.RLock()
+	defer r.units.RUnlock()
+
+	return r.update(identity)
+}
+
+// update is Update for writes inside a unit
+func (r *IdentityRepoMemo) update(identity *domain.Identity) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.identities[identity.ID]; !exists {
+		return domain.ErrIdentityNotFound
+	}
+
+	stored := *identity
+	r.identities[identity.ID] = &stored
+	return nil
+}
+
+// Delete removes an identity
+func (r *IdentityRepoMemo) Delete(id uuid.UUID) error {
+	r.units.RLock()
+	defer r.units.RUnlock()
+
+	return r.remove(id)
+}
+
+// remove is Delete for writes inside a unit
+func (r *IdentityRepoMemo) remove(id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.identities[id]; !exists {
+		return domain.ErrIdentityNotFound
+	}
+
+	delete(r.identities, id)
+	return nil
+}
+
+// DeleteByUserID removes every identity of a user
+func (r *IdentityRepoMemo) DeleteByUserID(userID uuid.UUID) error {
+	r.units.RLock()
+	defer r.units.RUnlock()
+
+	return r.removeByUserID(userID)
+}
+
+// removeByUserID is DeleteByUserID for writes inside a unit
+func (r *IdentityRepoMemo) removeByUserID(userID uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, identity := range r.identities {
+		if identity.UserID == userID {
+			delete(r.identities, id)
+		}
+	}
+
+	return nil
+}
+
+// deleteUserRecords removes the identities of a deleted user
+func (r *IdentityRepoMemo) deleteUserRecords(userID uuid.UUID, undo *undoLog) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, identity := range r.identities {
+		if identity.UserID == userID {
+			delete(r.identities, id)
+			undo.add(func() { r.restore(id, identity) })
+		}
+	}
+}
+
+// Ensure IdentityRepoMemo implements IdentityRepository interface
+var _ repository.IdentityRepository = (*IdentityRepoMemo)(nil)
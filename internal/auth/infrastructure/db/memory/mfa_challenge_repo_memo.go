@@ -0,0 +1,132 @@
+package memory
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/algosim/backend/internal/auth/repository"
+	"github.com/google/uuid"
+)
+
+// MFAChallengeRepoMemo implements MFAChallengeRepository interface using in-memory storage
+type MFAChallengeRepoMemo struct {
+	challenges map[uuid.UUID]*domain.MFAChallenge
+	mu         sync.RWMutex
+	// units is locked by a running unit, writes outside units wait for it
+	units sync.RWMutex
+}
+
+// NewMFAChallengeRepoMemo creates a new in-memory MFA challenge repository
+func NewMFAChallengeRepoMemo() *MFAChallengeRepoMemo {
+	return &MFAChallengeRepoMemo{
+		challenges: make(map[uuid.UUID]*domain.MFAChallenge),
+	}
+}
+
+// Create stores a new challenge and drops the ones that have expired
+func (r *MFAChallengeRepoMemo) Create(challenge *domain.MFAChallenge) error {
+	r.units.RLock()
+	defer r.units.RUnlock()
+
+	return r.create(challenge)
+}
+
+// create is Create for writes inside a unit
+func (r *MFAChallengeRepoMemo) create(challenge *domain.MFAChallenge) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for id, c := range r.challenges {
+		if c.IsExpired(now) {
+			delete(r.challenges, id)
+		}
+	}
+
+	for id, c := range r.challenges {
+		if id == challenge.ID || c.TokenHash == challenge.TokenHash {
+			return fmt.Errorf("mfa challenge already exists")
+		}
+	}
+
+	stored := *challenge
+	r.challenges[challenge.ID] = &stored
+	return nil
+}
+
+// FindByTokenHash retrieves an unexpired challenge by the hash of its pending token
+func (r *MFAChallengeRepoMemo) FindByTokenHash(tokenHash string) (*domain.MFAChallenge, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	now := time.Now()
+	for _, c := range r.challenges {
+		if c.TokenHash == tokenHash && !c.IsExpired(now) {
+			found := *c
+			return &found, nil
+		}
+	}
+
+	return nil, domain.ErrMFAChallengeNotFound
+}
+
+// Delete removes a challenge
+func (r *MFAChallengeRepoMemo) Delete(id uuid.UUID) error {
+	r.units.RLock()
+	defer r.units.RUnlock()
+
+	return r.remove(id)
+}
+
+// remove is Delete for writes inside a unit
+func (r *MFAChallengeRepoMemo) remove(id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.challenges[id]; !exists {
+		return domain.ErrMFAChallengeNotFound
+	}
+
+	delete(r.challenges, id)
+	return nil
+}
+
+// DeleteByUserID removes every challenge of a user
+func (r *MFAChallengeRepoMemo) DeleteByUserID(userID uuid.UUID) error {
+	r.units.RLock()
+	defer r.units.RUnlock()
+
+	return r.removeByUserID(userID)
+}
+
+// removeByUserID is DeleteByUserID for writes inside a unit
+func (r *MFAChallengeRepoMemo) removeByUserID(userID uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, c := range r.challenges {
+		if c.UserID == userID {
+			delete(r.challenges, id)
+		}
+	}
+
+	return nil
+}
+
+// deleteUserRecords removes the challenges of a deleted user
+func (r *MFAChallengeRepoMemo) deleteUserRecords(userID uuid.UUID, undo *undoLog) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, c := range r.challenges {
+		if c.UserID == userID {
+			delete(r.challenges, id)
+			undo.add(func() { r.restore(id, c) })
+		}
+	}
+}
+
+// Ensure MFAChallengeRepoMemo implements MFAChallengeRepository interface
+var _ repository.MFAChallengeRepository = (*MFAChallengeRepoMemo)(nil)
@@ -0,0 +1,97 @@
+package memory
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/algosim/backend/internal/auth/repository"
+	"github.com/google/uuid"
+)
+
+// MFARepoMemo implements MFARepository interface using in-memory storage
+type MFARepoMemo struct {
+	authenticators map[uuid.UUID]*domain.MFA
+	mu             sync.RWMutex
+}
+
+// NewMFARepoMemo creates a new in-memory MFA repository
+func NewMFARepoMemo() *MFARepoMemo {
+	return &MFARepoMemo{
+		authenticators: make(map[uuid.UUID]*domain.MFA),
+	}
+}
+
+// Save stores the authenticator of a user
+func (r *MFARepoMemo) Save(mfa *domain.MFA) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.authenticators[mfa.UserID] = copyMFA(mfa)
+	return nil
+}
+
+// FindByUserID retrieves the authenticator of a user
+func (r *MFARepoMemo) FindByUserID(userID uuid.UUID) (*domain.MFA, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	mfa, exists := r.authenticators[userID]
+	if !exists {
+		return nil, domain.ErrMFANotEnrolled
+	}
+	return copyMFA(mfa), nil
+}
+
+// CompareAndSwap replaces the authenticator of a user while it is unchanged since old was read
+func (r *MFARepoMemo) CompareAndSwap(old, mfa *domain.MFA) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, exists := r.authenticators[old.UserID]
+	if !exists || stored.LastUsedStep != old.LastUsedStep || stored.FailedAttempts != old.FailedAttempts ||
+		!slices.Equal(stored.RecoveryCodeHashes, old.RecoveryCodeHashes) {
+		return domain.ErrMFAChanged
+	}
+	r.authenticators[old.UserID] = copyMFA(mfa)
+	return nil
+}
+
+// DeleteByUserID removes the authenticator of a user
+func (r *MFARepoMemo) DeleteByUserID(userID uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.authenticators, userID)
+	return nil
+}
+
+// deleteUserRecords removes the authenticator of a deleted user
+func (r *MFARepoMemo) deleteUserRecords(userID uuid.UUID, undo *undoLog) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	mfa, exists := r.authenticators[userID]
+	if !exists {
+		return
+	}
+	delete(r.authenticators, userID)
+	undo.add(func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		if _, exists := r.authenticators[userID]; !exists {
+			r.authenticators[userID] = mfa
+		}
+	})
+}
+
+// copyMFA copies an authenticator so callers cannot change the stored recovery codes
+func copyMFA(mfa *domain.MFA) *domain.MFA {
+	copied := *mfa
+	copied.RecoveryCodeHashes = append([]string(nil), mfa.RecoveryCodeHashes...)
+	return &copied
+}
+
+// Ensure MFARepoMemo implements MFARepository interface
+var _ repository.MFARepository = (*MFARepoMemo)(nil)
@@ -0,0 +1,94 @@
+package memory
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/algosim/backend/internal/auth/repository"
+	"github.com/google/uuid"
+)
+
+// OAuthStateRepoMemo implements OAuthStateRepository interface using in-memory storage
+type OAuthStateRepoMemo struct {
+	states map[string]*domain.OAuthState
+	mu     sync.Mutex
+}
+
+// NewOAuthStateRepoMemo creates a new in-memory OAuth state repository
+func NewOAuthStateRepoMemo() *OAuthStateRepoMemo {
+	return &OAuthStateRepoMemo{
+		states: make(map[string]*domain.OAuthState),
+	}
+}
+
+// Create stores a new state and drops the ones that have expired
+func (r *OAuthStateRepoMemo) Create(state *domain.OAuthState) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for key, s := range r.states {
+		if s.IsExpired(now) {
+			delete(r.states, key)
+		}
+	}
+
+	if _, exists := r.states[state.State]; exists {
+		return fmt.Errorf("oauth state already exists")
+	}
+
+	stored := *state
+	r.states[state.State] = &stored
+	return nil
+}
+
+// Consume marks a state as used and returns it.
+// Consumed states are kept until they expire so that replays can be told apart from unknown states.
+func (r *OAuthStateRepoMemo) Consume(state string) (*domain.OAuthState, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, exists := r.states[state]
+	if !exists {
+		return nil, domain.ErrOAuthStateExpired
+	}
+
+	now := time.Now()
+	if stored.IsExpired(now) {
+		delete(r.states, state)
+		return nil, domain.ErrOAuthStateExpired
+	}
+
+	if stored.ConsumedAt != nil {
+		return nil, domain.ErrOAuthStateReplayed
+	}
+
+	stored.ConsumedAt = &now
+	consumed := *stored
+	return &consumed, nil
+}
+
+// deleteUserRecords removes the states of links a deleted user started
+func (r *OAuthStateRepoMemo) deleteUserRecords(userID uuid.UUID, undo *undoLog) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for key, s := range r.states {
+		if s.LinkUserID == userID {
+			delete(r.states, key)
+			undo.add(func() {
+				r.mu.Lock()
+				defer r.mu.Unlock()
+
+				if _, exists := r.states[key]; !exists {
+					r.states[key] = s
+				}
+			})
+		}
+	}
+}
+
+// Ensure OAuthStateRepoMemo implements OAuthStateRepository interface
+var _ repository.OAuthStateRepository = (*OAuthStateRepoMemo)(nil)
@@ -12,26 +12,49 @@ import (
 // TokenRepoMemo implements TokenRepository interface using in-memory storage
 type TokenRepoMemo struct {
 	tokens map[uuid.UUID]*domain.Token
-	mu     sync.RWMutex
+	// byRefreshTokenHash indexes token IDs by the hash of their refresh token
+	byRefreshTokenHash map[string]uuid.UUID
+	mu                 sync.RWMutex
+	// units is locked by a running unit, writes outside units wait for it
+	units sync.RWMutex
 }
 
 // NewTokenRepoMemo creates a new in-memory token repository
 func NewTokenRepoMemo() *TokenRepoMemo {
 	return &TokenRepoMemo{
-		tokens: make(map[uuid.UUID]*domain.Token),
+		tokens:             make(map[uuid.UUID]*domain.Token),
+		byRefreshTokenHash: make(map[string]uuid.UUID),
 	}
 }
 
-// Create stores a new token
+// Create stores a new token without its plaintext refresh and access tokens
 func (r *TokenRepoMemo) Create(token *domain.Token) error {
+	r.units.RLock()
+	defer r.units.RUnlock()
+
+	return r.create(token)
+}
+
+// create is Create for writes inside a unit
+func (r *TokenRepoMemo) create(token *domain.Token) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
 	if _, exists := r.tokens[token.ID]; exists {
 		return fmt.Errorf("token already exists")
 	}
+	if token.RefreshTokenHash == "" {
+		return fmt.Errorf("token has no refresh token hash")
+	}
+	if _, exists := r.byRefreshTokenHash[token.RefreshTokenHash]; exists {
+		return fmt.Errorf("refresh token hash already exists")
+	}
 
-	r.tokens[token.ID] = token
+	stored := *token
+	stored.RefreshToken = ""
+	stored.AccessToken = ""
+	r.tokens[token.ID] = &stored
+	r.byRefreshTokenHash[token.RefreshTokenHash] = token.ID
 	return nil
 }
 
@@ -42,35 +65,74 @@ func (r *TokenRepoMemo) FindByID(id uuid.UUID) (*domain.Token, error) {
 
 	token, exists := r.tokens[id]
 	if !exists {
-		return nil, fmt.Errorf("token not found")
+		return nil, domain.ErrTokenNotFound
 	}
 
-	return token, nil
+	found := *token
+	return &found, nil
 }
 
-// FindByRefreshToken retrieves a token by refresh token string
-func (r *TokenRepoMemo) FindByRefreshToken(refreshToken string) (*domain.Token, error) {
+// FindByRefreshTokenHash retrieves a token by the hash of its refresh token
+func (r *TokenRepoMemo) FindByRefreshTokenHash(refreshTokenHash string) (*domain.Token, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	for _, token := range r.tokens {
-		if token.RefreshToken == refreshToken {
-			return token, nil
-		}
+	id, exists := r.byRefreshTokenHash[refreshTokenHash]
+	if !exists {
+		return nil, domain.ErrTokenNotFound
 	}
 
-	return nil, fmt.Errorf("token not found")
+	found := *r.tokens[id]
+	return &found, nil
+}
+
+// Update replaces a stored token, its refresh token hash cannot change
+func (r *TokenRepoMemo) Update(token *domain.Token) error {
+	r.units.RLock()
+	defer r.units.RUnlock()
+
+	return r.update(token)
+}
+
+// update is Update for writes inside a unit
+func (r *TokenRepoMemo) update(token *domain.Token) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, exists := r.tokens[token.ID]
+	if !exists {
+		return domain.ErrTokenNotFound
+	}
+	if existing.RefreshTokenHash != token.RefreshTokenHash {
+		return fmt.Errorf("refresh token hash cannot be changed")
+	}
+
+	stored := *token
+	stored.RefreshToken = ""
+	stored.AccessToken = ""
+	r.tokens[token.ID] = &stored
+	return nil
 }
 
 // Delete removes a token
 func (r *TokenRepoMemo) Delete(id uuid.UUID) error {
+	r.units.RLock()
+	defer r.units.RUnlock()
+
+	return r.remove(id)
+}
+
+// remove is Delete for writes inside a unit
+func (r *TokenRepoMemo) remove(id uuid.UUID) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	if _, exists := r.tokens[id]; !exists {
-		return fmt.Errorf("token not found")
+	token, exists := r.tokens[id]
+	if !exists {
+		return domain.ErrTokenNotFound
 	}
 
+	delete(r.byRefreshTokenHash, token.RefreshTokenHash)
 	delete(r.tokens, id)
 	return nil
 }
@@ -83,7 +145,8 @@ func (r *TokenRepoMemo) FindByUserID(userID uuid.UUID) ([]*domain.Token, error)
 	var userTokens []*domain.Token
 	for _, token := range r.tokens {
 		if token.UserID == userID {
-			userTokens = append(userTokens, token)
+			found := *token
+			userTokens = append(userTokens, &found)
 		}
 	}
 
@@ -92,20 +155,59 @@ func (r *TokenRepoMemo) FindByUserID(userID uuid.UUID) ([]*domain.Token, error)
 
 // DeleteByUserID removes all tokens for a specific user
 func (r *TokenRepoMemo) DeleteByUserID(userID uuid.UUID) error {
+	r.units.RLock()
+	defer r.units.RUnlock()
+
+	return r.removeByUserID(userID)
+}
+
+// removeByUserID is DeleteByUserID for writes inside a unit
+func (r *TokenRepoMemo) removeByUserID(userID uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, token := range r.tokens {
+		if token.UserID == userID {
+			delete(r.byRefreshTokenHash, token.RefreshTokenHash)
+			delete(r.tokens, id)
+		}
+	}
+
+	return nil
+}
+
+// deleteUserRecords removes the tokens of a deleted user
+func (r *TokenRepoMemo) deleteUserRecords(userID uuid.UUID, undo *undoLog) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	// Find all tokens for the user
-	var tokensToDelete []uuid.UUID
 	for id, token := range r.tokens {
 		if token.UserID == userID {
-			tokensToDelete = append(tokensToDelete, id)
+			delete(r.byRefreshTokenHash, token.RefreshTokenHash)
+			delete(r.tokens, id)
+			undo.add(func() { r.restore(id, token) })
 		}
 	}
+}
+
+// DeleteByFamilyID removes all tokens rotated from the same login
+func (r *TokenRepoMemo) DeleteByFamilyID(familyID uuid.UUID) error {
+	r.units.RLock()
+	defer r.units.RUnlock()
+
+	return r.removeByFamilyID(familyID)
+}
+
+// removeByFamilyID is DeleteByFamilyID for writes inside a unit
+func (r *TokenRepoMemo) removeByFamilyID(familyID uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
-	// Delete all found tokens
-	for _, id := range tokensToDelete {
-		delete(r.tokens, id)
+	for id, token := range r.tokens {
+		if token.FamilyID == familyID {
+			delete(r.byRefreshTokenHash, token.RefreshTokenHash)
+			delete(r.tokens, id)
+		}
 	}
 
 	return nil
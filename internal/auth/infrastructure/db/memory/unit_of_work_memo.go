@@ -0,0 +1,369 @@
+package memory
+
+import (
+	"sync"
+
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/algosim/backend/internal/auth/repository"
+	"github.com/google/uuid"
+)
+
+// UnitOfWork implements UnitOfWork interface for the in-memory repositories. Units run one at a
+// time and undo their changes when they fail. Writes to the repositories outside a unit wait for
+// the running unit, so undoing never overwrites them. Readers outside a unit see its changes
+// before it finished.
+type UnitOfWork struct {
+	users      *UserRepoMemo
+	tokens     *TokenRepoMemo
+	identities *IdentityRepoMemo
+	challenges *MFAChallengeRepoMemo
+}
+
+// NewUnitOfWork creates a unit of work over in-memory repositories
+func NewUnitOfWork(users *UserRepoMemo, tokens *TokenRepoMemo, identities *IdentityRepoMemo, challenges *MFAChallengeRepoMemo) *UnitOfWork {
+	return &UnitOfWork{
+		users:      users,
+		tokens:     tokens,
+		identities: identities,
+		challenges: challenges,
+	}
+}
+
+// Do runs fn and undoes its changes when it fails
+func (w *UnitOfWork) Do(fn func(tx repository.Repositories) error) error {
+	// Units take the repositories in one order, starting with the users, so they never wait on
+	// each other halfway
+	for _, units := range []*sync.RWMutex{&w.users.units, &w.tokens.units, &w.identities.units, &w.challenges.units} {
+		units.Lock()
+		defer units.Unlock()
+	}
+
+	var undo undoLog
+	tx := repository.Repositories{
+		Users:         txUserRepo{w.users, &undo},
+		Tokens:        txTokenRepo{w.tokens, &undo},
+		Identities:    txIdentityRepo{w.identities, &undo},
+		MFAChallenges: txMFAChallengeRepo{w.challenges, &undo},
+	}
+
+	committed := false
+	defer func() {
+		if !committed {
+			undo.rollback()
+		}
+	}()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	committed = true
+	return nil
+}
+
+// undoLog collects the steps that revert the changes of a unit
+type undoLog []func()
+
+// add records how to revert a change, a nil log records nothing
+func (l *undoLog) add(step func()) {
+	if l == nil {
+		return
+	}
+	*l = append(*l, step)
+}
+
+// rollback reverts the recorded changes, latest first
+func (l undoLog) rollback() {
+	for i := len(l) - 1; i >= 0; i-- {
+		l[i]()
+	}
+}
+
+// txUserRepo records how to undo the changes it makes to users
+type txUserRepo struct {
+	*UserRepoMemo
+	undo *undoLog
+}
+
+func (r txUserRepo) Create(user *domain.User) error {
+	if err := r.create(user); err != nil {
+		return err
+	}
+	id := user.ID
+	r.undo.add(func() { r.restore(id, nil) })
+	return nil
+}
+
+func (r txUserRepo) Update(user *domain.User) error {
+	previous := r.snapshot(user.ID)
+	if err := r.update(user); err != nil {
+		return err
+	}
+	r.undo.add(func() { r.restore(previous.ID, previous) })
+	return nil
+}
+
+func (r txUserRepo) Delete(id uuid.UUID) error {
+	previous := r.snapshot(id)
+	if err := r.remove(id, nil); err != nil {
+		return err
+	}
+	r.undo.add(func() { r.restore(id, previous) })
+	r.deleteRecords(id, r.undo)
+	return nil
+}
+
+// snapshot returns a copy of a stored user, nil when there is none
+func (r *UserRepoMemo) snapshot(id uuid.UUID) *domain.User {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if user, exists := r.users[id]; exists {
+		return copyUser(user)
+	}
+	return nil
+}
+
+// restore puts back a snapshot of a user, a nil snapshot removes the user
+func (r *UserRepoMemo) restore(id uuid.UUID, user *domain.User) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if user == nil {
+		delete(r.users, id)
+		return
+	}
+	r.users[id] = user
+}
+
+// txTokenRepo records how to undo the changes it makes to tokens
+type txTokenRepo struct {
+	*TokenRepoMemo
+	undo *undoLog
+}
+
+func (r txTokenRepo) Create(token *domain.Token) error {
+	if err := r.create(token); err != nil {
+		return err
+	}
+	id := token.ID
+	r.undo.add(func() { r.restore(id, nil) })
+	return nil
+}
+
+func (r txTokenRepo) Update(token *domain.Token) error {
+	previous := r.snapshot(func(t *domain.Token) bool { return t.ID == token.ID })
+	if err := r.update(token); err != nil {
+		return err
+	}
+	r.undoAll(previous)
+	return nil
+}
+
+func (r txTokenRepo) Delete(id uuid.UUID) error {
+	previous := r.snapshot(func(t *domain.Token) bool { return t.ID == id })
+	if err := r.remove(id); err != nil {
+		return err
+	}
+	r.undoAll(previous)
+	return nil
+}
+
+func (r txTokenRepo) DeleteByUserID(userID uuid.UUID) error {
+	previous := r.snapshot(func(t *domain.Token) bool { return t.UserID == userID })
+	if err := r.removeByUserID(userID); err != nil {
+		return err
+	}
+	r.undoAll(previous)
+	return nil
+}
+
+func (r txTokenRepo) DeleteByFamilyID(familyID uuid.UUID) error {
+	previous := r.snapshot(func(t *domain.Token) bool { return t.FamilyID == familyID })
+	if err := r.removeByFamilyID(familyID); err != nil {
+		return err
+	}
+	r.undoAll(previous)
+	return nil
+}
+
+// undoAll records putting back snapshots of tokens
+func (r txTokenRepo) undoAll(tokens []*domain.Token) {
+	for _, token := range tokens {
+		r.undo.add(func() { r.restore(token.ID, token) })
+	}
+}
+
+// snapshot returns copies of the stored tokens matching keep
+func (r *TokenRepoMemo) snapshot(keep func(*domain.Token) bool) []*domain.Token {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var tokens []*domain.Token
+	for _, token := range r.tokens {
+		if keep(token) {
+			copied := *token
+			tokens = append(tokens, &copied)
+		}
+	}
+	return tokens
+}
+
+// restore puts back a snapshot of a token, a nil snapshot removes the token
+func (r *TokenRepoMemo) restore(id uuid.UUID, token *domain.Token) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, exists := r.tokens[id]; exists {
+		delete(r.byRefreshTokenHash, existing.RefreshTokenHash)
+		delete(r.tokens, id)
+	}
+	if token != nil {
+		r.tokens[id] = token
+		r.byRefreshTokenHash[token.RefreshTokenHash] = id
+	}
+}
+
+// txIdentityRepo records how to undo the changes it makes to identities
+type txIdentityRepo struct {
+	*IdentityRepoMemo
+	undo *undoLog
+}
+
+func (r txIdentityRepo) Create(identity *domain.Identity) error {
+	if err := r.create(identity); err != nil {
+		return err
+	}
+	id := identity.ID
+	r.undo.add(func() { r.restore(id, nil) })
+	return nil
+}
+
+func (r txIdentityRepo) Update(identity *domain.Identity) error {
+	previous := r.snapshot(func(i *domain.Identity) bool { return i.ID == identity.ID })
+	if err := r.update(identity); err != nil {
+		return err
+	}
+	r.undoAll(previous)
+	return nil
+}
+
+func (r txIdentityRepo) Delete(id uuid.UUID) error {
+	previous := r.snapshot(func(i *domain.Identity) bool { return i.ID == id })
+	if err := r.remove(id); err != nil {
+		return err
+	}
+	r.undoAll(previous)
+	return nil
+}
+
+func (r txIdentityRepo) DeleteByUserID(userID uuid.UUID) error {
+	previous := r.snapshot(func(i *domain.Identity) bool { return i.UserID == userID })
+	if err := r.removeByUserID(userID); err != nil {
+		return err
+	}
+	r.undoAll(previous)
+	return nil
+}
+
+// undoAll records putting back snapshots of identities
+func (r txIdentityRepo) undoAll(identities []*domain.Identity) {
+	for _, identity := range identities {
+		r.undo.add(func() { r.restore(identity.ID, identity) })
+	}
+}
+
+// snapshot returns copies of the stored identities matching keep
+func (r *IdentityRepoMemo) snapshot(keep func(*domain.Identity) bool) []*domain.Identity {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var identities []*domain.Identity
+	for _, identity := range r.identities {
+		if keep(identity) {
+			copied := *identity
+			identities = append(identities, &copied)
+		}
+	}
+	return identities
+}
+
+// restore puts back a snapshot of an identity, a nil snapshot removes the identity
+func (r *IdentityRepoMemo) restore(id uuid.UUID, identity *domain.Identity) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if identity == nil {
+		delete(r.identities, id)
+		return
+	}
+	r.identities[id] = identity
+}
+
+// txMFAChallengeRepo records how to undo the changes it makes to MFA challenges
+type txMFAChallengeRepo struct {
+	*MFAChallengeRepoMemo
+	undo *undoLog
+}
+
+func (r txMFAChallengeRepo) Create(challenge *domain.MFAChallenge) error {
+	if err := r.create(challenge); err != nil {
+		return err
+	}
+	id := challenge.ID
+	r.undo.add(func() { r.restore(id, nil) })
+	return nil
+}
+
+func (r txMFAChallengeRepo) Delete(id uuid.UUID) error {
+	previous := r.snapshot(func(c *domain.MFAChallenge) bool { return c.ID == id })
+	if err := r.remove(id); err != nil {
+		return err
+	}
+	r.undoAll(previous)
+	return nil
+}
+
+func (r txMFAChallengeRepo) DeleteByUserID(userID uuid.UUID) error {
+	previous := r.snapshot(func(c *domain.MFAChallenge) bool { return c.UserID == userID })
+	if err := r.removeByUserID(userID); err != nil {
+		return err
+	}
+	r.undoAll(previous)
+	return nil
+}
+
+// undoAll records putting back snapshots of challenges
+func (r txMFAChallengeRepo) undoAll(challenges []*domain.MFAChallenge) {
+	for _, challenge := range challenges {
+		r.undo.add(func() { r.restore(challenge.ID, challenge) })
+	}
+}
+
+// snapshot returns copies of the stored challenges matching keep
+func (r *MFAChallengeRepoMemo) snapshot(keep func(*domain.MFAChallenge) bool) []*domain.MFAChallenge {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var challenges []*domain.MFAChallenge
+	for _, challenge := range r.challenges {
+		if keep(challenge) {
+			copied := *challenge
+			challenges = append(challenges, &copied)
+		}
+	}
+	return challenges
+}
+
+// restore puts back a snapshot of a challenge, a nil snapshot removes the challenge
+func (r *MFAChallengeRepoMemo) restore(id uuid.UUID, challenge *domain.MFAChallenge) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if challenge == nil {
+		delete(r.challenges, id)
+		return
+	}
+	r.challenges[id] = challenge
+}
+
+// Ensure UnitOfWork implements UnitOfWork interface
+var _ repository.UnitOfWork = (*UnitOfWork)(nil)
@@ -1,8 +1,9 @@
 package memory
 
 import (
-	"fmt"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/algosim/backend/internal/auth/domain"
 	"github.com/algosim/backend/internal/auth/repository"
@@ -12,7 +13,18 @@ import (
 // UserRepoMemo implements UserRepository interface using in-memory storage
 type UserRepoMemo struct {
 	users map[uuid.UUID]*domain.User
-	mu    sync.RWMutex
+	// records are the repositories whose records go with a deleted user
+	records []userRecords
+	mu      sync.RWMutex
+	// units is locked by a running unit, writes outside units wait for it
+	units sync.RWMutex
+}
+
+// userRecords is a repository holding records that belong to users
+type userRecords interface {
+	// deleteUserRecords deletes the records of a user and logs how to put them back on undo.
+	// Putting them back keeps records stored in the meantime.
+	deleteUserRecords(userID uuid.UUID, undo *undoLog)
 }
 
 // NewUserRepoMemo creates a new in-memory user repository
@@ -22,16 +34,33 @@ func NewUserRepoMemo() *UserRepoMemo {
 	}
 }
 
-// Create stores a new user
+// CascadeDeletes makes deleting a user also delete their records in repos, the way the foreign
+// keys of the SQL stores do. It must be called before the repository is used.
+func (r *UserRepoMemo) CascadeDeletes(repos ...userRecords) {
+	r.records = append(r.records, repos...)
+}
+
+// Create stores a new user, its email and handle must not be taken
 func (r *UserRepoMemo) Create(user *domain.User) error {
+	r.units.RLock()
+	defer r.units.RUnlock()
+
+	return r.create(user)
+}
+
+// create is Create for writes inside a unit
+func (r *UserRepoMemo) create(user *domain.User) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
 	if _, exists := r.users[user.ID]; exists {
-		return fmt.Errorf("user already exists")
+		return domain.ErrUserAlreadyExists
+	}
+	if err := r.checkUnique(user); err != nil {
+		return err
 	}
 
-	r.users[user.ID] = user
+	r.users[user.ID] = copyUser(user)
 	return nil
 }
 
@@ -42,65 +71,178 @@ func (r *UserRepoMemo) FindByID(id uuid.UUID) (*domain.User, error) {
 
 	user, exists := r.users[id]
 	if !exists {
-		return nil, fmt.Errorf("user not found")
+		return nil, domain.ErrUserNotFound
 	}
 
-	return user, nil
+	return copyUser(user), nil
 }
 
 // FindByEmail retrieves a user by email
 func (r *UserRepoMemo) FindByEmail(email string) (*domain.User, error) {
+	if email == "" {
+		return nil, domain.ErrUserNotFound
+	}
+
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
 	for _, user := range r.users {
 		if user.Email == email {
-			return user, nil
+			return copyUser(user), nil
 		}
 	}
 
-	return nil, fmt.Errorf("user not found")
+	return nil, domain.ErrUserNotFound
 }
 
-// FindByOAuthProviderID retrieves a user by OAuth provider ID
-func (r *UserRepoMemo) FindByOAuthProviderID(provider, providerID string) (*domain.User, error) {
+// FindByHandle retrieves a user by profile handle, ignoring case
+func (r *UserRepoMemo) FindByHandle(handle string) (*domain.User, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
 	for _, user := range r.users {
-		if user.OAuthProvider == provider && user.OAuthProviderID == providerID {
-			return user, nil
+		if user.Handle != "" && strings.EqualFold(user.Handle, handle) {
+			return copyUser(user), nil
 		}
 	}
 
-	return nil, fmt.Errorf("user not found")
+	return nil, domain.ErrUserNotFound
 }
 
-// Update updates an existing user
+// FindByRole retrieves all users holding a role
+func (r *UserRepoMemo) FindByRole(role string) ([]*domain.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var users []*domain.User
+	for _, user := range r.users {
+		if user.HasRole(role) {
+			users = append(users, copyUser(user))
+		}
+	}
+
+	return users, nil
+}
+
+// FindScheduledForDeletion retrieves users whose account deletion is due at or before now
+func (r *UserRepoMemo) FindScheduledForDeletion(now time.Time) ([]*domain.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var users []*domain.User
+	for _, user := range r.users {
+		if user.IsDeletionScheduled() && !user.DeletionScheduledAt.After(now) {
+			users = append(users, copyUser(user))
+		}
+	}
+
+	return users, nil
+}
+
+// Update updates an existing user, its email and handle must not be taken by another user
 func (r *UserRepoMemo) Update(user *domain.User) error {
+	r.units.RLock()
+	defer r.units.RUnlock()
+
+	return r.update(user)
+}
+
+// update is Update for writes inside a unit
+func (r *UserRepoMemo) update(user *domain.User) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
 	if _, exists := r.users[user.ID]; !exists {
-		return fmt.Errorf("user not found")
+		return domain.ErrUserNotFound
+	}
+	if err := r.checkUnique(user); err != nil {
+		return err
 	}
 
-	r.users[user.ID] = user
+	r.users[user.ID] = copyUser(user)
 	return nil
 }
 
-// Delete removes a user
+// Delete removes a user with their records
 func (r *UserRepoMemo) Delete(id uuid.UUID) error {
+	r.units.RLock()
+	defer r.units.RUnlock()
+
+	if err := r.remove(id, nil); err != nil {
+		return err
+	}
+	r.deleteRecords(id, nil)
+	return nil
+}
+
+// DeleteScheduled removes a user whose account deletion is due, a cancelled deletion keeps the user
+func (r *UserRepoMemo) DeleteScheduled(id uuid.UUID, now time.Time) error {
+	r.units.RLock()
+	defer r.units.RUnlock()
+
+	due := func(user *domain.User) bool {
+		return user.IsDeletionScheduled() && !user.DeletionScheduledAt.After(now)
+	}
+	if err := r.remove(id, due); err != nil {
+		return err
+	}
+	r.deleteRecords(id, nil)
+	return nil
+}
+
+// remove removes a user but not their records. When only is set, the user is removed only if it
+// holds for them.
+func (r *UserRepoMemo) remove(id uuid.UUID, only func(*domain.User) bool) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	if _, exists := r.users[id]; !exists {
-		return fmt.Errorf("user not found")
+	user, exists := r.users[id]
+	if !exists || (only != nil && !only(user)) {
+		return domain.ErrUserNotFound
 	}
 
 	delete(r.users, id)
 	return nil
 }
 
+// deleteRecords deletes the records a removed user held in the other repositories
+func (r *UserRepoMemo) deleteRecords(id uuid.UUID, undo *undoLog) {
+	for _, records := range r.records {
+		records.deleteUserRecords(id, undo)
+	}
+}
+
+// checkUnique reports whether another user holds the email or, ignoring case, the handle of user.
+// Users without an email or handle never collide.
+func (r *UserRepoMemo) checkUnique(user *domain.User) error {
+	for _, other := range r.users {
+		if other.ID == user.ID {
+			continue
+		}
+		if user.Email != "" && other.Email == user.Email {
+			return domain.ErrUserAlreadyExists
+		}
+		if user.Handle != "" && strings.EqualFold(other.Handle, user.Handle) {
+			return domain.ErrHandleTaken
+		}
+	}
+	return nil
+}
+
+// copyUser returns a deep copy so callers cannot modify stored users
+func copyUser(user *domain.User) *domain.User {
+	c := *user
+	c.Roles = append([]string(nil), user.Roles...)
+	if user.BannedAt != nil {
+		bannedAt := *user.BannedAt
+		c.BannedAt = &bannedAt
+	}
+	if user.DeletionScheduledAt != nil {
+		deletionScheduledAt := *user.DeletionScheduledAt
+		c.DeletionScheduledAt = &deletionScheduledAt
+	}
+	return &c
+}
+
 // Ensure UserRepoMemo implements UserRepository interface
 var _ repository.UserRepository = (*UserRepoMemo)(nil)
@@ -0,0 +1,58 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/algosim/backend/internal/auth/repository"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// AccessTokenDenylistPostgres implements AccessTokenDenylist interface on PostgreSQL, so a
+// revocation applies on every instance
+type AccessTokenDenylistPostgres struct {
+	db *pgxpool.Pool
+}
+
+// NewAccessTokenDenylistPostgres creates a new PostgreSQL access token denylist
+func NewAccessTokenDenylistPostgres(pool *pgxpool.Pool) *AccessTokenDenylistPostgres {
+	return &AccessTokenDenylistPostgres{db: pool}
+}
+
+// Add stores a revoked jti and drops the entries whose tokens have expired. Revoking a jti again
+// never shortens its revocation.
+func (r *AccessTokenDenylistPostgres) Add(jti string, expiresAt time.Time) error {
+	ctx := context.Background()
+	now := time.Now()
+	if _, err := r.db.Exec(ctx, `DELETE FROM revoked_access_tokens WHERE expires_at <= $1`, now); err != nil {
+		return fmt.Errorf("failed to drop expired revocations: %w", err)
+	}
+
+	if !now.Before(expiresAt) {
+		return nil
+	}
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO revoked_access_tokens (jti, expires_at) VALUES ($1, $2)
+		ON CONFLICT (jti) DO UPDATE SET expires_at = GREATEST(revoked_access_tokens.expires_at, excluded.expires_at)`,
+		jti, expiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to revoke access token: %w", err)
+	}
+	return nil
+}
+
+// Contains reports whether a jti is revoked and its token not yet expired
+func (r *AccessTokenDenylistPostgres) Contains(jti string) (bool, error) {
+	var revoked bool
+	err := r.db.QueryRow(context.Background(), `
+		SELECT EXISTS (SELECT 1 FROM revoked_access_tokens WHERE jti = $1 AND expires_at > $2)`,
+		jti, time.Now()).Scan(&revoked)
+	if err != nil {
+		return false, fmt.Errorf("failed to check access token revocation: %w", err)
+	}
+	return revoked, nil
+}
+
+// Ensure AccessTokenDenylistPostgres implements AccessTokenDenylist interface
+var _ repository.AccessTokenDenylist = (*AccessTokenDenylistPostgres)(nil)
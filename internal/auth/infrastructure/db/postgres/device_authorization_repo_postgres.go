@@ -0,0 +1,220 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/algosim/backend/internal/auth/repository"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// expiredDeviceAuthorizationRetention is how long expired device authorizations are kept so
+// polling devices are told their codes expired rather than unknown
+const expiredDeviceAuthorizationRetention = time.Hour
+
+// deviceAuthorizationColumns lists the device_authorizations columns in the order
+// scanDeviceAuthorization reads them
+const deviceAuthorizationColumns = `id, device_code_hash, user_code, status, user_id, interval_seconds,
+	last_poll_at, user_agent, ip_address, expires_at, created_at`
+
+// DeviceAuthorizationRepoPostgres implements DeviceAuthorizationRepository interface on PostgreSQL
+type DeviceAuthorizationRepoPostgres struct {
+	db *pgxpool.Pool
+}
+
+// NewDeviceAuthorizationRepoPostgres creates a new PostgreSQL device authorization repository
+func NewDeviceAuthorizationRepoPostgres(pool *pgxpool.Pool) *DeviceAuthorizationRepoPostgres {
+	return &DeviceAuthorizationRepoPostgres{db: pool}
+}
+
+// Create stores a new device authorization and drops the ones that expired long ago.
+// User codes must be unique among the unexpired authorizations, a transaction level lock on the
+// user code keeps two instances from handing out the same one.
+func (r *DeviceAuthorizationRepoPostgres) Create(authorization *domain.DeviceAuthorization) error {
+	ctx := context.Background()
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to store device authorization: %w", err)
+	}
+	// Rolling back after commit does nothing
+	defer tx.Rollback(ctx)
+
+	now := time.Now()
+	if _, err := tx.Exec(ctx, `DELETE FROM device_authorizations WHERE expires_at <= $1`,
+		now.Add(-expiredDeviceAuthorizationRetention)); err != nil {
+		return fmt.Errorf("failed to drop expired device authorizations: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, authorization.UserCode); err != nil {
+		return fmt.Errorf("failed to lock user code: %w", err)
+	}
+	var inUse bool
+	err = tx.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM device_authorizations WHERE user_code = $1 AND expires_at > $2)`,
+		authorization.UserCode, now).Scan(&inUse)
+	if err != nil {
+		return fmt.Errorf("failed to store device authorization: %w", err)
+	}
+	if inUse {
+		return fmt.Errorf("user code already in use")
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO device_authorizations (`+deviceAuthorizationColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+		authorization.ID, authorization.DeviceCodeHash, authorization.UserCode, string(authorization.Status),
+		nullUUID(authorization.UserID), int64(authorization.Interval/time.Second),
+		authorization.LastPollAt, authorization.Client.UserAgent, authorization.Client.IPAddress,
+		authorization.ExpiresAt, authorization.CreatedAt,
+	)
+	switch uniqueViolationOf(err) {
+	case "":
+	case "device_authorizations_pkey":
+		return fmt.Errorf("device authorization already exists")
+	case "device_authorizations_device_code_hash_key":
+		return fmt.Errorf("device code hash already exists")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to store device authorization: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to store device authorization: %w", err)
+	}
+	return nil
+}
+
+// FindByDeviceCodeHash retrieves a device authorization by the hash of its device code
+func (r *DeviceAuthorizationRepoPostgres) FindByDeviceCodeHash(deviceCodeHash string) (*domain.DeviceAuthorization, error) {
+	return r.findOne(`SELECT `+deviceAuthorizationColumns+` FROM device_authorizations
+		WHERE device_code_hash = $1`, deviceCodeHash)
+}
+
+// FindByUserCode retrieves an unexpired device authorization by its user code
+func (r *DeviceAuthorizationRepoPostgres) FindByUserCode(userCode string) (*domain.DeviceAuthorization, error) {
+	return r.findOne(`SELECT `+deviceAuthorizationColumns+` FROM device_authorizations
+		WHERE user_code = $1 AND expires_at > $2`, userCode, time.Now())
+}
+
+// FindByUserID retrieves the device authorizations a user decided
+func (r *DeviceAuthorizationRepoPostgres) FindByUserID(userID uuid.UUID) ([]*domain.DeviceAuthorization, error) {
+	rows, err := r.db.Query(context.Background(),
+		`SELECT `+deviceAuthorizationColumns+` FROM device_authorizations WHERE user_id = $1`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find device authorizations: %w", err)
+	}
+	defer rows.Close()
+
+	var authorizations []*domain.DeviceAuthorization
+	for rows.Next() {
+		authorization, err := scanDeviceAuthorization(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read device authorization: %w", err)
+		}
+		authorizations = append(authorizations, authorization)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to find device authorizations: %w", err)
+	}
+	return authorizations, nil
+}
+
+// UpdatePoll records a poll of a device authorization
+func (r *DeviceAuthorizationRepoPostgres) UpdatePoll(id uuid.UUID, lastPollAt time.Time, interval time.Duration) error {
+	tag, err := r.db.Exec(context.Background(),
+		`UPDATE device_authorizations SET last_poll_at = $2, interval_seconds = $3 WHERE id = $1`,
+		id, lastPollAt, int64(interval/time.Second))
+	if err != nil {
+		return fmt.Errorf("failed to update device authorization: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrDeviceAuthorizationNotFound
+	}
+	return nil
+}
+
+// Decide records the decision on a device authorization that is still pending
+func (r *DeviceAuthorizationRepoPostgres) Decide(id uuid.UUID, status domain.DeviceAuthorizationStatus, userID uuid.UUID) error {
+	tag, err := r.db.Exec(context.Background(),
+		`UPDATE device_authorizations SET status = $2, user_id = $3 WHERE id = $1 AND status = $4`,
+		id, string(status), nullUUID(userID), string(domain.DeviceAuthorizationPending))
+	if err != nil {
+		return fmt.Errorf("failed to decide device authorization: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrDeviceAuthorizationNotFound
+	}
+	return nil
+}
+
+// Delete removes a device authorization
+func (r *DeviceAuthorizationRepoPostgres) Delete(id uuid.UUID) error {
+	tag, err := r.db.Exec(context.Background(), `DELETE FROM device_authorizations WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete device authorization: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrDeviceAuthorizationNotFound
+	}
+	return nil
+}
+
+// DeleteByUserID removes every device authorization a user decided
+func (r *DeviceAuthorizationRepoPostgres) DeleteByUserID(userID uuid.UUID) error {
+	if _, err := r.db.Exec(context.Background(), `DELETE FROM device_authorizations WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete device authorizations: %w", err)
+	}
+	return nil
+}
+
+// Redeem removes a device authorization that still has status
+func (r *DeviceAuthorizationRepoPostgres) Redeem(id uuid.UUID, status domain.DeviceAuthorizationStatus) error {
+	tag, err := r.db.Exec(context.Background(),
+		`DELETE FROM device_authorizations WHERE id = $1 AND status = $2`, id, string(status))
+	if err != nil {
+		return fmt.Errorf("failed to redeem device authorization: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrDeviceAuthorizationNotFound
+	}
+	return nil
+}
+
+// findOne runs a query for a single device authorization
+func (r *DeviceAuthorizationRepoPostgres) findOne(query string, args ...any) (*domain.DeviceAuthorization, error) {
+	authorization, err := scanDeviceAuthorization(r.db.QueryRow(context.Background(), query, args...))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrDeviceAuthorizationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find device authorization: %w", err)
+	}
+	return authorization, nil
+}
+
+// scanDeviceAuthorization reads a row selected with deviceAuthorizationColumns
+func scanDeviceAuthorization(row pgx.Row) (*domain.DeviceAuthorization, error) {
+	var authorization domain.DeviceAuthorization
+	var status string
+	var intervalSeconds int64
+	err := row.Scan(
+		&authorization.ID, &authorization.DeviceCodeHash, &authorization.UserCode, &status,
+		&authorization.UserID, &intervalSeconds, &authorization.LastPollAt,
+		&authorization.Client.UserAgent, &authorization.Client.IPAddress,
+		&authorization.ExpiresAt, &authorization.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	authorization.Status = domain.DeviceAuthorizationStatus(status)
+	authorization.Interval = time.Duration(intervalSeconds) * time.Second
+	return &authorization, nil
+}
+
+// Ensure DeviceAuthorizationRepoPostgres implements DeviceAuthorizationRepository interface
+var _ repository.DeviceAuthorizationRepository = (*DeviceAuthorizationRepoPostgres)(nil)
@@ -0,0 +1,119 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/algosim/backend/internal/auth/repository"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// handleVerificationColumns lists the handle_verifications columns in the order
+// scanHandleVerification reads them
+const handleVerificationColumns = `id, user_id, judge, handle, contest_id, problem_index, token, expires_at, created_at`
+
+// HandleVerificationRepoPostgres implements HandleVerificationRepository interface on PostgreSQL
+type HandleVerificationRepoPostgres struct {
+	db *pgxpool.Pool
+}
+
+// NewHandleVerificationRepoPostgres creates a new PostgreSQL handle verification repository
+func NewHandleVerificationRepoPostgres(pool *pgxpool.Pool) *HandleVerificationRepoPostgres {
+	return &HandleVerificationRepoPostgres{db: pool}
+}
+
+// Create stores a new verification, replacing the user's previous one for the same judge
+func (r *HandleVerificationRepoPostgres) Create(verification *domain.HandleVerification) error {
+	_, err := r.db.Exec(context.Background(), `
+		INSERT INTO handle_verifications (`+handleVerificationColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (user_id, judge) DO UPDATE SET id = excluded.id, handle = excluded.handle,
+			contest_id = excluded.contest_id, problem_index = excluded.problem_index,
+			token = excluded.token, expires_at = excluded.expires_at, created_at = excluded.created_at`,
+		verification.ID, verification.UserID, verification.Judge, verification.Handle,
+		verification.ContestID, verification.ProblemIndex, verification.Token,
+		verification.ExpiresAt, verification.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to store handle verification: %w", err)
+	}
+	return nil
+}
+
+// FindByUserAndJudge retrieves the user's pending verification for a judge
+func (r *HandleVerificationRepoPostgres) FindByUserAndJudge(userID uuid.UUID, judge string) (*domain.HandleVerification, error) {
+	verification, err := scanHandleVerification(r.db.QueryRow(context.Background(),
+		`SELECT `+handleVerificationColumns+` FROM handle_verifications WHERE user_id = $1 AND judge = $2`,
+		userID, judge))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrHandleVerificationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find handle verification: %w", err)
+	}
+	return verification, nil
+}
+
+// FindByUserID retrieves the pending verifications of a user
+func (r *HandleVerificationRepoPostgres) FindByUserID(userID uuid.UUID) ([]*domain.HandleVerification, error) {
+	rows, err := r.db.Query(context.Background(),
+		`SELECT `+handleVerificationColumns+` FROM handle_verifications WHERE user_id = $1`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find handle verifications: %w", err)
+	}
+	defer rows.Close()
+
+	var verifications []*domain.HandleVerification
+	for rows.Next() {
+		verification, err := scanHandleVerification(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read handle verification: %w", err)
+		}
+		verifications = append(verifications, verification)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to find handle verifications: %w", err)
+	}
+	return verifications, nil
+}
+
+// Delete removes a verification
+func (r *HandleVerificationRepoPostgres) Delete(id uuid.UUID) error {
+	tag, err := r.db.Exec(context.Background(), `DELETE FROM handle_verifications WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete handle verification: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrHandleVerificationNotFound
+	}
+	return nil
+}
+
+// DeleteByUserID removes every verification of a user
+func (r *HandleVerificationRepoPostgres) DeleteByUserID(userID uuid.UUID) error {
+	if _, err := r.db.Exec(context.Background(), `DELETE FROM handle_verifications WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete handle verifications: %w", err)
+	}
+	return nil
+}
+
+// scanHandleVerification reads a row selected with handleVerificationColumns
+func scanHandleVerification(row pgx.Row) (*domain.HandleVerification, error) {
+	var verification domain.HandleVerification
+	err := row.Scan(
+		&verification.ID, &verification.UserID, &verification.Judge, &verification.Handle,
+		&verification.ContestID, &verification.ProblemIndex, &verification.Token,
+		&verification.ExpiresAt, &verification.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &verification, nil
+}
+
+// Ensure HandleVerificationRepoPostgres implements HandleVerificationRepository interface
+var _ repository.HandleVerificationRepository = (*HandleVerificationRepoPostgres)(nil)
@@ -0,0 +1,145 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/algosim/backend/internal/auth/repository"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// identityColumns lists the identities columns in the order scanIdentity reads them
+const identityColumns = `id, user_id, provider, subject, email, email_verified, created_at, last_used_at`
+
+// IdentityRepoPostgres implements IdentityRepository interface on PostgreSQL
+type IdentityRepoPostgres struct {
+	db querier
+}
+
+// NewIdentityRepoPostgres creates a new PostgreSQL identity repository
+func NewIdentityRepoPostgres(pool *pgxpool.Pool) *IdentityRepoPostgres {
+	return &IdentityRepoPostgres{db: pool}
+}
+
+// Create stores a new identity, a linked provider account yields domain.ErrIdentityAlreadyLinked
+func (r *IdentityRepoPostgres) Create(identity *domain.Identity) error {
+	_, err := r.db.Exec(context.Background(), `
+		INSERT INTO identities (`+identityColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		identity.ID, identity.UserID, identity.Provider, identity.Subject, identity.Email,
+		identity.EmailVerified, identity.CreatedAt, identity.LastUsedAt,
+	)
+	if uniqueViolationOf(err) != "" {
+		return domain.ErrIdentityAlreadyLinked
+	}
+	if err != nil {
+		return fmt.Errorf("failed to store identity: %w", err)
+	}
+	return nil
+}
+
+// FindByID retrieves an identity by ID
+func (r *IdentityRepoPostgres) FindByID(id uuid.UUID) (*domain.Identity, error) {
+	return r.findOne(`SELECT `+identityColumns+` FROM identities WHERE id = $1`, id)
+}
+
+// FindByProviderSubject retrieves the identity of an account at a provider
+func (r *IdentityRepoPostgres) FindByProviderSubject(provider, subject string) (*domain.Identity, error) {
+	return r.findOne(`SELECT `+identityColumns+` FROM identities WHERE provider = $1 AND subject = $2`, provider, subject)
+}
+
+// FindByUserID retrieves the identities of a user, oldest first
+func (r *IdentityRepoPostgres) FindByUserID(userID uuid.UUID) ([]*domain.Identity, error) {
+	rows, err := r.db.Query(context.Background(),
+		`SELECT `+identityColumns+` FROM identities WHERE user_id = $1 ORDER BY created_at`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find identities: %w", err)
+	}
+	defer rows.Close()
+
+	var identities []*domain.Identity
+	for rows.Next() {
+		identity, err := scanIdentity(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read identity: %w", err)
+		}
+		identities = append(identities, identity)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to find identities: %w", err)
+	}
+	return identities, nil
+}
+
+// Update updates an existing identity
+func (r *IdentityRepoPostgres) Update(identity *domain.Identity) error {
+	tag, err := r.db.Exec(context.Background(), `
+		UPDATE identities SET user_id = $2, provider = $3, subject = $4, email = $5, email_verified = $6,
+			last_used_at = $7
+		WHERE id = $1`,
+		identity.ID, identity.UserID, identity.Provider, identity.Subject, identity.Email,
+		identity.EmailVerified, identity.LastUsedAt,
+	)
+	if uniqueViolationOf(err) != "" {
+		return domain.ErrIdentityAlreadyLinked
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update identity: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrIdentityNotFound
+	}
+	return nil
+}
+
+// Delete removes an identity
+func (r *IdentityRepoPostgres) Delete(id uuid.UUID) error {
+	tag, err := r.db.Exec(context.Background(), `DELETE FROM identities WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete identity: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrIdentityNotFound
+	}
+	return nil
+}
+
+// DeleteByUserID removes every identity of a user
+func (r *IdentityRepoPostgres) DeleteByUserID(userID uuid.UUID) error {
+	if _, err := r.db.Exec(context.Background(), `DELETE FROM identities WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete identities: %w", err)
+	}
+	return nil
+}
+
+// findOne runs a query for a single identity
+func (r *IdentityRepoPostgres) findOne(query string, args ...any) (*domain.Identity, error) {
+	identity, err := scanIdentity(r.db.QueryRow(context.Background(), query, args...))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrIdentityNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find identity: %w", err)
+	}
+	return identity, nil
+}
+
+// scanIdentity reads a row selected with identityColumns
+func scanIdentity(row pgx.Row) (*domain.Identity, error) {
+	var identity domain.Identity
+	err := row.Scan(
+		&identity.ID, &identity.UserID, &identity.Provider, &identity.Subject, &identity.Email,
+		&identity.EmailVerified, &identity.CreatedAt, &identity.LastUsedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+// Ensure IdentityRepoPostgres implements IdentityRepository interface
+var _ repository.IdentityRepository = (*IdentityRepoPostgres)(nil)
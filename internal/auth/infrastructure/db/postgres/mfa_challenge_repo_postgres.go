@@ -0,0 +1,85 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/algosim/backend/internal/auth/repository"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// MFAChallengeRepoPostgres implements MFAChallengeRepository interface on PostgreSQL
+type MFAChallengeRepoPostgres struct {
+	db querier
+}
+
+// NewMFAChallengeRepoPostgres creates a new PostgreSQL MFA challenge repository
+func NewMFAChallengeRepoPostgres(pool *pgxpool.Pool) *MFAChallengeRepoPostgres {
+	return &MFAChallengeRepoPostgres{db: pool}
+}
+
+// Create stores a new challenge and drops the ones that have expired
+func (r *MFAChallengeRepoPostgres) Create(challenge *domain.MFAChallenge) error {
+	ctx := context.Background()
+	if _, err := r.db.Exec(ctx, `DELETE FROM mfa_challenges WHERE expires_at <= $1`, time.Now()); err != nil {
+		return fmt.Errorf("failed to drop expired mfa challenges: %w", err)
+	}
+
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO mfa_challenges (id, user_id, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5)`,
+		challenge.ID, challenge.UserID, challenge.TokenHash, challenge.ExpiresAt, challenge.CreatedAt,
+	)
+	if uniqueViolationOf(err) != "" {
+		return fmt.Errorf("mfa challenge already exists")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to store mfa challenge: %w", err)
+	}
+	return nil
+}
+
+// FindByTokenHash retrieves an unexpired challenge by the hash of its pending token
+func (r *MFAChallengeRepoPostgres) FindByTokenHash(tokenHash string) (*domain.MFAChallenge, error) {
+	var challenge domain.MFAChallenge
+	err := r.db.QueryRow(context.Background(), `
+		SELECT id, user_id, token_hash, expires_at, created_at FROM mfa_challenges
+		WHERE token_hash = $1 AND expires_at > $2`,
+		tokenHash, time.Now(),
+	).Scan(&challenge.ID, &challenge.UserID, &challenge.TokenHash, &challenge.ExpiresAt, &challenge.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrMFAChallengeNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find mfa challenge: %w", err)
+	}
+	return &challenge, nil
+}
+
+// Delete removes a challenge
+func (r *MFAChallengeRepoPostgres) Delete(id uuid.UUID) error {
+	tag, err := r.db.Exec(context.Background(), `DELETE FROM mfa_challenges WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete mfa challenge: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrMFAChallengeNotFound
+	}
+	return nil
+}
+
+// DeleteByUserID removes every challenge of a user
+func (r *MFAChallengeRepoPostgres) DeleteByUserID(userID uuid.UUID) error {
+	if _, err := r.db.Exec(context.Background(), `DELETE FROM mfa_challenges WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete mfa challenges: %w", err)
+	}
+	return nil
+}
+
+// Ensure MFAChallengeRepoPostgres implements MFAChallengeRepository interface
+var _ repository.MFAChallengeRepository = (*MFAChallengeRepoPostgres)(nil)
@@ -0,0 +1,92 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/algosim/backend/internal/auth/repository"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// mfaColumns lists the mfa columns in the order FindByUserID reads them
+const mfaColumns = `user_id, secret, last_used_step, recovery_code_hashes, failed_attempts, locked_until,
+	confirmed_at, created_at, updated_at`
+
+// MFARepoPostgres implements MFARepository interface on PostgreSQL
+type MFARepoPostgres struct {
+	db *pgxpool.Pool
+}
+
+// NewMFARepoPostgres creates a new PostgreSQL MFA repository
+func NewMFARepoPostgres(pool *pgxpool.Pool) *MFARepoPostgres {
+	return &MFARepoPostgres{db: pool}
+}
+
+// Save stores the authenticator of a user, replacing the one they had
+func (r *MFARepoPostgres) Save(mfa *domain.MFA) error {
+	_, err := r.db.Exec(context.Background(), `
+		INSERT INTO mfa (`+mfaColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (user_id) DO UPDATE SET secret = excluded.secret,
+			last_used_step = excluded.last_used_step, recovery_code_hashes = excluded.recovery_code_hashes,
+			failed_attempts = excluded.failed_attempts, locked_until = excluded.locked_until,
+			confirmed_at = excluded.confirmed_at, created_at = excluded.created_at,
+			updated_at = excluded.updated_at`,
+		mfa.UserID, mfa.Secret, mfa.LastUsedStep, textArray(mfa.RecoveryCodeHashes), mfa.FailedAttempts,
+		mfa.LockedUntil, mfa.ConfirmedAt, mfa.CreatedAt, mfa.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to store mfa: %w", err)
+	}
+	return nil
+}
+
+// FindByUserID retrieves the authenticator of a user
+func (r *MFARepoPostgres) FindByUserID(userID uuid.UUID) (*domain.MFA, error) {
+	var mfa domain.MFA
+	err := r.db.QueryRow(context.Background(), `SELECT `+mfaColumns+` FROM mfa WHERE user_id = $1`, userID).Scan(
+		&mfa.UserID, &mfa.Secret, &mfa.LastUsedStep, &mfa.RecoveryCodeHashes, &mfa.FailedAttempts,
+		&mfa.LockedUntil, &mfa.ConfirmedAt, &mfa.CreatedAt, &mfa.UpdatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrMFANotEnrolled
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find mfa: %w", err)
+	}
+	return &mfa, nil
+}
+
+// CompareAndSwap replaces the authenticator of a user while it is unchanged since old was read
+func (r *MFARepoPostgres) CompareAndSwap(old, mfa *domain.MFA) error {
+	tag, err := r.db.Exec(context.Background(), `
+		UPDATE mfa SET secret = $1, last_used_step = $2, recovery_code_hashes = $3, failed_attempts = $4,
+			locked_until = $5, confirmed_at = $6, updated_at = $7
+		WHERE user_id = $8 AND last_used_step = $9 AND recovery_code_hashes = $10 AND failed_attempts = $11`,
+		mfa.Secret, mfa.LastUsedStep, textArray(mfa.RecoveryCodeHashes), mfa.FailedAttempts,
+		mfa.LockedUntil, mfa.ConfirmedAt, mfa.UpdatedAt,
+		old.UserID, old.LastUsedStep, textArray(old.RecoveryCodeHashes), old.FailedAttempts,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update mfa: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrMFAChanged
+	}
+	return nil
+}
+
+// DeleteByUserID removes the authenticator of a user
+func (r *MFARepoPostgres) DeleteByUserID(userID uuid.UUID) error {
+	if _, err := r.db.Exec(context.Background(), `DELETE FROM mfa WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete mfa: %w", err)
+	}
+	return nil
+}
+
+// Ensure MFARepoPostgres implements MFARepository interface
+var _ repository.MFARepository = (*MFARepoPostgres)(nil)
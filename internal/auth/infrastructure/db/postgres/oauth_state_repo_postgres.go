@@ -0,0 +1,95 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/algosim/backend/internal/auth/repository"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// oauthStateColumns lists the oauth_states columns in the order scanOAuthState reads them
+const oauthStateColumns = `state, provider, code_verifier, nonce, link_user_id, expires_at, created_at, consumed_at`
+
+// OAuthStateRepoPostgres implements OAuthStateRepository interface on PostgreSQL
+type OAuthStateRepoPostgres struct {
+	db *pgxpool.Pool
+}
+
+// NewOAuthStateRepoPostgres creates a new PostgreSQL OAuth state repository
+func NewOAuthStateRepoPostgres(pool *pgxpool.Pool) *OAuthStateRepoPostgres {
+	return &OAuthStateRepoPostgres{db: pool}
+}
+
+// Create stores a new state and drops the ones that have expired
+func (r *OAuthStateRepoPostgres) Create(state *domain.OAuthState) error {
+	ctx := context.Background()
+	if _, err := r.db.Exec(ctx, `DELETE FROM oauth_states WHERE expires_at <= $1`, time.Now()); err != nil {
+		return fmt.Errorf("failed to drop expired oauth states: %w", err)
+	}
+
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO oauth_states (`+oauthStateColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		state.State, state.Provider, state.CodeVerifier, state.Nonce, nullUUID(state.LinkUserID),
+		state.ExpiresAt, state.CreatedAt, state.ConsumedAt,
+	)
+	if uniqueViolationOf(err) != "" {
+		return fmt.Errorf("oauth state already exists")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to store oauth state: %w", err)
+	}
+	return nil
+}
+
+// Consume marks a state as used and returns it in one statement, so only one of concurrent
+// callbacks gets it. Consumed states are kept until they expire so that replays can be told
+// apart from unknown states.
+func (r *OAuthStateRepoPostgres) Consume(state string) (*domain.OAuthState, error) {
+	ctx := context.Background()
+	now := time.Now()
+
+	consumed, err := scanOAuthState(r.db.QueryRow(ctx, `
+		UPDATE oauth_states SET consumed_at = $1
+		WHERE state = $2 AND consumed_at IS NULL AND expires_at > $1
+		RETURNING `+oauthStateColumns, now, state))
+	if err == nil {
+		return consumed, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("failed to consume oauth state: %w", err)
+	}
+
+	var replayed bool
+	err = r.db.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM oauth_states WHERE state = $1 AND consumed_at IS NOT NULL AND expires_at > $2)`,
+		state, now).Scan(&replayed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume oauth state: %w", err)
+	}
+	if replayed {
+		return nil, domain.ErrOAuthStateReplayed
+	}
+	return nil, domain.ErrOAuthStateExpired
+}
+
+// scanOAuthState reads a row selected with oauthStateColumns
+func scanOAuthState(row pgx.Row) (*domain.OAuthState, error) {
+	var state domain.OAuthState
+	err := row.Scan(
+		&state.State, &state.Provider, &state.CodeVerifier, &state.Nonce, &state.LinkUserID,
+		&state.ExpiresAt, &state.CreatedAt, &state.ConsumedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// Ensure OAuthStateRepoPostgres implements OAuthStateRepository interface
+var _ repository.OAuthStateRepository = (*OAuthStateRepoPostgres)(nil)
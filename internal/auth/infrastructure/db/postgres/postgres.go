@@ -0,0 +1,48 @@
+// Package postgres implements the auth repositories on PostgreSQL. Their tables are created by
+// the migrations in migrations/postgres.
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// uniqueViolation is the SQLSTATE of a unique constraint violation
+const uniqueViolation = "23505"
+
+// querier is implemented by both *pgxpool.Pool and pgx.Tx, so repositories can run in a unit of
+// work
+type querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// uniqueViolationOf returns the name of the unique constraint err violated, or "" for other errors
+func uniqueViolationOf(err error) string {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return pgErr.ConstraintName
+	}
+	return ""
+}
+
+// nullUUID returns id as stored, NULL when it is uuid.Nil
+func nullUUID(id uuid.UUID) any {
+	if id == uuid.Nil {
+		return nil
+	}
+	return id
+}
+
+// textArray returns values as stored, never nil as array columns are not nullable
+func textArray(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
@@ -0,0 +1,163 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/algosim/backend/internal/auth/repository"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// tokenColumns lists the tokens columns in the order scanToken reads them
+const tokenColumns = `id, user_id, family_id, refresh_token_hash, access_token_id, access_token_expires_at,
+	expires_at, rotated_at, user_agent, ip_address, session_started_at, last_used_at, created_at`
+
+// TokenRepoPostgres implements TokenRepository interface on PostgreSQL.
+// Neither the refresh token nor the access token itself is stored.
+type TokenRepoPostgres struct {
+	db querier
+}
+
+// NewTokenRepoPostgres creates a new PostgreSQL token repository
+func NewTokenRepoPostgres(pool *pgxpool.Pool) *TokenRepoPostgres {
+	return &TokenRepoPostgres{db: pool}
+}
+
+// Create stores a new token without its plaintext refresh token
+func (r *TokenRepoPostgres) Create(token *domain.Token) error {
+	if token.RefreshTokenHash == "" {
+		return fmt.Errorf("token has no refresh token hash")
+	}
+
+	_, err := r.db.Exec(context.Background(), `
+		INSERT INTO tokens (`+tokenColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`,
+		token.ID, token.UserID, token.FamilyID, token.RefreshTokenHash, token.AccessTokenID,
+		token.AccessTokenExpiresAt, token.ExpiresAt, token.RotatedAt, token.UserAgent, token.IPAddress,
+		token.SessionStartedAt, token.LastUsedAt, token.CreatedAt,
+	)
+	switch uniqueViolationOf(err) {
+	case "":
+	case "tokens_pkey":
+		return fmt.Errorf("token already exists")
+	case "tokens_refresh_token_hash_key":
+		return fmt.Errorf("refresh token hash already exists")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to store token: %w", err)
+	}
+	return nil
+}
+
+// FindByID retrieves a token by ID
+func (r *TokenRepoPostgres) FindByID(id uuid.UUID) (*domain.Token, error) {
+	return r.findOne(`SELECT `+tokenColumns+` FROM tokens WHERE id = $1`, id)
+}
+
+// FindByRefreshTokenHash retrieves a token by the hash of its refresh token
+func (r *TokenRepoPostgres) FindByRefreshTokenHash(refreshTokenHash string) (*domain.Token, error) {
+	return r.findOne(`SELECT `+tokenColumns+` FROM tokens WHERE refresh_token_hash = $1`, refreshTokenHash)
+}
+
+// FindByUserID retrieves all tokens for a specific user
+func (r *TokenRepoPostgres) FindByUserID(userID uuid.UUID) ([]*domain.Token, error) {
+	rows, err := r.db.Query(context.Background(), `SELECT `+tokenColumns+` FROM tokens WHERE user_id = $1`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find tokens: %w", err)
+	}
+	defer rows.Close()
+
+	var tokens []*domain.Token
+	for rows.Next() {
+		token, err := scanToken(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read token: %w", err)
+		}
+		tokens = append(tokens, token)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to find tokens: %w", err)
+	}
+	return tokens, nil
+}
+
+// Update replaces a stored token, its refresh token hash cannot change
+func (r *TokenRepoPostgres) Update(token *domain.Token) error {
+	tag, err := r.db.Exec(context.Background(), `
+		UPDATE tokens SET user_id = $3, family_id = $4, access_token_id = $5,
+			access_token_expires_at = $6, expires_at = $7, rotated_at = $8, user_agent = $9,
+			ip_address = $10, session_started_at = $11, last_used_at = $12
+		WHERE id = $1 AND refresh_token_hash = $2`,
+		token.ID, token.RefreshTokenHash, token.UserID, token.FamilyID, token.AccessTokenID,
+		token.AccessTokenExpiresAt, token.ExpiresAt, token.RotatedAt, token.UserAgent, token.IPAddress,
+		token.SessionStartedAt, token.LastUsedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update token: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrTokenNotFound
+	}
+	return nil
+}
+
+// Delete removes a token
+func (r *TokenRepoPostgres) Delete(id uuid.UUID) error {
+	tag, err := r.db.Exec(context.Background(), `DELETE FROM tokens WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete token: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrTokenNotFound
+	}
+	return nil
+}
+
+// DeleteByUserID removes all tokens for a specific user
+func (r *TokenRepoPostgres) DeleteByUserID(userID uuid.UUID) error {
+	if _, err := r.db.Exec(context.Background(), `DELETE FROM tokens WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete tokens: %w", err)
+	}
+	return nil
+}
+
+// DeleteByFamilyID removes all tokens rotated from the same login
+func (r *TokenRepoPostgres) DeleteByFamilyID(familyID uuid.UUID) error {
+	if _, err := r.db.Exec(context.Background(), `DELETE FROM tokens WHERE family_id = $1`, familyID); err != nil {
+		return fmt.Errorf("failed to delete tokens: %w", err)
+	}
+	return nil
+}
+
+// findOne runs a query for a single token
+func (r *TokenRepoPostgres) findOne(query string, args ...any) (*domain.Token, error) {
+	token, err := scanToken(r.db.QueryRow(context.Background(), query, args...))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrTokenNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find token: %w", err)
+	}
+	return token, nil
+}
+
+// scanToken reads a row selected with tokenColumns
+func scanToken(row pgx.Row) (*domain.Token, error) {
+	var token domain.Token
+	err := row.Scan(
+		&token.ID, &token.UserID, &token.FamilyID, &token.RefreshTokenHash, &token.AccessTokenID,
+		&token.AccessTokenExpiresAt, &token.ExpiresAt, &token.RotatedAt, &token.UserAgent, &token.IPAddress,
+		&token.SessionStartedAt, &token.LastUsedAt, &token.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// Ensure TokenRepoPostgres implements TokenRepository interface
+var _ repository.TokenRepository = (*TokenRepoPostgres)(nil)
@@ -0,0 +1,46 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/algosim/backend/internal/auth/repository"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// UnitOfWork implements UnitOfWork interface on PostgreSQL transactions
+type UnitOfWork struct {
+	pool *pgxpool.Pool
+}
+
+// NewUnitOfWork creates a new PostgreSQL unit of work
+func NewUnitOfWork(pool *pgxpool.Pool) *UnitOfWork {
+	return &UnitOfWork{pool: pool}
+}
+
+// Do runs fn in a transaction, units only wait for each other on the rows they both write
+func (w *UnitOfWork) Do(fn func(tx repository.Repositories) error) error {
+	ctx := context.Background()
+	tx, err := w.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	// Rolling back after commit does nothing
+	defer tx.Rollback(ctx)
+
+	if err := fn(repository.Repositories{
+		Users:         &UserRepoPostgres{db: tx},
+		Tokens:        &TokenRepoPostgres{db: tx},
+		Identities:    &IdentityRepoPostgres{db: tx},
+		MFAChallenges: &MFAChallengeRepoPostgres{db: tx},
+	}); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// Ensure UnitOfWork implements UnitOfWork interface
+var _ repository.UnitOfWork = (*UnitOfWork)(nil)
@@ -0,0 +1,181 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/algosim/backend/internal/auth/repository"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// userColumns lists the users columns in the order scanUser reads them
+const userColumns = `id, email, handle, display_name, timezone, preferred_judge, codeforces_handle,
+	atcoder_handle, password_hash, roles, banned_at, deletion_scheduled_at, created_at, updated_at`
+
+// UserRepoPostgres implements UserRepository interface on PostgreSQL
+type UserRepoPostgres struct {
+	db querier
+}
+
+// NewUserRepoPostgres creates a new PostgreSQL user repository
+func NewUserRepoPostgres(pool *pgxpool.Pool) *UserRepoPostgres {
+	return &UserRepoPostgres{db: pool}
+}
+
+// Create stores a new user, a taken email yields domain.ErrUserAlreadyExists
+func (r *UserRepoPostgres) Create(user *domain.User) error {
+	_, err := r.db.Exec(context.Background(), `
+		INSERT INTO users (`+userColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)`,
+		user.ID, user.Email, user.Handle, user.DisplayName, user.Timezone, user.PreferredJudge,
+		user.CodeforcesHandle, user.AtcoderHandle, user.PasswordHash, textArray(user.Roles),
+		user.BannedAt, user.DeletionScheduledAt, user.CreatedAt, user.UpdatedAt,
+	)
+	if err != nil {
+		return userWriteError(err)
+	}
+	return nil
+}
+
+// FindByID retrieves a user by ID
+func (r *UserRepoPostgres) FindByID(id uuid.UUID) (*domain.User, error) {
+	return r.findOne(`SELECT `+userColumns+` FROM users WHERE id = $1`, id)
+}
+
+// FindByEmail retrieves a user by email
+func (r *UserRepoPostgres) FindByEmail(email string) (*domain.User, error) {
+	if email == "" {
+		return nil, domain.ErrUserNotFound
+	}
+	return r.findOne(`SELECT `+userColumns+` FROM users WHERE email = $1`, email)
+}
+
+// FindByHandle retrieves a user by profile handle, ignoring case
+func (r *UserRepoPostgres) FindByHandle(handle string) (*domain.User, error) {
+	if handle == "" {
+		return nil, domain.ErrUserNotFound
+	}
+	return r.findOne(`SELECT `+userColumns+` FROM users WHERE handle <> '' AND LOWER(handle) = LOWER($1)`, handle)
+}
+
+// FindByRole retrieves all users holding a role
+func (r *UserRepoPostgres) FindByRole(role string) ([]*domain.User, error) {
+	return r.findMany(`SELECT `+userColumns+` FROM users WHERE roles @> ARRAY[$1]::TEXT[]`, role)
+}
+
+// FindScheduledForDeletion retrieves users whose account deletion is due at or before now
+func (r *UserRepoPostgres) FindScheduledForDeletion(now time.Time) ([]*domain.User, error) {
+	return r.findMany(`SELECT `+userColumns+` FROM users WHERE deletion_scheduled_at <= $1`, now)
+}
+
+// Update updates an existing user
+func (r *UserRepoPostgres) Update(user *domain.User) error {
+	tag, err := r.db.Exec(context.Background(), `
+		UPDATE users SET email = $2, handle = $3, display_name = $4, timezone = $5,
+			preferred_judge = $6, codeforces_handle = $7, atcoder_handle = $8, password_hash = $9,
+			roles = $10, banned_at = $11, deletion_scheduled_at = $12, updated_at = $13
+		WHERE id = $1`,
+		user.ID, user.Email, user.Handle, user.DisplayName, user.Timezone, user.PreferredJudge,
+		user.CodeforcesHandle, user.AtcoderHandle, user.PasswordHash, textArray(user.Roles),
+		user.BannedAt, user.DeletionScheduledAt, user.UpdatedAt,
+	)
+	if err != nil {
+		return userWriteError(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrUserNotFound
+	}
+	return nil
+}
+
+// Delete removes a user, their tokens go with them
+func (r *UserRepoPostgres) Delete(id uuid.UUID) error {
+	tag, err := r.db.Exec(context.Background(), `DELETE FROM users WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrUserNotFound
+	}
+	return nil
+}
+
+// DeleteScheduled removes a user whose account deletion is due, a cancelled deletion keeps the user
+func (r *UserRepoPostgres) DeleteScheduled(id uuid.UUID, now time.Time) error {
+	tag, err := r.db.Exec(context.Background(),
+		`DELETE FROM users WHERE id = $1 AND deletion_scheduled_at <= $2`, id, now)
+	if err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrUserNotFound
+	}
+	return nil
+}
+
+// findOne runs a query for a single user
+func (r *UserRepoPostgres) findOne(query string, args ...any) (*domain.User, error) {
+	user, err := scanUser(r.db.QueryRow(context.Background(), query, args...))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+	return user, nil
+}
+
+// findMany runs a query for any number of users
+func (r *UserRepoPostgres) findMany(query string, args ...any) ([]*domain.User, error) {
+	rows, err := r.db.Query(context.Background(), query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find users: %w", err)
+	}
+	defer rows.Close()
+
+	var users []*domain.User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read user: %w", err)
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to find users: %w", err)
+	}
+	return users, nil
+}
+
+// scanUser reads a row selected with userColumns
+func scanUser(row pgx.Row) (*domain.User, error) {
+	var user domain.User
+	err := row.Scan(
+		&user.ID, &user.Email, &user.Handle, &user.DisplayName, &user.Timezone, &user.PreferredJudge,
+		&user.CodeforcesHandle, &user.AtcoderHandle, &user.PasswordHash, &user.Roles,
+		&user.BannedAt, &user.DeletionScheduledAt, &user.CreatedAt, &user.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// userWriteError maps unique violations of a user write to domain errors
+func userWriteError(err error) error {
+	switch uniqueViolationOf(err) {
+	case "users_pkey", "users_email_key":
+		return domain.ErrUserAlreadyExists
+	case "users_handle_key":
+		return domain.ErrHandleTaken
+	}
+	return fmt.Errorf("failed to store user: %w", err)
+}
+
+// Ensure UserRepoPostgres implements UserRepository interface
+var _ repository.UserRepository = (*UserRepoPostgres)(nil)
@@ -0,0 +1,57 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/algosim/backend/internal/auth/repository"
+)
+
+// AccessTokenDenylistSQLite implements AccessTokenDenylist interface on SQLite
+type AccessTokenDenylistSQLite struct {
+	db *sql.DB
+}
+
+// NewAccessTokenDenylistSQLite creates a new SQLite access token denylist
+func NewAccessTokenDenylistSQLite(db *sql.DB) *AccessTokenDenylistSQLite {
+	return &AccessTokenDenylistSQLite{db: db}
+}
+
+// Add stores a revoked jti and drops the entries whose tokens have expired. Revoking a jti again
+// never shortens its revocation.
+func (r *AccessTokenDenylistSQLite) Add(jti string, expiresAt time.Time) error {
+	ctx := context.Background()
+	now := time.Now()
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM revoked_access_tokens WHERE expires_at <= ?`, formatTime(now)); err != nil {
+		return fmt.Errorf("failed to drop expired revocations: %w", err)
+	}
+
+	if !now.Before(expiresAt) {
+		return nil
+	}
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO revoked_access_tokens (jti, expires_at) VALUES (?, ?)
+		ON CONFLICT (jti) DO UPDATE SET expires_at = MAX(expires_at, excluded.expires_at)`,
+		jti, formatTime(expiresAt))
+	if err != nil {
+		return fmt.Errorf("failed to revoke access token: %w", err)
+	}
+	return nil
+}
+
+// Contains reports whether a jti is revoked and its token not yet expired
+func (r *AccessTokenDenylistSQLite) Contains(jti string) (bool, error) {
+	var revoked bool
+	err := r.db.QueryRowContext(context.Background(), `
+		SELECT EXISTS (SELECT 1 FROM revoked_access_tokens WHERE jti = ? AND expires_at > ?)`,
+		jti, formatTime(time.Now())).Scan(&revoked)
+	if err != nil {
+		return false, fmt.Errorf("failed to check access token revocation: %w", err)
+	}
+	return revoked, nil
+}
+
+// Ensure AccessTokenDenylistSQLite implements AccessTokenDenylist interface
+var _ repository.AccessTokenDenylist = (*AccessTokenDenylistSQLite)(nil)
@@ -0,0 +1,202 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/algosim/backend/internal/auth/repository"
+	"github.com/google/uuid"
+)
+
+// expiredDeviceAuthorizationRetention is how long expired device authorizations are kept so
+// polling devices are told their codes expired rather than unknown
+const expiredDeviceAuthorizationRetention = time.Hour
+
+// deviceAuthorizationColumns lists the device_authorizations columns in the order
+// scanDeviceAuthorization reads them
+const deviceAuthorizationColumns = `id, device_code_hash, user_code, status, user_id, interval_seconds,
+	last_poll_at, user_agent, ip_address, expires_at, created_at`
+
+// DeviceAuthorizationRepoSQLite implements DeviceAuthorizationRepository interface on SQLite
+type DeviceAuthorizationRepoSQLite struct {
+	db *sql.DB
+}
+
+// NewDeviceAuthorizationRepoSQLite creates a new SQLite device authorization repository
+func NewDeviceAuthorizationRepoSQLite(db *sql.DB) *DeviceAuthorizationRepoSQLite {
+	return &DeviceAuthorizationRepoSQLite{db: db}
+}
+
+// Create stores a new device authorization and drops the ones that expired long ago.
+// User codes must be unique among the unexpired authorizations.
+func (r *DeviceAuthorizationRepoSQLite) Create(authorization *domain.DeviceAuthorization) error {
+	ctx := context.Background()
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to store device authorization: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	if _, err := tx.ExecContext(ctx, `DELETE FROM device_authorizations WHERE expires_at <= ?`,
+		formatTime(now.Add(-expiredDeviceAuthorizationRetention))); err != nil {
+		return fmt.Errorf("failed to drop expired device authorizations: %w", err)
+	}
+
+	var inUse bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM device_authorizations WHERE user_code = ? AND expires_at > ?)`,
+		authorization.UserCode, formatTime(now)).Scan(&inUse)
+	if err != nil {
+		return fmt.Errorf("failed to store device authorization: %w", err)
+	}
+	if inUse {
+		return fmt.Errorf("user code already in use")
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO device_authorizations (`+deviceAuthorizationColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		authorization.ID, authorization.DeviceCodeHash, authorization.UserCode, string(authorization.Status),
+		nullUUID(authorization.UserID), int64(authorization.Interval/time.Second),
+		formatNullTime(authorization.LastPollAt), authorization.Client.UserAgent, authorization.Client.IPAddress,
+		formatTime(authorization.ExpiresAt), formatTime(authorization.CreatedAt),
+	)
+	switch uniqueViolationOf(err) {
+	case "":
+	case "device_authorizations.id":
+		return fmt.Errorf("device authorization already exists")
+	case "device_authorizations.device_code_hash":
+		return fmt.Errorf("device code hash already exists")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to store device authorization: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to store device authorization: %w", err)
+	}
+	return nil
+}
+
+// FindByDeviceCodeHash retrieves a device authorization by the hash of its device code
+func (r *DeviceAuthorizationRepoSQLite) FindByDeviceCodeHash(deviceCodeHash string) (*domain.DeviceAuthorization, error) {
+	return r.findOne(`SELECT `+deviceAuthorizationColumns+` FROM device_authorizations
+		WHERE device_code_hash = ?`, deviceCodeHash)
+}
+
+// FindByUserCode retrieves an unexpired device authorization by its user code
+func (r *DeviceAuthorizationRepoSQLite) FindByUserCode(userCode string) (*domain.DeviceAuthorization, error) {
+	return r.findOne(`SELECT `+deviceAuthorizationColumns+` FROM device_authorizations
+		WHERE user_code = ? AND expires_at > ?`, userCode, formatTime(time.Now()))
+}
+
+// FindByUserID retrieves the device authorizations a user decided
+func (r *DeviceAuthorizationRepoSQLite) FindByUserID(userID uuid.UUID) ([]*domain.DeviceAuthorization, error) {
+	rows, err := r.db.QueryContext(context.Background(),
+		`SELECT `+deviceAuthorizationColumns+` FROM device_authorizations WHERE user_id = ?`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find device authorizations: %w", err)
+	}
+	defer rows.Close()
+
+	var authorizations []*domain.DeviceAuthorization
+	for rows.Next() {
+		authorization, err := scanDeviceAuthorization(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read device authorization: %w", err)
+		}
+		authorizations = append(authorizations, authorization)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to find device authorizations: %w", err)
+	}
+	return authorizations, nil
+}
+
+// UpdatePoll records a poll of a device authorization
+func (r *DeviceAuthorizationRepoSQLite) UpdatePoll(id uuid.UUID, lastPollAt time.Time, interval time.Duration) error {
+	result, err := r.db.ExecContext(context.Background(),
+		`UPDATE device_authorizations SET last_poll_at = ?, interval_seconds = ? WHERE id = ?`,
+		formatTime(lastPollAt), int64(interval/time.Second), id)
+	if err != nil {
+		return fmt.Errorf("failed to update device authorization: %w", err)
+	}
+	return affectedOne(result, domain.ErrDeviceAuthorizationNotFound)
+}
+
+// Decide records the decision on a device authorization that is still pending
+func (r *DeviceAuthorizationRepoSQLite) Decide(id uuid.UUID, status domain.DeviceAuthorizationStatus, userID uuid.UUID) error {
+	result, err := r.db.ExecContext(context.Background(),
+		`UPDATE device_authorizations SET status = ?, user_id = ? WHERE id = ? AND status = ?`,
+		string(status), nullUUID(userID), id, string(domain.DeviceAuthorizationPending))
+	if err != nil {
+		return fmt.Errorf("failed to decide device authorization: %w", err)
+	}
+	return affectedOne(result, domain.ErrDeviceAuthorizationNotFound)
+}
+
+// Delete removes a device authorization
+func (r *DeviceAuthorizationRepoSQLite) Delete(id uuid.UUID) error {
+	result, err := r.db.ExecContext(context.Background(), `DELETE FROM device_authorizations WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete device authorization: %w", err)
+	}
+	return affectedOne(result, domain.ErrDeviceAuthorizationNotFound)
+}
+
+// DeleteByUserID removes every device authorization a user decided
+func (r *DeviceAuthorizationRepoSQLite) DeleteByUserID(userID uuid.UUID) error {
+	if _, err := r.db.ExecContext(context.Background(), `DELETE FROM device_authorizations WHERE user_id = ?`, userID); err != nil {
+		return fmt.Errorf("failed to delete device authorizations: %w", err)
+	}
+	return nil
+}
+
+// Redeem removes a device authorization that still has status
+func (r *DeviceAuthorizationRepoSQLite) Redeem(id uuid.UUID, status domain.DeviceAuthorizationStatus) error {
+	result, err := r.db.ExecContext(context.Background(),
+		`DELETE FROM device_authorizations WHERE id = ? AND status = ?`, id, string(status))
+	if err != nil {
+		return fmt.Errorf("failed to redeem device authorization: %w", err)
+	}
+	return affectedOne(result, domain.ErrDeviceAuthorizationNotFound)
+}
+
+// findOne runs a query for a single device authorization
+func (r *DeviceAuthorizationRepoSQLite) findOne(query string, args ...any) (*domain.DeviceAuthorization, error) {
+	authorization, err := scanDeviceAuthorization(r.db.QueryRowContext(context.Background(), query, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrDeviceAuthorizationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find device authorization: %w", err)
+	}
+	return authorization, nil
+}
+
+// scanDeviceAuthorization reads a row selected with deviceAuthorizationColumns
+func scanDeviceAuthorization(row scanner) (*domain.DeviceAuthorization, error) {
+	var authorization domain.DeviceAuthorization
+	var status string
+	var intervalSeconds int64
+	err := row.Scan(
+		&authorization.ID, &authorization.DeviceCodeHash, &authorization.UserCode, &status,
+		&authorization.UserID, &intervalSeconds, nullTimeColumn{&authorization.LastPollAt},
+		&authorization.Client.UserAgent, &authorization.Client.IPAddress,
+		timeColumn{&authorization.ExpiresAt}, timeColumn{&authorization.CreatedAt},
+	)
+	if err != nil {
+		return nil, err
+	}
+	authorization.Status = domain.DeviceAuthorizationStatus(status)
+	authorization.Interval = time.Duration(intervalSeconds) * time.Second
+	return &authorization, nil
+}
+
+// Ensure DeviceAuthorizationRepoSQLite implements DeviceAuthorizationRepository interface
+var _ repository.DeviceAuthorizationRepository = (*DeviceAuthorizationRepoSQLite)(nil)
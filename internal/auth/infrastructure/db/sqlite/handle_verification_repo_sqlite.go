@@ -0,0 +1,115 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/algosim/backend/internal/auth/repository"
+	"github.com/google/uuid"
+)
+
+// handleVerificationColumns lists the handle_verifications columns in the order
+// scanHandleVerification reads them
+const handleVerificationColumns = `id, user_id, judge, handle, contest_id, problem_index, token, expires_at, created_at`
+
+// HandleVerificationRepoSQLite implements HandleVerificationRepository interface on SQLite
+type HandleVerificationRepoSQLite struct {
+	db *sql.DB
+}
+
+// NewHandleVerificationRepoSQLite creates a new SQLite handle verification repository
+func NewHandleVerificationRepoSQLite(db *sql.DB) *HandleVerificationRepoSQLite {
+	return &HandleVerificationRepoSQLite{db: db}
+}
+
+// Create stores a new verification, replacing the user's previous one for the same judge
+func (r *HandleVerificationRepoSQLite) Create(verification *domain.HandleVerification) error {
+	_, err := r.db.ExecContext(context.Background(), `
+		INSERT INTO handle_verifications (`+handleVerificationColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (user_id, judge) DO UPDATE SET id = excluded.id, handle = excluded.handle,
+			contest_id = excluded.contest_id, problem_index = excluded.problem_index,
+			token = excluded.token, expires_at = excluded.expires_at, created_at = excluded.created_at`,
+		verification.ID, verification.UserID, verification.Judge, verification.Handle,
+		verification.ContestID, verification.ProblemIndex, verification.Token,
+		formatTime(verification.ExpiresAt), formatTime(verification.CreatedAt),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to store handle verification: %w", err)
+	}
+	return nil
+}
+
+// FindByUserAndJudge retrieves the user's pending verification for a judge
+func (r *HandleVerificationRepoSQLite) FindByUserAndJudge(userID uuid.UUID, judge string) (*domain.HandleVerification, error) {
+	verification, err := scanHandleVerification(r.db.QueryRowContext(context.Background(),
+		`SELECT `+handleVerificationColumns+` FROM handle_verifications WHERE user_id = ? AND judge = ?`,
+		userID, judge))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrHandleVerificationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find handle verification: %w", err)
+	}
+	return verification, nil
+}
+
+// FindByUserID retrieves the pending verifications of a user
+func (r *HandleVerificationRepoSQLite) FindByUserID(userID uuid.UUID) ([]*domain.HandleVerification, error) {
+	rows, err := r.db.QueryContext(context.Background(),
+		`SELECT `+handleVerificationColumns+` FROM handle_verifications WHERE user_id = ?`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find handle verifications: %w", err)
+	}
+	defer rows.Close()
+
+	var verifications []*domain.HandleVerification
+	for rows.Next() {
+		verification, err := scanHandleVerification(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read handle verification: %w", err)
+		}
+		verifications = append(verifications, verification)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to find handle verifications: %w", err)
+	}
+	return verifications, nil
+}
+
+// Delete removes a verification
+func (r *HandleVerificationRepoSQLite) Delete(id uuid.UUID) error {
+	result, err := r.db.ExecContext(context.Background(), `DELETE FROM handle_verifications WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete handle verification: %w", err)
+	}
+	return affectedOne(result, domain.ErrHandleVerificationNotFound)
+}
+
+// DeleteByUserID removes every verification of a user
+func (r *HandleVerificationRepoSQLite) DeleteByUserID(userID uuid.UUID) error {
+	if _, err := r.db.ExecContext(context.Background(), `DELETE FROM handle_verifications WHERE user_id = ?`, userID); err != nil {
+		return fmt.Errorf("failed to delete handle verifications: %w", err)
+	}
+	return nil
+}
+
+// scanHandleVerification reads a row selected with handleVerificationColumns
+func scanHandleVerification(row scanner) (*domain.HandleVerification, error) {
+	var verification domain.HandleVerification
+	err := row.Scan(
+		&verification.ID, &verification.UserID, &verification.Judge, &verification.Handle,
+		&verification.ContestID, &verification.ProblemIndex, &verification.Token,
+		timeColumn{&verification.ExpiresAt}, timeColumn{&verification.CreatedAt},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &verification, nil
+}
+
+// Ensure HandleVerificationRepoSQLite implements HandleVerificationRepository interface
+var _ repository.HandleVerificationRepository = (*HandleVerificationRepoSQLite)(nil)
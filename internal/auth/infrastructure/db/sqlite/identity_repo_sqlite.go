@@ -0,0 +1,138 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/algosim/backend/internal/auth/repository"
+	"github.com/google/uuid"
+)
+
+// identityColumns lists the identities columns in the order scanIdentity reads them
+const identityColumns = `id, user_id, provider, subject, email, email_verified, created_at, last_used_at`
+
+// IdentityRepoSQLite implements IdentityRepository interface on SQLite
+type IdentityRepoSQLite struct {
+	db querier
+}
+
+// NewIdentityRepoSQLite creates a new SQLite identity repository
+func NewIdentityRepoSQLite(db *sql.DB) *IdentityRepoSQLite {
+	return &IdentityRepoSQLite{db: db}
+}
+
+// Create stores a new identity, a linked provider account yields domain.ErrIdentityAlreadyLinked
+func (r *IdentityRepoSQLite) Create(identity *domain.Identity) error {
+	_, err := r.db.ExecContext(context.Background(), `
+		INSERT INTO identities (`+identityColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		identity.ID, identity.UserID, identity.Provider, identity.Subject, identity.Email,
+		identity.EmailVerified, formatTime(identity.CreatedAt), formatTime(identity.LastUsedAt),
+	)
+	if uniqueViolationOf(err) != "" {
+		return domain.ErrIdentityAlreadyLinked
+	}
+	if err != nil {
+		return fmt.Errorf("failed to store identity: %w", err)
+	}
+	return nil
+}
+
+// FindByID retrieves an identity by ID
+func (r *IdentityRepoSQLite) FindByID(id uuid.UUID) (*domain.Identity, error) {
+	return r.findOne(`SELECT `+identityColumns+` FROM identities WHERE id = ?`, id)
+}
+
+// FindByProviderSubject retrieves the identity of an account at a provider
+func (r *IdentityRepoSQLite) FindByProviderSubject(provider, subject string) (*domain.Identity, error) {
+	return r.findOne(`SELECT `+identityColumns+` FROM identities WHERE provider = ? AND subject = ?`, provider, subject)
+}
+
+// FindByUserID retrieves the identities of a user, oldest first
+func (r *IdentityRepoSQLite) FindByUserID(userID uuid.UUID) ([]*domain.Identity, error) {
+	rows, err := r.db.QueryContext(context.Background(),
+		`SELECT `+identityColumns+` FROM identities WHERE user_id = ? ORDER BY created_at`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find identities: %w", err)
+	}
+	defer rows.Close()
+
+	var identities []*domain.Identity
+	for rows.Next() {
+		identity, err := scanIdentity(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read identity: %w", err)
+		}
+		identities = append(identities, identity)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to find identities: %w", err)
+	}
+	return identities, nil
+}
+
+// Update updates an existing identity
+func (r *IdentityRepoSQLite) Update(identity *domain.Identity) error {
+	result, err := r.db.ExecContext(context.Background(), `
+		UPDATE identities SET user_id = ?, provider = ?, subject = ?, email = ?, email_verified = ?,
+			last_used_at = ?
+		WHERE id = ?`,
+		identity.UserID, identity.Provider, identity.Subject, identity.Email, identity.EmailVerified,
+		formatTime(identity.LastUsedAt), identity.ID,
+	)
+	if uniqueViolationOf(err) != "" {
+		return domain.ErrIdentityAlreadyLinked
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update identity: %w", err)
+	}
+	return affectedOne(result, domain.ErrIdentityNotFound)
+}
+
+// Delete removes an identity
+func (r *IdentityRepoSQLite) Delete(id uuid.UUID) error {
+	result, err := r.db.ExecContext(context.Background(), `DELETE FROM identities WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete identity: %w", err)
+	}
+	return affectedOne(result, domain.ErrIdentityNotFound)
+}
+
+// DeleteByUserID removes every identity of a user
+func (r *IdentityRepoSQLite) DeleteByUserID(userID uuid.UUID) error {
+	if _, err := r.db.ExecContext(context.Background(), `DELETE FROM identities WHERE user_id = ?`, userID); err != nil {
+		return fmt.Errorf("failed to delete identities: %w", err)
+	}
+	return nil
+}
+
+// findOne runs a query for a single identity
+func (r *IdentityRepoSQLite) findOne(query string, args ...any) (*domain.Identity, error) {
+	identity, err := scanIdentity(r.db.QueryRowContext(context.Background(), query, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrIdentityNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find identity: %w", err)
+	}
+	return identity, nil
+}
+
+// scanIdentity reads a row selected with identityColumns
+func scanIdentity(row scanner) (*domain.Identity, error) {
+	var identity domain.Identity
+	err := row.Scan(
+		&identity.ID, &identity.UserID, &identity.Provider, &identity.Subject, &identity.Email,
+		&identity.EmailVerified, timeColumn{&identity.CreatedAt}, timeColumn{&identity.LastUsedAt},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+// Ensure IdentityRepoSQLite implements IdentityRepository interface
+var _ repository.IdentityRepository = (*IdentityRepoSQLite)(nil)
@@ -0,0 +1,85 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/algosim/backend/internal/auth/repository"
+	"github.com/google/uuid"
+)
+
+// MFAChallengeRepoSQLite implements MFAChallengeRepository interface on SQLite
+type MFAChallengeRepoSQLite struct {
+	db querier
+}
+
+// NewMFAChallengeRepoSQLite creates a new SQLite MFA challenge repository
+func NewMFAChallengeRepoSQLite(db *sql.DB) *MFAChallengeRepoSQLite {
+	return &MFAChallengeRepoSQLite{db: db}
+}
+
+// Create stores a new challenge and drops the ones that have expired
+func (r *MFAChallengeRepoSQLite) Create(challenge *domain.MFAChallenge) error {
+	ctx := context.Background()
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM mfa_challenges WHERE expires_at <= ?`, formatTime(time.Now())); err != nil {
+		return fmt.Errorf("failed to drop expired mfa challenges: %w", err)
+	}
+
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO mfa_challenges (id, user_id, token_hash, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?)`,
+		challenge.ID, challenge.UserID, challenge.TokenHash,
+		formatTime(challenge.ExpiresAt), formatTime(challenge.CreatedAt),
+	)
+	if uniqueViolationOf(err) != "" {
+		return fmt.Errorf("mfa challenge already exists")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to store mfa challenge: %w", err)
+	}
+	return nil
+}
+
+// FindByTokenHash retrieves an unexpired challenge by the hash of its pending token
+func (r *MFAChallengeRepoSQLite) FindByTokenHash(tokenHash string) (*domain.MFAChallenge, error) {
+	var challenge domain.MFAChallenge
+	err := r.db.QueryRowContext(context.Background(), `
+		SELECT id, user_id, token_hash, expires_at, created_at FROM mfa_challenges
+		WHERE token_hash = ? AND expires_at > ?`,
+		tokenHash, formatTime(time.Now()),
+	).Scan(
+		&challenge.ID, &challenge.UserID, &challenge.TokenHash,
+		timeColumn{&challenge.ExpiresAt}, timeColumn{&challenge.CreatedAt},
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrMFAChallengeNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find mfa challenge: %w", err)
+	}
+	return &challenge, nil
+}
+
+// Delete removes a challenge
+func (r *MFAChallengeRepoSQLite) Delete(id uuid.UUID) error {
+	result, err := r.db.ExecContext(context.Background(), `DELETE FROM mfa_challenges WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete mfa challenge: %w", err)
+	}
+	return affectedOne(result, domain.ErrMFAChallengeNotFound)
+}
+
+// DeleteByUserID removes every challenge of a user
+func (r *MFAChallengeRepoSQLite) DeleteByUserID(userID uuid.UUID) error {
+	if _, err := r.db.ExecContext(context.Background(), `DELETE FROM mfa_challenges WHERE user_id = ?`, userID); err != nil {
+		return fmt.Errorf("failed to delete mfa challenges: %w", err)
+	}
+	return nil
+}
+
+// Ensure MFAChallengeRepoSQLite implements MFAChallengeRepository interface
+var _ repository.MFAChallengeRepository = (*MFAChallengeRepoSQLite)(nil)
@@ -0,0 +1,90 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/algosim/backend/internal/auth/repository"
+	"github.com/google/uuid"
+)
+
+// mfaColumns lists the mfa columns in the order FindByUserID reads them
+const mfaColumns = `user_id, secret, last_used_step, recovery_code_hashes, failed_attempts, locked_until,
+	confirmed_at, created_at, updated_at`
+
+// MFARepoSQLite implements MFARepository interface on SQLite
+type MFARepoSQLite struct {
+	db *sql.DB
+}
+
+// NewMFARepoSQLite creates a new SQLite MFA repository
+func NewMFARepoSQLite(db *sql.DB) *MFARepoSQLite {
+	return &MFARepoSQLite{db: db}
+}
+
+// Save stores the authenticator of a user, replacing the one they had
+func (r *MFARepoSQLite) Save(mfa *domain.MFA) error {
+	_, err := r.db.ExecContext(context.Background(), `
+		INSERT INTO mfa (`+mfaColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (user_id) DO UPDATE SET secret = excluded.secret,
+			last_used_step = excluded.last_used_step, recovery_code_hashes = excluded.recovery_code_hashes,
+			failed_attempts = excluded.failed_attempts, locked_until = excluded.locked_until,
+			confirmed_at = excluded.confirmed_at, created_at = excluded.created_at,
+			updated_at = excluded.updated_at`,
+		mfa.UserID, mfa.Secret, mfa.LastUsedStep, formatStrings(mfa.RecoveryCodeHashes), mfa.FailedAttempts,
+		formatNullTime(mfa.LockedUntil), formatNullTime(mfa.ConfirmedAt),
+		formatTime(mfa.CreatedAt), formatTime(mfa.UpdatedAt),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to store mfa: %w", err)
+	}
+	return nil
+}
+
+// FindByUserID retrieves the authenticator of a user
+func (r *MFARepoSQLite) FindByUserID(userID uuid.UUID) (*domain.MFA, error) {
+	var mfa domain.MFA
+	err := r.db.QueryRowContext(context.Background(), `SELECT `+mfaColumns+` FROM mfa WHERE user_id = ?`, userID).Scan(
+		&mfa.UserID, &mfa.Secret, &mfa.LastUsedStep, stringsColumn{&mfa.RecoveryCodeHashes}, &mfa.FailedAttempts,
+		nullTimeColumn{&mfa.LockedUntil}, nullTimeColumn{&mfa.ConfirmedAt},
+		timeColumn{&mfa.CreatedAt}, timeColumn{&mfa.UpdatedAt},
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrMFANotEnrolled
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find mfa: %w", err)
+	}
+	return &mfa, nil
+}
+
+// CompareAndSwap replaces the authenticator of a user while it is unchanged since old was read
+func (r *MFARepoSQLite) CompareAndSwap(old, mfa *domain.MFA) error {
+	result, err := r.db.ExecContext(context.Background(), `
+		UPDATE mfa SET secret = ?, last_used_step = ?, recovery_code_hashes = ?, failed_attempts = ?,
+			locked_until = ?, confirmed_at = ?, updated_at = ?
+		WHERE user_id = ? AND last_used_step = ? AND recovery_code_hashes = ? AND failed_attempts = ?`,
+		mfa.Secret, mfa.LastUsedStep, formatStrings(mfa.RecoveryCodeHashes), mfa.FailedAttempts,
+		formatNullTime(mfa.LockedUntil), formatNullTime(mfa.ConfirmedAt), formatTime(mfa.UpdatedAt),
+		old.UserID, old.LastUsedStep, formatStrings(old.RecoveryCodeHashes), old.FailedAttempts,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update mfa: %w", err)
+	}
+	return affectedOne(result, domain.ErrMFAChanged)
+}
+
+// DeleteByUserID removes the authenticator of a user
+func (r *MFARepoSQLite) DeleteByUserID(userID uuid.UUID) error {
+	if _, err := r.db.ExecContext(context.Background(), `DELETE FROM mfa WHERE user_id = ?`, userID); err != nil {
+		return fmt.Errorf("failed to delete mfa: %w", err)
+	}
+	return nil
+}
+
+// Ensure MFARepoSQLite implements MFARepository interface
+var _ repository.MFARepository = (*MFARepoSQLite)(nil)
@@ -0,0 +1,94 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/algosim/backend/internal/auth/repository"
+)
+
+// oauthStateColumns lists the oauth_states columns in the order scanOAuthState reads them
+const oauthStateColumns = `state, provider, code_verifier, nonce, link_user_id, expires_at, created_at, consumed_at`
+
+// OAuthStateRepoSQLite implements OAuthStateRepository interface on SQLite
+type OAuthStateRepoSQLite struct {
+	db *sql.DB
+}
+
+// NewOAuthStateRepoSQLite creates a new SQLite OAuth state repository
+func NewOAuthStateRepoSQLite(db *sql.DB) *OAuthStateRepoSQLite {
+	return &OAuthStateRepoSQLite{db: db}
+}
+
+// Create stores a new state and drops the ones that have expired
+func (r *OAuthStateRepoSQLite) Create(state *domain.OAuthState) error {
+	ctx := context.Background()
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM oauth_states WHERE expires_at <= ?`, formatTime(time.Now())); err != nil {
+		return fmt.Errorf("failed to drop expired oauth states: %w", err)
+	}
+
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO oauth_states (`+oauthStateColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		state.State, state.Provider, state.CodeVerifier, state.Nonce, nullUUID(state.LinkUserID),
+		formatTime(state.ExpiresAt), formatTime(state.CreatedAt), formatNullTime(state.ConsumedAt),
+	)
+	if uniqueViolationOf(err) != "" {
+		return fmt.Errorf("oauth state already exists")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to store oauth state: %w", err)
+	}
+	return nil
+}
+
+// Consume marks a state as used and returns it in one statement, so only one of concurrent
+// callbacks gets it. Consumed states are kept until they expire so that replays can be told
+// apart from unknown states.
+func (r *OAuthStateRepoSQLite) Consume(state string) (*domain.OAuthState, error) {
+	ctx := context.Background()
+	now := formatTime(time.Now())
+
+	consumed, err := scanOAuthState(r.db.QueryRowContext(ctx, `
+		UPDATE oauth_states SET consumed_at = ?
+		WHERE state = ? AND consumed_at IS NULL AND expires_at > ?
+		RETURNING `+oauthStateColumns, now, state, now))
+	if err == nil {
+		return consumed, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to consume oauth state: %w", err)
+	}
+
+	var replayed bool
+	err = r.db.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM oauth_states WHERE state = ? AND consumed_at IS NOT NULL AND expires_at > ?)`,
+		state, now).Scan(&replayed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume oauth state: %w", err)
+	}
+	if replayed {
+		return nil, domain.ErrOAuthStateReplayed
+	}
+	return nil, domain.ErrOAuthStateExpired
+}
+
+// scanOAuthState reads a row selected with oauthStateColumns
+func scanOAuthState(row scanner) (*domain.OAuthState, error) {
+	var state domain.OAuthState
+	err := row.Scan(
+		&state.State, &state.Provider, &state.CodeVerifier, &state.Nonce, &state.LinkUserID,
+		timeColumn{&state.ExpiresAt}, timeColumn{&state.CreatedAt}, nullTimeColumn{&state.ConsumedAt},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// Ensure OAuthStateRepoSQLite implements OAuthStateRepository interface
+var _ repository.OAuthStateRepository = (*OAuthStateRepoSQLite)(nil)
@@ -0,0 +1,163 @@
+// Package sqlite implements the auth repositories on SQLite for single-node deployments. Their
+// tables are created by the migrations in migrations/sqlite.
+//
+// IDs are stored as UUID text and lists as JSON arrays. Times are stored as UTC text of a fixed
+// width, so comparing them in SQL compares the times.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// timeLayout is the layout times are stored in
+const timeLayout = "2006-01-02T15:04:05.000000000Z"
+
+// uniqueViolationOf returns what the unique constraint err violated is on, e.g. "users.email" or
+// "index 'users_handle_key'", or "" for other errors
+func uniqueViolationOf(err error) string {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return ""
+	}
+	switch sqliteErr.Code() {
+	case sqlite3.SQLITE_CONSTRAINT_UNIQUE, sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY:
+	default:
+		return ""
+	}
+	_, detail, _ := strings.Cut(sqliteErr.Error(), "UNIQUE constraint failed: ")
+	detail, _, _ = strings.Cut(detail, " (")
+	return detail
+}
+
+// querier is implemented by both *sql.DB and *sql.Tx, so repositories can run in a unit of work
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// scanner is implemented by both *sql.Row and *sql.Rows
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// affectedOne returns notFound when a write changed no row
+func affectedOne(result sql.Result, notFound error) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return notFound
+	}
+	return nil
+}
+
+// formatTime returns t as stored
+func formatTime(t time.Time) string {
+	return t.UTC().Format(timeLayout)
+}
+
+// formatNullTime returns t as stored, NULL when t is nil
+func formatNullTime(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return formatTime(*t)
+}
+
+// nullUUID returns id as stored, NULL when it is uuid.Nil
+func nullUUID(id uuid.UUID) any {
+	if id == uuid.Nil {
+		return nil
+	}
+	return id.String()
+}
+
+// formatStrings returns values as stored JSON array
+func formatStrings(values []string) string {
+	if values == nil {
+		return "[]"
+	}
+	encoded, _ := json.Marshal(values)
+	return string(encoded)
+}
+
+// timeColumn scans a time written by formatTime
+type timeColumn struct {
+	dest *time.Time
+}
+
+// Scan implements sql.Scanner
+func (c timeColumn) Scan(src any) error {
+	s, err := text(src)
+	if err != nil {
+		return err
+	}
+	t, err := time.Parse(timeLayout, s)
+	if err != nil {
+		return fmt.Errorf("invalid time %q: %w", s, err)
+	}
+	*c.dest = t.Local()
+	return nil
+}
+
+// nullTimeColumn scans a time written by formatNullTime
+type nullTimeColumn struct {
+	dest **time.Time
+}
+
+// Scan implements sql.Scanner
+func (c nullTimeColumn) Scan(src any) error {
+	if src == nil {
+		*c.dest = nil
+		return nil
+	}
+	var t time.Time
+	if err := (timeColumn{dest: &t}).Scan(src); err != nil {
+		return err
+	}
+	*c.dest = &t
+	return nil
+}
+
+// stringsColumn scans a JSON array written by formatStrings
+type stringsColumn struct {
+	dest *[]string
+}
+
+// Scan implements sql.Scanner
+func (c stringsColumn) Scan(src any) error {
+	s, err := text(src)
+	if err != nil {
+		return err
+	}
+	var values []string
+	if err := json.Unmarshal([]byte(s), &values); err != nil {
+		return fmt.Errorf("invalid list %q: %w", s, err)
+	}
+	*c.dest = values
+	return nil
+}
+
+// text returns a TEXT column value
+func text(src any) (string, error) {
+	switch v := src.(type) {
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	default:
+		return "", fmt.Errorf("expected text, got %T", src)
+	}
+}
@@ -0,0 +1,159 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/algosim/backend/internal/auth/repository"
+	"github.com/google/uuid"
+)
+
+// tokenColumns lists the tokens columns in the order scanToken reads them
+const tokenColumns = `id, user_id, family_id, refresh_token_hash, access_token_id, access_token_expires_at,
+	expires_at, rotated_at, user_agent, ip_address, session_started_at, last_used_at, created_at`
+
+// TokenRepoSQLite implements TokenRepository interface on SQLite.
+// Neither the refresh token nor the access token itself is stored.
+type TokenRepoSQLite struct {
+	db querier
+}
+
+// NewTokenRepoSQLite creates a new SQLite token repository
+func NewTokenRepoSQLite(db *sql.DB) *TokenRepoSQLite {
+	return &TokenRepoSQLite{db: db}
+}
+
+// Create stores a new token without its plaintext refresh token
+func (r *TokenRepoSQLite) Create(token *domain.Token) error {
+	if token.RefreshTokenHash == "" {
+		return fmt.Errorf("token has no refresh token hash")
+	}
+
+	_, err := r.db.ExecContext(context.Background(), `
+		INSERT INTO tokens (`+tokenColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		token.ID, token.UserID, token.FamilyID, token.RefreshTokenHash, token.AccessTokenID,
+		formatTime(token.AccessTokenExpiresAt), formatTime(token.ExpiresAt), formatNullTime(token.RotatedAt),
+		token.UserAgent, token.IPAddress, formatTime(token.SessionStartedAt), formatTime(token.LastUsedAt),
+		formatTime(token.CreatedAt),
+	)
+	switch uniqueViolationOf(err) {
+	case "":
+	case "tokens.id":
+		return fmt.Errorf("token already exists")
+	case "tokens.refresh_token_hash":
+		return fmt.Errorf("refresh token hash already exists")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to store token: %w", err)
+	}
+	return nil
+}
+
+// FindByID retrieves a token by ID
+func (r *TokenRepoSQLite) FindByID(id uuid.UUID) (*domain.Token, error) {
+	return r.findOne(`SELECT `+tokenColumns+` FROM tokens WHERE id = ?`, id)
+}
+
+// FindByRefreshTokenHash retrieves a token by the hash of its refresh token
+func (r *TokenRepoSQLite) FindByRefreshTokenHash(refreshTokenHash string) (*domain.Token, error) {
+	return r.findOne(`SELECT `+tokenColumns+` FROM tokens WHERE refresh_token_hash = ?`, refreshTokenHash)
+}
+
+// FindByUserID retrieves all tokens for a specific user
+func (r *TokenRepoSQLite) FindByUserID(userID uuid.UUID) ([]*domain.Token, error) {
+	rows, err := r.db.QueryContext(context.Background(), `SELECT `+tokenColumns+` FROM tokens WHERE user_id = ?`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find tokens: %w", err)
+	}
+	defer rows.Close()
+
+	var tokens []*domain.Token
+	for rows.Next() {
+		token, err := scanToken(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read token: %w", err)
+		}
+		tokens = append(tokens, token)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to find tokens: %w", err)
+	}
+	return tokens, nil
+}
+
+// Update replaces a stored token, its refresh token hash cannot change
+func (r *TokenRepoSQLite) Update(token *domain.Token) error {
+	result, err := r.db.ExecContext(context.Background(), `
+		UPDATE tokens SET user_id = ?, family_id = ?, access_token_id = ?,
+			access_token_expires_at = ?, expires_at = ?, rotated_at = ?, user_agent = ?,
+			ip_address = ?, session_started_at = ?, last_used_at = ?
+		WHERE id = ? AND refresh_token_hash = ?`,
+		token.UserID, token.FamilyID, token.AccessTokenID, formatTime(token.AccessTokenExpiresAt),
+		formatTime(token.ExpiresAt), formatNullTime(token.RotatedAt), token.UserAgent, token.IPAddress,
+		formatTime(token.SessionStartedAt), formatTime(token.LastUsedAt),
+		token.ID, token.RefreshTokenHash,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update token: %w", err)
+	}
+	return affectedOne(result, domain.ErrTokenNotFound)
+}
+
+// Delete removes a token
+func (r *TokenRepoSQLite) Delete(id uuid.UUID) error {
+	result, err := r.db.ExecContext(context.Background(), `DELETE FROM tokens WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete token: %w", err)
+	}
+	return affectedOne(result, domain.ErrTokenNotFound)
+}
+
+// DeleteByUserID removes all tokens for a specific user
+func (r *TokenRepoSQLite) DeleteByUserID(userID uuid.UUID) error {
+	if _, err := r.db.ExecContext(context.Background(), `DELETE FROM tokens WHERE user_id = ?`, userID); err != nil {
+		return fmt.Errorf("failed to delete tokens: %w", err)
+	}
+	return nil
+}
+
+// DeleteByFamilyID removes all tokens rotated from the same login
+func (r *TokenRepoSQLite) DeleteByFamilyID(familyID uuid.UUID) error {
+	if _, err := r.db.ExecContext(context.Background(), `DELETE FROM tokens WHERE family_id = ?`, familyID); err != nil {
+		return fmt.Errorf("failed to delete tokens: %w", err)
+	}
+	return nil
+}
+
+// findOne runs a query for a single token
+func (r *TokenRepoSQLite) findOne(query string, args ...any) (*domain.Token, error) {
+	token, err := scanToken(r.db.QueryRowContext(context.Background(), query, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrTokenNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find token: %w", err)
+	}
+	return token, nil
+}
+
+// scanToken reads a row selected with tokenColumns
+func scanToken(row scanner) (*domain.Token, error) {
+	var token domain.Token
+	err := row.Scan(
+		&token.ID, &token.UserID, &token.FamilyID, &token.RefreshTokenHash, &token.AccessTokenID,
+		timeColumn{&token.AccessTokenExpiresAt}, timeColumn{&token.ExpiresAt}, nullTimeColumn{&token.RotatedAt},
+		&token.UserAgent, &token.IPAddress, timeColumn{&token.SessionStartedAt}, timeColumn{&token.LastUsedAt},
+		timeColumn{&token.CreatedAt},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// Ensure TokenRepoSQLite implements TokenRepository interface
+var _ repository.TokenRepository = (*TokenRepoSQLite)(nil)
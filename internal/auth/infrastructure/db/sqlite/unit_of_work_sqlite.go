@@ -0,0 +1,45 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/algosim/backend/internal/auth/repository"
+)
+
+// UnitOfWork implements UnitOfWork interface on SQLite transactions
+type UnitOfWork struct {
+	db *sql.DB
+}
+
+// NewUnitOfWork creates a new SQLite unit of work
+func NewUnitOfWork(db *sql.DB) *UnitOfWork {
+	return &UnitOfWork{db: db}
+}
+
+// Do runs fn in a transaction, which holds the database write lock until it ends
+func (w *UnitOfWork) Do(fn func(tx repository.Repositories) error) error {
+	tx, err := w.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	// Rolling back after commit does nothing
+	defer tx.Rollback()
+
+	if err := fn(repository.Repositories{
+		Users:         &UserRepoSQLite{db: tx},
+		Tokens:        &TokenRepoSQLite{db: tx},
+		Identities:    &IdentityRepoSQLite{db: tx},
+		MFAChallenges: &MFAChallengeRepoSQLite{db: tx},
+	}); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// Ensure UnitOfWork implements UnitOfWork interface
+var _ repository.UnitOfWork = (*UnitOfWork)(nil)
@@ -0,0 +1,175 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/algosim/backend/internal/auth/repository"
+	"github.com/google/uuid"
+)
+
+// userColumns lists the users columns in the order scanUser reads them
+const userColumns = `id, email, handle, display_name, timezone, preferred_judge, codeforces_handle,
+	atcoder_handle, password_hash, roles, banned_at, deletion_scheduled_at, created_at, updated_at`
+
+// UserRepoSQLite implements UserRepository interface on SQLite
+type UserRepoSQLite struct {
+	db querier
+}
+
+// NewUserRepoSQLite creates a new SQLite user repository
+func NewUserRepoSQLite(db *sql.DB) *UserRepoSQLite {
+	return &UserRepoSQLite{db: db}
+}
+
+// Create stores a new user, a taken email yields domain.ErrUserAlreadyExists
+func (r *UserRepoSQLite) Create(user *domain.User) error {
+	_, err := r.db.ExecContext(context.Background(), `
+		INSERT INTO users (`+userColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		user.ID, user.Email, user.Handle, user.DisplayName, user.Timezone, user.PreferredJudge,
+		user.CodeforcesHandle, user.AtcoderHandle, user.PasswordHash, formatStrings(user.Roles),
+		formatNullTime(user.BannedAt), formatNullTime(user.DeletionScheduledAt),
+		formatTime(user.CreatedAt), formatTime(user.UpdatedAt),
+	)
+	if err != nil {
+		return userWriteError(err)
+	}
+	return nil
+}
+
+// FindByID retrieves a user by ID
+func (r *UserRepoSQLite) FindByID(id uuid.UUID) (*domain.User, error) {
+	return r.findOne(`SELECT `+userColumns+` FROM users WHERE id = ?`, id)
+}
+
+// FindByEmail retrieves a user by email
+func (r *UserRepoSQLite) FindByEmail(email string) (*domain.User, error) {
+	if email == "" {
+		return nil, domain.ErrUserNotFound
+	}
+	return r.findOne(`SELECT `+userColumns+` FROM users WHERE email = ?`, email)
+}
+
+// FindByHandle retrieves a user by profile handle, ignoring case
+func (r *UserRepoSQLite) FindByHandle(handle string) (*domain.User, error) {
+	if handle == "" {
+		return nil, domain.ErrUserNotFound
+	}
+	return r.findOne(`SELECT `+userColumns+` FROM users WHERE handle <> '' AND LOWER(handle) = LOWER(?)`, handle)
+}
+
+// FindByRole retrieves all users holding a role
+func (r *UserRepoSQLite) FindByRole(role string) ([]*domain.User, error) {
+	return r.findMany(`SELECT `+userColumns+` FROM users
+		WHERE EXISTS (SELECT 1 FROM json_each(users.roles) WHERE json_each.value = ?)`, role)
+}
+
+// FindScheduledForDeletion retrieves users whose account deletion is due at or before now
+func (r *UserRepoSQLite) FindScheduledForDeletion(now time.Time) ([]*domain.User, error) {
+	return r.findMany(`SELECT `+userColumns+` FROM users WHERE deletion_scheduled_at <= ?`, formatTime(now))
+}
+
+// Update updates an existing user
+func (r *UserRepoSQLite) Update(user *domain.User) error {
+	result, err := r.db.ExecContext(context.Background(), `
+		UPDATE users SET email = ?, handle = ?, display_name = ?, timezone = ?,
+			preferred_judge = ?, codeforces_handle = ?, atcoder_handle = ?, password_hash = ?,
+			roles = ?, banned_at = ?, deletion_scheduled_at = ?, updated_at = ?
+		WHERE id = ?`,
+		user.Email, user.Handle, user.DisplayName, user.Timezone, user.PreferredJudge,
+		user.CodeforcesHandle, user.AtcoderHandle, user.PasswordHash, formatStrings(user.Roles),
+		formatNullTime(user.BannedAt), formatNullTime(user.DeletionScheduledAt), formatTime(user.UpdatedAt),
+		user.ID,
+	)
+	if err != nil {
+		return userWriteError(err)
+	}
+	return affectedOne(result, domain.ErrUserNotFound)
+}
+
+// Delete removes a user, everything stored about them goes with them
+func (r *UserRepoSQLite) Delete(id uuid.UUID) error {
+	result, err := r.db.ExecContext(context.Background(), `DELETE FROM users WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+	return affectedOne(result, domain.ErrUserNotFound)
+}
+
+// DeleteScheduled removes a user whose account deletion is due, a cancelled deletion keeps the user
+func (r *UserRepoSQLite) DeleteScheduled(id uuid.UUID, now time.Time) error {
+	result, err := r.db.ExecContext(context.Background(),
+		`DELETE FROM users WHERE id = ? AND deletion_scheduled_at <= ?`, id, formatTime(now))
+	if err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+	return affectedOne(result, domain.ErrUserNotFound)
+}
+
+// findOne runs a query for a single user
+func (r *UserRepoSQLite) findOne(query string, args ...any) (*domain.User, error) {
+	user, err := scanUser(r.db.QueryRowContext(context.Background(), query, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+	return user, nil
+}
+
+// findMany runs a query for any number of users
+func (r *UserRepoSQLite) findMany(query string, args ...any) ([]*domain.User, error) {
+	rows, err := r.db.QueryContext(context.Background(), query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find users: %w", err)
+	}
+	defer rows.Close()
+
+	var users []*domain.User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read user: %w", err)
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to find users: %w", err)
+	}
+	return users, nil
+}
+
+// scanUser reads a row selected with userColumns
+func scanUser(row scanner) (*domain.User, error) {
+	var user domain.User
+	err := row.Scan(
+		&user.ID, &user.Email, &user.Handle, &user.DisplayName, &user.Timezone, &user.PreferredJudge,
+		&user.CodeforcesHandle, &user.AtcoderHandle, &user.PasswordHash, stringsColumn{&user.Roles},
+		nullTimeColumn{&user.BannedAt}, nullTimeColumn{&user.DeletionScheduledAt},
+		timeColumn{&user.CreatedAt}, timeColumn{&user.UpdatedAt},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// userWriteError maps unique violations of a user write to domain errors
+func userWriteError(err error) error {
+	switch uniqueViolationOf(err) {
+	case "users.id", "users.email":
+		return domain.ErrUserAlreadyExists
+	case "index 'users_handle_key'":
+		return domain.ErrHandleTaken
+	}
+	return fmt.Errorf("failed to store user: %w", err)
+}
+
+// Ensure UserRepoSQLite implements UserRepository interface
+var _ repository.UserRepository = (*UserRepoSQLite)(nil)
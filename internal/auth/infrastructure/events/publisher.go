@@ -0,0 +1,55 @@
+package events
+
+import (
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/google/uuid"
+)
+
+// Publisher delivers security events to whoever audits or alerts on them
+type Publisher interface {
+	Publish(event *domain.SecurityEvent)
+}
+
+// LogPublisher writes security events to the standard logger
+type LogPublisher struct{}
+
+// NewLogPublisher creates a new LogPublisher
+func NewLogPublisher() *LogPublisher {
+	return &LogPublisher{}
+}
+
+// Publish logs the event on a single line
+func (p *LogPublisher) Publish(event *domain.SecurityEvent) {
+	var b strings.Builder
+	b.WriteString("security event type=")
+	b.WriteString(event.Type)
+	if event.UserID != uuid.Nil {
+		b.WriteString(" user_id=")
+		b.WriteString(event.UserID.String())
+	}
+	if event.FamilyID != uuid.Nil {
+		b.WriteString(" family_id=")
+		b.WriteString(event.FamilyID.String())
+	}
+
+	keys := make([]string, 0, len(event.Details))
+	for k := range event.Details {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		b.WriteString(" ")
+		b.WriteString(k)
+		b.WriteString("=")
+		b.WriteString(event.Details[k])
+	}
+
+	log.Print(b.String())
+}
+
+// Ensure LogPublisher implements Publisher interface
+var _ Publisher = (*LogPublisher)(nil)
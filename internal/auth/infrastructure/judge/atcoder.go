@@ -0,0 +1,68 @@
+package judge
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/algosim/backend/internal/auth/domain"
+)
+
+const atcoderURL = "https://atcoder.jp"
+
+// affiliationPattern matches the affiliation row of an AtCoder profile page in English
+var affiliationPattern = regexp.MustCompile(`<th[^>]*>\s*Affiliation\s*</th>\s*<td[^>]*>([^<]*)</td>`)
+
+// AtCoderClientImpl reads public AtCoder profile pages, AtCoder has no profile API
+type AtCoderClientImpl struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewAtCoderClient creates a new AtCoder client, an empty baseURL means atcoder.jp
+func NewAtCoderClient(baseURL string) *AtCoderClientImpl {
+	if baseURL == "" {
+		baseURL = atcoderURL
+	}
+	return &AtCoderClientImpl{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Affiliation retrieves the affiliation field from the user's profile page
+func (c *AtCoderClientImpl) Affiliation(handle string) (string, error) {
+	resp, err := c.client.Get(fmt.Sprintf("%s/users/%s?lang=en", c.baseURL, url.PathEscape(handle)))
+	if err != nil {
+		return "", fmt.Errorf("failed to get atcoder profile: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return "", domain.ErrHandleNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to get atcoder profile: status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("failed to read atcoder profile: %w", err)
+	}
+
+	// Users without an affiliation have no such row
+	match := affiliationPattern.FindSubmatch(body)
+	if match == nil {
+		return "", nil
+	}
+
+	return strings.TrimSpace(html.UnescapeString(string(match[1]))), nil
+}
+
+// Ensure AtCoderClientImpl implements AtCoderClient interface
+var _ AtCoderClient = (*AtCoderClientImpl)(nil)
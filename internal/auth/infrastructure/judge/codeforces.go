@@ -0,0 +1,85 @@
+package judge
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/algosim/backend/internal/auth/domain"
+)
+
+const codeforcesAPIURL = "https://codeforces.com/api"
+
+// CodeforcesClientImpl talks to the public Codeforces API
+type CodeforcesClientImpl struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewCodeforcesClient creates a new Codeforces API client, an empty baseURL means the public API
+func NewCodeforcesClient(baseURL string) *CodeforcesClientImpl {
+	if baseURL == "" {
+		baseURL = codeforcesAPIURL
+	}
+	return &CodeforcesClientImpl{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// RecentSubmissions retrieves the latest submissions of a user through user.status
+func (c *CodeforcesClientImpl) RecentSubmissions(handle string, count int) ([]Submission, error) {
+	params := url.Values{}
+	params.Add("handle", handle)
+	params.Add("from", "1")
+	params.Add("count", strconv.Itoa(count))
+
+	resp, err := c.client.Get(fmt.Sprintf("%s/user.status?%s", c.baseURL, params.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get codeforces submissions: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var apiResp struct {
+		Status  string `json:"status"`
+		Comment string `json:"comment"`
+		Result  []struct {
+			ContestID           int    `json:"contestId"`
+			CreationTimeSeconds int64  `json:"creationTimeSeconds"`
+			Verdict             string `json:"verdict"`
+			Problem             struct {
+				Index string `json:"index"`
+			} `json:"problem"`
+		} `json:"result"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return nil, fmt.Errorf("failed to decode codeforces response: %w", err)
+	}
+
+	if apiResp.Status != "OK" {
+		if strings.Contains(apiResp.Comment, "not found") {
+			return nil, domain.ErrHandleNotFound
+		}
+		return nil, fmt.Errorf("failed to get codeforces submissions: %s", apiResp.Comment)
+	}
+
+	submissions := make([]Submission, 0, len(apiResp.Result))
+	for _, r := range apiResp.Result {
+		submissions = append(submissions, Submission{
+			ContestID:    r.ContestID,
+			ProblemIndex: r.Problem.Index,
+			Verdict:      r.Verdict,
+			CreatedAt:    time.Unix(r.CreationTimeSeconds, 0),
+		})
+	}
+
+	return submissions, nil
+}
+
+// Ensure CodeforcesClientImpl implements CodeforcesClient interface
+var _ CodeforcesClient = (*CodeforcesClientImpl)(nil)
@@ -0,0 +1,83 @@
+package judge
+
+import (
+	"sync"
+
+	"github.com/algosim/backend/internal/auth/domain"
+)
+
+// Fake is an in-memory stand-in for the judges, for tests and offline development
+type Fake struct {
+	submissions  map[string][]Submission
+	affiliations map[string]string
+	mu           sync.RWMutex
+}
+
+// NewFake creates a fake judge that knows no handles
+func NewFake() *Fake {
+	return &Fake{
+		submissions:  make(map[string][]Submission),
+		affiliations: make(map[string]string),
+	}
+}
+
+// AddHandle registers a handle with no submissions and no affiliation
+func (f *Fake) AddHandle(handle string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, exists := f.submissions[handle]; !exists {
+		f.submissions[handle] = nil
+	}
+}
+
+// AddSubmission records a new submission of handle
+func (f *Fake) AddSubmission(handle string, submission Submission) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.submissions[handle] = append([]Submission{submission}, f.submissions[handle]...)
+}
+
+// SetAffiliation sets the affiliation shown on the profile of handle
+func (f *Fake) SetAffiliation(handle, affiliation string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, exists := f.submissions[handle]; !exists {
+		f.submissions[handle] = nil
+	}
+	f.affiliations[handle] = affiliation
+}
+
+// RecentSubmissions returns up to count latest submissions of handle
+func (f *Fake) RecentSubmissions(handle string, count int) ([]Submission, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	submissions, exists := f.submissions[handle]
+	if !exists {
+		return nil, domain.ErrHandleNotFound
+	}
+	if len(submissions) > count {
+		submissions = submissions[:count]
+	}
+	return append([]Submission(nil), submissions...), nil
+}
+
+// Affiliation returns the affiliation of handle
+func (f *Fake) Affiliation(handle string) (string, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if _, exists := f.submissions[handle]; !exists {
+		return "", domain.ErrHandleNotFound
+	}
+	return f.affiliations[handle], nil
+}
+
+// Ensure Fake implements both judge client interfaces
+var (
+	_ CodeforcesClient = (*Fake)(nil)
+	_ AtCoderClient    = (*Fake)(nil)
+)
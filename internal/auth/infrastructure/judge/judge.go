@@ -0,0 +1,28 @@
+package judge
+
+import (
+	"time"
+)
+
+// Codeforces verdict of a submission that failed to compile
+const VerdictCompilationError = "COMPILATION_ERROR"
+
+// Submission represents a judge submission as far as handle verification cares
+type Submission struct {
+	ContestID    int
+	ProblemIndex string
+	Verdict      string
+	CreatedAt    time.Time
+}
+
+// CodeforcesClient defines the Codeforces operations used to verify handle ownership
+type CodeforcesClient interface {
+	// RecentSubmissions returns up to count latest submissions of handle, newest first
+	RecentSubmissions(handle string, count int) ([]Submission, error)
+}
+
+// AtCoderClient defines the AtCoder operations used to verify handle ownership
+type AtCoderClient interface {
+	// Affiliation returns the affiliation shown on the profile of handle
+	Affiliation(handle string) (string, error)
+}
@@ -1,11 +1,17 @@
 package jwt
 
 import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"time"
 
 	"github.com/algosim/backend/configs"
 	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/algosim/backend/internal/auth/repository"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 )
@@ -14,68 +20,181 @@ import (
 type Claims struct {
 	UserID uuid.UUID `json:"user_id"`
 	Email  string    `json:"email"`
+	// SessionID is the token family the access token was issued with
+	SessionID   uuid.UUID `json:"sid"`
+	Roles       []string  `json:"roles"`
+	Permissions []string  `json:"permissions"`
 	jwt.RegisteredClaims
 }
 
 // JWTManager handles JWT operations
 type JWTManager struct {
-	secretKey       []byte
+	secretKey []byte
+	// keys signs access tokens when configured, otherwise secretKey signs with HS256
+	keys            *KeySet
+	denylist        repository.AccessTokenDenylist
+	refreshTokenKey []byte
 	tokenTTL        time.Duration
 	refreshTokenTTL time.Duration
 }
 
-// NewJWTManager creates a new JWT manager instance
-func NewJWTManager(config *configs.Config) *JWTManager {
-	return &JWTManager{
+// NewJWTManager creates a new JWT manager instance, loading the configured signing keys
+func NewJWTManager(config *configs.Config, denylist repository.AccessTokenDenylist) (*JWTManager, error) {
+	m := &JWTManager{
 		secretKey:       []byte(config.Auth.JWTSecret),
+		denylist:        denylist,
+		refreshTokenKey: []byte(config.Auth.RefreshTokenSecret),
 		tokenTTL:        time.Duration(config.Auth.TokenTTL) * time.Second,
 		refreshTokenTTL: 24 * time.Hour, // Refresh tokens last 24 hours
 	}
+
+	if len(config.Auth.SigningKeys) > 0 {
+		keys, err := LoadKeySet(config.Auth.SigningKeys)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load signing keys: %w", err)
+		}
+		m.keys = keys
+	}
+
+	return m, nil
 }
 
-// GenerateAccessToken creates a new access token
+// GenerateToken creates a token pair that starts a new session
 func (m *JWTManager) GenerateToken(user *domain.User) (*domain.Token, error) {
+	return m.generateToken(user, nil)
+}
+
+// RotateToken creates the token pair that succeeds previous in its session
+func (m *JWTManager) RotateToken(user *domain.User, previous *domain.Token) (*domain.Token, error) {
+	return m.generateToken(user, previous)
+}
+
+// generateToken creates a token pair, in the session of previous when there is one
+func (m *JWTManager) generateToken(user *domain.User, previous *domain.Token) (*domain.Token, error) {
+	// Refresh tokens are opaque 256-bit secrets
+	refreshTokenBytes := make([]byte, 32)
+	if _, err := rand.Read(refreshTokenBytes); err != nil {
+		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+	refreshTokenString := base64.RawURLEncoding.EncodeToString(refreshTokenBytes)
+	expiresAt := time.Now().Add(m.refreshTokenTTL)
+
+	token := domain.NewToken(user.ID, "", refreshTokenString, m.HashRefreshToken(refreshTokenString), expiresAt)
+	if previous != nil {
+		token.FamilyID = previous.FamilyID
+		token.SessionStartedAt = previous.SessionStartedAt
+		token.UserAgent = previous.UserAgent
+		token.IPAddress = previous.IPAddress
+	}
+
+	now := time.Now()
+	token.AccessTokenID = uuid.NewString()
+	token.AccessTokenExpiresAt = now.Add(m.tokenTTL)
 	claims := Claims{
-		UserID: user.ID,
-		Email:  user.Email,
+		UserID:      user.ID,
+		Email:       user.Email,
+		SessionID:   token.FamilyID,
+		Roles:       user.Roles,
+		Permissions: user.Permissions(),
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(m.tokenTTL)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
+			ID:        token.AccessTokenID,
+			ExpiresAt: jwt.NewNumericDate(token.AccessTokenExpiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
 		},
 	}
 
-	accessToken := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	accessTokenString, err := accessToken.SignedString(m.secretKey)
+	accessTokenString, err := m.sign(claims)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate access token: %w", err)
 	}
+	token.AccessToken = accessTokenString
 
-	refreshTokenString := uuid.New().String()
-	expiresAt := time.Now().Add(m.refreshTokenTTL)
-
-	token := domain.NewToken(user.ID, accessTokenString, refreshTokenString, expiresAt)
 	return token, nil
 }
 
-// ValidateAccessToken validates an access token and returns the claims
-func (m *JWTManager) ValidateAccessToken(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
+// HashRefreshToken returns the keyed hash a refresh token is stored and looked up by
+func (m *JWTManager) HashRefreshToken(refreshToken string) string {
+	mac := hmac.New(sha256.New, m.refreshTokenKey)
+	mac.Write([]byte(refreshToken))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// sign signs the claims with the current key of the key set, or with the HS256 secret
+func (m *JWTManager) sign(claims Claims) (string, error) {
+	if m.keys == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(m.secretKey)
+	}
+
+	key, err := m.keys.SigningKey(time.Now())
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.privateKey)
+}
+
+// verificationKey picks the key of a token by its kid, refusing any algorithm other than the key's
+func (m *JWTManager) verificationKey(token *jwt.Token) (interface{}, error) {
+	if m.keys == nil {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
 		return m.secretKey, nil
-	})
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	key, err := m.keys.VerificationKey(kid, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.PublicKey(), nil
+}
+
+// JWKS returns the public keys access tokens can be verified with, empty with HS256
+func (m *JWTManager) JWKS() JWKS {
+	if m.keys == nil {
+		return JWKS{Keys: []JWK{}}
+	}
+	return m.keys.JWKS(time.Now())
+}
+
+// ValidateAccessToken validates an access token and returns the claims
+func (m *JWTManager) ValidateAccessToken(tokenString string) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, m.verificationKey)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse token: %w", err)
 	}
 
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
-		return claims, nil
+	// Tokens without a jti could never be revoked
+	claims, ok := token.Claims.(*Claims)
+	if !ok || !token.Valid || claims.ID == "" {
+		return nil, fmt.Errorf("invalid token")
 	}
 
-	return nil, fmt.Errorf("invalid token")
+	revoked, err := m.denylist.Contains(claims.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check token revocation: %w", err)
+	}
+	if revoked {
+		return nil, domain.ErrTokenRevoked
+	}
+
+	return claims, nil
+}
+
+// RevokeAccessToken denies the access token issued with token until it expires
+func (m *JWTManager) RevokeAccessToken(token *domain.Token) error {
+	if token.AccessTokenID == "" || !time.Now().Before(token.AccessTokenExpiresAt) {
+		return nil
+	}
+	return m.denylist.Add(token.AccessTokenID, token.AccessTokenExpiresAt)
 }
 
 // ValidateRefreshToken validates a refresh token
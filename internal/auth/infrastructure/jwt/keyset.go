@@ -0,0 +1,248 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/algosim/backend/configs"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Supported asymmetric signing algorithms
+const (
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+// SigningKey is one asymmetric key of a KeySet
+type SigningKey struct {
+	ID        string
+	Algorithm string
+	// ActiveFrom is when the key starts signing, it verifies as soon as it is loaded
+	ActiveFrom time.Time
+	// RetireAt is when the key stops verifying, zero means never
+	RetireAt time.Time
+
+	privateKey crypto.Signer
+	method     jwt.SigningMethod
+}
+
+// NewSigningKey creates a signing key and checks the private key fits the algorithm
+func NewSigningKey(id, algorithm string, privateKey crypto.Signer, activeFrom, retireAt time.Time) (*SigningKey, error) {
+	if id == "" {
+		return nil, fmt.Errorf("signing key has no id")
+	}
+
+	key := &SigningKey{
+		ID:         id,
+		Algorithm:  algorithm,
+		ActiveFrom: activeFrom,
+		RetireAt:   retireAt,
+		privateKey: privateKey,
+	}
+
+	switch algorithm {
+	case AlgorithmRS256:
+		if _, ok := privateKey.(*rsa.PrivateKey); !ok {
+			return nil, fmt.Errorf("signing key %s: %s needs an RSA key", id, algorithm)
+		}
+		key.method = jwt.SigningMethodRS256
+	case AlgorithmEdDSA:
+		if _, ok := privateKey.(ed25519.PrivateKey); !ok {
+			return nil, fmt.Errorf("signing key %s: %s needs an Ed25519 key", id, algorithm)
+		}
+		key.method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("signing key %s: unsupported algorithm %q", id, algorithm)
+	}
+
+	return key, nil
+}
+
+// PublicKey returns the public half of the key
+func (k *SigningKey) PublicKey() crypto.PublicKey {
+	return k.privateKey.Public()
+}
+
+// isActive reports whether the key may sign at now
+func (k *SigningKey) isActive(now time.Time) bool {
+	return !now.Before(k.ActiveFrom) && !k.isRetired(now)
+}
+
+// isRetired reports whether the key no longer verifies at now
+func (k *SigningKey) isRetired(now time.Time) bool {
+	return !k.RetireAt.IsZero() && !now.Before(k.RetireAt)
+}
+
+// KeySet holds the keys access tokens are signed and verified with
+type KeySet struct {
+	keys map[string]*SigningKey
+}
+
+// NewKeySet creates a key set, key IDs must be unique
+func NewKeySet(keys ...*SigningKey) (*KeySet, error) {
+	set := &KeySet{keys: make(map[string]*SigningKey, len(keys))}
+	for _, key := range keys {
+		if _, exists := set.keys[key.ID]; exists {
+			return nil, fmt.Errorf("duplicate signing key id %q", key.ID)
+		}
+		set.keys[key.ID] = key
+	}
+	return set, nil
+}
+
+// LoadKeySet reads the configured PEM private keys
+func LoadKeySet(keyConfigs []configs.SigningKeyConfig) (*KeySet, error) {
+	keys := make([]*SigningKey, 0, len(keyConfigs))
+	for _, kc := range keyConfigs {
+		data, err := os.ReadFile(kc.PrivateKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("signing key %s: %w", kc.ID, err)
+		}
+		privateKey, err := ParsePrivateKeyPEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("signing key %s: %w", kc.ID, err)
+		}
+
+		activeFrom, err := parseOptionalTime(kc.ActiveFrom)
+		if err != nil {
+			return nil, fmt.Errorf("signing key %s: invalid active_from: %w", kc.ID, err)
+		}
+		retireAt, err := parseOptionalTime(kc.RetireAt)
+		if err != nil {
+			return nil, fmt.Errorf("signing key %s: invalid retire_at: %w", kc.ID, err)
+		}
+
+		key, err := NewSigningKey(kc.ID, kc.Algorithm, privateKey, activeFrom, retireAt)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return NewKeySet(keys...)
+}
+
+// ParsePrivateKeyPEM parses a PKCS #8 or PKCS #1 PEM encoded private key
+func ParsePrivateKeyPEM(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found")
+	}
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse private key: %w", err)
+		}
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("unsupported private key type %T", key)
+		}
+		return signer, nil
+	case "RSA PRIVATE KEY":
+		key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse private key: %w", err)
+		}
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+}
+
+// SigningKey returns the most recently activated key that may sign at now
+func (s *KeySet) SigningKey(now time.Time) (*SigningKey, error) {
+	var current *SigningKey
+	for _, key := range s.keys {
+		if !key.isActive(now) {
+			continue
+		}
+		if current == nil || key.ActiveFrom.After(current.ActiveFrom) ||
+			(key.ActiveFrom.Equal(current.ActiveFrom) && key.ID > current.ID) {
+			current = key
+		}
+	}
+
+	if current == nil {
+		return nil, fmt.Errorf("no active signing key")
+	}
+	return current, nil
+}
+
+// VerificationKey returns the key with the given ID unless it is unknown or retired
+func (s *KeySet) VerificationKey(kid string, now time.Time) (*SigningKey, error) {
+	key, exists := s.keys[kid]
+	if !exists || key.isRetired(now) {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return key, nil
+}
+
+// JWK is a public key in RFC 7517 JSON Web Key format
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	// RSA parameters
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// OKP parameters
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+}
+
+// JWKS is an RFC 7517 JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys that verify at now, including keys that will only sign later
+// so verifiers have them cached before rotation
+func (s *KeySet) JWKS(now time.Time) JWKS {
+	jwks := JWKS{Keys: []JWK{}}
+	for _, key := range s.keys {
+		if key.isRetired(now) {
+			continue
+		}
+
+		jwk := JWK{
+			KeyID:     key.ID,
+			Use:       "sig",
+			Algorithm: key.Algorithm,
+		}
+		switch pub := key.PublicKey().(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+
+	sort.Slice(jwks.Keys, func(i, j int) bool { return jwks.Keys[i].KeyID < jwks.Keys[j].KeyID })
+	return jwks
+}
+
+// parseOptionalTime parses an RFC 3339 timestamp, empty means the zero time
+func parseOptionalTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
@@ -0,0 +1,208 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/algosim/backend/configs"
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/algosim/backend/pkg/httpclient"
+	"github.com/google/uuid"
+)
+
+const (
+	githubAuthURL     = "https://github.com/login/oauth/authorize"
+	githubTokenURL    = "https://github.com/login/oauth/access_token"
+	githubUserInfoURL = "https://api.github.com/user"
+)
+
+// GitHubUserInfo represents the user information returned by GitHub
+type GitHubUserInfo struct {
+	ID        int64  `json:"id"`
+	Login     string `json:"login"`
+	Name      string `json:"name"`
+	Email     string `json:"email"`
+	AvatarURL string `json:"avatar_url"`
+}
+
+// GitHubEmail represents one entry of the authenticated user's email list
+type GitHubEmail struct {
+	Email    string `json:"email"`
+	Primary  bool   `json:"primary"`
+	Verified bool   `json:"verified"`
+}
+
+// GitHubOAuthImpl handles GitHub OAuth authentication
+type GitHubOAuthImpl struct {
+	config      configs.OAuthProviderConfig
+	authURL     string
+	tokenURL    string
+	userInfoURL string
+	emailsURL   string
+	scopes      []string
+	client      *httpclient.Client
+}
+
+// NewGitHubOAuth creates a new GitHub OAuth handler calling GitHub through client
+func NewGitHubOAuth(config configs.OAuthProviderConfig, client *httpclient.Client) *GitHubOAuthImpl {
+	scopes := config.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"read:user", "user:email"}
+	}
+
+	userInfoURL := valueOrDefault(config.UserInfoURL, githubUserInfoURL)
+	return &GitHubOAuthImpl{
+		config:      config,
+		authURL:     valueOrDefault(config.AuthURL, githubAuthURL),
+		tokenURL:    valueOrDefault(config.TokenURL, githubTokenURL),
+		userInfoURL: userInfoURL,
+		emailsURL:   valueOrDefault(config.EmailsURL, userInfoURL+"/emails"),
+		scopes:      scopes,
+		client:      client,
+	}
+}
+
+// Name returns the registry key of the GitHub provider
+func (g *GitHubOAuthImpl) Name() string {
+	return ProviderGitHub
+}
+
+// GetAuthURL generates the GitHub OAuth authorization URL with an S256 PKCE challenge
+func (g *GitHubOAuthImpl) GetAuthURL(ctx context.Context, state, codeChallenge, nonce string) (string, error) {
+	params := url.Values{}
+	params.Add("client_id", g.config.ClientID)
+	params.Add("redirect_uri", g.config.RedirectURI)
+	params.Add("scope", strings.Join(g.scopes, " "))
+	params.Add("state", state)
+	params.Add("code_challenge", codeChallenge)
+	params.Add("code_challenge_method", CodeChallengeMethodS256)
+
+	return fmt.Sprintf("%s?%s", g.authURL, params.Encode()), nil
+}
+
+// ExchangeCodeForToken exchanges the authorization code and its PKCE verifier for an access token
+func (g *GitHubOAuthImpl) ExchangeCodeForToken(ctx context.Context, code, codeVerifier string) (*domain.Token, error) {
+	params := url.Values{}
+	params.Add("client_id", g.config.ClientID)
+	params.Add("client_secret", g.config.ClientSecret)
+	params.Add("code", code)
+	params.Add("code_verifier", codeVerifier)
+	params.Add("redirect_uri", g.config.RedirectURI)
+
+	// GitHub answers with a form-encoded body unless JSON is asked for
+	resp, err := g.client.PostForm(ctx, g.tokenURL, params, http.Header{"Accept": {"application/json"}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange code for token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("failed to exchange code for token: %s", string(body))
+	}
+
+	var tokenResp struct {
+		AccessToken      string `json:"access_token"`
+		RefreshToken     string `json:"refresh_token"`
+		ExpiresIn        int    `json:"expires_in"`
+		TokenType        string `json:"token_type"`
+		Scope            string `json:"scope"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
+		return nil, fmt.Errorf("failed to decode token response: %w", err)
+	}
+
+	// GitHub reports grant errors with a 200 status
+	if tokenResp.Error != "" {
+		return nil, fmt.Errorf("failed to exchange code for token: %s: %s", tokenResp.Error, tokenResp.ErrorDescription)
+	}
+
+	// Create domain token
+	token := &domain.Token{
+		ID:           uuid.New(),
+		AccessToken:  tokenResp.AccessToken,
+		RefreshToken: tokenResp.RefreshToken,
+	}
+	if tokenResp.ExpiresIn > 0 {
+		token.ExpiresAt = time.Now().Add(time.Duration(tokenResp.ExpiresIn) * time.Second)
+	}
+
+	return token, nil
+}
+
+// GetUserInfo retrieves user information from GitHub
+func (g *GitHubOAuthImpl) GetUserInfo(ctx context.Context, accessToken string) (*UserInfo, error) {
+	var userInfo GitHubUserInfo
+	if err := g.getJSON(ctx, g.userInfoURL, accessToken, &userInfo); err != nil {
+		return nil, fmt.Errorf("failed to get user info: %w", err)
+	}
+
+	// The profile email is only set when the user made it public and says nothing about
+	// verification, the email list does
+	email := userInfo.Email
+	verified := false
+	var emails []GitHubEmail
+	if err := g.getJSON(ctx, g.emailsURL, accessToken, &emails); err != nil {
+		if email == "" {
+			return nil, fmt.Errorf("failed to get user emails: %w", err)
+		}
+	}
+	for _, e := range emails {
+		if email == "" && e.Primary && e.Verified {
+			email = e.Email
+		}
+		if e.Email == email {
+			verified = e.Verified
+			break
+		}
+	}
+
+	name := userInfo.Name
+	if name == "" {
+		name = userInfo.Login
+	}
+
+	return &UserInfo{
+		ID:            strconv.FormatInt(userInfo.ID, 10),
+		Email:         email,
+		EmailVerified: verified,
+		Name:          name,
+		AvatarURL:     userInfo.AvatarURL,
+	}, nil
+}
+
+// getJSON performs an authenticated GitHub API request and decodes the response into v
+func (g *GitHubOAuthImpl) getJSON(ctx context.Context, endpoint, accessToken string, v any) error {
+	resp, err := g.client.Get(ctx, endpoint, http.Header{
+		"Authorization": {fmt.Sprintf("Bearer %s", accessToken)},
+		"Accept":        {"application/vnd.github+json"},
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("%s", string(body))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return nil
+}
+
+// Ensure GitHubOAuthImpl implements OAuthProvider interface
+var _ OAuthProvider = (*GitHubOAuthImpl)(nil)
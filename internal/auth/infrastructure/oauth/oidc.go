@@ -0,0 +1,493 @@
+package oauth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/algosim/backend/configs"
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/algosim/backend/pkg/httpclient"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	oidcDiscoveryPath = "/.well-known/openid-configuration"
+	// idTokenLeeway tolerates clock skew between us and the issuer
+	idTokenLeeway = 30 * time.Second
+)
+
+// idTokenAlgorithms are the ID token signing algorithms we accept, never HMAC
+var idTokenAlgorithms = []string{"RS256", "ES256"}
+
+// oidcMetadata is the part of the issuer's discovery document we use
+type oidcMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserInfoEndpoint      string `json:"userinfo_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// OIDCProvider signs users in with an OpenID Connect provider. Endpoints and keys are
+// discovered from the issuer on first use, configured endpoint URLs take precedence.
+type OIDCProvider struct {
+	config configs.OAuthProviderConfig
+	// acceptedIssuers are the iss values an ID token may carry
+	acceptedIssuers []string
+	scopes          []string
+	// authParams are extra parameters sent with every login
+	authParams url.Values
+	client     *httpclient.Client
+
+	// mu guards the cached metadata and keys, it is never held while calling the issuer
+	mu       sync.Mutex
+	metadata *oidcMetadata
+	keys     map[string]crypto.PublicKey
+}
+
+// NewOIDCProvider creates a provider for the issuer in config calling it through client
+func NewOIDCProvider(config configs.OAuthProviderConfig, client *httpclient.Client) *OIDCProvider {
+	config.Issuer = strings.TrimSuffix(config.Issuer, "/")
+
+	scopes := config.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"openid", "email", "profile"}
+	}
+	if !slices.Contains(scopes, "openid") {
+		scopes = append([]string{"openid"}, scopes...)
+	}
+
+	return &OIDCProvider{
+		config:          config,
+		acceptedIssuers: []string{config.Issuer},
+		scopes:          scopes,
+		authParams:      url.Values{},
+		client:          client,
+	}
+}
+
+// Name returns the registry key of the provider
+func (p *OIDCProvider) Name() string {
+	return p.config.Name
+}
+
+// GetAuthURL generates the authorization URL with an S256 PKCE challenge and the nonce
+func (p *OIDCProvider) GetAuthURL(ctx context.Context, state, codeChallenge, nonce string) (string, error) {
+	authURL, err := p.endpoint(ctx, p.config.AuthURL, func(m *oidcMetadata) string { return m.AuthorizationEndpoint })
+	if err != nil {
+		return "", err
+	}
+
+	params := url.Values{}
+	for key, values := range p.authParams {
+		params[key] = values
+	}
+	params.Add("client_id", p.config.ClientID)
+	params.Add("redirect_uri", p.config.RedirectURI)
+	params.Add("response_type", "code")
+	params.Add("scope", strings.Join(p.scopes, " "))
+	params.Add("state", state)
+	params.Add("nonce", nonce)
+	params.Add("code_challenge", codeChallenge)
+	params.Add("code_challenge_method", CodeChallengeMethodS256)
+
+	return fmt.Sprintf("%s?%s", authURL, params.Encode()), nil
+}
+
+// oidcTokenResponse is the token endpoint response of an OpenID Connect provider
+type oidcTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	IDToken      string `json:"id_token"`
+	ExpiresIn    int    `json:"expires_in"`
+	TokenType    string `json:"token_type"`
+}
+
+// exchange redeems the authorization code and its PKCE verifier at the token endpoint
+func (p *OIDCProvider) exchange(ctx context.Context, code, codeVerifier string) (*oidcTokenResponse, error) {
+	tokenURL, err := p.endpoint(ctx, p.config.TokenURL, func(m *oidcMetadata) string { return m.TokenEndpoint })
+	if err != nil {
+		return nil, err
+	}
+
+	params := url.Values{}
+	params.Add("client_id", p.config.ClientID)
+	params.Add("client_secret", p.config.ClientSecret)
+	params.Add("code", code)
+	params.Add("code_verifier", codeVerifier)
+	params.Add("grant_type", "authorization_code")
+	params.Add("redirect_uri", p.config.RedirectURI)
+
+	resp, err := p.client.PostForm(ctx, tokenURL, params, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange code for token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("failed to exchange code for token: %s", string(body))
+	}
+
+	var tokenResp oidcTokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
+		return nil, fmt.Errorf("failed to decode token response: %w", err)
+	}
+	return &tokenResp, nil
+}
+
+// ExchangeCodeForToken exchanges the authorization code and its PKCE verifier for access and refresh tokens
+func (p *OIDCProvider) ExchangeCodeForToken(ctx context.Context, code, codeVerifier string) (*domain.Token, error) {
+	tokenResp, err := p.exchange(ctx, code, codeVerifier)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.Token{
+		ID:           uuid.New(),
+		AccessToken:  tokenResp.AccessToken,
+		RefreshToken: tokenResp.RefreshToken,
+		ExpiresAt:    time.Now().Add(time.Duration(tokenResp.ExpiresIn) * time.Second),
+	}, nil
+}
+
+// ExchangeCodeForIDToken exchanges the authorization code and returns the user of the verified ID token
+func (p *OIDCProvider) ExchangeCodeForIDToken(ctx context.Context, code, codeVerifier, nonce string) (*UserInfo, error) {
+	tokenResp, err := p.exchange(ctx, code, codeVerifier)
+	if err != nil {
+		return nil, err
+	}
+	if tokenResp.IDToken == "" {
+		return nil, fmt.Errorf("%w: token response has no id_token", domain.ErrInvalidIDToken)
+	}
+	return p.verifyIDToken(ctx, tokenResp.IDToken, nonce)
+}
+
+// idTokenClaims are the ID token claims we check or use
+type idTokenClaims struct {
+	Nonce           string    `json:"nonce"`
+	AuthorizedParty string    `json:"azp"`
+	Email           string    `json:"email"`
+	EmailVerified   claimBool `json:"email_verified"`
+	Name            string    `json:"name"`
+	Picture         string    `json:"picture"`
+	jwt.RegisteredClaims
+}
+
+// verifyIDToken checks the signature, issuer, audience, expiry and nonce of an ID token
+func (p *OIDCProvider) verifyIDToken(ctx context.Context, rawIDToken, nonce string) (*UserInfo, error) {
+	claims := &idTokenClaims{}
+	keyFunc := func(token *jwt.Token) (any, error) { return p.verificationKey(ctx, token) }
+	_, err := jwt.ParseWithClaims(rawIDToken, claims, keyFunc,
+		jwt.WithValidMethods(idTokenAlgorithms),
+		jwt.WithAudience(p.config.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(idTokenLeeway),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrInvalidIDToken, err)
+	}
+
+	if !slices.Contains(p.acceptedIssuers, claims.Issuer) {
+		return nil, fmt.Errorf("%w: unexpected issuer %q", domain.ErrInvalidIDToken, claims.Issuer)
+	}
+	// A token for several clients must name us as the one it was issued to
+	if len(claims.Audience) > 1 && claims.AuthorizedParty != p.config.ClientID {
+		return nil, fmt.Errorf("%w: unexpected authorized party %q", domain.ErrInvalidIDToken, claims.AuthorizedParty)
+	}
+	if nonce == "" || subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1 {
+		return nil, fmt.Errorf("%w: nonce mismatch", domain.ErrInvalidIDToken)
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: no subject", domain.ErrInvalidIDToken)
+	}
+
+	return &UserInfo{
+		ID:            claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: bool(claims.EmailVerified),
+		Name:          claims.Name,
+		AvatarURL:     claims.Picture,
+	}, nil
+}
+
+// GetUserInfo retrieves the standard claims of the user from the user info endpoint
+func (p *OIDCProvider) GetUserInfo(ctx context.Context, accessToken string) (*UserInfo, error) {
+	userInfoURL, err := p.endpoint(ctx, p.config.UserInfoURL, func(m *oidcMetadata) string { return m.UserInfoEndpoint })
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.client.Get(ctx, userInfoURL, http.Header{"Authorization": {fmt.Sprintf("Bearer %s", accessToken)}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user info: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("failed to get user info: %s", string(body))
+	}
+
+	var claims idTokenClaims
+	if err := json.NewDecoder(resp.Body).Decode(&claims); err != nil {
+		return nil, fmt.Errorf("failed to decode user info: %w", err)
+	}
+
+	return &UserInfo{
+		ID:            claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: bool(claims.EmailVerified),
+		Name:          claims.Name,
+		AvatarURL:     claims.Picture,
+	}, nil
+}
+
+// endpoint returns the configured override or else the endpoint the issuer publishes
+func (p *OIDCProvider) endpoint(ctx context.Context, override string, published func(*oidcMetadata) string) (string, error) {
+	if override != "" {
+		return override, nil
+	}
+
+	metadata, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	if endpoint := published(metadata); endpoint != "" {
+		return endpoint, nil
+	}
+	return "", fmt.Errorf("oidc issuer %s publishes no such endpoint", p.config.Issuer)
+}
+
+// discover fetches the discovery document once. Callers racing on the first use each fetch it
+// and the first to finish is kept.
+func (p *OIDCProvider) discover(ctx context.Context) (*oidcMetadata, error) {
+	p.mu.Lock()
+	cached := p.metadata
+	p.mu.Unlock()
+	if cached != nil {
+		return cached, nil
+	}
+	if p.config.Issuer == "" {
+		return nil, fmt.Errorf("oauth provider %s has no oidc issuer", p.config.Name)
+	}
+
+	var metadata oidcMetadata
+	if err := p.getJSON(ctx, p.config.Issuer+oidcDiscoveryPath, &metadata); err != nil {
+		return nil, fmt.Errorf("failed to discover oidc issuer: %w", err)
+	}
+	// The document must describe the issuer we asked, not one it was redirected to
+	if strings.TrimSuffix(metadata.Issuer, "/") != p.config.Issuer {
+		return nil, fmt.Errorf("oidc discovery issuer %q does not match %q", metadata.Issuer, p.config.Issuer)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.metadata == nil {
+		p.metadata = &metadata
+	}
+	return p.metadata, nil
+}
+
+// verificationKey returns the issuer key an ID token is signed with. The key set is cached
+// and fetched again when the token names a key it does not have, which happens after the
+// issuer rotates. ID tokens only come from the token endpoint so a refetch cannot be forced
+// by a client.
+func (p *OIDCProvider) verificationKey(ctx context.Context, token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	p.mu.Lock()
+	cached := p.keys
+	p.mu.Unlock()
+	if key, ok := lookupKey(cached, kid); ok {
+		return key, nil
+	}
+
+	keys, err := p.fetchKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	p.keys = keys
+	p.mu.Unlock()
+	if key, ok := lookupKey(keys, kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// lookupKey finds the key with kid, a token without kid matches a set of one key
+func lookupKey(keys map[string]crypto.PublicKey, kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(keys) == 1 {
+		for _, key := range keys {
+			return key, true
+		}
+	}
+	key, ok := keys[kid]
+	return key, ok
+}
+
+// jsonWebKey is an RFC 7517 public key as published by an issuer
+type jsonWebKey struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n"`
+	E         string `json:"e"`
+	Curve     string `json:"crv"`
+	X         string `json:"x"`
+	Y         string `json:"y"`
+}
+
+// fetchKeys fetches the issuer's key set
+func (p *OIDCProvider) fetchKeys(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	jwksURL := p.config.JWKSURL
+	if jwksURL == "" {
+		metadata, err := p.discover(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if metadata.JWKSURI == "" {
+			return nil, fmt.Errorf("oidc issuer %s publishes no jwks_uri", p.config.Issuer)
+		}
+		jwksURL = metadata.JWKSURI
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := p.getJSON(ctx, jwksURL, &set); err != nil {
+		return nil, fmt.Errorf("failed to fetch oidc signing keys: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			// Keys of types we do not verify with are skipped, not fatal
+			continue
+		}
+		keys[jwk.KeyID] = key
+	}
+
+	return keys, nil
+}
+
+// publicKey decodes an RSA or P-256 key
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.KeyType {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if k.Curve != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Curve)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != 32 || len(y) != 32 {
+			return nil, fmt.Errorf("invalid P-256 coordinates")
+		}
+		// Rejects points that are not on the curve
+		if _, err := ecdh.P256().NewPublicKey(append(append([]byte{4}, x...), y...)); err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.KeyType)
+	}
+}
+
+// decodeBigInt decodes a base64url encoded unsigned big-endian integer
+func decodeBigInt(value string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, fmt.Errorf("empty integer")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// getJSON fetches url and decodes the JSON response into out
+func (p *OIDCProvider) getJSON(ctx context.Context, url string, out any) error {
+	resp, err := p.client.Get(ctx, url, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s answered %d", url, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// claimBool is a boolean claim that some issuers send as a string
+type claimBool bool
+
+func (b *claimBool) UnmarshalJSON(data []byte) error {
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+
+	switch v := value.(type) {
+	case bool:
+		*b = claimBool(v)
+	case string:
+		parsed, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid boolean claim %q", v)
+		}
+		*b = claimBool(parsed)
+	case nil:
+		*b = false
+	default:
+		return fmt.Errorf("invalid boolean claim %s", data)
+	}
+	return nil
+}
+
+// Ensure OIDCProvider implements IDTokenProvider interface
+var _ IDTokenProvider = (*OIDCProvider)(nil)
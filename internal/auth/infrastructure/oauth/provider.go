@@ -0,0 +1,43 @@
+package oauth
+
+import (
+	"context"
+
+	"github.com/algosim/backend/internal/auth/domain"
+)
+
+// Names of the built-in OAuth providers
+const (
+	ProviderGoogle = "google"
+	ProviderGitHub = "github"
+)
+
+// OAuthProvider defines the operations every OAuth login provider supports
+type OAuthProvider interface {
+	// Name returns the key the provider is registered under
+	Name() string
+	// GetAuthURL returns the login URL, nonce is only sent by OpenID Connect providers
+	GetAuthURL(ctx context.Context, state, codeChallenge, nonce string) (string, error)
+	ExchangeCodeForToken(ctx context.Context, code, codeVerifier string) (*domain.Token, error)
+	GetUserInfo(ctx context.Context, accessToken string) (*UserInfo, error)
+}
+
+// IDTokenProvider is an OAuthProvider signing users in with an OpenID Connect ID token.
+// The user comes from the verified token response, no user info request is made.
+type IDTokenProvider interface {
+	OAuthProvider
+	// ExchangeCodeForIDToken exchanges the authorization code and returns the user of the
+	// ID token after checking its signature, issuer, audience, expiry and nonce
+	ExchangeCodeForIDToken(ctx context.Context, code, codeVerifier, nonce string) (*UserInfo, error)
+}
+
+// UserInfo represents the provider-independent profile of a signed-in user
+type UserInfo struct {
+	// ID is the stable subject identifier assigned by the provider
+	ID    string
+	Email string
+	// EmailVerified is true only when the provider vouches the user owns Email
+	EmailVerified bool
+	Name          string
+	AvatarURL     string
+}
@@ -0,0 +1,98 @@
+package oauth
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/algosim/backend/configs"
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/algosim/backend/pkg/httpclient"
+)
+
+// Registry holds the configured OAuth providers keyed by name
+type Registry struct {
+	providers map[string]OAuthProvider
+}
+
+// NewRegistry creates a registry of the given providers
+func NewRegistry(providers ...OAuthProvider) *Registry {
+	r := &Registry{
+		providers: make(map[string]OAuthProvider, len(providers)),
+	}
+	for _, p := range providers {
+		r.providers[p.Name()] = p
+	}
+	return r
+}
+
+// NewRegistryFromConfig creates a registry of the providers listed in config. Each provider
+// gets its own HTTP client, so one failing provider does not trip the others, and its calls
+// are recorded with recorder.
+func NewRegistryFromConfig(config *configs.Config, recorder httpclient.Recorder) (*Registry, error) {
+	clientConfig, err := httpClientConfig(config.OAuth.HTTP)
+	if err != nil {
+		return nil, err
+	}
+
+	r := NewRegistry()
+	for _, p := range config.OAuth.Providers {
+		if _, exists := r.providers[p.Name]; exists {
+			return nil, fmt.Errorf("oauth provider %q configured twice", p.Name)
+		}
+
+		client := httpclient.New("oauth_"+p.Name, clientConfig, recorder)
+		switch {
+		case p.Name == ProviderGoogle:
+			r.providers[p.Name] = NewGoogleOAuth(p, client)
+		case p.Name == ProviderGitHub:
+			r.providers[p.Name] = NewGitHubOAuth(p, client)
+		case p.Name != "" && p.Issuer != "":
+			// Any other provider speaking OpenID Connect
+			r.providers[p.Name] = NewOIDCProvider(p, client)
+		default:
+			return nil, fmt.Errorf("oauth provider %q: %w", p.Name, domain.ErrOAuthProviderNotSupported)
+		}
+	}
+	return r, nil
+}
+
+// httpClientConfig converts the configured seconds and milliseconds
+func httpClientConfig(c configs.HTTPClientConfig) (httpclient.Config, error) {
+	clientConfig := httpclient.Config{
+		Timeout:          time.Duration(c.Timeout) * time.Second,
+		MaxRetries:       c.MaxRetries,
+		RetryBaseDelay:   time.Duration(c.RetryBaseDelayMs) * time.Millisecond,
+		RetryMaxDelay:    time.Duration(c.RetryMaxDelayMs) * time.Millisecond,
+		BreakerThreshold: c.BreakerThreshold,
+		BreakerCooldown:  time.Duration(c.BreakerCooldown) * time.Second,
+	}
+	if c.ProxyURL != "" {
+		proxy, err := url.Parse(c.ProxyURL)
+		if err != nil || proxy.Host == "" {
+			return httpclient.Config{}, fmt.Errorf("invalid oauth proxy url %q", c.ProxyURL)
+		}
+		clientConfig.Proxy = proxy
+	}
+	return clientConfig, nil
+}
+
+// Get returns the provider registered under name
+func (r *Registry) Get(name string) (OAuthProvider, error) {
+	p, exists := r.providers[name]
+	if !exists {
+		return nil, domain.ErrOAuthProviderNotSupported
+	}
+	return p, nil
+}
+
+// Names returns the names of all registered providers in sorted order
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.providers))
+	for name := range r.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
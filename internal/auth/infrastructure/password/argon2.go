@@ -0,0 +1,129 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/algosim/backend/configs"
+	"golang.org/x/crypto/argon2"
+)
+
+// Default argon2id cost, the second recommended option of RFC 9106
+const (
+	defaultMemory      = 64 * 1024
+	defaultIterations  = 3
+	defaultParallelism = 2
+	saltLength         = 16
+	keyLength          = 32
+)
+
+// Hasher defines the interface for password hashing
+type Hasher interface {
+	// Hash derives a self-describing hash of password with a fresh salt
+	Hash(password string) (string, error)
+	// Verify reports whether password matches the encoded hash
+	Verify(password, encoded string) (bool, error)
+	// NeedsRehash reports whether encoded was produced with other than the current cost
+	NeedsRehash(encoded string) bool
+}
+
+// Argon2idHasher hashes passwords with argon2id and encodes them in the PHC string format
+type Argon2idHasher struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+}
+
+// NewArgon2idHasher creates a new argon2id hasher with the cost from config
+func NewArgon2idHasher(config *configs.Config) *Argon2idHasher {
+	h := &Argon2idHasher{
+		memory:      config.Password.Argon2Memory,
+		iterations:  config.Password.Argon2Iterations,
+		parallelism: config.Password.Argon2Parallelism,
+	}
+	if h.memory == 0 {
+		h.memory = defaultMemory
+	}
+	if h.iterations == 0 {
+		h.iterations = defaultIterations
+	}
+	if h.parallelism == 0 {
+		h.parallelism = defaultParallelism
+	}
+	return h
+}
+
+// Hash derives an argon2id hash of password
+func (h *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	key := argon2.IDKey([]byte(password), salt, h.iterations, h.memory, h.parallelism, keyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, h.memory, h.iterations, h.parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// Verify checks password against an encoded hash in constant time, using the cost stored in the hash
+func (h *Argon2idHasher) Verify(password, encoded string) (bool, error) {
+	params, salt, key, err := decodeHash(encoded)
+	if err != nil {
+		return false, err
+	}
+
+	candidate := argon2.IDKey([]byte(password), salt, params.iterations, params.memory, params.parallelism, uint32(len(key)))
+	return subtle.ConstantTimeCompare(candidate, key) == 1, nil
+}
+
+// NeedsRehash reports whether encoded should be replaced after the configured cost changed
+func (h *Argon2idHasher) NeedsRehash(encoded string) bool {
+	params, _, _, err := decodeHash(encoded)
+	if err != nil {
+		return true
+	}
+	return params != *h
+}
+
+// decodeHash parses a PHC formatted argon2id hash
+func decodeHash(encoded string) (Argon2idHasher, []byte, []byte, error) {
+	var params Argon2idHasher
+
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return params, nil, nil, fmt.Errorf("unsupported password hash format")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("unsupported argon2 version")
+	}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.iterations, &params.parallelism); err != nil {
+		return params, nil, nil, fmt.Errorf("invalid argon2 parameters: %w", err)
+	}
+	if params.iterations == 0 || params.parallelism == 0 {
+		return params, nil, nil, fmt.Errorf("invalid argon2 parameters")
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, fmt.Errorf("invalid salt: %w", err)
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, fmt.Errorf("invalid hash")
+	}
+
+	return params, salt, key, nil
+}
+
+// Ensure Argon2idHasher implements Hasher interface
+var _ Hasher = (*Argon2idHasher)(nil)
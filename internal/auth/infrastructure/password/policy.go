@@ -0,0 +1,70 @@
+package password
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/algosim/backend/internal/auth/domain"
+)
+
+// MaxLength caps passwords so a request cannot make hashing arbitrarily expensive
+const MaxLength = 128
+
+// commonPasswords are rejected regardless of length
+var commonPasswords = map[string]bool{
+	"password":     true,
+	"password1":    true,
+	"password123":  true,
+	"passw0rd":     true,
+	"1234567890":   true,
+	"12345678910":  true,
+	"123456789012": true,
+	"qwertyuiop":   true,
+	"qwerty12345":  true,
+	"1q2w3e4r5t":   true,
+	"iloveyou123":  true,
+	"letmein123":   true,
+	"welcome123":   true,
+	"abcdefghij":   true,
+	"0123456789":   true,
+	"codeforces":   true,
+	"codeforces1":  true,
+	"atcoder123":   true,
+}
+
+// Validate checks password against the strength rules for an account with the given email
+func Validate(password, email string, minLength int) error {
+	length := utf8.RuneCountInString(password)
+	if length < minLength {
+		return fmt.Errorf("%w: must be at least %d characters", domain.ErrWeakPassword, minLength)
+	}
+	if length > MaxLength {
+		return fmt.Errorf("%w: must be at most %d characters", domain.ErrWeakPassword, MaxLength)
+	}
+
+	lower := strings.ToLower(password)
+	if commonPasswords[lower] {
+		return fmt.Errorf("%w: too common", domain.ErrWeakPassword)
+	}
+
+	if distinctRunes(password) < 4 {
+		return fmt.Errorf("%w: too few distinct characters", domain.ErrWeakPassword)
+	}
+
+	local, _, _ := strings.Cut(strings.ToLower(email), "@")
+	if len(local) >= 3 && strings.Contains(lower, local) {
+		return fmt.Errorf("%w: must not contain the email address", domain.ErrWeakPassword)
+	}
+
+	return nil
+}
+
+// distinctRunes counts the different characters in s
+func distinctRunes(s string) int {
+	seen := make(map[rune]bool)
+	for _, r := range s {
+		seen[r] = true
+	}
+	return len(seen)
+}
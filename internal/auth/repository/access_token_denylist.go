@@ -0,0 +1,11 @@
+package repository
+
+import "time"
+
+// AccessTokenDenylist defines the interface for revoked access token persistence
+type AccessTokenDenylist interface {
+	// Add revokes the access token with the given jti until it expires on its own
+	Add(jti string, expiresAt time.Time) error
+	// Contains reports whether the access token with the given jti is revoked
+	Contains(jti string) (bool, error)
+}
@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/google/uuid"
+)
+
+// DeviceAuthorizationRepository defines the interface for device sign-in persistence operations
+type DeviceAuthorizationRepository interface {
+	// Create stores a new device authorization
+	Create(authorization *domain.DeviceAuthorization) error
+	// FindByDeviceCodeHash finds a device authorization by the hash of its device code, expired
+	// ones included so the device learns its codes expired. Unknown codes yield
+	// domain.ErrDeviceAuthorizationNotFound.
+	FindByDeviceCodeHash(deviceCodeHash string) (*domain.DeviceAuthorization, error)
+	// FindByUserCode finds a device authorization by its normalized user code. Unknown or
+	// expired codes yield domain.ErrDeviceAuthorizationNotFound.
+	FindByUserCode(userCode string) (*domain.DeviceAuthorization, error)
+	// FindByUserID finds the device authorizations a user decided that no device redeemed yet
+	FindByUserID(userID uuid.UUID) ([]*domain.DeviceAuthorization, error)
+	// UpdatePoll records that the device polled at lastPollAt and the interval it must keep from
+	// now on. The decision is left alone, a poll never undoes an approval made meanwhile.
+	UpdatePoll(id uuid.UUID, lastPollAt time.Time, interval time.Duration) error
+	// Decide records the decision of userID on a device authorization as long as it is pending,
+	// so of concurrent decisions only the first counts. Otherwise it returns
+	// domain.ErrDeviceAuthorizationNotFound.
+	Decide(id uuid.UUID, status domain.DeviceAuthorizationStatus, userID uuid.UUID) error
+	// Delete deletes a device authorization by its ID
+	Delete(id uuid.UUID) error
+	// DeleteByUserID deletes every device authorization a user decided
+	DeleteByUserID(userID uuid.UUID) error
+	// Redeem deletes a device authorization by its ID as long as it has status, so of concurrent
+	// polls only one redeems a decision. Otherwise it returns domain.ErrDeviceAuthorizationNotFound.
+	Redeem(id uuid.UUID, status domain.DeviceAuthorizationStatus) error
+}
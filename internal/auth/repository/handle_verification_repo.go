@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/google/uuid"
+)
+
+// HandleVerificationRepository defines the interface for handle verification persistence operations
+type HandleVerificationRepository interface {
+	// Create stores a new verification, replacing the user's pending one for the same judge
+	Create(verification *domain.HandleVerification) error
+	// FindByUserAndJudge finds the user's pending verification for a judge
+	FindByUserAndJudge(userID uuid.UUID, judge string) (*domain.HandleVerification, error)
+	// FindByUserID finds the pending verifications of a user
+	FindByUserID(userID uuid.UUID) ([]*domain.HandleVerification, error)
+	// Delete deletes a verification by its ID
+	Delete(id uuid.UUID) error
+	// DeleteByUserID deletes every verification of a user
+	DeleteByUserID(userID uuid.UUID) error
+}
@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/google/uuid"
+)
+
+// IdentityRepository defines the interface for linked identity persistence operations
+type IdentityRepository interface {
+	// Create stores a new identity, the provider and subject pair must not be linked yet
+	Create(identity *domain.Identity) error
+	// FindByID finds an identity by its ID
+	FindByID(id uuid.UUID) (*domain.Identity, error)
+	// FindByProviderSubject finds the identity of an account at a provider
+	FindByProviderSubject(provider, subject string) (*domain.Identity, error)
+	// FindByUserID finds all identities linked to a user
+	FindByUserID(userID uuid.UUID) ([]*domain.Identity, error)
+	// Update updates an existing identity
+	Update(identity *domain.Identity) error
+	// Delete deletes an identity by its ID
+	Delete(id uuid.UUID) error
+	// DeleteByUserID deletes all identities linked to a user
+	DeleteByUserID(userID uuid.UUID) error
+}
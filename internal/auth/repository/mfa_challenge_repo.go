@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/google/uuid"
+)
+
+// MFAChallengeRepository defines the interface for pending MFA sign-in persistence operations
+type MFAChallengeRepository interface {
+	// Create stores a new challenge
+	Create(challenge *domain.MFAChallenge) error
+	// FindByTokenHash finds a challenge by the hash of its pending token. Unknown or expired
+	// challenges yield domain.ErrMFAChallengeNotFound.
+	FindByTokenHash(tokenHash string) (*domain.MFAChallenge, error)
+	// Delete deletes a challenge by its ID
+	Delete(id uuid.UUID) error
+	// DeleteByUserID deletes every challenge of a user
+	DeleteByUserID(userID uuid.UUID) error
+}
@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/google/uuid"
+)
+
+// MFARepository defines the interface for TOTP authenticator persistence operations
+type MFARepository interface {
+	// Save stores the authenticator of a user, replacing the one they had
+	Save(mfa *domain.MFA) error
+	// FindByUserID finds the authenticator of a user, domain.ErrMFANotEnrolled when there is none
+	FindByUserID(userID uuid.UUID) (*domain.MFA, error)
+	// CompareAndSwap replaces the authenticator old was read from with mfa, as long as its last
+	// used step, recovery codes and failed attempts are still those of old. Otherwise, or when
+	// it was deleted, it returns domain.ErrMFAChanged, so a code is accepted once.
+	CompareAndSwap(old, mfa *domain.MFA) error
+	// DeleteByUserID deletes the authenticator of a user
+	DeleteByUserID(userID uuid.UUID) error
+}
@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"github.com/algosim/backend/internal/auth/domain"
+)
+
+// OAuthStateRepository defines the interface for OAuth login state persistence
+type OAuthStateRepository interface {
+	// Create stores a newly issued state
+	Create(state *domain.OAuthState) error
+	// Consume atomically marks a state as used and returns it. Unknown or
+	// expired states yield domain.ErrOAuthStateExpired, states that were
+	// already consumed yield domain.ErrOAuthStateReplayed.
+	Consume(state string) (*domain.OAuthState, error)
+}
@@ -0,0 +1,54 @@
+package repositorytest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testAccessTokenDenylist(t *testing.T, newRepositories Factory) {
+	t.Run("Revoke", func(t *testing.T) {
+		denylist := newRepositories(t).AccessTokenDenylist
+		require.NoError(t, denylist.Add("revoked", time.Now().Add(time.Hour)))
+		require.NoError(t, denylist.Add("expired", time.Now().Add(-time.Second)))
+
+		cases := []struct {
+			name string
+			jti  string
+			want bool
+		}{
+			{"Revoked", "revoked", true},
+			{"Expired", "expired", false},
+			{"Unknown", "unknown", false},
+		}
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				revoked, err := denylist.Contains(tc.jti)
+				require.NoError(t, err)
+				assert.Equal(t, tc.want, revoked)
+			})
+		}
+	})
+
+	t.Run("Revoking Again Never Shortens", func(t *testing.T) {
+		denylist := newRepositories(t).AccessTokenDenylist
+		require.NoError(t, denylist.Add("jti", time.Now().Add(time.Hour)))
+		require.NoError(t, denylist.Add("jti", time.Now().Add(-time.Second)))
+
+		revoked, err := denylist.Contains("jti")
+		require.NoError(t, err)
+		assert.True(t, revoked)
+	})
+
+	t.Run("Concurrent Adds", func(t *testing.T) {
+		denylist := newRepositories(t).AccessTokenDenylist
+		errs := race(func() error { return denylist.Add("jti", time.Now().Add(time.Hour)) })
+		assert.Equal(t, concurrency, succeeded(errs))
+
+		revoked, err := denylist.Contains("jti")
+		require.NoError(t, err)
+		assert.True(t, revoked)
+	})
+}
@@ -0,0 +1,260 @@
+package repositorytest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newDeviceAuthorization returns a pending device authorization that expires after ttl
+func newDeviceAuthorization(deviceCodeHash, userCode string, ttl time.Duration) *domain.DeviceAuthorization {
+	return domain.NewDeviceAuthorization(deviceCodeHash, userCode, 5*time.Second, ttl,
+		domain.NewClientInfo("algosim-cli/1.0", "203.0.113.7"))
+}
+
+func testDeviceAuthorizations(t *testing.T, newRepositories Factory) {
+	t.Run("Create And Find", func(t *testing.T) {
+		repos := newRepositories(t)
+		authorization := newDeviceAuthorization("device-hash", "BCDFGHJK", time.Hour)
+		require.NoError(t, repos.DeviceAuthorizations.Create(authorization))
+
+		finds := []struct {
+			name string
+			find func() (*domain.DeviceAuthorization, error)
+		}{
+			{"By Device Code Hash", func() (*domain.DeviceAuthorization, error) {
+				return repos.DeviceAuthorizations.FindByDeviceCodeHash("device-hash")
+			}},
+			{"By User Code", func() (*domain.DeviceAuthorization, error) {
+				return repos.DeviceAuthorizations.FindByUserCode("BCDFGHJK")
+			}},
+		}
+		for _, tc := range finds {
+			t.Run(tc.name, func(t *testing.T) {
+				found, err := tc.find()
+				require.NoError(t, err)
+				assert.Equal(t, authorization.ID, found.ID)
+				assert.Equal(t, domain.DeviceAuthorizationPending, found.Status)
+				assert.Equal(t, uuid.Nil, found.UserID)
+				assert.Equal(t, 5*time.Second, found.Interval)
+				assert.Equal(t, authorization.Client, found.Client)
+				assert.Nil(t, found.LastPollAt)
+				assert.True(t, authorization.ExpiresAt.Equal(found.ExpiresAt))
+			})
+		}
+	})
+
+	t.Run("Decide And Poll", func(t *testing.T) {
+		repos := newRepositories(t)
+		user := createUser(t, repos, "alice@example.com")
+		authorization := newDeviceAuthorization("device-hash", "BCDFGHJK", time.Hour)
+		require.NoError(t, repos.DeviceAuthorizations.Create(authorization))
+
+		now := time.Now()
+		require.NoError(t, repos.DeviceAuthorizations.Decide(authorization.ID, domain.DeviceAuthorizationApproved, user.ID))
+		require.NoError(t, repos.DeviceAuthorizations.UpdatePoll(authorization.ID, now, 10*time.Second))
+
+		found, err := repos.DeviceAuthorizations.FindByDeviceCodeHash("device-hash")
+		require.NoError(t, err)
+		assert.Equal(t, domain.DeviceAuthorizationApproved, found.Status)
+		assert.Equal(t, user.ID, found.UserID)
+		assert.Equal(t, 10*time.Second, found.Interval)
+		require.NotNil(t, found.LastPollAt)
+		assert.True(t, now.Equal(*found.LastPollAt))
+
+		// A decision is final
+		assert.ErrorIs(t, repos.DeviceAuthorizations.Decide(authorization.ID, domain.DeviceAuthorizationDenied, user.ID),
+			domain.ErrDeviceAuthorizationNotFound)
+		found, err = repos.DeviceAuthorizations.FindByDeviceCodeHash("device-hash")
+		require.NoError(t, err)
+		assert.Equal(t, domain.DeviceAuthorizationApproved, found.Status)
+	})
+
+	t.Run("Poll Keeps A Decision From Another Instance", func(t *testing.T) {
+		repos := newRepositories(t)
+		user := createUser(t, repos, "alice@example.com")
+		authorization := newDeviceAuthorization("device-hash", "BCDFGHJK", time.Hour)
+		require.NoError(t, repos.DeviceAuthorizations.Create(authorization))
+
+		// One instance reads the pending authorization for a poll, another approves it before
+		// the poll is recorded
+		polled, err := repos.DeviceAuthorizations.FindByDeviceCodeHash("device-hash")
+		require.NoError(t, err)
+		require.Equal(t, domain.DeviceAuthorizationPending, polled.Status)
+		require.NoError(t, repos.DeviceAuthorizations.Decide(authorization.ID, domain.DeviceAuthorizationApproved, user.ID))
+		require.NoError(t, repos.DeviceAuthorizations.UpdatePoll(polled.ID, time.Now(), polled.Interval))
+
+		found, err := repos.DeviceAuthorizations.FindByDeviceCodeHash("device-hash")
+		require.NoError(t, err)
+		assert.Equal(t, domain.DeviceAuthorizationApproved, found.Status)
+		assert.Equal(t, user.ID, found.UserID)
+		require.NoError(t, repos.DeviceAuthorizations.Redeem(authorization.ID, domain.DeviceAuthorizationApproved))
+	})
+
+	t.Run("Concurrent Decisions", func(t *testing.T) {
+		repos := newRepositories(t)
+		user := createUser(t, repos, "alice@example.com")
+		authorization := newDeviceAuthorization("device-hash", "BCDFGHJK", time.Hour)
+		require.NoError(t, repos.DeviceAuthorizations.Create(authorization))
+
+		errs := race(func() error {
+			return repos.DeviceAuthorizations.Decide(authorization.ID, domain.DeviceAuthorizationApproved, user.ID)
+		})
+		assert.Equal(t, 1, succeeded(errs), "a device is decided once")
+		for _, err := range errs {
+			if err != nil {
+				assert.ErrorIs(t, err, domain.ErrDeviceAuthorizationNotFound)
+			}
+		}
+	})
+
+	t.Run("By User", func(t *testing.T) {
+		repos := newRepositories(t)
+		alice := createUser(t, repos, "alice@example.com")
+		bob := createUser(t, repos, "bob@example.com")
+		approved := newDeviceAuthorization("approved-hash", "BCDFGHJK", time.Hour)
+		pending := newDeviceAuthorization("pending-hash", "MNPQRSTV", time.Hour)
+		other := newDeviceAuthorization("other-hash", "WXZBCDFG", time.Hour)
+		for _, a := range []*domain.DeviceAuthorization{approved, pending, other} {
+			require.NoError(t, repos.DeviceAuthorizations.Create(a))
+		}
+		require.NoError(t, repos.DeviceAuthorizations.Decide(approved.ID, domain.DeviceAuthorizationApproved, alice.ID))
+		require.NoError(t, repos.DeviceAuthorizations.Decide(other.ID, domain.DeviceAuthorizationDenied, bob.ID))
+
+		found, err := repos.DeviceAuthorizations.FindByUserID(alice.ID)
+		require.NoError(t, err)
+		require.Len(t, found, 1)
+		assert.Equal(t, approved.ID, found[0].ID)
+
+		require.NoError(t, repos.DeviceAuthorizations.DeleteByUserID(alice.ID))
+		found, err = repos.DeviceAuthorizations.FindByUserID(alice.ID)
+		require.NoError(t, err)
+		assert.Empty(t, found)
+		_, err = repos.DeviceAuthorizations.FindByDeviceCodeHash("pending-hash")
+		assert.NoError(t, err, "pending authorizations belong to nobody yet")
+		_, err = repos.DeviceAuthorizations.FindByDeviceCodeHash("other-hash")
+		assert.NoError(t, err)
+	})
+
+	t.Run("Redeem", func(t *testing.T) {
+		repos := newRepositories(t)
+		user := createUser(t, repos, "alice@example.com")
+		authorization := newDeviceAuthorization("device-hash", "BCDFGHJK", time.Hour)
+		require.NoError(t, repos.DeviceAuthorizations.Create(authorization))
+
+		// A pending authorization is not redeemed as approved
+		assert.ErrorIs(t, repos.DeviceAuthorizations.Redeem(authorization.ID, domain.DeviceAuthorizationApproved),
+			domain.ErrDeviceAuthorizationNotFound)
+		_, err := repos.DeviceAuthorizations.FindByDeviceCodeHash("device-hash")
+		require.NoError(t, err)
+
+		require.NoError(t, repos.DeviceAuthorizations.Decide(authorization.ID, domain.DeviceAuthorizationApproved, user.ID))
+		require.NoError(t, repos.DeviceAuthorizations.Redeem(authorization.ID, domain.DeviceAuthorizationApproved))
+		_, err = repos.DeviceAuthorizations.FindByDeviceCodeHash("device-hash")
+		assert.ErrorIs(t, err, domain.ErrDeviceAuthorizationNotFound)
+		assert.ErrorIs(t, repos.DeviceAuthorizations.Redeem(authorization.ID, domain.DeviceAuthorizationApproved),
+			domain.ErrDeviceAuthorizationNotFound)
+	})
+
+	t.Run("Concurrent Redeems", func(t *testing.T) {
+		repos := newRepositories(t)
+		user := createUser(t, repos, "alice@example.com")
+		authorization := newDeviceAuthorization("device-hash", "BCDFGHJK", time.Hour)
+		authorization.Status = domain.DeviceAuthorizationApproved
+		authorization.UserID = user.ID
+		require.NoError(t, repos.DeviceAuthorizations.Create(authorization))
+
+		errs := race(func() error {
+			return repos.DeviceAuthorizations.Redeem(authorization.ID, domain.DeviceAuthorizationApproved)
+		})
+		assert.Equal(t, 1, succeeded(errs), "an approval is redeemed once")
+		for _, err := range errs {
+			if err != nil {
+				assert.ErrorIs(t, err, domain.ErrDeviceAuthorizationNotFound)
+			}
+		}
+	})
+
+	t.Run("Unique", func(t *testing.T) {
+		repos := newRepositories(t)
+		require.NoError(t, repos.DeviceAuthorizations.Create(newDeviceAuthorization("device-hash", "BCDFGHJK", time.Hour)))
+
+		cases := []struct {
+			name string
+			call func() error
+		}{
+			{"Same Device Code Hash", func() error {
+				return repos.DeviceAuthorizations.Create(newDeviceAuthorization("device-hash", "MNPQRSTV", time.Hour))
+			}},
+			{"Same Unexpired User Code", func() error {
+				return repos.DeviceAuthorizations.Create(newDeviceAuthorization("other-hash", "BCDFGHJK", time.Hour))
+			}},
+		}
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				require.Error(t, tc.call())
+			})
+		}
+	})
+
+	t.Run("Expiry", func(t *testing.T) {
+		repos := newRepositories(t)
+		expired := newDeviceAuthorization("expired-hash", "BCDFGHJK", -time.Second)
+		require.NoError(t, repos.DeviceAuthorizations.Create(expired))
+
+		_, err := repos.DeviceAuthorizations.FindByUserCode("BCDFGHJK")
+		assert.ErrorIs(t, err, domain.ErrDeviceAuthorizationNotFound, "expired user codes cannot be approved")
+
+		found, err := repos.DeviceAuthorizations.FindByDeviceCodeHash("expired-hash")
+		require.NoError(t, err, "polling devices learn that their codes expired")
+		assert.True(t, found.IsExpired(time.Now()))
+
+		// The user code of an expired authorization can be handed out again
+		fresh := newDeviceAuthorization("fresh-hash", "BCDFGHJK", time.Hour)
+		require.NoError(t, repos.DeviceAuthorizations.Create(fresh))
+		found, err = repos.DeviceAuthorizations.FindByUserCode("BCDFGHJK")
+		require.NoError(t, err)
+		assert.Equal(t, fresh.ID, found.ID)
+	})
+
+	t.Run("Not Found", func(t *testing.T) {
+		repos := newRepositories(t)
+		deleted := newDeviceAuthorization("deleted-hash", "BCDFGHJK", time.Hour)
+		require.NoError(t, repos.DeviceAuthorizations.Create(deleted))
+		require.NoError(t, repos.DeviceAuthorizations.Delete(deleted.ID))
+
+		runNotFound(t, []notFoundCase{
+			{"FindByDeviceCodeHash", func() error {
+				_, err := repos.DeviceAuthorizations.FindByDeviceCodeHash("unknown")
+				return err
+			}, domain.ErrDeviceAuthorizationNotFound},
+			{"FindByUserCode", func() error {
+				_, err := repos.DeviceAuthorizations.FindByUserCode("MNPQRSTV")
+				return err
+			}, domain.ErrDeviceAuthorizationNotFound},
+			{"FindByDeviceCodeHash Deleted", func() error {
+				_, err := repos.DeviceAuthorizations.FindByDeviceCodeHash("deleted-hash")
+				return err
+			}, domain.ErrDeviceAuthorizationNotFound},
+			{"UpdatePoll", func() error {
+				return repos.DeviceAuthorizations.UpdatePoll(deleted.ID, time.Now(), 5*time.Second)
+			}, domain.ErrDeviceAuthorizationNotFound},
+			{"Decide", func() error {
+				return repos.DeviceAuthorizations.Decide(deleted.ID, domain.DeviceAuthorizationDenied, uuid.New())
+			}, domain.ErrDeviceAuthorizationNotFound},
+			{"Delete", func() error { return repos.DeviceAuthorizations.Delete(deleted.ID) }, domain.ErrDeviceAuthorizationNotFound},
+		})
+	})
+
+	t.Run("Concurrent Creates With One User Code", func(t *testing.T) {
+		repos := newRepositories(t)
+		errs := race(func() error {
+			return repos.DeviceAuthorizations.Create(newDeviceAuthorization(uuid.NewString(), "BCDFGHJK", time.Hour))
+		})
+		assert.Equal(t, 1, succeeded(errs), "user codes are unique among unexpired authorizations")
+	})
+}
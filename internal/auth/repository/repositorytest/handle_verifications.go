@@ -0,0 +1,108 @@
+package repositorytest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testHandleVerifications(t *testing.T, newRepositories Factory) {
+	t.Run("Create And Find", func(t *testing.T) {
+		repos := newRepositories(t)
+		user := createUser(t, repos, "alice@example.com")
+		codeforces := domain.NewHandleVerification(user.ID, domain.JudgeCodeforces, "tourist", time.Hour)
+		codeforces.ContestID = 4
+		codeforces.ProblemIndex = "A"
+		require.NoError(t, repos.HandleVerifications.Create(codeforces))
+		atcoder := domain.NewHandleVerification(user.ID, domain.JudgeAtCoder, "tourist", time.Hour)
+		atcoder.Token = "algosim-token"
+		require.NoError(t, repos.HandleVerifications.Create(atcoder))
+
+		found, err := repos.HandleVerifications.FindByUserAndJudge(user.ID, domain.JudgeCodeforces)
+		require.NoError(t, err)
+		assert.Equal(t, codeforces.ID, found.ID)
+		assert.Equal(t, "tourist", found.Handle)
+		assert.Equal(t, 4, found.ContestID)
+		assert.Equal(t, "A", found.ProblemIndex)
+		assert.True(t, codeforces.ExpiresAt.Equal(found.ExpiresAt))
+
+		found, err = repos.HandleVerifications.FindByUserAndJudge(user.ID, domain.JudgeAtCoder)
+		require.NoError(t, err)
+		assert.Equal(t, "algosim-token", found.Token)
+
+		verifications, err := repos.HandleVerifications.FindByUserID(user.ID)
+		require.NoError(t, err)
+		assert.Len(t, verifications, 2)
+	})
+
+	t.Run("Create Replaces Pending One Per Judge", func(t *testing.T) {
+		repos := newRepositories(t)
+		alice := createUser(t, repos, "alice@example.com")
+		bob := createUser(t, repos, "bob@example.com")
+		first := domain.NewHandleVerification(alice.ID, domain.JudgeCodeforces, "tourist", time.Hour)
+		first.ContestID = 4
+		require.NoError(t, repos.HandleVerifications.Create(first))
+		require.NoError(t, repos.HandleVerifications.Create(domain.NewHandleVerification(bob.ID, domain.JudgeCodeforces, "petr", time.Hour)))
+
+		second := domain.NewHandleVerification(alice.ID, domain.JudgeCodeforces, "petr", time.Hour)
+		require.NoError(t, repos.HandleVerifications.Create(second))
+
+		found, err := repos.HandleVerifications.FindByUserAndJudge(alice.ID, domain.JudgeCodeforces)
+		require.NoError(t, err)
+		assert.Equal(t, second.ID, found.ID)
+		assert.Equal(t, "petr", found.Handle)
+		assert.Zero(t, found.ContestID)
+		assert.ErrorIs(t, repos.HandleVerifications.Delete(first.ID), domain.ErrHandleVerificationNotFound)
+
+		// Other users keep theirs
+		_, err = repos.HandleVerifications.FindByUserAndJudge(bob.ID, domain.JudgeCodeforces)
+		require.NoError(t, err)
+	})
+
+	t.Run("Not Found", func(t *testing.T) {
+		repos := newRepositories(t)
+		user := createUser(t, repos, "alice@example.com")
+		verification := domain.NewHandleVerification(user.ID, domain.JudgeCodeforces, "tourist", time.Hour)
+		require.NoError(t, repos.HandleVerifications.Create(verification))
+		require.NoError(t, repos.HandleVerifications.Delete(verification.ID))
+		require.NoError(t, repos.HandleVerifications.Create(domain.NewHandleVerification(user.ID, domain.JudgeAtCoder, "tourist", time.Hour)))
+		require.NoError(t, repos.HandleVerifications.DeleteByUserID(user.ID))
+
+		runNotFound(t, []notFoundCase{
+			{"FindByUserAndJudge Unknown User", func() error {
+				_, err := repos.HandleVerifications.FindByUserAndJudge(uuid.New(), domain.JudgeCodeforces)
+				return err
+			}, domain.ErrHandleVerificationNotFound},
+			{"FindByUserAndJudge Deleted", func() error {
+				_, err := repos.HandleVerifications.FindByUserAndJudge(user.ID, domain.JudgeCodeforces)
+				return err
+			}, domain.ErrHandleVerificationNotFound},
+			{"FindByUserAndJudge Deleted With User", func() error {
+				_, err := repos.HandleVerifications.FindByUserAndJudge(user.ID, domain.JudgeAtCoder)
+				return err
+			}, domain.ErrHandleVerificationNotFound},
+			{"Delete", func() error { return repos.HandleVerifications.Delete(verification.ID) }, domain.ErrHandleVerificationNotFound},
+		})
+
+		verifications, err := repos.HandleVerifications.FindByUserID(user.ID)
+		require.NoError(t, err)
+		assert.Empty(t, verifications)
+	})
+
+	t.Run("Concurrent Creates For One Judge", func(t *testing.T) {
+		repos := newRepositories(t)
+		user := createUser(t, repos, "alice@example.com")
+		errs := race(func() error {
+			return repos.HandleVerifications.Create(domain.NewHandleVerification(user.ID, domain.JudgeCodeforces, "tourist", time.Hour))
+		})
+		assert.Equal(t, concurrency, succeeded(errs))
+
+		verifications, err := repos.HandleVerifications.FindByUserID(user.ID)
+		require.NoError(t, err)
+		assert.Len(t, verifications, 1, "one pending verification per judge")
+	})
+}
@@ -0,0 +1,117 @@
+package repositorytest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testIdentities(t *testing.T, newRepositories Factory) {
+	t.Run("Create And Find", func(t *testing.T) {
+		repos := newRepositories(t)
+		user := createUser(t, repos, "alice@example.com")
+		first := domain.NewIdentity(user.ID, "github", "1001", "alice@example.com", true)
+		require.NoError(t, repos.Identities.Create(first))
+		second := domain.NewIdentity(user.ID, "google", "2002", "alice@gmail.com", false)
+		second.CreatedAt = first.CreatedAt.Add(time.Second)
+		require.NoError(t, repos.Identities.Create(second))
+
+		found, err := repos.Identities.FindByID(first.ID)
+		require.NoError(t, err)
+		assert.Equal(t, user.ID, found.UserID)
+		assert.Equal(t, "github", found.Provider)
+		assert.Equal(t, "1001", found.Subject)
+		assert.Equal(t, "alice@example.com", found.Email)
+		assert.True(t, found.EmailVerified)
+
+		found, err = repos.Identities.FindByProviderSubject("google", "2002")
+		require.NoError(t, err)
+		assert.Equal(t, second.ID, found.ID)
+		assert.False(t, found.EmailVerified)
+
+		identities, err := repos.Identities.FindByUserID(user.ID)
+		require.NoError(t, err)
+		require.Len(t, identities, 2)
+		assert.Equal(t, first.ID, identities[0].ID, "oldest first")
+		assert.Equal(t, second.ID, identities[1].ID)
+	})
+
+	t.Run("Not Found", func(t *testing.T) {
+		repos := newRepositories(t)
+		user := createUser(t, repos, "alice@example.com")
+		require.NoError(t, repos.Identities.Create(domain.NewIdentity(user.ID, "github", "1001", "", false)))
+
+		runNotFound(t, []notFoundCase{
+			{"FindByID", func() error { _, err := repos.Identities.FindByID(uuid.New()); return err }, domain.ErrIdentityNotFound},
+			{"FindByProviderSubject Other Provider", func() error {
+				_, err := repos.Identities.FindByProviderSubject("google", "1001")
+				return err
+			}, domain.ErrIdentityNotFound},
+			{"Update", func() error {
+				return repos.Identities.Update(domain.NewIdentity(user.ID, "google", "2002", "", false))
+			}, domain.ErrIdentityNotFound},
+			{"Delete", func() error { return repos.Identities.Delete(uuid.New()) }, domain.ErrIdentityNotFound},
+		})
+	})
+
+	t.Run("Unique Provider Subject", func(t *testing.T) {
+		repos := newRepositories(t)
+		alice := createUser(t, repos, "alice@example.com")
+		bob := createUser(t, repos, "bob@example.com")
+		require.NoError(t, repos.Identities.Create(domain.NewIdentity(alice.ID, "github", "1001", "", false)))
+
+		err := repos.Identities.Create(domain.NewIdentity(bob.ID, "github", "1001", "", false))
+		assert.ErrorIs(t, err, domain.ErrIdentityAlreadyLinked)
+		// The same subject at another provider is another account
+		require.NoError(t, repos.Identities.Create(domain.NewIdentity(bob.ID, "google", "1001", "", false)))
+	})
+
+	t.Run("Update And Delete", func(t *testing.T) {
+		repos := newRepositories(t)
+		user := createUser(t, repos, "alice@example.com")
+		identity := domain.NewIdentity(user.ID, "github", "1001", "alice@example.com", false)
+		require.NoError(t, repos.Identities.Create(identity))
+
+		lastUsedAt := time.Now().Add(time.Minute)
+		identity.Email = "alice@example.org"
+		identity.EmailVerified = true
+		identity.LastUsedAt = lastUsedAt
+		require.NoError(t, repos.Identities.Update(identity))
+
+		found, err := repos.Identities.FindByID(identity.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "alice@example.org", found.Email)
+		assert.True(t, found.EmailVerified)
+		assert.True(t, lastUsedAt.Equal(found.LastUsedAt))
+
+		require.NoError(t, repos.Identities.Delete(identity.ID))
+		_, err = repos.Identities.FindByProviderSubject("github", "1001")
+		assert.ErrorIs(t, err, domain.ErrIdentityNotFound)
+		// The account can be linked again
+		require.NoError(t, repos.Identities.Create(domain.NewIdentity(user.ID, "github", "1001", "", false)))
+
+		require.NoError(t, repos.Identities.DeleteByUserID(user.ID))
+		identities, err := repos.Identities.FindByUserID(user.ID)
+		require.NoError(t, err)
+		assert.Empty(t, identities)
+	})
+
+	t.Run("Concurrent Links Of One Account", func(t *testing.T) {
+		repos := newRepositories(t)
+		user := createUser(t, repos, "alice@example.com")
+		errs := race(func() error {
+			return repos.Identities.Create(domain.NewIdentity(user.ID, "github", "1001", "", false))
+		})
+
+		assert.Equal(t, 1, succeeded(errs))
+		for _, err := range errs {
+			if err != nil {
+				assert.ErrorIs(t, err, domain.ErrIdentityAlreadyLinked)
+			}
+		}
+	})
+}
@@ -0,0 +1,201 @@
+package repositorytest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testMFA(t *testing.T, newRepositories Factory) {
+	t.Run("Save And Find", func(t *testing.T) {
+		repos := newRepositories(t)
+		user := createUser(t, repos, "alice@example.com")
+		mfa := domain.NewMFA(user.ID, "JBSWY3DPEHPK3PXP")
+		require.NoError(t, repos.MFA.Save(mfa))
+
+		found, err := repos.MFA.FindByUserID(user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "JBSWY3DPEHPK3PXP", found.Secret)
+		assert.False(t, found.IsEnabled())
+		assert.Empty(t, found.RecoveryCodeHashes)
+		assert.Nil(t, found.LockedUntil)
+	})
+
+	t.Run("Save Replaces", func(t *testing.T) {
+		repos := newRepositories(t)
+		user := createUser(t, repos, "alice@example.com")
+		require.NoError(t, repos.MFA.Save(domain.NewMFA(user.ID, "JBSWY3DPEHPK3PXP")))
+
+		now := time.Now()
+		mfa := domain.NewMFA(user.ID, "KRSXG5CTMVRXEZLU")
+		mfa.ConfirmedAt = &now
+		mfa.LockedUntil = &now
+		mfa.LastUsedStep = 58000000
+		mfa.FailedAttempts = 3
+		mfa.RecoveryCodeHashes = []string{"hash-1", "hash-2"}
+		require.NoError(t, repos.MFA.Save(mfa))
+
+		found, err := repos.MFA.FindByUserID(user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "KRSXG5CTMVRXEZLU", found.Secret)
+		assert.True(t, found.IsEnabled())
+		require.NotNil(t, found.LockedUntil)
+		assert.True(t, now.Equal(*found.LockedUntil))
+		assert.Equal(t, int64(58000000), found.LastUsedStep)
+		assert.Equal(t, 3, found.FailedAttempts)
+		assert.Equal(t, []string{"hash-1", "hash-2"}, found.RecoveryCodeHashes)
+
+		// Changes are stored by Save only
+		found.RecoveryCodeHashes[0] = "changed"
+		found, err = repos.MFA.FindByUserID(user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"hash-1", "hash-2"}, found.RecoveryCodeHashes)
+	})
+
+	t.Run("Compare And Swap", func(t *testing.T) {
+		repos := newRepositories(t)
+		user := createUser(t, repos, "alice@example.com")
+		stored := domain.NewMFA(user.ID, "JBSWY3DPEHPK3PXP")
+		stored.LastUsedStep = 58000000
+		stored.RecoveryCodeHashes = []string{"hash-1", "hash-2"}
+		require.NoError(t, repos.MFA.Save(stored))
+
+		read, err := repos.MFA.FindByUserID(user.ID)
+		require.NoError(t, err)
+		changes := []struct {
+			name   string
+			change func(mfa *domain.MFA)
+		}{
+			{"Step", func(mfa *domain.MFA) { mfa.LastUsedStep++ }},
+			{"Recovery Codes", func(mfa *domain.MFA) { mfa.RecoveryCodeHashes = mfa.RecoveryCodeHashes[1:] }},
+			{"Failed Attempts", func(mfa *domain.MFA) { mfa.FailedAttempts++ }},
+		}
+		for _, tc := range changes {
+			t.Run(tc.name, func(t *testing.T) {
+				changed := *read
+				tc.change(&changed)
+				require.NoError(t, repos.MFA.CompareAndSwap(read, &changed))
+
+				// A write based on the old read loses
+				stale := *read
+				stale.LastUsedStep = changed.LastUsedStep + 1
+				assert.ErrorIs(t, repos.MFA.CompareAndSwap(read, &stale), domain.ErrMFAChanged)
+
+				found, err := repos.MFA.FindByUserID(user.ID)
+				require.NoError(t, err)
+				assert.Equal(t, changed.LastUsedStep, found.LastUsedStep)
+				assert.Equal(t, changed.RecoveryCodeHashes, found.RecoveryCodeHashes)
+				assert.Equal(t, changed.FailedAttempts, found.FailedAttempts)
+				read = found
+			})
+		}
+
+		require.NoError(t, repos.MFA.DeleteByUserID(user.ID))
+		assert.ErrorIs(t, repos.MFA.CompareAndSwap(read, read), domain.ErrMFAChanged, "a deleted authenticator is not brought back")
+		_, err = repos.MFA.FindByUserID(user.ID)
+		assert.ErrorIs(t, err, domain.ErrMFANotEnrolled)
+	})
+
+	t.Run("Concurrent Code Checks", func(t *testing.T) {
+		repos := newRepositories(t)
+		user := createUser(t, repos, "alice@example.com")
+		stored := domain.NewMFA(user.ID, "JBSWY3DPEHPK3PXP")
+		stored.RecoveryCodeHashes = []string{"hash-1", "hash-2"}
+		require.NoError(t, repos.MFA.Save(stored))
+		read, err := repos.MFA.FindByUserID(user.ID)
+		require.NoError(t, err)
+
+		// Every check read the same authenticator and uses up the same recovery code
+		errs := race(func() error {
+			used := *read
+			used.RecoveryCodeHashes = []string{"hash-2"}
+			return repos.MFA.CompareAndSwap(read, &used)
+		})
+		assert.Equal(t, 1, succeeded(errs))
+		for _, err := range errs {
+			if err != nil {
+				assert.ErrorIs(t, err, domain.ErrMFAChanged)
+			}
+		}
+	})
+
+	t.Run("Not Enrolled", func(t *testing.T) {
+		repos := newRepositories(t)
+		user := createUser(t, repos, "alice@example.com")
+		require.NoError(t, repos.MFA.Save(domain.NewMFA(user.ID, "JBSWY3DPEHPK3PXP")))
+		require.NoError(t, repos.MFA.DeleteByUserID(user.ID))
+
+		runNotFound(t, []notFoundCase{
+			{"Unknown User", func() error { _, err := repos.MFA.FindByUserID(uuid.New()); return err }, domain.ErrMFANotEnrolled},
+			{"Deleted", func() error { _, err := repos.MFA.FindByUserID(user.ID); return err }, domain.ErrMFANotEnrolled},
+		})
+		// Deleting nothing is not an error
+		require.NoError(t, repos.MFA.DeleteByUserID(user.ID))
+	})
+}
+
+func testMFAChallenges(t *testing.T, newRepositories Factory) {
+	t.Run("Create And Find", func(t *testing.T) {
+		repos := newRepositories(t)
+		user := createUser(t, repos, "alice@example.com")
+		challenge := domain.NewMFAChallenge(user.ID, "token-hash", time.Hour)
+		require.NoError(t, repos.MFAChallenges.Create(challenge))
+
+		found, err := repos.MFAChallenges.FindByTokenHash("token-hash")
+		require.NoError(t, err)
+		assert.Equal(t, challenge.ID, found.ID)
+		assert.Equal(t, user.ID, found.UserID)
+		assert.True(t, challenge.ExpiresAt.Equal(found.ExpiresAt))
+	})
+
+	t.Run("Unique Token Hash", func(t *testing.T) {
+		repos := newRepositories(t)
+		user := createUser(t, repos, "alice@example.com")
+		challenge := domain.NewMFAChallenge(user.ID, "token-hash", time.Hour)
+		require.NoError(t, repos.MFAChallenges.Create(challenge))
+		assert.Error(t, repos.MFAChallenges.Create(domain.NewMFAChallenge(user.ID, "token-hash", time.Hour)))
+
+		found, err := repos.MFAChallenges.FindByTokenHash("token-hash")
+		require.NoError(t, err)
+		assert.Equal(t, challenge.ID, found.ID)
+	})
+
+	t.Run("Not Found", func(t *testing.T) {
+		repos := newRepositories(t)
+		user := createUser(t, repos, "alice@example.com")
+		require.NoError(t, repos.MFAChallenges.Create(domain.NewMFAChallenge(user.ID, "expired-hash", -time.Second)))
+		deleted := domain.NewMFAChallenge(user.ID, "deleted-hash", time.Hour)
+		require.NoError(t, repos.MFAChallenges.Create(deleted))
+		require.NoError(t, repos.MFAChallenges.Delete(deleted.ID))
+		require.NoError(t, repos.MFAChallenges.Create(domain.NewMFAChallenge(user.ID, "other-hash", time.Hour)))
+		require.NoError(t, repos.MFAChallenges.DeleteByUserID(user.ID))
+
+		runNotFound(t, []notFoundCase{
+			{"Unknown", func() error { _, err := repos.MFAChallenges.FindByTokenHash("unknown"); return err }, domain.ErrMFAChallengeNotFound},
+			{"Expired", func() error { _, err := repos.MFAChallenges.FindByTokenHash("expired-hash"); return err }, domain.ErrMFAChallengeNotFound},
+			{"Deleted", func() error { _, err := repos.MFAChallenges.FindByTokenHash("deleted-hash"); return err }, domain.ErrMFAChallengeNotFound},
+			{"Deleted With User", func() error { _, err := repos.MFAChallenges.FindByTokenHash("other-hash"); return err }, domain.ErrMFAChallengeNotFound},
+			{"Delete", func() error { return repos.MFAChallenges.Delete(deleted.ID) }, domain.ErrMFAChallengeNotFound},
+		})
+	})
+
+	t.Run("Concurrent Delete", func(t *testing.T) {
+		repos := newRepositories(t)
+		user := createUser(t, repos, "alice@example.com")
+		challenge := domain.NewMFAChallenge(user.ID, "token-hash", time.Hour)
+		require.NoError(t, repos.MFAChallenges.Create(challenge))
+
+		// Deleting is how a challenge is redeemed, only one sign-in may complete it
+		errs := race(func() error { return repos.MFAChallenges.Delete(challenge.ID) })
+		assert.Equal(t, 1, succeeded(errs))
+		for _, err := range errs {
+			if err != nil {
+				assert.ErrorIs(t, err, domain.ErrMFAChallengeNotFound)
+			}
+		}
+	})
+}
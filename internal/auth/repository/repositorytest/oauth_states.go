@@ -0,0 +1,69 @@
+package repositorytest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testOAuthStates(t *testing.T, newRepositories Factory) {
+	t.Run("Consume", func(t *testing.T) {
+		repos := newRepositories(t)
+		user := createUser(t, repos, "alice@example.com")
+		state := domain.NewOAuthState("state", "github", "verifier", "nonce", time.Hour)
+		state.LinkUserID = user.ID
+		require.NoError(t, repos.OAuthStates.Create(state))
+
+		consumed, err := repos.OAuthStates.Consume("state")
+		require.NoError(t, err)
+		assert.Equal(t, "github", consumed.Provider)
+		assert.Equal(t, "verifier", consumed.CodeVerifier)
+		assert.Equal(t, "nonce", consumed.Nonce)
+		assert.Equal(t, user.ID, consumed.LinkUserID)
+		assert.True(t, state.ExpiresAt.Equal(consumed.ExpiresAt))
+		require.NotNil(t, consumed.ConsumedAt)
+	})
+
+	t.Run("Unique", func(t *testing.T) {
+		repos := newRepositories(t)
+		require.NoError(t, repos.OAuthStates.Create(domain.NewOAuthState("state", "github", "", "", time.Hour)))
+		assert.Error(t, repos.OAuthStates.Create(domain.NewOAuthState("state", "google", "", "", time.Hour)))
+
+		consumed, err := repos.OAuthStates.Consume("state")
+		require.NoError(t, err)
+		assert.Equal(t, "github", consumed.Provider)
+	})
+
+	t.Run("Rejected", func(t *testing.T) {
+		repos := newRepositories(t)
+		require.NoError(t, repos.OAuthStates.Create(domain.NewOAuthState("consumed", "github", "", "", time.Hour)))
+		_, err := repos.OAuthStates.Consume("consumed")
+		require.NoError(t, err)
+		require.NoError(t, repos.OAuthStates.Create(domain.NewOAuthState("expired", "github", "", "", -time.Second)))
+
+		runNotFound(t, []notFoundCase{
+			{"Unknown", func() error { _, err := repos.OAuthStates.Consume("unknown"); return err }, domain.ErrOAuthStateExpired},
+			{"Expired", func() error { _, err := repos.OAuthStates.Consume("expired"); return err }, domain.ErrOAuthStateExpired},
+			{"Replayed", func() error { _, err := repos.OAuthStates.Consume("consumed"); return err }, domain.ErrOAuthStateReplayed},
+		})
+	})
+
+	t.Run("Concurrent Consume", func(t *testing.T) {
+		repos := newRepositories(t)
+		require.NoError(t, repos.OAuthStates.Create(domain.NewOAuthState("state", "github", "", "", time.Hour)))
+		errs := race(func() error {
+			_, err := repos.OAuthStates.Consume("state")
+			return err
+		})
+
+		assert.Equal(t, 1, succeeded(errs), "a state is consumed once")
+		for _, err := range errs {
+			if err != nil {
+				assert.ErrorIs(t, err, domain.ErrOAuthStateReplayed)
+			}
+		}
+	})
+}
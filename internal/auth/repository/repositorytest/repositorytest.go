@@ -0,0 +1,136 @@
+// Package repositorytest is a conformance suite for the repository interfaces. Every storage
+// backend runs it from its tests to check that it keeps the promises of the interfaces the same
+// way the others do:
+//
+//	func TestConformance(t *testing.T) {
+//		repositorytest.Run(t, func(t *testing.T) repositorytest.Repositories {
+//			return repositorytest.Repositories{Users: memory.NewUserRepoMemo(), ...}
+//		})
+//	}
+//
+// Expiry is checked with records that expired in the past or expire far in the future, so the
+// suite needs no clock and runs against any backend, including ones whose time lives elsewhere.
+package repositorytest
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/algosim/backend/internal/auth/repository"
+	"github.com/stretchr/testify/require"
+)
+
+// concurrency is the number of goroutines racing in the concurrency tests
+const concurrency = 8
+
+// Repositories are the repositories of one backend sharing one store. A backend leaves the
+// repositories it does not implement nil and their tests are skipped.
+type Repositories struct {
+	Users                repository.UserRepository
+	Tokens               repository.TokenRepository
+	Identities           repository.IdentityRepository
+	OAuthStates          repository.OAuthStateRepository
+	MFA                  repository.MFARepository
+	MFAChallenges        repository.MFAChallengeRepository
+	DeviceAuthorizations repository.DeviceAuthorizationRepository
+	HandleVerifications  repository.HandleVerificationRepository
+	AccessTokenDenylist  repository.AccessTokenDenylist
+	// UnitOfWork spans the users and whichever other repositories of the store it supports
+	UnitOfWork repository.UnitOfWork
+}
+
+// Factory returns the repositories of a new, empty store. Cleanup is registered on t.
+type Factory func(t *testing.T) Repositories
+
+// suite tests one repository
+type suite struct {
+	name string
+	// implemented reports whether the backend has the repository under test
+	implemented func(r Repositories) bool
+	run         func(t *testing.T, newRepositories Factory)
+}
+
+// suites are run in this order by Run
+var suites = []suite{
+	{"Users", func(r Repositories) bool { return r.Users != nil }, testUsers},
+	{"Tokens", func(r Repositories) bool { return r.Tokens != nil }, testTokens},
+	{"Identities", func(r Repositories) bool { return r.Identities != nil }, testIdentities},
+	{"OAuthStates", func(r Repositories) bool { return r.OAuthStates != nil }, testOAuthStates},
+	{"MFA", func(r Repositories) bool { return r.MFA != nil }, testMFA},
+	{"MFAChallenges", func(r Repositories) bool { return r.MFAChallenges != nil }, testMFAChallenges},
+	{"DeviceAuthorizations", func(r Repositories) bool { return r.DeviceAuthorizations != nil }, testDeviceAuthorizations},
+	{"HandleVerifications", func(r Repositories) bool { return r.HandleVerifications != nil }, testHandleVerifications},
+	{"AccessTokenDenylist", func(r Repositories) bool { return r.AccessTokenDenylist != nil }, testAccessTokenDenylist},
+	{"UnitOfWork", func(r Repositories) bool { return r.UnitOfWork != nil }, testUnitOfWork},
+}
+
+// Run runs the conformance suite of every repository the backend implements. Each test gets a
+// fresh store from newRepositories.
+func Run(t *testing.T, newRepositories Factory) {
+	implemented := newRepositories(t)
+	for _, s := range suites {
+		t.Run(s.name, func(t *testing.T) {
+			if !s.implemented(implemented) {
+				t.Skipf("backend has no %s repository", s.name)
+			}
+			s.run(t, newRepositories)
+		})
+	}
+}
+
+// notFoundCase is an operation on a missing record and the sentinel it must fail with
+type notFoundCase struct {
+	name string
+	call func() error
+	want error
+}
+
+// runNotFound runs a table of operations on missing records
+func runNotFound(t *testing.T, cases []notFoundCase) {
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.ErrorIs(t, tc.call(), tc.want)
+		})
+	}
+}
+
+// race runs call from concurrency goroutines at once and returns their errors
+func race(call func() error) []error {
+	errs := make([]error, concurrency)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			errs[i] = call()
+		}()
+	}
+	close(start)
+	wg.Wait()
+	return errs
+}
+
+// succeeded counts the calls of a race that returned no error
+func succeeded(errs []error) int {
+	n := 0
+	for _, err := range errs {
+		if err == nil {
+			n++
+		}
+	}
+	return n
+}
+
+// createUser stores a new user for the records of other repositories to belong to. Backends
+// without a user repository have no users to refer to, the user is only made up for them.
+func createUser(t *testing.T, repos Repositories, email string) *domain.User {
+	t.Helper()
+	user := domain.NewUser(email)
+	if repos.Users != nil {
+		require.NoError(t, repos.Users.Create(user))
+	}
+	return user
+}
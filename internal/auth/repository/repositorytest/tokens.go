@@ -0,0 +1,172 @@
+package repositorytest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newToken returns a refresh token of userID with a unique hash
+func newToken(userID uuid.UUID) *domain.Token {
+	token := domain.NewToken(userID, "access", "refresh", uuid.NewString(), time.Now().Add(time.Hour))
+	token.AccessTokenID = uuid.NewString()
+	token.AccessTokenExpiresAt = time.Now().Add(15 * time.Minute)
+	token.UserAgent = "Mozilla/5.0"
+	token.IPAddress = "203.0.113.7"
+	return token
+}
+
+func testTokens(t *testing.T, newRepositories Factory) {
+	t.Run("Create And Find", func(t *testing.T) {
+		repos := newRepositories(t)
+		user := createUser(t, repos, "alice@example.com")
+		token := newToken(user.ID)
+		require.NoError(t, repos.Tokens.Create(token))
+
+		finds := []struct {
+			name string
+			find func() (*domain.Token, error)
+		}{
+			{"By ID", func() (*domain.Token, error) { return repos.Tokens.FindByID(token.ID) }},
+			{"By Refresh Token Hash", func() (*domain.Token, error) { return repos.Tokens.FindByRefreshTokenHash(token.RefreshTokenHash) }},
+		}
+		for _, tc := range finds {
+			t.Run(tc.name, func(t *testing.T) {
+				found, err := tc.find()
+				require.NoError(t, err)
+				assert.Equal(t, token.ID, found.ID)
+				assert.Equal(t, user.ID, found.UserID)
+				assert.Equal(t, token.FamilyID, found.FamilyID)
+				assert.Equal(t, token.AccessTokenID, found.AccessTokenID)
+				assert.Equal(t, "Mozilla/5.0", found.UserAgent)
+				assert.Equal(t, "203.0.113.7", found.IPAddress)
+				assert.True(t, token.ExpiresAt.Equal(found.ExpiresAt))
+				assert.Nil(t, found.RotatedAt)
+				assert.Empty(t, found.RefreshToken, "the refresh token itself is never stored")
+				assert.Empty(t, found.AccessToken, "nor the access token")
+			})
+		}
+	})
+
+	t.Run("Not Found", func(t *testing.T) {
+		repos := newRepositories(t)
+		user := createUser(t, repos, "alice@example.com")
+
+		runNotFound(t, []notFoundCase{
+			{"FindByID", func() error { _, err := repos.Tokens.FindByID(uuid.New()); return err }, domain.ErrTokenNotFound},
+			{"FindByRefreshTokenHash", func() error { _, err := repos.Tokens.FindByRefreshTokenHash("unknown"); return err }, domain.ErrTokenNotFound},
+			{"Update", func() error { return repos.Tokens.Update(newToken(user.ID)) }, domain.ErrTokenNotFound},
+			{"Delete", func() error { return repos.Tokens.Delete(uuid.New()) }, domain.ErrTokenNotFound},
+		})
+	})
+
+	t.Run("Unique", func(t *testing.T) {
+		repos := newRepositories(t)
+		user := createUser(t, repos, "alice@example.com")
+		token := newToken(user.ID)
+		require.NoError(t, repos.Tokens.Create(token))
+
+		sameID := newToken(user.ID)
+		sameID.ID = token.ID
+		sameHash := newToken(user.ID)
+		sameHash.RefreshTokenHash = token.RefreshTokenHash
+		changedHash := *token
+		changedHash.RefreshTokenHash = "other"
+
+		cases := []struct {
+			name string
+			call func() error
+		}{
+			{"Create Same ID", func() error { return repos.Tokens.Create(sameID) }},
+			{"Create Same Refresh Token Hash", func() error { return repos.Tokens.Create(sameHash) }},
+			{"Update Refresh Token Hash", func() error { return repos.Tokens.Update(&changedHash) }},
+		}
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				require.Error(t, tc.call())
+			})
+		}
+
+		found, err := repos.Tokens.FindByRefreshTokenHash(token.RefreshTokenHash)
+		require.NoError(t, err)
+		assert.Equal(t, token.ID, found.ID)
+	})
+
+	t.Run("Rotate", func(t *testing.T) {
+		repos := newRepositories(t)
+		user := createUser(t, repos, "alice@example.com")
+		token := newToken(user.ID)
+		require.NoError(t, repos.Tokens.Create(token))
+
+		now := time.Now()
+		token.RotatedAt = &now
+		token.LastUsedAt = now
+		require.NoError(t, repos.Tokens.Update(token))
+
+		found, err := repos.Tokens.FindByID(token.ID)
+		require.NoError(t, err)
+		require.NotNil(t, found.RotatedAt)
+		assert.True(t, now.Equal(*found.RotatedAt))
+		assert.True(t, now.Equal(found.LastUsedAt))
+		assert.Empty(t, found.RefreshToken)
+		assert.Empty(t, found.AccessToken)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		repos := newRepositories(t)
+		alice := createUser(t, repos, "alice@example.com")
+		bob := createUser(t, repos, "bob@example.com")
+
+		first := newToken(alice.ID)
+		successor := newToken(alice.ID)
+		successor.FamilyID = first.FamilyID
+		other := newToken(alice.ID)
+		single := newToken(alice.ID)
+		bobs := newToken(bob.ID)
+		for _, token := range []*domain.Token{first, successor, other, single, bobs} {
+			require.NoError(t, repos.Tokens.Create(token))
+		}
+
+		tokens, err := repos.Tokens.FindByUserID(alice.ID)
+		require.NoError(t, err)
+		assert.Len(t, tokens, 4)
+
+		require.NoError(t, repos.Tokens.Delete(single.ID))
+		_, err = repos.Tokens.FindByRefreshTokenHash(single.RefreshTokenHash)
+		assert.ErrorIs(t, err, domain.ErrTokenNotFound)
+
+		require.NoError(t, repos.Tokens.DeleteByFamilyID(first.FamilyID))
+		tokens, err = repos.Tokens.FindByUserID(alice.ID)
+		require.NoError(t, err)
+		require.Len(t, tokens, 1)
+		assert.Equal(t, other.ID, tokens[0].ID)
+
+		require.NoError(t, repos.Tokens.DeleteByUserID(alice.ID))
+		tokens, err = repos.Tokens.FindByUserID(alice.ID)
+		require.NoError(t, err)
+		assert.Empty(t, tokens)
+
+		tokens, err = repos.Tokens.FindByUserID(bob.ID)
+		require.NoError(t, err)
+		assert.Len(t, tokens, 1, "tokens of other users stay")
+
+		// Deleting nothing is not an error
+		require.NoError(t, repos.Tokens.DeleteByUserID(alice.ID))
+		require.NoError(t, repos.Tokens.DeleteByFamilyID(uuid.New()))
+	})
+
+	t.Run("Concurrent Creates With One Hash", func(t *testing.T) {
+		repos := newRepositories(t)
+		user := createUser(t, repos, "alice@example.com")
+		errs := race(func() error {
+			token := newToken(user.ID)
+			token.RefreshTokenHash = "race"
+			return repos.Tokens.Create(token)
+		})
+		assert.Equal(t, 1, succeeded(errs))
+	})
+}
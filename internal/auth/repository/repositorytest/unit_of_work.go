@@ -0,0 +1,261 @@
+package repositorytest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/algosim/backend/internal/auth/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// errUnitFailed is returned by the units that must roll back
+var errUnitFailed = errors.New("unit failed")
+
+// existing is what a store holds before a unit runs
+type existing struct {
+	user      *domain.User
+	token     *domain.Token
+	identity  *domain.Identity
+	challenge *domain.MFAChallenge
+}
+
+// seed stores a user with one record in every repository the unit spans
+func seed(t *testing.T, repos Repositories, tx repository.Repositories) existing {
+	t.Helper()
+	user := createUser(t, repos, "alice@example.com")
+	seeded := existing{user: user}
+	if tx.Tokens != nil {
+		seeded.token = newToken(user.ID)
+		require.NoError(t, repos.Tokens.Create(seeded.token))
+	}
+	if tx.Identities != nil {
+		seeded.identity = domain.NewIdentity(user.ID, "github", "1001", user.Email, true)
+		require.NoError(t, repos.Identities.Create(seeded.identity))
+	}
+	if tx.MFAChallenges != nil {
+		seeded.challenge = domain.NewMFAChallenge(user.ID, "alice-hash", time.Hour)
+		require.NoError(t, repos.MFAChallenges.Create(seeded.challenge))
+	}
+	return seeded
+}
+
+// spanned returns the repositories a unit of the backend spans
+func spanned(t *testing.T, repos Repositories) repository.Repositories {
+	t.Helper()
+	var spans repository.Repositories
+	require.NoError(t, repos.UnitOfWork.Do(func(tx repository.Repositories) error {
+		spans = tx
+		return nil
+	}))
+	require.NotNil(t, spans.Users, "units span the users")
+	return spans
+}
+
+// changeEverything creates, updates and deletes records in every repository of tx and returns
+// the new user
+func changeEverything(t *testing.T, tx repository.Repositories, seeded existing) *domain.User {
+	user := domain.NewUser("bob@example.com")
+	require.NoError(t, tx.Users.Create(user))
+	seeded.user.Handle = "alice"
+	require.NoError(t, tx.Users.Update(seeded.user))
+
+	if tx.Tokens != nil {
+		require.NoError(t, tx.Tokens.Create(newToken(user.ID)))
+		require.NoError(t, tx.Tokens.DeleteByUserID(seeded.user.ID))
+	}
+	if tx.Identities != nil {
+		require.NoError(t, tx.Identities.Create(domain.NewIdentity(user.ID, "github", "2002", user.Email, true)))
+		require.NoError(t, tx.Identities.DeleteByUserID(seeded.user.ID))
+	}
+	if tx.MFAChallenges != nil {
+		require.NoError(t, tx.MFAChallenges.Create(domain.NewMFAChallenge(user.ID, "bob-hash", time.Hour)))
+		require.NoError(t, tx.MFAChallenges.Delete(seeded.challenge.ID))
+	}
+	return user
+}
+
+func testUnitOfWork(t *testing.T, newRepositories Factory) {
+	t.Run("Commit", func(t *testing.T) {
+		repos := newRepositories(t)
+		seeded := seed(t, repos, spanned(t, repos))
+
+		var user *domain.User
+		err := repos.UnitOfWork.Do(func(tx repository.Repositories) error {
+			user = changeEverything(t, tx, seeded)
+
+			// A unit reads its own changes
+			found, err := tx.Users.FindByID(user.ID)
+			require.NoError(t, err)
+			assert.Equal(t, user.Email, found.Email)
+			return nil
+		})
+		require.NoError(t, err)
+
+		_, err = repos.Users.FindByID(user.ID)
+		require.NoError(t, err)
+		found, err := repos.Users.FindByID(seeded.user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "alice", found.Handle)
+		if seeded.token != nil {
+			tokens, err := repos.Tokens.FindByUserID(user.ID)
+			require.NoError(t, err)
+			assert.Len(t, tokens, 1)
+			_, err = repos.Tokens.FindByID(seeded.token.ID)
+			assert.ErrorIs(t, err, domain.ErrTokenNotFound)
+		}
+		if seeded.identity != nil {
+			identities, err := repos.Identities.FindByUserID(user.ID)
+			require.NoError(t, err)
+			assert.Len(t, identities, 1)
+			_, err = repos.Identities.FindByID(seeded.identity.ID)
+			assert.ErrorIs(t, err, domain.ErrIdentityNotFound)
+		}
+		if seeded.challenge != nil {
+			_, err := repos.MFAChallenges.FindByTokenHash("bob-hash")
+			require.NoError(t, err)
+			_, err = repos.MFAChallenges.FindByTokenHash("alice-hash")
+			assert.ErrorIs(t, err, domain.ErrMFAChallengeNotFound)
+		}
+	})
+
+	rollbacks := []struct {
+		name string
+		// run runs a unit that fails after fn changed the store
+		run func(t *testing.T, uow repository.UnitOfWork, fn func(tx repository.Repositories))
+	}{
+		{"Error", func(t *testing.T, uow repository.UnitOfWork, fn func(tx repository.Repositories)) {
+			err := uow.Do(func(tx repository.Repositories) error {
+				fn(tx)
+				return errUnitFailed
+			})
+			assert.ErrorIs(t, err, errUnitFailed)
+		}},
+		{"Panic", func(t *testing.T, uow repository.UnitOfWork, fn func(tx repository.Repositories)) {
+			assert.PanicsWithValue(t, "unit failed", func() {
+				_ = uow.Do(func(tx repository.Repositories) error {
+					fn(tx)
+					panic("unit failed")
+				})
+			})
+		}},
+	}
+	for _, tc := range rollbacks {
+		t.Run("Rollback On "+tc.name, func(t *testing.T) {
+			repos := newRepositories(t)
+			seeded := seed(t, repos, spanned(t, repos))
+
+			var user *domain.User
+			tc.run(t, repos.UnitOfWork, func(tx repository.Repositories) {
+				user = changeEverything(t, tx, seeded)
+			})
+
+			_, err := repos.Users.FindByID(user.ID)
+			assert.ErrorIs(t, err, domain.ErrUserNotFound)
+			_, err = repos.Users.FindByEmail(user.Email)
+			assert.ErrorIs(t, err, domain.ErrUserNotFound)
+			found, err := repos.Users.FindByID(seeded.user.ID)
+			require.NoError(t, err)
+			assert.Empty(t, found.Handle)
+			if seeded.token != nil {
+				tokens, err := repos.Tokens.FindByUserID(user.ID)
+				require.NoError(t, err)
+				assert.Empty(t, tokens)
+				found, err := repos.Tokens.FindByRefreshTokenHash(seeded.token.RefreshTokenHash)
+				require.NoError(t, err)
+				assert.Equal(t, seeded.token.ID, found.ID)
+			}
+			if seeded.identity != nil {
+				identities, err := repos.Identities.FindByUserID(user.ID)
+				require.NoError(t, err)
+				assert.Empty(t, identities)
+				identities, err = repos.Identities.FindByUserID(seeded.user.ID)
+				require.NoError(t, err)
+				assert.Len(t, identities, 1)
+			}
+			if seeded.challenge != nil {
+				_, err := repos.MFAChallenges.FindByTokenHash("bob-hash")
+				assert.ErrorIs(t, err, domain.ErrMFAChallengeNotFound)
+				_, err = repos.MFAChallenges.FindByTokenHash("alice-hash")
+				require.NoError(t, err)
+			}
+
+			// The store takes changes after a rollback
+			require.NoError(t, repos.Users.Create(domain.NewUser(user.Email)))
+		})
+	}
+
+	t.Run("Rollback Of A User Delete", func(t *testing.T) {
+		repos := newRepositories(t)
+		seeded := seed(t, repos, spanned(t, repos))
+
+		err := repos.UnitOfWork.Do(func(tx repository.Repositories) error {
+			require.NoError(t, tx.Users.Delete(seeded.user.ID))
+			return errUnitFailed
+		})
+		assert.ErrorIs(t, err, errUnitFailed)
+
+		_, err = repos.Users.FindByID(seeded.user.ID)
+		require.NoError(t, err)
+		if seeded.token != nil {
+			_, err := repos.Tokens.FindByID(seeded.token.ID)
+			assert.NoError(t, err)
+		}
+		if seeded.identity != nil {
+			_, err := repos.Identities.FindByID(seeded.identity.ID)
+			assert.NoError(t, err)
+		}
+		if seeded.challenge != nil {
+			_, err := repos.MFAChallenges.FindByTokenHash("alice-hash")
+			assert.NoError(t, err)
+		}
+	})
+
+	t.Run("Rollback Keeps Concurrent Writes", func(t *testing.T) {
+		repos := newRepositories(t)
+		user := createUser(t, repos, "alice@example.com")
+
+		written := make(chan error, 1)
+		err := repos.UnitOfWork.Do(func(tx repository.Repositories) error {
+			found, err := tx.Users.FindByID(user.ID)
+			require.NoError(t, err)
+			found.Handle = "alice"
+			require.NoError(t, tx.Users.Update(found))
+
+			// Another writer changes the user while the unit still runs
+			go func() {
+				user.DisplayName = "Alice"
+				written <- repos.Users.Update(user)
+			}()
+			time.Sleep(50 * time.Millisecond)
+			return errUnitFailed
+		})
+		assert.ErrorIs(t, err, errUnitFailed)
+		require.NoError(t, <-written)
+
+		found, err := repos.Users.FindByID(user.ID)
+		require.NoError(t, err)
+		assert.Empty(t, found.Handle)
+		assert.Equal(t, "Alice", found.DisplayName)
+	})
+
+	t.Run("Concurrent Units With One Email", func(t *testing.T) {
+		repos := newRepositories(t)
+		errs := race(func() error {
+			return repos.UnitOfWork.Do(func(tx repository.Repositories) error {
+				if _, err := tx.Users.FindByEmail("alice@example.com"); err == nil {
+					return domain.ErrUserAlreadyExists
+				}
+				return tx.Users.Create(domain.NewUser("alice@example.com"))
+			})
+		})
+		assert.Equal(t, 1, succeeded(errs))
+		for _, err := range errs {
+			if err != nil {
+				assert.ErrorIs(t, err, domain.ErrUserAlreadyExists)
+			}
+		}
+	})
+}
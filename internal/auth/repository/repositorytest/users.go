@@ -0,0 +1,352 @@
+package repositorytest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testUsers(t *testing.T, newRepositories Factory) {
+	t.Run("Create And Find", func(t *testing.T) {
+		users := newRepositories(t).Users
+		user := domain.NewUser("alice@example.com")
+		user.Handle = "Alice"
+		user.PasswordHash = "$argon2id$hash"
+		require.NoError(t, users.Create(user))
+
+		finds := []struct {
+			name string
+			find func() (*domain.User, error)
+		}{
+			{"By ID", func() (*domain.User, error) { return users.FindByID(user.ID) }},
+			{"By Email", func() (*domain.User, error) { return users.FindByEmail("alice@example.com") }},
+			{"By Handle Ignoring Case", func() (*domain.User, error) { return users.FindByHandle("aLICE") }},
+		}
+		for _, tc := range finds {
+			t.Run(tc.name, func(t *testing.T) {
+				found, err := tc.find()
+				require.NoError(t, err)
+				assert.Equal(t, user.ID, found.ID)
+				assert.Equal(t, "alice@example.com", found.Email)
+				assert.Equal(t, "Alice", found.Handle)
+				assert.Equal(t, user.PasswordHash, found.PasswordHash)
+				assert.Equal(t, []string{domain.RoleUser}, found.Roles)
+				assert.True(t, user.CreatedAt.Equal(found.CreatedAt))
+			})
+		}
+	})
+
+	t.Run("Not Found", func(t *testing.T) {
+		users := newRepositories(t).Users
+		require.NoError(t, users.Create(domain.NewUser("alice@example.com")))
+
+		runNotFound(t, []notFoundCase{
+			{"FindByID", func() error { _, err := users.FindByID(uuid.New()); return err }, domain.ErrUserNotFound},
+			{"FindByEmail", func() error { _, err := users.FindByEmail("bob@example.com"); return err }, domain.ErrUserNotFound},
+			{"FindByHandle", func() error { _, err := users.FindByHandle("bob"); return err }, domain.ErrUserNotFound},
+			{"FindByHandle Empty", func() error { _, err := users.FindByHandle(""); return err }, domain.ErrUserNotFound},
+			{"FindByEmail Empty", func() error { _, err := users.FindByEmail(""); return err }, domain.ErrUserNotFound},
+			{"Update", func() error { return users.Update(domain.NewUser("bob@example.com")) }, domain.ErrUserNotFound},
+			{"Delete", func() error { return users.Delete(uuid.New()) }, domain.ErrUserNotFound},
+		})
+	})
+
+	t.Run("Unique", func(t *testing.T) {
+		users := newRepositories(t).Users
+		alice := domain.NewUser("alice@example.com")
+		alice.Handle = "Alice"
+		require.NoError(t, users.Create(alice))
+		bob := domain.NewUser("bob@example.com")
+		require.NoError(t, users.Create(bob))
+		// Users without a handle do not collide
+		require.NoError(t, users.Create(domain.NewUser("carol@example.com")))
+		// Neither do users without an email, e.g. from providers that share no address
+		require.NoError(t, users.Create(domain.NewUser("")))
+		require.NoError(t, users.Create(domain.NewUser("")))
+
+		sameID := domain.NewUser("dave@example.com")
+		sameID.ID = alice.ID
+		sameHandle := domain.NewUser("erin@example.com")
+		sameHandle.Handle = "ALICE"
+
+		cases := []struct {
+			name string
+			call func() error
+			want error
+		}{
+			{"Create Same ID", func() error { return users.Create(sameID) }, domain.ErrUserAlreadyExists},
+			{"Create Same Email", func() error { return users.Create(domain.NewUser("alice@example.com")) }, domain.ErrUserAlreadyExists},
+			{"Create Same Handle", func() error { return users.Create(sameHandle) }, domain.ErrHandleTaken},
+			{"Update To Taken Email", func() error {
+				changed := *bob
+				changed.Email = "alice@example.com"
+				return users.Update(&changed)
+			}, domain.ErrUserAlreadyExists},
+			{"Update To Taken Handle", func() error {
+				changed := *bob
+				changed.Handle = "alice"
+				return users.Update(&changed)
+			}, domain.ErrHandleTaken},
+		}
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				require.ErrorIs(t, tc.call(), tc.want)
+			})
+		}
+
+		t.Run("Update To No Email", func(t *testing.T) {
+			changed := *bob
+			changed.Email = ""
+			require.NoError(t, users.Update(&changed))
+		})
+
+		t.Run("Update Keeping Own Email And Handle", func(t *testing.T) {
+			alice.Handle = "alice"
+			require.NoError(t, users.Update(alice))
+		})
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		users := newRepositories(t).Users
+		user := domain.NewUser("alice@example.com")
+		require.NoError(t, users.Create(user))
+
+		now := time.Now()
+		user.Email = "alice@example.org"
+		user.DisplayName = "Alice"
+		user.CodeforcesHandle = "tourist"
+		user.AddRole(domain.RoleAdmin)
+		user.BannedAt = &now
+		require.NoError(t, users.Update(user))
+
+		found, err := users.FindByID(user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "alice@example.org", found.Email)
+		assert.Equal(t, "Alice", found.DisplayName)
+		assert.Equal(t, "tourist", found.CodeforcesHandle)
+		assert.Equal(t, []string{domain.RoleUser, domain.RoleAdmin}, found.Roles)
+		require.NotNil(t, found.BannedAt)
+		assert.True(t, now.Equal(*found.BannedAt))
+
+		_, err = users.FindByEmail("alice@example.com")
+		assert.ErrorIs(t, err, domain.ErrUserNotFound)
+	})
+
+	t.Run("Returned Users Are Copies", func(t *testing.T) {
+		users := newRepositories(t).Users
+		user := domain.NewUser("alice@example.com")
+		require.NoError(t, users.Create(user))
+
+		user.Email = "changed@example.com"
+		found, err := users.FindByID(user.ID)
+		require.NoError(t, err)
+		found.AddRole(domain.RoleAdmin)
+		found.Handle = "changed"
+
+		found, err = users.FindByID(user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "alice@example.com", found.Email, "changes are stored by Update only")
+		assert.Equal(t, []string{domain.RoleUser}, found.Roles)
+		assert.Empty(t, found.Handle)
+	})
+
+	t.Run("FindByRole", func(t *testing.T) {
+		users := newRepositories(t).Users
+		admin := domain.NewUser("admin@example.com")
+		admin.AddRole(domain.RoleAdmin)
+		require.NoError(t, users.Create(admin))
+		require.NoError(t, users.Create(domain.NewUser("alice@example.com")))
+
+		admins, err := users.FindByRole(domain.RoleAdmin)
+		require.NoError(t, err)
+		require.Len(t, admins, 1)
+		assert.Equal(t, admin.ID, admins[0].ID)
+
+		everyone, err := users.FindByRole(domain.RoleUser)
+		require.NoError(t, err)
+		assert.Len(t, everyone, 2)
+	})
+
+	t.Run("FindScheduledForDeletion", func(t *testing.T) {
+		users := newRepositories(t).Users
+		due := time.Now().Add(time.Hour)
+		scheduled := domain.NewUser("alice@example.com")
+		scheduled.DeletionScheduledAt = &due
+		require.NoError(t, users.Create(scheduled))
+		require.NoError(t, users.Create(domain.NewUser("bob@example.com")))
+
+		cases := []struct {
+			name string
+			now  time.Time
+			want int
+		}{
+			{"Before", due.Add(-time.Millisecond), 0},
+			{"At", due, 1},
+			{"After", due.Add(time.Hour), 1},
+		}
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				found, err := users.FindScheduledForDeletion(tc.now)
+				require.NoError(t, err)
+				require.Len(t, found, tc.want)
+				if tc.want > 0 {
+					assert.Equal(t, scheduled.ID, found[0].ID)
+				}
+			})
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		users := newRepositories(t).Users
+		user := domain.NewUser("alice@example.com")
+		require.NoError(t, users.Create(user))
+		require.NoError(t, users.Delete(user.ID))
+
+		_, err := users.FindByID(user.ID)
+		assert.ErrorIs(t, err, domain.ErrUserNotFound)
+		// The email is free again
+		require.NoError(t, users.Create(domain.NewUser("alice@example.com")))
+	})
+
+	t.Run("DeleteScheduled", func(t *testing.T) {
+		users := newRepositories(t).Users
+		due := time.Now().Add(time.Hour)
+		scheduled := domain.NewUser("alice@example.com")
+		scheduled.DeletionScheduledAt = &due
+		require.NoError(t, users.Create(scheduled))
+		kept := domain.NewUser("bob@example.com")
+		require.NoError(t, users.Create(kept))
+
+		// Not due yet, or not scheduled at all, the user stays
+		assert.ErrorIs(t, users.DeleteScheduled(scheduled.ID, due.Add(-time.Millisecond)), domain.ErrUserNotFound)
+		assert.ErrorIs(t, users.DeleteScheduled(kept.ID, due), domain.ErrUserNotFound)
+		assert.ErrorIs(t, users.DeleteScheduled(uuid.New(), due), domain.ErrUserNotFound)
+		_, err := users.FindByID(kept.ID)
+		assert.NoError(t, err)
+
+		require.NoError(t, users.DeleteScheduled(scheduled.ID, due))
+		_, err = users.FindByID(scheduled.ID)
+		assert.ErrorIs(t, err, domain.ErrUserNotFound)
+		assert.ErrorIs(t, users.DeleteScheduled(scheduled.ID, due), domain.ErrUserNotFound)
+	})
+
+	t.Run("Delete Cascades", func(t *testing.T) {
+		testDeleteCascades(t, newRepositories(t))
+	})
+
+	t.Run("Concurrent Creates With One Email", func(t *testing.T) {
+		users := newRepositories(t).Users
+		errs := race(func() error { return users.Create(domain.NewUser("race@example.com")) })
+
+		assert.Equal(t, 1, succeeded(errs))
+		for _, err := range errs {
+			if err != nil {
+				assert.ErrorIs(t, err, domain.ErrUserAlreadyExists)
+			}
+		}
+	})
+
+	t.Run("Concurrent Updates", func(t *testing.T) {
+		users := newRepositories(t).Users
+		user := domain.NewUser("alice@example.com")
+		require.NoError(t, users.Create(user))
+
+		errs := race(func() error {
+			found, err := users.FindByID(user.ID)
+			if err != nil {
+				return err
+			}
+			found.DisplayName = "Alice"
+			return users.Update(found)
+		})
+		assert.Equal(t, concurrency, succeeded(errs))
+
+		found, err := users.FindByID(user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Alice", found.DisplayName)
+	})
+}
+
+// testDeleteCascades checks that deleting a user deletes their records in every other repository
+func testDeleteCascades(t *testing.T, repos Repositories) {
+	user := createUser(t, repos, "alice@example.com")
+	other := createUser(t, repos, "bob@example.com")
+
+	// checks report whether the user's record in a repository is gone after the delete
+	var checks []func(t *testing.T)
+	if repos.Tokens != nil {
+		token := newToken(user.ID)
+		require.NoError(t, repos.Tokens.Create(token))
+		checks = append(checks, func(t *testing.T) {
+			_, err := repos.Tokens.FindByID(token.ID)
+			assert.ErrorIs(t, err, domain.ErrTokenNotFound, "tokens")
+		})
+	}
+	if repos.Identities != nil {
+		identity := domain.NewIdentity(user.ID, "github", "1001", user.Email, true)
+		require.NoError(t, repos.Identities.Create(identity))
+		checks = append(checks, func(t *testing.T) {
+			_, err := repos.Identities.FindByID(identity.ID)
+			assert.ErrorIs(t, err, domain.ErrIdentityNotFound, "identities")
+		})
+	}
+	if repos.OAuthStates != nil {
+		state := domain.NewOAuthState("link-state", "github", "", "", time.Hour)
+		state.LinkUserID = user.ID
+		require.NoError(t, repos.OAuthStates.Create(state))
+		checks = append(checks, func(t *testing.T) {
+			_, err := repos.OAuthStates.Consume(state.State)
+			assert.ErrorIs(t, err, domain.ErrOAuthStateExpired, "oauth states")
+		})
+	}
+	if repos.MFA != nil {
+		require.NoError(t, repos.MFA.Save(domain.NewMFA(user.ID, "JBSWY3DPEHPK3PXP")))
+		checks = append(checks, func(t *testing.T) {
+			_, err := repos.MFA.FindByUserID(user.ID)
+			assert.ErrorIs(t, err, domain.ErrMFANotEnrolled, "mfa")
+		})
+	}
+	if repos.MFAChallenges != nil {
+		require.NoError(t, repos.MFAChallenges.Create(domain.NewMFAChallenge(user.ID, "challenge-hash", time.Hour)))
+		checks = append(checks, func(t *testing.T) {
+			_, err := repos.MFAChallenges.FindByTokenHash("challenge-hash")
+			assert.ErrorIs(t, err, domain.ErrMFAChallengeNotFound, "mfa challenges")
+		})
+	}
+	if repos.DeviceAuthorizations != nil {
+		authorization := newDeviceAuthorization("device-hash", "BCDFGHJK", time.Hour)
+		authorization.Status = domain.DeviceAuthorizationApproved
+		authorization.UserID = user.ID
+		require.NoError(t, repos.DeviceAuthorizations.Create(authorization))
+		checks = append(checks, func(t *testing.T) {
+			_, err := repos.DeviceAuthorizations.FindByDeviceCodeHash("device-hash")
+			assert.ErrorIs(t, err, domain.ErrDeviceAuthorizationNotFound, "device authorizations")
+		})
+	}
+	if repos.HandleVerifications != nil {
+		require.NoError(t, repos.HandleVerifications.Create(domain.NewHandleVerification(user.ID, domain.JudgeCodeforces, "tourist", time.Hour)))
+		checks = append(checks, func(t *testing.T) {
+			_, err := repos.HandleVerifications.FindByUserAndJudge(user.ID, domain.JudgeCodeforces)
+			assert.ErrorIs(t, err, domain.ErrHandleVerificationNotFound, "handle verifications")
+		})
+	}
+
+	// Records of other users stay
+	otherToken := newToken(other.ID)
+	if repos.Tokens != nil {
+		require.NoError(t, repos.Tokens.Create(otherToken))
+	}
+
+	require.NoError(t, repos.Users.Delete(user.ID))
+	for _, check := range checks {
+		check(t)
+	}
+	if repos.Tokens != nil {
+		_, err := repos.Tokens.FindByID(otherToken.ID)
+		assert.NoError(t, err)
+	}
+}
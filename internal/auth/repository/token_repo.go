@@ -7,16 +7,20 @@ import (
 
 // TokenRepository defines the interface for token persistence operations
 type TokenRepository interface {
-	// Create creates a new refresh token
+	// Create stores a new refresh token, keeping its hash but never the refresh token itself
 	Create(token *domain.Token) error
 	// FindByID finds a token by its ID
 	FindByID(id uuid.UUID) (*domain.Token, error)
 	// FindByUserID finds all tokens for a user
 	FindByUserID(userID uuid.UUID) ([]*domain.Token, error)
-	// FindByRefreshToken finds a token by its refresh token string
-	FindByRefreshToken(refreshToken string) (*domain.Token, error)
+	// FindByRefreshTokenHash finds a token by the keyed hash of its refresh token
+	FindByRefreshTokenHash(refreshTokenHash string) (*domain.Token, error)
+	// Update updates an existing token
+	Update(token *domain.Token) error
 	// Delete deletes a token by its ID
 	Delete(id uuid.UUID) error
 	// DeleteByUserID deletes all tokens for a user
 	DeleteByUserID(userID uuid.UUID) error
+	// DeleteByFamilyID deletes every token rotated from the same login
+	DeleteByFamilyID(familyID uuid.UUID) error
 }
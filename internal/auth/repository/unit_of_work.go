@@ -0,0 +1,18 @@
+package repository
+
+// Repositories are the repositories a unit of work spans
+type Repositories struct {
+	Users         UserRepository
+	Tokens        TokenRepository
+	Identities    IdentityRepository
+	MFAChallenges MFAChallengeRepository
+}
+
+// UnitOfWork defines the interface for running steps across repositories as a whole
+type UnitOfWork interface {
+	// Do calls fn with repositories whose changes are committed together once fn returns nil.
+	// When fn returns an error or panics, none of its changes are kept and Do returns the error.
+	// fn must not change the same data through other repositories, which may block until Do
+	// returns.
+	Do(fn func(tx Repositories) error) error
+}
@@ -1,22 +1,31 @@
 package repository
 
 import (
+	"time"
+
 	"github.com/algosim/backend/internal/auth/domain"
 	"github.com/google/uuid"
 )
 
 // UserRepository defines the interface for user persistence operations
 type UserRepository interface {
-	// Create creates a new user
+	// Create creates a new user. Emails are unique, except that any number of users may have none.
 	Create(user *domain.User) error
 	// FindByID finds a user by their ID
 	FindByID(id uuid.UUID) (*domain.User, error)
-	// FindByEmail finds a user by their email
+	// FindByEmail finds a user by their email, users without one are never found
 	FindByEmail(email string) (*domain.User, error)
-	// FindByOAuthProviderID finds a user by their OAuth provider ID
-	FindByOAuthProviderID(provider, providerID string) (*domain.User, error)
+	// FindByHandle finds a user by their profile handle, ignoring case
+	FindByHandle(handle string) (*domain.User, error)
+	// FindByRole finds all users holding a role
+	FindByRole(role string) ([]*domain.User, error)
+	// FindScheduledForDeletion finds users whose account deletion is due at or before now
+	FindScheduledForDeletion(now time.Time) ([]*domain.User, error)
 	// Update updates an existing user
 	Update(user *domain.User) error
 	// Delete deletes a user by their ID
 	Delete(id uuid.UUID) error
+	// DeleteScheduled deletes a user only while their account deletion is due at or before now,
+	// otherwise the user is not found
+	DeleteScheduled(id uuid.UUID, now time.Time) error
 }
@@ -0,0 +1,86 @@
+package usecase
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/google/uuid"
+)
+
+// RequestAccountDeletion schedules the deletion of a user's account after the grace period and signs
+// them out everywhere. Password accounts confirm with their password, OAuth-only accounts must call
+// from a session that signed in within the re-authentication window.
+func (u *AuthUseCase) RequestAccountDeletion(userID, sessionID uuid.UUID, plainPassword string) (*domain.User, error) {
+	user, err := u.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, domain.ErrUserNotFound
+	}
+
+	if err := u.reauthenticate(user, sessionID, plainPassword); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	scheduledAt := now.Add(u.deletionGracePeriod)
+	user.DeletionScheduledAt = &scheduledAt
+	user.UpdatedAt = now
+	if err := u.userRepo.Update(user); err != nil {
+		return nil, fmt.Errorf("failed to schedule account deletion: %w", err)
+	}
+
+	if err := u.LogoutAll(user.ID); err != nil {
+		return nil, err
+	}
+
+	event := domain.NewSecurityEvent(domain.SecurityEventAccountDeletionRequested, user.ID)
+	event.Details["scheduled_at"] = scheduledAt.UTC().Format(time.RFC3339)
+	u.events.Publish(event)
+
+	return user, nil
+}
+
+// reauthenticate checks the caller just proved they own the account
+func (u *AuthUseCase) reauthenticate(user *domain.User, sessionID uuid.UUID, plainPassword string) error {
+	if user.PasswordHash != "" {
+		if plainPassword == "" {
+			return domain.ErrReauthenticationRequired
+		}
+		ok, err := u.passwordHasher.Verify(plainPassword, user.PasswordHash)
+		if err != nil {
+			return fmt.Errorf("failed to verify password: %w", err)
+		}
+		if !ok {
+			return domain.ErrInvalidCredentials
+		}
+		return nil
+	}
+
+	// Without a password the session must have just signed in, rotation keeps its start time
+	tokens, err := u.tokenRepo.FindByUserID(user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to find tokens: %w", err)
+	}
+	for _, token := range tokens {
+		if token.FamilyID == sessionID && time.Since(token.SessionStartedAt) <= u.reauthWindow {
+			return nil
+		}
+	}
+	return domain.ErrReauthenticationRequired
+}
+
+// cancelAccountDeletion restores an account scheduled for deletion when its owner signs in again
+func (u *AuthUseCase) cancelAccountDeletion(tx *unit, user *domain.User) error {
+	if !user.IsDeletionScheduled() {
+		return nil
+	}
+
+	user.DeletionScheduledAt = nil
+	user.UpdatedAt = time.Now()
+	if err := tx.Users.Update(user); err != nil {
+		return fmt.Errorf("failed to cancel account deletion: %w", err)
+	}
+
+	tx.publish(domain.NewSecurityEvent(domain.SecurityEventAccountDeletionCancelled, user.ID))
+	return nil
+}
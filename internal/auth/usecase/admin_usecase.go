@@ -0,0 +1,162 @@
+package usecase
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/algosim/backend/internal/auth/infrastructure/events"
+	"github.com/algosim/backend/internal/auth/repository"
+	"github.com/google/uuid"
+)
+
+// AdminUseCase handles user moderation and role management by admins
+type AdminUseCase struct {
+	userRepo    repository.UserRepository
+	authUseCase *AuthUseCase
+	events      events.Publisher
+}
+
+// NewAdminUseCase creates a new AdminUseCase instance
+func NewAdminUseCase(userRepo repository.UserRepository, authUseCase *AuthUseCase, publisher events.Publisher) *AdminUseCase {
+	return &AdminUseCase{
+		userRepo:    userRepo,
+		authUseCase: authUseCase,
+		events:      publisher,
+	}
+}
+
+// GrantRole gives a user a role, it takes effect with the user's next access token
+func (u *AdminUseCase) GrantRole(actorID, userID uuid.UUID, role string) (*domain.User, error) {
+	if !domain.IsValidRole(role) {
+		return nil, domain.ErrInvalidRole
+	}
+
+	user, err := u.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.AddRole(role) {
+		if err := u.update(user); err != nil {
+			return nil, err
+		}
+		u.publish(domain.SecurityEventRoleGranted, actorID, user.ID, role)
+	}
+
+	return user, nil
+}
+
+// RevokeRole takes a role from a user, guards stop honouring it at once
+func (u *AdminUseCase) RevokeRole(actorID, userID uuid.UUID, role string) (*domain.User, error) {
+	if !domain.IsValidRole(role) {
+		return nil, domain.ErrInvalidRole
+	}
+	// An admin demoting themselves could leave nobody to undo it
+	if actorID == userID && role == domain.RoleAdmin {
+		return nil, domain.ErrSelfModification
+	}
+
+	user, err := u.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.RemoveRole(role) {
+		if err := u.update(user); err != nil {
+			return nil, err
+		}
+		u.publish(domain.SecurityEventRoleRevoked, actorID, user.ID, role)
+	}
+
+	return user, nil
+}
+
+// BanUser bans a user and signs them out everywhere
+func (u *AdminUseCase) BanUser(actorID, userID uuid.UUID) (*domain.User, error) {
+	if actorID == userID {
+		return nil, domain.ErrSelfModification
+	}
+
+	user, err := u.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !user.IsBanned() {
+		now := time.Now()
+		user.BannedAt = &now
+		if err := u.update(user); err != nil {
+			return nil, err
+		}
+		u.publish(domain.SecurityEventUserBanned, actorID, user.ID, "")
+	}
+
+	if err := u.authUseCase.LogoutAll(user.ID); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// UnbanUser lifts the ban of a user
+func (u *AdminUseCase) UnbanUser(actorID, userID uuid.UUID) (*domain.User, error) {
+	user, err := u.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.IsBanned() {
+		user.BannedAt = nil
+		if err := u.update(user); err != nil {
+			return nil, err
+		}
+		u.publish(domain.SecurityEventUserUnbanned, actorID, user.ID, "")
+	}
+
+	return user, nil
+}
+
+// ResetMFA removes the authenticator of a user who lost it, they sign in with their first factor
+// alone until they enroll again
+func (u *AdminUseCase) ResetMFA(actorID, userID uuid.UUID) (*domain.User, error) {
+	user, err := u.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.authUseCase.resetMFA(user.ID); err != nil {
+		return nil, err
+	}
+	u.publish(domain.SecurityEventMFAReset, actorID, user.ID, "")
+
+	return user, nil
+}
+
+// findUser loads the user an admin acts on
+func (u *AdminUseCase) findUser(userID uuid.UUID) (*domain.User, error) {
+	user, err := u.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, domain.ErrUserNotFound
+	}
+	return user, nil
+}
+
+// update stores a moderated user
+func (u *AdminUseCase) update(user *domain.User) error {
+	user.UpdatedAt = time.Now()
+	if err := u.userRepo.Update(user); err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	return nil
+}
+
+// publish emits the audit event of an admin action
+func (u *AdminUseCase) publish(eventType string, actorID, userID uuid.UUID, role string) {
+	event := domain.NewSecurityEvent(eventType, userID)
+	event.Details["actor_id"] = actorID.String()
+	if role != "" {
+		event.Details["role"] = role
+	}
+	u.events.Publish(event)
+}
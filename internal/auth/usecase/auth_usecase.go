@@ -1,86 +1,419 @@
 package usecase
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/mail"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
 
 	"github.com/algosim/backend/configs"
 	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/algosim/backend/internal/auth/infrastructure/events"
 	"github.com/algosim/backend/internal/auth/infrastructure/jwt"
 	"github.com/algosim/backend/internal/auth/infrastructure/oauth"
+	"github.com/algosim/backend/internal/auth/infrastructure/password"
 	"github.com/algosim/backend/internal/auth/repository"
+	"github.com/google/uuid"
 )
 
 // AuthUseCase handles authentication business logic
 type AuthUseCase struct {
-	userRepo    repository.UserRepository
-	tokenRepo   repository.TokenRepository
-	googleOAuth oauth.GoogleOAuth
-	jwtManager  *jwt.JWTManager
+	userRepo      repository.UserRepository
+	tokenRepo     repository.TokenRepository
+	identityRepo  repository.IdentityRepository
+	stateRepo     repository.OAuthStateRepository
+	mfaRepo       repository.MFARepository
+	challengeRepo repository.MFAChallengeRepository
+	providers     *oauth.Registry
+	events        events.Publisher
+	jwtManager    *jwt.JWTManager
+	stateSecret   []byte
+	stateTTL      time.Duration
+	// bootstrapAdminEmail is promoted to admin on sign-in while there is no admin
+	bootstrapAdminEmail string
+	// refreshGracePeriod is how long a rotated refresh token is still accepted for concurrent refreshes
+	refreshGracePeriod time.Duration
+	// deletionGracePeriod is how long a deleted account can be restored by signing in
+	deletionGracePeriod time.Duration
+	// reauthWindow is how recent a session must be to stand in for a password
+	reauthWindow time.Duration
+
+	mfaIssuer       string
+	mfaChallengeTTL time.Duration
+	mfaMaxAttempts  int
+	mfaLockout      time.Duration
+	// mfaMu serializes the code checks and resets of this instance. Conditional writes keep a code
+	// from being used twice across instances, the lock only spares them from retrying.
+	mfaMu sync.Mutex
+
+	passwordHasher    password.Hasher
+	passwordMinLength int
+	// dummyHash is verified against when the email is unknown so failures take the same time
+	dummyHash     string
+	dummyHashOnce sync.Once
+
+	// uow spans the steps of a sign-in, see SetUnitOfWork
+	uow repository.UnitOfWork
+}
+
+// OAuthLogin holds everything a client needs to start an OAuth login
+type OAuthLogin struct {
+	AuthURL string
+	State   string
+	// Binding ties the state to the browser and must come back with the callback
+	Binding   string
+	ExpiresAt time.Time
 }
 
 // NewAuthUseCase creates a new AuthUseCase instance
 func NewAuthUseCase(
 	userRepo repository.UserRepository,
 	tokenRepo repository.TokenRepository,
-	googleOAuth oauth.GoogleOAuth,
+	identityRepo repository.IdentityRepository,
+	stateRepo repository.OAuthStateRepository,
+	mfaRepo repository.MFARepository,
+	challengeRepo repository.MFAChallengeRepository,
+	providers *oauth.Registry,
+	publisher events.Publisher,
+	jwtManager *jwt.JWTManager,
 	config *configs.Config,
 ) *AuthUseCase {
 	return &AuthUseCase{
-		userRepo:    userRepo,
-		tokenRepo:   tokenRepo,
-		googleOAuth: googleOAuth,
-		jwtManager:  jwt.NewJWTManager(config),
+		userRepo:           userRepo,
+		tokenRepo:          tokenRepo,
+		identityRepo:       identityRepo,
+		stateRepo:          stateRepo,
+		mfaRepo:            mfaRepo,
+		challengeRepo:      challengeRepo,
+		providers:          providers,
+		events:             publisher,
+		jwtManager:         jwtManager,
+		stateSecret:        []byte(config.OAuth.StateSecret),
+		stateTTL:           time.Duration(config.OAuth.StateTTL) * time.Second,
+		refreshGracePeriod: time.Duration(config.Auth.RefreshGracePeriod) * time.Second,
+
+		deletionGracePeriod: time.Duration(config.Account.DeletionGracePeriod) * time.Second,
+		reauthWindow:        time.Duration(config.Account.ReauthWindow) * time.Second,
+
+		mfaIssuer:       config.MFA.Issuer,
+		mfaChallengeTTL: time.Duration(config.MFA.ChallengeTTL) * time.Second,
+		mfaMaxAttempts:  config.MFA.MaxAttempts,
+		mfaLockout:      time.Duration(config.MFA.Lockout) * time.Second,
+
+		bootstrapAdminEmail: strings.ToLower(strings.TrimSpace(config.Auth.BootstrapAdminEmail)),
+
+		passwordHasher:    password.NewArgon2idHasher(config),
+		passwordMinLength: config.Password.MinLength,
+
+		uow: directUnitOfWork{repository.Repositories{
+			Users:         userRepo,
+			Tokens:        tokenRepo,
+			Identities:    identityRepo,
+			MFAChallenges: challengeRepo,
+		}},
+	}
+}
+
+// InitiateOAuthLogin mints a single-use state and generates the login URL of the given provider.
+// ctx bounds the calls to the provider.
+func (u *AuthUseCase) InitiateOAuthLogin(ctx context.Context, providerName string) (*OAuthLogin, error) {
+	return u.initiateOAuth(ctx, providerName, uuid.Nil)
+}
+
+// initiateOAuth starts a login, or links the provider account to linkUserID unless it is uuid.Nil
+func (u *AuthUseCase) initiateOAuth(ctx context.Context, providerName string, linkUserID uuid.UUID) (*OAuthLogin, error) {
+	provider, err := u.providers.Get(providerName)
+	if err != nil {
+		return nil, err
 	}
+
+	state, err := generateRandomString(32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate oauth state: %w", err)
+	}
+
+	codeVerifier, err := oauth.GenerateCodeVerifier()
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, err := generateRandomString(32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate oauth nonce: %w", err)
+	}
+
+	authURL, err := provider.GetAuthURL(ctx, state, oauth.CodeChallengeS256(codeVerifier), nonce)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build login url: %w", err)
+	}
+
+	oauthState := domain.NewOAuthState(state, provider.Name(), codeVerifier, nonce, u.stateTTL)
+	oauthState.LinkUserID = linkUserID
+	if err := u.stateRepo.Create(oauthState); err != nil {
+		return nil, fmt.Errorf("failed to store oauth state: %w", err)
+	}
+
+	return &OAuthLogin{
+		AuthURL:   authURL,
+		State:     state,
+		Binding:   signStateBinding(u.stateSecret, state),
+		ExpiresAt: oauthState.ExpiresAt,
+	}, nil
 }
 
-// InitiateOAuthLogin generates the OAuth login URL
-func (u *AuthUseCase) InitiateOAuthLogin(state string) string {
-	return u.googleOAuth.GetAuthURL(state)
+// OAuthCallbackResult is the outcome of an OAuth callback, a new session for a login
+// or the linked identity when a signed-in user linked the provider account
+type OAuthCallbackResult struct {
+	Token          *domain.Token
+	LinkedIdentity *domain.Identity
+	// MFA is set instead of Token when the user has to send their second factor
+	MFA *MFAPending
 }
 
-// HandleOAuthCallback processes the OAuth callback
-func (u *AuthUseCase) HandleOAuthCallback(code string) (*domain.Token, error) {
+// LoginResult is the outcome of a password login, a new session or the pending
+// second factor when the user has MFA enabled
+type LoginResult struct {
+	Token *domain.Token
+	MFA   *MFAPending
+}
+
+// HandleOAuthCallback verifies the login state and processes the callback of the given provider.
+// ctx bounds the calls to the provider.
+func (u *AuthUseCase) HandleOAuthCallback(ctx context.Context, providerName, code, state, binding string, client domain.ClientInfo) (*OAuthCallbackResult, error) {
+	provider, err := u.providers.Get(providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	if state == "" || binding == "" {
+		return nil, domain.ErrOAuthStateMissing
+	}
+
+	if err := verifyStateBinding(u.stateSecret, binding, state); err != nil {
+		return nil, err
+	}
+
+	oauthState, err := u.stateRepo.Consume(state)
+	if err != nil {
+		return nil, err
+	}
+
+	// A state issued for one provider must not complete another provider's login
+	if oauthState.Provider != provider.Name() {
+		return nil, domain.ErrOAuthStateMismatch
+	}
+
+	userInfo, err := fetchUserInfo(ctx, provider, code, oauthState)
+	if err != nil {
+		return nil, err
+	}
+
+	if oauthState.IsLink() {
+		identity, err := u.linkIdentity(oauthState.LinkUserID, provider.Name(), userInfo)
+		if err != nil {
+			return nil, err
+		}
+		return &OAuthCallbackResult{LinkedIdentity: identity}, nil
+	}
+
+	// A new user is only kept together with their session
+	result := &OAuthCallbackResult{}
+	err = u.inUnit(func(tx *unit) error {
+		user, err := u.resolveOAuthUser(tx, provider.Name(), userInfo)
+		if err != nil {
+			return err
+		}
+		result.Token, result.MFA, err = u.startSession(tx, user, client)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// fetchUserInfo exchanges the authorization code and returns who signed in. OpenID Connect
+// providers answer with a verified ID token, others from their user info endpoint.
+func fetchUserInfo(ctx context.Context, provider oauth.OAuthProvider, code string, oauthState *domain.OAuthState) (*oauth.UserInfo, error) {
+	if oidc, ok := provider.(oauth.IDTokenProvider); ok {
+		userInfo, err := oidc.ExchangeCodeForIDToken(ctx, code, oauthState.CodeVerifier, oauthState.Nonce)
+		if err != nil {
+			return nil, fmt.Errorf("failed to verify id token: %w", err)
+		}
+		return userInfo, nil
+	}
+
 	// Exchange code for token
-	token, err := u.googleOAuth.ExchangeCodeForToken(code)
+	token, err := provider.ExchangeCodeForToken(ctx, code, oauthState.CodeVerifier)
 	if err != nil {
 		return nil, fmt.Errorf("failed to exchange code for token: %w", err)
 	}
 
-	// Get user info from Google
-	userInfo, err := u.googleOAuth.GetUserInfo(token.AccessToken)
+	// Get user info from the provider
+	userInfo, err := provider.GetUserInfo(ctx, token.AccessToken)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user info: %w", err)
 	}
+	return userInfo, nil
+}
 
-	// Check if user exists
-	user, err := u.userRepo.FindByOAuthProviderID("google", userInfo.ID)
+// Register creates a password account, which then signs in with Login. A taken email gets the
+// same answer after the same password hash, so registering does not reveal which emails have
+// accounts.
+func (u *AuthUseCase) Register(email, plainPassword string) error {
+	email, err := normalizeEmail(email)
 	if err != nil {
-		// Create new user if not found
-		newUser := u.googleOAuth.CreateUserFromGoogleInfo(userInfo)
-		if err := u.userRepo.Create(newUser); err != nil {
-			return nil, fmt.Errorf("failed to create user: %w", err)
+		return err
+	}
+
+	if err := password.Validate(plainPassword, email, u.passwordMinLength); err != nil {
+		return err
+	}
+
+	// Hash before the lookup so a taken email does not answer faster
+	passwordHash, err := u.passwordHasher.Hash(plainPassword)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	if existingUser, err := u.userRepo.FindByEmail(email); err == nil && existingUser != nil {
+		return nil
+	}
+
+	user := domain.NewUser(email)
+	user.PasswordHash = passwordHash
+	if err := u.userRepo.Create(user); err != nil && !errors.Is(err, domain.ErrUserAlreadyExists) {
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+	return nil
+}
+
+// Login signs in a password account, accounts with MFA get an MFA challenge instead of a session.
+// Unknown emails, OAuth-only accounts and wrong passwords all cost one hash verification
+// and fail with domain.ErrInvalidCredentials, so callers cannot tell them apart.
+func (u *AuthUseCase) Login(email, plainPassword string, client domain.ClientInfo) (*LoginResult, error) {
+	email, err := normalizeEmail(email)
+	if err != nil || utf8.RuneCountInString(plainPassword) > password.MaxLength {
+		u.verifyDummyHash(plainPassword)
+		return nil, domain.ErrInvalidCredentials
+	}
+
+	user, err := u.userRepo.FindByEmail(email)
+	if err != nil || user.PasswordHash == "" {
+		u.verifyDummyHash(plainPassword)
+		return nil, domain.ErrInvalidCredentials
+	}
+
+	ok, err := u.passwordHasher.Verify(plainPassword, user.PasswordHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify password: %w", err)
+	}
+	if !ok {
+		return nil, domain.ErrInvalidCredentials
+	}
+
+	// Upgrade hashes made with an older cost while the plain password is at hand
+	if u.passwordHasher.NeedsRehash(user.PasswordHash) {
+		if passwordHash, err := u.passwordHasher.Hash(plainPassword); err == nil {
+			user.PasswordHash = passwordHash
+			user.UpdatedAt = time.Now()
+			if err := u.userRepo.Update(user); err != nil {
+				return nil, fmt.Errorf("failed to update password hash: %w", err)
+			}
 		}
+	}
+
+	result := &LoginResult{}
+	err = u.inUnit(func(tx *unit) error {
+		var err error
+		result.Token, result.MFA, err = u.startSession(tx, user, client)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
 
-		user = newUser
+// bootstrapAdmin promotes the configured bootstrap admin as long as no admin exists
+func (u *AuthUseCase) bootstrapAdmin(tx *unit, user *domain.User) error {
+	if u.bootstrapAdminEmail == "" || user.Email != u.bootstrapAdminEmail || user.HasRole(domain.RoleAdmin) {
+		return nil
 	}
 
-	token, err = u.jwtManager.GenerateToken(user)
+	admins, err := tx.Users.FindByRole(domain.RoleAdmin)
+	if err != nil {
+		return fmt.Errorf("failed to find admins: %w", err)
+	}
+	if len(admins) > 0 {
+		return nil
+	}
+
+	user.AddRole(domain.RoleAdmin)
+	user.UpdatedAt = time.Now()
+	if err := tx.Users.Update(user); err != nil {
+		return fmt.Errorf("failed to bootstrap admin: %w", err)
+	}
+
+	tx.publish(domain.NewSecurityEvent(domain.SecurityEventAdminBootstrapped, user.ID))
+	return nil
+}
+
+// verifyDummyHash spends the time of a real password check
+func (u *AuthUseCase) verifyDummyHash(plainPassword string) {
+	u.dummyHashOnce.Do(func() {
+		u.dummyHash, _ = u.passwordHasher.Hash("dummy-password-for-timing")
+	})
+	u.passwordHasher.Verify(plainPassword, u.dummyHash)
+}
+
+// issueToken starts a session of user on client and stores its refresh token
+func (u *AuthUseCase) issueToken(tx *unit, user *domain.User, client domain.ClientInfo) (*domain.Token, error) {
+	if user.IsBanned() {
+		return nil, domain.ErrUserBanned
+	}
+
+	if err := u.bootstrapAdmin(tx, user); err != nil {
+		return nil, err
+	}
+
+	if err := u.cancelAccountDeletion(tx, user); err != nil {
+		return nil, err
+	}
+
+	token, err := u.jwtManager.GenerateToken(user)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate token: %w", err)
 	}
+	token.UserAgent = client.UserAgent
+	token.IPAddress = client.IPAddress
 
 	// Store refresh token
-	if err := u.tokenRepo.Create(token); err != nil {
+	if err := tx.Tokens.Create(token); err != nil {
 		return nil, fmt.Errorf("failed to store refresh token: %w", err)
 	}
 
 	return token, nil
 }
 
-// RefreshToken generates a new access token using a refresh token
-func (u *AuthUseCase) RefreshToken(refreshTokenString string) (*domain.Token, error) {
+// normalizeEmail validates an email address and returns its canonical form
+func normalizeEmail(email string) (string, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return "", domain.ErrInvalidEmail
+	}
+	return email, nil
+}
+
+// RefreshToken rotates a refresh token into a new token of the same family
+func (u *AuthUseCase) RefreshToken(refreshTokenString string, client domain.ClientInfo) (*domain.Token, error) {
 	// Find refresh token
-	token, err := u.tokenRepo.FindByRefreshToken(refreshTokenString)
+	token, err := u.tokenRepo.FindByRefreshTokenHash(u.jwtManager.HashRefreshToken(refreshTokenString))
 	if err != nil {
 		return nil, fmt.Errorf("failed to find refresh token: %w", err)
 	}
@@ -90,59 +423,107 @@ func (u *AuthUseCase) RefreshToken(refreshTokenString string) (*domain.Token, er
 		return nil, fmt.Errorf("invalid refresh token: %w", err)
 	}
 
+	// A superseded token presented after the grace period has leaked, so the whole family is revoked
+	now := time.Now()
+	if token.IsRotated() && now.Sub(*token.RotatedAt) > u.refreshGracePeriod {
+		if _, err := u.revokeSession(token.UserID, token.FamilyID); err != nil {
+			return nil, fmt.Errorf("failed to revoke token family: %w", err)
+		}
+
+		event := domain.NewSecurityEvent(domain.SecurityEventRefreshTokenReuse, token.UserID)
+		event.FamilyID = token.FamilyID
+		event.Details["token_id"] = token.ID.String()
+		u.events.Publish(event)
+
+		return nil, domain.ErrRefreshTokenReused
+	}
+
 	// Get user
 	user, err := u.userRepo.FindByID(token.UserID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to find user: %w", err)
 	}
+	if user.IsBanned() {
+		return nil, domain.ErrUserBanned
+	}
 
-	// Generate new refresh token
-	newToken, err := u.jwtManager.GenerateToken(user)
+	// Generate new refresh token in the same family
+	newToken, err := u.jwtManager.RotateToken(user, token)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate new refresh token: %w", err)
 	}
-
-	// Store new refresh token
-	if err := u.tokenRepo.Create(newToken); err != nil {
-		return nil, fmt.Errorf("failed to store new refresh token: %w", err)
+	if client.UserAgent != "" {
+		newToken.UserAgent = client.UserAgent
+	}
+	if client.IPAddress != "" {
+		newToken.IPAddress = client.IPAddress
 	}
 
-	// Delete old refresh token
-	if err := u.tokenRepo.Delete(token.ID); err != nil {
-		return nil, fmt.Errorf("failed to delete old refresh token: %w", err)
+	// The new refresh token only counts together with the old one being rotated
+	err = u.inUnit(func(tx *unit) error {
+		// Store new refresh token
+		if err := tx.Tokens.Create(newToken); err != nil {
+			return fmt.Errorf("failed to store new refresh token: %w", err)
+		}
+
+		// Keep the old refresh token as rotated so a later reuse is detected
+		if !token.IsRotated() {
+			token.RotatedAt = &now
+			if err := tx.Tokens.Update(token); err != nil {
+				return fmt.Errorf("failed to rotate old refresh token: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return newToken, nil
 }
 
-// Logout invalidates the refresh token
+// Logout invalidates the refresh token, every token rotated from the same login and their access tokens
 func (u *AuthUseCase) Logout(refreshTokenString string) error {
 	// Find refresh token
-	token, err := u.tokenRepo.FindByRefreshToken(refreshTokenString)
+	token, err := u.tokenRepo.FindByRefreshTokenHash(u.jwtManager.HashRefreshToken(refreshTokenString))
 	if err != nil {
 		return fmt.Errorf("failed to find refresh token: %w", err)
 	}
 
-	// Delete refresh token
-	if err := u.tokenRepo.Delete(token.ID); err != nil {
+	// Revoke the token family and its access tokens
+	if _, err := u.revokeSession(token.UserID, token.FamilyID); err != nil {
 		return fmt.Errorf("failed to delete refresh token: %w", err)
 	}
 
 	return nil
 }
 
+// JWKS returns the public keys other services verify access tokens with
+func (u *AuthUseCase) JWKS() jwt.JWKS {
+	return u.jwtManager.JWKS()
+}
+
 // ValidateToken validates an access token and returns the user information
 func (u *AuthUseCase) ValidateToken(tokenString string) (*domain.User, error) {
-	user, err := u.jwtManager.ValidateToken(tokenString)
+	user, _, err := u.Authenticate(tokenString)
+	return user, err
+}
+
+// Authenticate validates an access token and returns its user and verified claims
+func (u *AuthUseCase) Authenticate(tokenString string) (*domain.User, *jwt.Claims, error) {
+	claims, err := u.jwtManager.ValidateAccessToken(tokenString)
 	if err != nil {
-		return nil, fmt.Errorf("failed to validate token: %w", err)
+		return nil, nil, fmt.Errorf("failed to validate token: %w", err)
 	}
 
 	// Verify user exists in database
-	dbUser, err := u.userRepo.FindByID(user.ID)
+	dbUser, err := u.userRepo.FindByID(claims.UserID)
 	if err != nil {
-		return nil, fmt.Errorf("user not found: %w", err)
+		return nil, nil, fmt.Errorf("user not found: %w", err)
+	}
+	if dbUser.IsBanned() {
+		return nil, nil, domain.ErrUserBanned
 	}
 
-	return dbUser, nil
+	return dbUser, claims, nil
 }
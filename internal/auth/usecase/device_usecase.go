@@ -0,0 +1,234 @@
+package usecase
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/algosim/backend/configs"
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/algosim/backend/internal/auth/infrastructure/events"
+	"github.com/algosim/backend/internal/auth/repository"
+	"github.com/google/uuid"
+)
+
+const (
+	// userCodeAlphabet leaves out vowels so user codes do not spell words, and digits that look like letters
+	userCodeAlphabet = "BCDFGHJKLMNPQRSTVWXZ"
+	// userCodeLength gives 20^8, about 34 bits of entropy
+	userCodeLength = 8
+	// slowDownStep is how much the interval grows each time a device polls too fast (RFC 8628 3.5)
+	slowDownStep = 5 * time.Second
+)
+
+// DeviceUseCase handles signing in devices without a browser, such as the CLI, with the
+// OAuth 2.0 device authorization grant (RFC 8628)
+type DeviceUseCase struct {
+	deviceRepo      repository.DeviceAuthorizationRepository
+	userRepo        repository.UserRepository
+	authUseCase     *AuthUseCase
+	events          events.Publisher
+	verificationURI string
+	codeTTL         time.Duration
+	interval        time.Duration
+}
+
+// DeviceCodes is what a device shows the user and polls with
+type DeviceCodes struct {
+	DeviceCode string
+	// UserCode is formatted as XXXX-XXXX for display
+	UserCode        string
+	VerificationURI string
+	// VerificationURIComplete carries the user code so it does not have to be typed
+	VerificationURIComplete string
+	ExpiresAt               time.Time
+	Interval                time.Duration
+}
+
+// NewDeviceUseCase creates a new DeviceUseCase instance
+func NewDeviceUseCase(
+	deviceRepo repository.DeviceAuthorizationRepository,
+	userRepo repository.UserRepository,
+	authUseCase *AuthUseCase,
+	publisher events.Publisher,
+	config *configs.Config,
+) *DeviceUseCase {
+	return &DeviceUseCase{
+		deviceRepo:      deviceRepo,
+		userRepo:        userRepo,
+		authUseCase:     authUseCase,
+		events:          publisher,
+		verificationURI: config.Device.VerificationURI,
+		codeTTL:         time.Duration(config.Device.CodeTTL) * time.Second,
+		interval:        time.Duration(config.Device.Interval) * time.Second,
+	}
+}
+
+// StartDeviceAuthorization issues the codes of a new device sign-in. The device shows the user
+// code and verification URI, then polls PollDeviceToken with the device code.
+func (u *DeviceUseCase) StartDeviceAuthorization(client domain.ClientInfo) (*DeviceCodes, error) {
+	deviceCode, err := generateRandomString(32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate device code: %w", err)
+	}
+
+	userCode, err := generateUserCode()
+	if err != nil {
+		return nil, err
+	}
+
+	authorization := domain.NewDeviceAuthorization(hashDeviceCode(deviceCode), userCode, u.interval, u.codeTTL, client)
+	if err := u.deviceRepo.Create(authorization); err != nil {
+		return nil, fmt.Errorf("failed to store device authorization: %w", err)
+	}
+
+	displayCode := formatUserCode(userCode)
+	return &DeviceCodes{
+		DeviceCode:              deviceCode,
+		UserCode:                displayCode,
+		VerificationURI:         u.verificationURI,
+		VerificationURIComplete: u.verificationURI + "?user_code=" + url.QueryEscape(displayCode),
+		ExpiresAt:               authorization.ExpiresAt,
+		Interval:                authorization.Interval,
+	}, nil
+}
+
+// GetDeviceAuthorization returns the pending device sign-in of a user code so the user can check
+// it is their device before approving it
+func (u *DeviceUseCase) GetDeviceAuthorization(userCode string) (*domain.DeviceAuthorization, error) {
+	authorization, err := u.deviceRepo.FindByUserCode(normalizeUserCode(userCode))
+	if err != nil {
+		return nil, err
+	}
+	if authorization.Status != domain.DeviceAuthorizationPending {
+		return nil, domain.ErrDeviceAuthorizationDecided
+	}
+	return authorization, nil
+}
+
+// ApproveDevice signs the device of a user code in as the user once it polls again
+func (u *DeviceUseCase) ApproveDevice(userID uuid.UUID, userCode string) error {
+	return u.decide(userID, userCode, domain.DeviceAuthorizationApproved, domain.SecurityEventDeviceApproved)
+}
+
+// DenyDevice refuses the device of a user code
+func (u *DeviceUseCase) DenyDevice(userID uuid.UUID, userCode string) error {
+	return u.decide(userID, userCode, domain.DeviceAuthorizationDenied, domain.SecurityEventDeviceDenied)
+}
+
+// decide records the decision of a user on a pending device sign-in. The store only records it
+// while the sign-in is pending, so of decisions made at once on any instance the first one counts.
+func (u *DeviceUseCase) decide(userID uuid.UUID, userCode string, status domain.DeviceAuthorizationStatus, eventType string) error {
+	authorization, err := u.GetDeviceAuthorization(userCode)
+	if err != nil {
+		return err
+	}
+
+	err = u.deviceRepo.Decide(authorization.ID, status, userID)
+	if errors.Is(err, domain.ErrDeviceAuthorizationNotFound) {
+		return domain.ErrDeviceAuthorizationDecided
+	}
+	if err != nil {
+		return fmt.Errorf("failed to decide device authorization: %w", err)
+	}
+
+	event := domain.NewSecurityEvent(eventType, userID)
+	event.Details["user_agent"] = authorization.Client.UserAgent
+	event.Details["ip_address"] = authorization.Client.IPAddress
+	u.events.Publish(event)
+
+	return nil
+}
+
+// PollDeviceToken answers a polling device. Until the user decided it fails with
+// domain.ErrDeviceAuthorizationPending, once approved it starts a session of the approving user on
+// client, which then shows up among the user's sessions like any other sign-in.
+func (u *DeviceUseCase) PollDeviceToken(deviceCode string, client domain.ClientInfo) (*domain.Token, error) {
+	authorization, err := u.deviceRepo.FindByDeviceCodeHash(hashDeviceCode(deviceCode))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if authorization.IsExpired(now) {
+		return nil, domain.ErrDeviceAuthorizationExpired
+	}
+
+	tooFast := authorization.LastPollAt != nil && now.Sub(*authorization.LastPollAt) < authorization.Interval
+	interval := authorization.Interval
+	if tooFast {
+		interval += slowDownStep
+	}
+
+	// Recording the poll leaves the decision alone, an approval made meanwhile is redeemed next time
+	if tooFast || authorization.Status == domain.DeviceAuthorizationPending {
+		if err := u.deviceRepo.UpdatePoll(authorization.ID, now, interval); err != nil {
+			return nil, fmt.Errorf("failed to update device authorization: %w", err)
+		}
+		if tooFast {
+			return nil, domain.ErrDeviceSlowDown
+		}
+		return nil, domain.ErrDeviceAuthorizationPending
+	}
+
+	// The decision is final, the device code cannot be used again. Of concurrent polls only the
+	// one that deletes the decided authorization redeems it.
+	err = u.deviceRepo.Redeem(authorization.ID, authorization.Status)
+	if errors.Is(err, domain.ErrDeviceAuthorizationNotFound) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to redeem device authorization: %w", err)
+	}
+	if authorization.Status == domain.DeviceAuthorizationDenied {
+		return nil, domain.ErrDeviceAuthorizationDenied
+	}
+
+	user, err := u.userRepo.FindByID(authorization.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+	return u.authUseCase.signIn(user, client)
+}
+
+// hashDeviceCode returns the hash a device code is stored and looked up by
+func hashDeviceCode(deviceCode string) string {
+	sum := sha256.Sum256([]byte(deviceCode))
+	return hex.EncodeToString(sum[:])
+}
+
+// generateUserCode returns a new normalized user code
+func generateUserCode() (string, error) {
+	code := make([]byte, userCodeLength)
+	alphabetSize := big.NewInt(int64(len(userCodeAlphabet)))
+	for i := range code {
+		n, err := rand.Int(rand.Reader, alphabetSize)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate user code: %w", err)
+		}
+		code[i] = userCodeAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
+
+// formatUserCode splits a normalized user code in two halves for display
+func formatUserCode(userCode string) string {
+	return userCode[:userCodeLength/2] + "-" + userCode[userCodeLength/2:]
+}
+
+// normalizeUserCode undoes the display formatting and the case a user typed a code in
+func normalizeUserCode(userCode string) string {
+	userCode = strings.ToUpper(userCode)
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, userCode)
+}
@@ -0,0 +1,191 @@
+package usecase
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/algosim/backend/configs"
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/algosim/backend/internal/auth/infrastructure/judge"
+	"github.com/algosim/backend/internal/auth/repository"
+	"github.com/google/uuid"
+)
+
+var (
+	codeforcesHandlePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{3,24}$`)
+	atcoderHandlePattern    = regexp.MustCompile(`^[A-Za-z0-9_]{3,16}$`)
+)
+
+// verificationProblems are well-known Codeforces problems a compilation error is submitted to
+var verificationProblems = []struct {
+	ContestID int
+	Index     string
+}{
+	{1, "A"}, {4, "A"}, {71, "A"}, {158, "A"}, {231, "A"}, {282, "A"}, {339, "A"}, {546, "A"},
+}
+
+// recentSubmissionsChecked is how many of the latest submissions are searched for the task
+const recentSubmissionsChecked = 10
+
+// HandleUseCase handles linking and verifying judge handles
+type HandleUseCase struct {
+	userRepo         repository.UserRepository
+	verificationRepo repository.HandleVerificationRepository
+	codeforces       judge.CodeforcesClient
+	atcoder          judge.AtCoderClient
+	verificationTTL  time.Duration
+}
+
+// NewHandleUseCase creates a new HandleUseCase instance
+func NewHandleUseCase(
+	userRepo repository.UserRepository,
+	verificationRepo repository.HandleVerificationRepository,
+	codeforces judge.CodeforcesClient,
+	atcoder judge.AtCoderClient,
+	config *configs.Config,
+) *HandleUseCase {
+	return &HandleUseCase{
+		userRepo:         userRepo,
+		verificationRepo: verificationRepo,
+		codeforces:       codeforces,
+		atcoder:          atcoder,
+		verificationTTL:  time.Duration(config.Judges.VerificationTTL) * time.Second,
+	}
+}
+
+// ClaimHandle starts the verification of a judge handle and returns the task proving ownership
+func (u *HandleUseCase) ClaimHandle(userID uuid.UUID, judgeName, handle string) (*domain.HandleVerification, error) {
+	handle = strings.TrimSpace(handle)
+	if err := validateHandle(judgeName, handle); err != nil {
+		return nil, err
+	}
+
+	verification := domain.NewHandleVerification(userID, judgeName, handle, u.verificationTTL)
+	switch judgeName {
+	case domain.JudgeCodeforces:
+		problem := verificationProblems[rand.IntN(len(verificationProblems))]
+		verification.ContestID = problem.ContestID
+		verification.ProblemIndex = problem.Index
+	case domain.JudgeAtCoder:
+		token, err := generateRandomString(12)
+		if err != nil {
+			return nil, err
+		}
+		verification.Token = "algosim-" + token
+	}
+
+	if err := u.verificationRepo.Create(verification); err != nil {
+		return nil, fmt.Errorf("failed to store handle verification: %w", err)
+	}
+
+	return verification, nil
+}
+
+// VerifyHandle checks the pending verification task with the judge and links the handle once it is done
+func (u *HandleUseCase) VerifyHandle(userID uuid.UUID, judgeName string) (*domain.User, error) {
+	if judgeName != domain.JudgeCodeforces && judgeName != domain.JudgeAtCoder {
+		return nil, domain.ErrJudgeNotSupported
+	}
+
+	verification, err := u.verificationRepo.FindByUserAndJudge(userID, judgeName)
+	if err != nil {
+		return nil, err
+	}
+
+	if verification.IsExpired(time.Now()) {
+		if err := u.verificationRepo.Delete(verification.ID); err != nil {
+			return nil, fmt.Errorf("failed to delete handle verification: %w", err)
+		}
+		return nil, domain.ErrHandleVerificationExpired
+	}
+
+	var completed bool
+	switch judgeName {
+	case domain.JudgeCodeforces:
+		completed, err = u.checkCodeforces(verification)
+	case domain.JudgeAtCoder:
+		completed, err = u.checkAtCoder(verification)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !completed {
+		return nil, domain.ErrHandleVerificationIncomplete
+	}
+
+	user, err := u.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	switch judgeName {
+	case domain.JudgeCodeforces:
+		user.CodeforcesHandle = verification.Handle
+	case domain.JudgeAtCoder:
+		user.AtcoderHandle = verification.Handle
+	}
+	user.UpdatedAt = time.Now()
+
+	if err := u.userRepo.Update(user); err != nil {
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+
+	if err := u.verificationRepo.Delete(verification.ID); err != nil {
+		return nil, fmt.Errorf("failed to delete handle verification: %w", err)
+	}
+
+	return user, nil
+}
+
+// checkCodeforces looks for a compilation error on the task problem submitted after the task was issued
+func (u *HandleUseCase) checkCodeforces(v *domain.HandleVerification) (bool, error) {
+	submissions, err := u.codeforces.RecentSubmissions(v.Handle, recentSubmissionsChecked)
+	if err != nil {
+		return false, err
+	}
+
+	for _, s := range submissions {
+		if s.ContestID == v.ContestID &&
+			s.ProblemIndex == v.ProblemIndex &&
+			s.Verdict == judge.VerdictCompilationError &&
+			// Codeforces reports whole seconds
+			!s.CreatedAt.Before(v.CreatedAt.Truncate(time.Second)) &&
+			s.CreatedAt.Before(v.ExpiresAt) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// checkAtCoder looks for the task token in the affiliation field
+func (u *HandleUseCase) checkAtCoder(v *domain.HandleVerification) (bool, error) {
+	affiliation, err := u.atcoder.Affiliation(v.Handle)
+	if err != nil {
+		return false, err
+	}
+
+	return strings.Contains(affiliation, v.Token), nil
+}
+
+// validateHandle checks the handle against the judge's handle rules
+func validateHandle(judgeName, handle string) error {
+	var pattern *regexp.Regexp
+	switch judgeName {
+	case domain.JudgeCodeforces:
+		pattern = codeforcesHandlePattern
+	case domain.JudgeAtCoder:
+		pattern = atcoderHandlePattern
+	default:
+		return domain.ErrJudgeNotSupported
+	}
+
+	if !pattern.MatchString(handle) {
+		return domain.ErrInvalidHandle
+	}
+
+	return nil
+}
@@ -0,0 +1,184 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/algosim/backend/internal/auth/infrastructure/oauth"
+	"github.com/algosim/backend/internal/auth/repository"
+	"github.com/google/uuid"
+)
+
+// InitiateOAuthLink starts linking an account of the given provider to a signed-in user.
+// The callback of the returned login links the identity instead of signing in.
+func (u *AuthUseCase) InitiateOAuthLink(ctx context.Context, userID uuid.UUID, providerName string) (*OAuthLogin, error) {
+	return u.initiateOAuth(ctx, providerName, userID)
+}
+
+// ListIdentities returns the provider accounts linked to a user, oldest first
+func (u *AuthUseCase) ListIdentities(userID uuid.UUID) ([]*domain.Identity, error) {
+	identities, err := u.identityRepo.FindByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find identities: %w", err)
+	}
+	return identities, nil
+}
+
+// UnlinkIdentity removes a provider account from a user, keeping at least one way to sign in
+func (u *AuthUseCase) UnlinkIdentity(userID, identityID uuid.UUID) error {
+	identity, err := u.identityRepo.FindByID(identityID)
+	if err != nil || identity.UserID != userID {
+		// Another user's identity looks missing
+		return domain.ErrIdentityNotFound
+	}
+
+	user, err := u.userRepo.FindByID(userID)
+	if err != nil {
+		return domain.ErrUserNotFound
+	}
+	identities, err := u.identityRepo.FindByUserID(userID)
+	if err != nil {
+		return fmt.Errorf("failed to find identities: %w", err)
+	}
+	if user.PasswordHash == "" && len(identities) <= 1 {
+		return domain.ErrLastSignInMethod
+	}
+
+	if err := u.identityRepo.Delete(identity.ID); err != nil {
+		return fmt.Errorf("failed to unlink identity: %w", err)
+	}
+
+	u.events.Publish(identityEvent(domain.SecurityEventIdentityUnlinked, identity, false))
+	return nil
+}
+
+// resolveOAuthUser finds the user a provider account signs in as. On first use the account is
+// linked to the user with the same email if both the provider and that user verified it, or gets
+// a new user.
+func (u *AuthUseCase) resolveOAuthUser(tx *unit, provider string, info *oauth.UserInfo) (*domain.User, error) {
+	identity, err := tx.Identities.FindByProviderSubject(provider, info.ID)
+	if err == nil {
+		user, err := tx.Users.FindByID(identity.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find user: %w", err)
+		}
+		if err := touchIdentity(tx.Identities, identity, info); err != nil {
+			return nil, err
+		}
+		return user, nil
+	}
+	if !errors.Is(err, domain.ErrIdentityNotFound) {
+		return nil, fmt.Errorf("failed to find identity: %w", err)
+	}
+
+	var user *domain.User
+	email, err := normalizeEmail(info.Email)
+	if err != nil {
+		// Providers may not share an address, the account then has none
+		email = ""
+	} else if existing, err := tx.Users.FindByEmail(email); err == nil && existing != nil {
+		// Only an address the provider verified proves the person owns the existing account
+		if !info.EmailVerified {
+			return nil, domain.ErrEmailNotVerified
+		}
+		// and only if the account owns it too, anyone could have registered an address unverified
+		verified, err := accountEmailVerified(tx.Identities, existing)
+		if err != nil {
+			return nil, err
+		}
+		if !verified {
+			return nil, domain.ErrAccountEmailNotVerified
+		}
+		user = existing
+	}
+
+	autoLinked := user != nil
+	if !autoLinked {
+		user = domain.NewUser(email)
+		if err := tx.Users.Create(user); err != nil {
+			return nil, fmt.Errorf("failed to create user: %w", err)
+		}
+	}
+
+	identity = domain.NewIdentity(user.ID, provider, info.ID, info.Email, info.EmailVerified)
+	if err := tx.Identities.Create(identity); err != nil {
+		return nil, fmt.Errorf("failed to link identity: %w", err)
+	}
+	if autoLinked {
+		tx.publish(identityEvent(domain.SecurityEventIdentityLinked, identity, true))
+	}
+
+	return user, nil
+}
+
+// accountEmailVerified reports whether a provider verified the email of user through one of their
+// identities. Password accounts never verify their email, so they only link explicitly.
+func accountEmailVerified(identities repository.IdentityRepository, user *domain.User) (bool, error) {
+	linked, err := identities.FindByUserID(user.ID)
+	if err != nil {
+		return false, fmt.Errorf("failed to find identities: %w", err)
+	}
+	for _, identity := range linked {
+		if !identity.EmailVerified {
+			continue
+		}
+		if email, err := normalizeEmail(identity.Email); err == nil && email == user.Email {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// linkIdentity links a provider account to a signed-in user who proved they own it
+func (u *AuthUseCase) linkIdentity(userID uuid.UUID, provider string, info *oauth.UserInfo) (*domain.Identity, error) {
+	if _, err := u.userRepo.FindByID(userID); err != nil {
+		return nil, domain.ErrUserNotFound
+	}
+
+	identity, err := u.identityRepo.FindByProviderSubject(provider, info.ID)
+	if err == nil {
+		if identity.UserID != userID {
+			return nil, domain.ErrIdentityAlreadyLinked
+		}
+		if err := touchIdentity(u.identityRepo, identity, info); err != nil {
+			return nil, err
+		}
+		return identity, nil
+	}
+	if !errors.Is(err, domain.ErrIdentityNotFound) {
+		return nil, fmt.Errorf("failed to find identity: %w", err)
+	}
+
+	identity = domain.NewIdentity(userID, provider, info.ID, info.Email, info.EmailVerified)
+	if err := u.identityRepo.Create(identity); err != nil {
+		return nil, fmt.Errorf("failed to link identity: %w", err)
+	}
+
+	u.events.Publish(identityEvent(domain.SecurityEventIdentityLinked, identity, false))
+	return identity, nil
+}
+
+// touchIdentity records a sign-in with the identity and what the provider reported this time
+func touchIdentity(identities repository.IdentityRepository, identity *domain.Identity, info *oauth.UserInfo) error {
+	identity.Email = info.Email
+	identity.EmailVerified = info.EmailVerified
+	identity.LastUsedAt = time.Now()
+	if err := identities.Update(identity); err != nil {
+		return fmt.Errorf("failed to update identity: %w", err)
+	}
+	return nil
+}
+
+// identityEvent returns a security event about a linked identity
+func identityEvent(eventType string, identity *domain.Identity, auto bool) *domain.SecurityEvent {
+	event := domain.NewSecurityEvent(eventType, identity.UserID)
+	event.Details["provider"] = identity.Provider
+	event.Details["identity_id"] = identity.ID.String()
+	if auto {
+		event.Details["auto"] = "verified_email"
+	}
+	return event
+}
@@ -0,0 +1,396 @@
+package usecase
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/algosim/backend/internal/auth/infrastructure/totp"
+	"github.com/google/uuid"
+)
+
+const (
+	// recoveryCodeCount is how many recovery codes a user gets when enabling MFA
+	recoveryCodeCount = 10
+	// recoveryCodeLength is the number of characters of a recovery code, 5 bits each
+	recoveryCodeLength = 12
+	// recoveryCodeAlphabet is lower case base32, which leaves out 0, 1 and 8 as they look like letters
+	recoveryCodeAlphabet = "abcdefghijklmnopqrstuvwxyz234567"
+	// mfaCheckTries is how often a code check starts over when the authenticator changed meanwhile
+	mfaCheckTries = 3
+)
+
+// MFAEnrollment is what an authenticator app needs to enroll
+type MFAEnrollment struct {
+	Secret string
+	// ProvisioningURI is the otpauth URI clients show as QR code
+	ProvisioningURI string
+}
+
+// MFAStatus describes the MFA settings of a user
+type MFAStatus struct {
+	Enabled                bool
+	RecoveryCodesRemaining int
+	EnabledAt              *time.Time
+}
+
+// MFAPending is handed out instead of a session when a sign-in still needs its second factor
+type MFAPending struct {
+	// Token identifies the sign-in when the code is posted
+	Token     string
+	ExpiresAt time.Time
+}
+
+// EnrollMFA creates a new TOTP secret for a user, replacing an unconfirmed one. MFA is only
+// enabled once ConfirmMFA receives a first code.
+func (u *AuthUseCase) EnrollMFA(userID uuid.UUID) (*MFAEnrollment, error) {
+	user, err := u.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, domain.ErrUserNotFound
+	}
+
+	u.mfaMu.Lock()
+	defer u.mfaMu.Unlock()
+
+	existing, err := u.findMFA(userID)
+	if err != nil && !errors.Is(err, domain.ErrMFANotEnrolled) {
+		return nil, err
+	}
+	if existing != nil && existing.IsEnabled() {
+		return nil, domain.ErrMFAAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := u.mfaRepo.Save(domain.NewMFA(user.ID, secret)); err != nil {
+		return nil, fmt.Errorf("failed to store mfa enrollment: %w", err)
+	}
+
+	return &MFAEnrollment{
+		Secret:          secret,
+		ProvisioningURI: totp.ProvisioningURI(u.mfaIssuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmMFA enables MFA with the first code of the enrolled authenticator and returns the
+// recovery codes. They are only ever shown here, the user has to keep them.
+func (u *AuthUseCase) ConfirmMFA(userID uuid.UUID, code string) ([]string, error) {
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	u.mfaMu.Lock()
+	defer u.mfaMu.Unlock()
+
+	err = u.withMFA(userID, func(mfa *domain.MFA) error {
+		if mfa.IsEnabled() {
+			return domain.ErrMFAAlreadyEnabled
+		}
+		// The codes are stored with the accepted code, so a concurrent confirmation cannot
+		// replace them
+		return u.checkMFACode(mfa, code, func(mfa *domain.MFA) {
+			now := time.Now()
+			mfa.RecoveryCodeHashes = hashes
+			mfa.ConfirmedAt = &now
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	u.events.Publish(domain.NewSecurityEvent(domain.SecurityEventMFAEnabled, userID))
+	return codes, nil
+}
+
+// DisableMFA turns MFA off after checking a TOTP or recovery code
+func (u *AuthUseCase) DisableMFA(userID uuid.UUID, code string) error {
+	u.mfaMu.Lock()
+	defer u.mfaMu.Unlock()
+
+	err := u.withMFA(userID, func(mfa *domain.MFA) error {
+		if !mfa.IsEnabled() {
+			return domain.ErrMFANotEnrolled
+		}
+		return u.checkMFACode(mfa, code, nil)
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := u.removeMFA(userID); err != nil {
+		return err
+	}
+
+	u.events.Publish(domain.NewSecurityEvent(domain.SecurityEventMFADisabled, userID))
+	return nil
+}
+
+// GetMFAStatus returns the MFA settings of a user
+func (u *AuthUseCase) GetMFAStatus(userID uuid.UUID) (*MFAStatus, error) {
+	mfa, err := u.findMFA(userID)
+	if errors.Is(err, domain.ErrMFANotEnrolled) || (err == nil && !mfa.IsEnabled()) {
+		return &MFAStatus{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &MFAStatus{
+		Enabled:                true,
+		RecoveryCodesRemaining: len(mfa.RecoveryCodeHashes),
+		EnabledAt:              mfa.ConfirmedAt,
+	}, nil
+}
+
+// VerifyMFA completes a sign-in waiting for its second factor. code is a TOTP code or an
+// unused recovery code.
+func (u *AuthUseCase) VerifyMFA(pendingToken, code string, client domain.ClientInfo) (*domain.Token, error) {
+	challenge, err := u.challengeRepo.FindByTokenHash(hashMFAToken(pendingToken))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.verifyMFAChallenge(challenge, code); err != nil {
+		return nil, err
+	}
+
+	user, err := u.userRepo.FindByID(challenge.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+	return u.signIn(user, client)
+}
+
+// verifyMFAChallenge checks the code of a challenge and uses the challenge up once it passed
+func (u *AuthUseCase) verifyMFAChallenge(challenge *domain.MFAChallenge, code string) error {
+	u.mfaMu.Lock()
+	defer u.mfaMu.Unlock()
+
+	err := u.withMFA(challenge.UserID, func(mfa *domain.MFA) error {
+		if !mfa.IsEnabled() {
+			// MFA was disabled or reset since the sign-in started
+			return domain.ErrMFAChallengeNotFound
+		}
+		return u.checkMFACode(mfa, code, nil)
+	})
+	if errors.Is(err, domain.ErrMFANotEnrolled) {
+		return domain.ErrMFAChallengeNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	// A concurrent verification may have used the challenge already
+	if err := u.challengeRepo.Delete(challenge.ID); err != nil {
+		return err
+	}
+	return nil
+}
+
+// startSession signs user in on client, or starts an MFA challenge when the user has MFA enabled
+func (u *AuthUseCase) startSession(tx *unit, user *domain.User, client domain.ClientInfo) (*domain.Token, *MFAPending, error) {
+	if user.IsBanned() {
+		return nil, nil, domain.ErrUserBanned
+	}
+
+	mfa, err := u.findMFA(user.ID)
+	if err != nil && !errors.Is(err, domain.ErrMFANotEnrolled) {
+		return nil, nil, err
+	}
+	if err != nil || !mfa.IsEnabled() {
+		token, err := u.issueToken(tx, user, client)
+		return token, nil, err
+	}
+
+	pendingToken, err := generateRandomString(32)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate mfa token: %w", err)
+	}
+	challenge := domain.NewMFAChallenge(user.ID, hashMFAToken(pendingToken), u.mfaChallengeTTL)
+	if err := tx.MFAChallenges.Create(challenge); err != nil {
+		return nil, nil, fmt.Errorf("failed to store mfa challenge: %w", err)
+	}
+
+	return nil, &MFAPending{Token: pendingToken, ExpiresAt: challenge.ExpiresAt}, nil
+}
+
+// withMFA calls fn with the stored authenticator of a user and starts over with a fresh copy
+// while fn fails with domain.ErrMFAChanged, i.e. while other code checks or resets, possibly on
+// other instances, change it first
+func (u *AuthUseCase) withMFA(userID uuid.UUID, fn func(mfa *domain.MFA) error) error {
+	var err error
+	for range mfaCheckTries {
+		var mfa *domain.MFA
+		mfa, err = u.findMFA(userID)
+		if err != nil {
+			return err
+		}
+		if err = fn(mfa); !errors.Is(err, domain.ErrMFAChanged) {
+			return err
+		}
+	}
+	return err
+}
+
+// checkMFACode accepts an unused TOTP code or, once MFA is enabled, an unused recovery code, and
+// applies accept to the authenticator when accept is not nil. Wrong codes count towards the
+// lockout. The authenticator is only stored when no other check changed it since mfa was read,
+// domain.ErrMFAChanged otherwise, so each code is accepted once. The caller must hold mfaMu.
+func (u *AuthUseCase) checkMFACode(mfa *domain.MFA, code string, accept func(mfa *domain.MFA)) error {
+	now := time.Now()
+	if mfa.IsLocked(now) {
+		return domain.ErrMFALocked
+	}
+	read := *mfa
+
+	if step, ok := totp.Validate(mfa.Secret, code, now, mfa.LastUsedStep); ok {
+		mfa.LastUsedStep = step
+		mfa.FailedAttempts = 0
+		mfa.LockedUntil = nil
+		if accept != nil {
+			accept(mfa)
+		}
+		return u.updateMFA(&read, mfa)
+	}
+
+	if mfa.IsEnabled() {
+		if i := matchRecoveryCode(mfa.RecoveryCodeHashes, code); i >= 0 {
+			mfa.RecoveryCodeHashes = append(mfa.RecoveryCodeHashes[:i:i], mfa.RecoveryCodeHashes[i+1:]...)
+			mfa.FailedAttempts = 0
+			mfa.LockedUntil = nil
+			if accept != nil {
+				accept(mfa)
+			}
+			if err := u.updateMFA(&read, mfa); err != nil {
+				return err
+			}
+
+			event := domain.NewSecurityEvent(domain.SecurityEventRecoveryCodeUsed, mfa.UserID)
+			event.Details["remaining"] = strconv.Itoa(len(mfa.RecoveryCodeHashes))
+			u.events.Publish(event)
+			return nil
+		}
+	}
+
+	mfa.FailedAttempts++
+	var locked *domain.SecurityEvent
+	if u.mfaMaxAttempts > 0 && mfa.FailedAttempts >= u.mfaMaxAttempts {
+		lockedUntil := now.Add(u.mfaLockout)
+		mfa.LockedUntil = &lockedUntil
+		mfa.FailedAttempts = 0
+
+		locked = domain.NewSecurityEvent(domain.SecurityEventMFALocked, mfa.UserID)
+		locked.Details["locked_until"] = lockedUntil.UTC().Format(time.RFC3339)
+	}
+	if err := u.updateMFA(&read, mfa); err != nil {
+		return err
+	}
+	if locked != nil {
+		u.events.Publish(locked)
+	}
+	return domain.ErrInvalidMFACode
+}
+
+// resetMFA removes the authenticator of a user without checking a code, e.g. for an admin
+func (u *AuthUseCase) resetMFA(userID uuid.UUID) error {
+	u.mfaMu.Lock()
+	defer u.mfaMu.Unlock()
+
+	return u.removeMFA(userID)
+}
+
+// removeMFA deletes the authenticator of a user and their pending sign-ins. The caller must hold
+// mfaMu.
+func (u *AuthUseCase) removeMFA(userID uuid.UUID) error {
+	if err := u.mfaRepo.DeleteByUserID(userID); err != nil {
+		return fmt.Errorf("failed to delete mfa: %w", err)
+	}
+	if err := u.challengeRepo.DeleteByUserID(userID); err != nil {
+		return fmt.Errorf("failed to delete mfa challenges: %w", err)
+	}
+	return nil
+}
+
+// findMFA loads the authenticator of a user
+func (u *AuthUseCase) findMFA(userID uuid.UUID) (*domain.MFA, error) {
+	mfa, err := u.mfaRepo.FindByUserID(userID)
+	if errors.Is(err, domain.ErrMFANotEnrolled) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find mfa: %w", err)
+	}
+	return mfa, nil
+}
+
+// updateMFA stores a changed authenticator unless it changed since old was read
+func (u *AuthUseCase) updateMFA(old, mfa *domain.MFA) error {
+	mfa.UpdatedAt = time.Now()
+	err := u.mfaRepo.CompareAndSwap(old, mfa)
+	if errors.Is(err, domain.ErrMFAChanged) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("failed to store mfa: %w", err)
+	}
+	return nil
+}
+
+// hashMFAToken returns the hash an mfa pending token is stored and looked up by
+func hashMFAToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// generateRecoveryCodes returns new recovery codes formatted as xxxx-xxxx-xxxx and their hashes
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, recoveryCodeLength)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+
+		var code strings.Builder
+		for j, v := range b {
+			if j > 0 && j%4 == 0 {
+				code.WriteByte('-')
+			}
+			// 256 is a multiple of the 32 letters, so every letter is equally likely
+			code.WriteByte(recoveryCodeAlphabet[int(v)%len(recoveryCodeAlphabet)])
+		}
+		codes[i] = code.String()
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode hashes a recovery code ignoring case, spaces and dashes
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// matchRecoveryCode returns the index of the hash code matches, or -1
+func matchRecoveryCode(hashes []string, code string) int {
+	hash := hashRecoveryCode(code)
+	match := -1
+	for i, h := range hashes {
+		if subtle.ConstantTimeCompare([]byte(h), []byte(hash)) == 1 {
+			match = i
+		}
+	}
+	return match
+}
@@ -0,0 +1,47 @@
+package usecase
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/algosim/backend/internal/auth/domain"
+)
+
+// generateRandomString returns n random bytes encoded as unpadded base64url
+func generateRandomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to read random bytes: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// signStateBinding binds a state to the browser by signing it for a cookie
+func signStateBinding(secret []byte, state string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(state))
+	return state + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// verifyStateBinding checks that binding is a valid signature for state
+func verifyStateBinding(secret []byte, binding, state string) error {
+	boundState, _, ok := strings.Cut(binding, ".")
+	if !ok {
+		return domain.ErrOAuthStateMismatch
+	}
+
+	expected := signStateBinding(secret, boundState)
+	if !hmac.Equal([]byte(binding), []byte(expected)) {
+		return domain.ErrOAuthStateMismatch
+	}
+
+	if !hmac.Equal([]byte(boundState), []byte(state)) {
+		return domain.ErrOAuthStateMismatch
+	}
+
+	return nil
+}
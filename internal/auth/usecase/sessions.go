@@ -0,0 +1,106 @@
+package usecase
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/google/uuid"
+)
+
+// ListSessions returns the active sessions of a user, most recently used first
+func (u *AuthUseCase) ListSessions(userID uuid.UUID) ([]*domain.Session, error) {
+	tokens, err := u.tokenRepo.FindByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find tokens: %w", err)
+	}
+
+	// Concurrent refreshes in the grace period can leave two live tokens in a family, keep the newest
+	now := time.Now()
+	heads := make(map[uuid.UUID]*domain.Token)
+	for _, token := range tokens {
+		if !token.IsActive(now) {
+			continue
+		}
+		if head, exists := heads[token.FamilyID]; !exists || token.CreatedAt.After(head.CreatedAt) {
+			heads[token.FamilyID] = token
+		}
+	}
+
+	sessions := make([]*domain.Session, 0, len(heads))
+	for _, token := range heads {
+		sessions = append(sessions, domain.NewSessionFromToken(token))
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastUsedAt.After(sessions[j].LastUsedAt)
+	})
+
+	return sessions, nil
+}
+
+// RevokeSession signs a user out of one session
+func (u *AuthUseCase) RevokeSession(userID, sessionID uuid.UUID) error {
+	found, err := u.revokeSession(userID, sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	if !found {
+		// Only the owner may revoke a session, anyone else sees it as missing
+		return domain.ErrSessionNotFound
+	}
+	return nil
+}
+
+// LogoutAll signs a user out of every session and revokes their access tokens
+func (u *AuthUseCase) LogoutAll(userID uuid.UUID) error {
+	tokens, err := u.tokenRepo.FindByUserID(userID)
+	if err != nil {
+		return fmt.Errorf("failed to find tokens: %w", err)
+	}
+	if err := u.revokeAccessTokens(tokens); err != nil {
+		return err
+	}
+
+	if err := u.tokenRepo.DeleteByUserID(userID); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	return nil
+}
+
+// revokeSession deletes the token family of a user and denies its access tokens,
+// reporting whether the user had such a session
+func (u *AuthUseCase) revokeSession(userID, familyID uuid.UUID) (bool, error) {
+	tokens, err := u.tokenRepo.FindByUserID(userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to find tokens: %w", err)
+	}
+
+	var family []*domain.Token
+	for _, token := range tokens {
+		if token.FamilyID == familyID {
+			family = append(family, token)
+		}
+	}
+	if len(family) == 0 {
+		return false, nil
+	}
+
+	if err := u.revokeAccessTokens(family); err != nil {
+		return false, err
+	}
+	if err := u.tokenRepo.DeleteByFamilyID(familyID); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// revokeAccessTokens denies the access tokens issued with tokens that have not expired yet
+func (u *AuthUseCase) revokeAccessTokens(tokens []*domain.Token) error {
+	for _, token := range tokens {
+		if err := u.jwtManager.RevokeAccessToken(token); err != nil {
+			return fmt.Errorf("failed to revoke access token: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package usecase
+
+import (
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/algosim/backend/internal/auth/repository"
+)
+
+// unit is a unit of work in progress. Its security events are published once it committed.
+type unit struct {
+	repository.Repositories
+	events []*domain.SecurityEvent
+}
+
+// publish queues an event until the unit committed
+func (tx *unit) publish(event *domain.SecurityEvent) {
+	tx.events = append(tx.events, event)
+}
+
+// directUnitOfWork runs steps on the repositories directly, so a failed step keeps the earlier ones
+type directUnitOfWork struct {
+	repos repository.Repositories
+}
+
+// Do calls fn with the repositories
+func (w directUnitOfWork) Do(fn func(tx repository.Repositories) error) error {
+	return fn(w.repos)
+}
+
+// SetUnitOfWork makes multi-step sign-ins commit or roll back as a whole. uow must span the
+// repositories the use case was created with. Without one, a failed step keeps the earlier ones.
+func (u *AuthUseCase) SetUnitOfWork(uow repository.UnitOfWork) {
+	u.uow = uow
+}
+
+// inUnit runs fn in a unit of work and publishes its events once the unit committed
+func (u *AuthUseCase) inUnit(fn func(tx *unit) error) error {
+	var events []*domain.SecurityEvent
+	err := u.uow.Do(func(repos repository.Repositories) error {
+		tx := &unit{Repositories: repos}
+		if err := fn(tx); err != nil {
+			return err
+		}
+		events = tx.events
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, event := range events {
+		u.events.Publish(event)
+	}
+	return nil
+}
+
+// signIn starts a session of user on client in a unit of its own
+func (u *AuthUseCase) signIn(user *domain.User, client domain.ClientInfo) (*domain.Token, error) {
+	var token *domain.Token
+	err := u.inUnit(func(tx *unit) error {
+		var err error
+		token, err = u.issueToken(tx, user, client)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
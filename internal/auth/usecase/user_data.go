@@ -0,0 +1,266 @@
+package usecase
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserDataHook lets a module take part in exporting and deleting everything it stores about a user.
+// Each module keeping data keyed by user registers one with UserUseCase.RegisterDataHook.
+type UserDataHook interface {
+	// Name identifies the module and keys its section of the export
+	Name() string
+	// ExportUserData returns the module's data about the user, it is encoded as JSON
+	ExportUserData(userID uuid.UUID) (any, error)
+	// DeleteUserData removes the module's data about the user
+	DeleteUserData(userID uuid.UUID) error
+}
+
+// UserDataExport is everything stored about a user
+type UserDataExport struct {
+	ExportedAt time.Time      `json:"exported_at"`
+	User       UserExport     `json:"user"`
+	Modules    map[string]any `json:"modules"`
+}
+
+// UserExport is the user record without secrets
+type UserExport struct {
+	ID                  uuid.UUID  `json:"id"`
+	Email               string     `json:"email"`
+	Handle              string     `json:"handle,omitempty"`
+	DisplayName         string     `json:"display_name,omitempty"`
+	Timezone            string     `json:"timezone,omitempty"`
+	PreferredJudge      string     `json:"preferred_judge,omitempty"`
+	CodeforcesHandle    string     `json:"codeforces_handle,omitempty"`
+	AtcoderHandle       string     `json:"atcoder_handle,omitempty"`
+	HasPassword         bool       `json:"has_password"`
+	Roles               []string   `json:"roles"`
+	BannedAt            *time.Time `json:"banned_at,omitempty"`
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+}
+
+// sessionDataHook exports and deletes the sessions of a user
+type sessionDataHook struct {
+	authUseCase *AuthUseCase
+}
+
+// SessionExport is a session without its tokens
+type SessionExport struct {
+	ID         uuid.UUID `json:"id"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+	IPAddress  string    `json:"ip_address,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// SessionDataHook returns the hook exporting and deleting the sessions of a user
+func (u *AuthUseCase) SessionDataHook() UserDataHook {
+	return &sessionDataHook{authUseCase: u}
+}
+
+func (h *sessionDataHook) Name() string {
+	return "sessions"
+}
+
+func (h *sessionDataHook) ExportUserData(userID uuid.UUID) (any, error) {
+	sessions, err := h.authUseCase.ListSessions(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	exports := make([]SessionExport, 0, len(sessions))
+	for _, s := range sessions {
+		exports = append(exports, SessionExport{
+			ID:         s.ID,
+			UserAgent:  s.UserAgent,
+			IPAddress:  s.IPAddress,
+			CreatedAt:  s.CreatedAt,
+			LastUsedAt: s.LastUsedAt,
+			ExpiresAt:  s.ExpiresAt,
+		})
+	}
+	return exports, nil
+}
+
+func (h *sessionDataHook) DeleteUserData(userID uuid.UUID) error {
+	return h.authUseCase.LogoutAll(userID)
+}
+
+// identityDataHook exports and deletes the linked identities of a user
+type identityDataHook struct {
+	authUseCase *AuthUseCase
+}
+
+// IdentityExport is a linked provider account
+type IdentityExport struct {
+	Provider      string    `json:"provider"`
+	Subject       string    `json:"subject"`
+	Email         string    `json:"email,omitempty"`
+	EmailVerified bool      `json:"email_verified"`
+	CreatedAt     time.Time `json:"created_at"`
+	LastUsedAt    time.Time `json:"last_used_at"`
+}
+
+// IdentityDataHook returns the hook exporting and deleting the linked identities of a user
+func (u *AuthUseCase) IdentityDataHook() UserDataHook {
+	return &identityDataHook{authUseCase: u}
+}
+
+func (h *identityDataHook) Name() string {
+	return "identities"
+}
+
+func (h *identityDataHook) ExportUserData(userID uuid.UUID) (any, error) {
+	identities, err := h.authUseCase.ListIdentities(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	exports := make([]IdentityExport, 0, len(identities))
+	for _, identity := range identities {
+		exports = append(exports, IdentityExport{
+			Provider:      identity.Provider,
+			Subject:       identity.Subject,
+			Email:         identity.Email,
+			EmailVerified: identity.EmailVerified,
+			CreatedAt:     identity.CreatedAt,
+			LastUsedAt:    identity.LastUsedAt,
+		})
+	}
+	return exports, nil
+}
+
+func (h *identityDataHook) DeleteUserData(userID uuid.UUID) error {
+	return h.authUseCase.identityRepo.DeleteByUserID(userID)
+}
+
+// mfaDataHook exports and deletes the authenticator of a user
+type mfaDataHook struct {
+	authUseCase *AuthUseCase
+}
+
+// MFAExport describes the authenticator of a user without its secret or recovery codes
+type MFAExport struct {
+	Enabled                bool       `json:"enabled"`
+	RecoveryCodesRemaining int        `json:"recovery_codes_remaining"`
+	EnabledAt              *time.Time `json:"enabled_at,omitempty"`
+}
+
+// MFADataHook returns the hook exporting and deleting the authenticator of a user
+func (u *AuthUseCase) MFADataHook() UserDataHook {
+	return &mfaDataHook{authUseCase: u}
+}
+
+func (h *mfaDataHook) Name() string {
+	return "mfa"
+}
+
+func (h *mfaDataHook) ExportUserData(userID uuid.UUID) (any, error) {
+	status, err := h.authUseCase.GetMFAStatus(userID)
+	if err != nil {
+		return nil, err
+	}
+	return MFAExport{
+		Enabled:                status.Enabled,
+		RecoveryCodesRemaining: status.RecoveryCodesRemaining,
+		EnabledAt:              status.EnabledAt,
+	}, nil
+}
+
+func (h *mfaDataHook) DeleteUserData(userID uuid.UUID) error {
+	return h.authUseCase.resetMFA(userID)
+}
+
+// handleDataHook exports and deletes the pending handle verifications of a user
+type handleDataHook struct {
+	handleUseCase *HandleUseCase
+}
+
+// HandleVerificationExport is a pending handle verification
+type HandleVerificationExport struct {
+	Judge     string    `json:"judge"`
+	Handle    string    `json:"handle"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// DataHook returns the hook exporting and deleting the pending handle verifications of a user
+func (u *HandleUseCase) DataHook() UserDataHook {
+	return &handleDataHook{handleUseCase: u}
+}
+
+func (h *handleDataHook) Name() string {
+	return "handle_verifications"
+}
+
+func (h *handleDataHook) ExportUserData(userID uuid.UUID) (any, error) {
+	verifications, err := h.handleUseCase.verificationRepo.FindByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	exports := make([]HandleVerificationExport, 0, len(verifications))
+	for _, v := range verifications {
+		exports = append(exports, HandleVerificationExport{
+			Judge:     v.Judge,
+			Handle:    v.Handle,
+			ExpiresAt: v.ExpiresAt,
+			CreatedAt: v.CreatedAt,
+		})
+	}
+	return exports, nil
+}
+
+func (h *handleDataHook) DeleteUserData(userID uuid.UUID) error {
+	return h.handleUseCase.verificationRepo.DeleteByUserID(userID)
+}
+
+// deviceDataHook exports and deletes the device sign-ins a user decided
+type deviceDataHook struct {
+	deviceUseCase *DeviceUseCase
+}
+
+// DeviceAuthorizationExport is a device sign-in a user decided that the device did not redeem yet
+type DeviceAuthorizationExport struct {
+	Status    string    `json:"status"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	IPAddress string    `json:"ip_address,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// DataHook returns the hook exporting and deleting the device sign-ins a user decided
+func (u *DeviceUseCase) DataHook() UserDataHook {
+	return &deviceDataHook{deviceUseCase: u}
+}
+
+func (h *deviceDataHook) Name() string {
+	return "device_authorizations"
+}
+
+func (h *deviceDataHook) ExportUserData(userID uuid.UUID) (any, error) {
+	authorizations, err := h.deviceUseCase.deviceRepo.FindByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	exports := make([]DeviceAuthorizationExport, 0, len(authorizations))
+	for _, a := range authorizations {
+		exports = append(exports, DeviceAuthorizationExport{
+			Status:    string(a.Status),
+			UserAgent: a.Client.UserAgent,
+			IPAddress: a.Client.IPAddress,
+			ExpiresAt: a.ExpiresAt,
+			CreatedAt: a.CreatedAt,
+		})
+	}
+	return exports, nil
+}
+
+func (h *deviceDataHook) DeleteUserData(userID uuid.UUID) error {
+	return h.deviceUseCase.deviceRepo.DeleteByUserID(userID)
+}
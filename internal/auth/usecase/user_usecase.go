@@ -1,14 +1,31 @@
 package usecase
 
 import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	_ "time/tzdata" // timezone validation must not depend on the host zoneinfo
+	"unicode"
+	"unicode/utf8"
+
 	"github.com/algosim/backend/internal/auth/domain"
 	"github.com/algosim/backend/internal/auth/repository"
 	"github.com/google/uuid"
 )
 
+// profileHandlePattern is the format of the public profile handle
+var profileHandlePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{3,24}$`)
+
+// maxDisplayNameLength is the longest display name in characters
+const maxDisplayNameLength = 64
+
 // UserUseCase handles user-related business logic
 type UserUseCase struct {
 	userRepo repository.UserRepository
+	// dataHooks export and delete the data other modules keep about users
+	dataHooks []UserDataHook
 }
 
 // NewUserUseCase creates a new UserUseCase instance
@@ -18,8 +35,13 @@ func NewUserUseCase(userRepo repository.UserRepository) *UserUseCase {
 	}
 }
 
+// RegisterDataHook adds a module to user data exports and account deletion
+func (u *UserUseCase) RegisterDataHook(hook UserDataHook) {
+	u.dataHooks = append(u.dataHooks, hook)
+}
+
 // CreateUser creates a new user
-func (u *UserUseCase) CreateUser(email, oauthProvider, oauthProviderID string) (*domain.User, error) {
+func (u *UserUseCase) CreateUser(email string) (*domain.User, error) {
 	// Check if user already exists
 	existingUser, err := u.userRepo.FindByEmail(email)
 	if err == nil && existingUser != nil {
@@ -27,7 +49,7 @@ func (u *UserUseCase) CreateUser(email, oauthProvider, oauthProviderID string) (
 	}
 
 	// Create new user
-	user := domain.NewUser(email, oauthProvider, oauthProviderID)
+	user := domain.NewUser(email)
 	if err := u.userRepo.Create(user); err != nil {
 		return nil, err
 	}
@@ -37,7 +59,11 @@ func (u *UserUseCase) CreateUser(email, oauthProvider, oauthProviderID string) (
 
 // GetUser retrieves a user by ID
 func (u *UserUseCase) GetUser(id uuid.UUID) (*domain.User, error) {
-	return u.userRepo.FindByID(id)
+	user, err := u.userRepo.FindByID(id)
+	if err != nil {
+		return nil, domain.ErrUserNotFound
+	}
+	return user, nil
 }
 
 // GetUserByEmail retrieves a user by email
@@ -45,11 +71,6 @@ func (u *UserUseCase) GetUserByEmail(email string) (*domain.User, error) {
 	return u.userRepo.FindByEmail(email)
 }
 
-// GetUserByOAuthID retrieves a user by OAuth provider ID
-func (u *UserUseCase) GetUserByOAuthID(provider, providerID string) (*domain.User, error) {
-	return u.userRepo.FindByOAuthProviderID(provider, providerID)
-}
-
 // UpdateUser updates an existing user
 func (u *UserUseCase) UpdateUser(user *domain.User) error {
 	// Check if user exists
@@ -59,10 +80,199 @@ func (u *UserUseCase) UpdateUser(user *domain.User) error {
 	}
 
 	// Update user
+	user.UpdatedAt = time.Now()
 	return u.userRepo.Update(user)
 }
 
-// DeleteUser deletes a user by ID
+// UpdateProfile validates and applies a profile update of the user.
+// Validation errors are *domain.FieldError naming the offending field.
+func (u *UserUseCase) UpdateProfile(userID uuid.UUID, update domain.ProfileUpdate) (*domain.User, error) {
+	user, err := u.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, domain.ErrUserNotFound
+	}
+
+	// Validate everything before touching the user so a bad field changes nothing
+	var handle, displayName, timezone, preferredJudge string
+	if update.Handle != nil {
+		handle = strings.TrimSpace(*update.Handle)
+		if err := u.validateProfileHandle(user, handle); err != nil {
+			return nil, err
+		}
+	}
+	if update.DisplayName != nil {
+		displayName = strings.TrimSpace(*update.DisplayName)
+		if !validDisplayName(displayName) {
+			return nil, domain.NewFieldError("display_name", domain.ErrInvalidDisplayName)
+		}
+	}
+	if update.Timezone != nil {
+		timezone = strings.TrimSpace(*update.Timezone)
+		if !validTimezone(timezone) {
+			return nil, domain.NewFieldError("timezone", domain.ErrInvalidTimezone)
+		}
+	}
+	if update.PreferredJudge != nil {
+		preferredJudge = strings.TrimSpace(*update.PreferredJudge)
+		if preferredJudge != "" && preferredJudge != domain.JudgeCodeforces && preferredJudge != domain.JudgeAtCoder {
+			return nil, domain.NewFieldError("preferred_judge", domain.ErrJudgeNotSupported)
+		}
+	}
+
+	if update.Handle != nil {
+		user.Handle = handle
+	}
+	if update.DisplayName != nil {
+		user.DisplayName = displayName
+	}
+	if update.Timezone != nil {
+		user.Timezone = timezone
+	}
+	if update.PreferredJudge != nil {
+		user.PreferredJudge = preferredJudge
+	}
+
+	user.UpdatedAt = time.Now()
+	if err := u.userRepo.Update(user); err != nil {
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+
+	return user, nil
+}
+
+// validateProfileHandle checks the format of a profile handle and that nobody else uses it
+func (u *UserUseCase) validateProfileHandle(user *domain.User, handle string) error {
+	if handle == "" {
+		return nil
+	}
+	if !profileHandlePattern.MatchString(handle) {
+		return domain.NewFieldError("handle", domain.ErrInvalidHandle)
+	}
+	if owner, err := u.userRepo.FindByHandle(handle); err == nil && owner.ID != user.ID {
+		return domain.NewFieldError("handle", domain.ErrHandleTaken)
+	}
+	return nil
+}
+
+// validDisplayName reports whether name fits the display name rules
+func validDisplayName(name string) bool {
+	if utf8.RuneCountInString(name) > maxDisplayNameLength {
+		return false
+	}
+	for _, r := range name {
+		if unicode.IsControl(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// validTimezone reports whether tz is empty or an IANA time zone name
+func validTimezone(tz string) bool {
+	if tz == "" {
+		return true
+	}
+	// Local depends on the server and is not a zone name
+	if tz == "Local" {
+		return false
+	}
+	_, err := time.LoadLocation(tz)
+	return err == nil
+}
+
+// DeleteUser deletes a user and, through the registered hooks, everything modules keep about them.
+// The user row goes last so a failed hook can be retried.
 func (u *UserUseCase) DeleteUser(id uuid.UUID) error {
+	if err := u.deleteUserData(id); err != nil {
+		return err
+	}
+
 	return u.userRepo.Delete(id)
 }
+
+// deleteUserData runs the delete of every registered hook
+func (u *UserUseCase) deleteUserData(id uuid.UUID) error {
+	for _, hook := range u.dataHooks {
+		if err := hook.DeleteUserData(id); err != nil {
+			return fmt.Errorf("failed to delete %s data: %w", hook.Name(), err)
+		}
+	}
+	return nil
+}
+
+// PurgeScheduledDeletions deletes the accounts whose grace period ended by now and returns how many.
+// Several instances may purge at once and users may cancel meanwhile, so each account is read
+// again before its data goes and its row is only deleted while the deletion is still due. An
+// account that is gone already was purged by another instance.
+func (u *UserUseCase) PurgeScheduledDeletions(now time.Time) (int, error) {
+	users, err := u.userRepo.FindScheduledForDeletion(now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find accounts scheduled for deletion: %w", err)
+	}
+
+	deleted := 0
+	for _, scheduled := range users {
+		user, err := u.userRepo.FindByID(scheduled.ID)
+		if errors.Is(err, domain.ErrUserNotFound) {
+			continue
+		}
+		if err != nil {
+			return deleted, fmt.Errorf("failed to find user %s: %w", scheduled.ID, err)
+		}
+		if !user.IsDeletionScheduled() || user.DeletionScheduledAt.After(now) {
+			continue
+		}
+
+		if err := u.deleteUserData(user.ID); err != nil {
+			return deleted, fmt.Errorf("failed to delete user %s: %w", user.ID, err)
+		}
+		err = u.userRepo.DeleteScheduled(user.ID, now)
+		if errors.Is(err, domain.ErrUserNotFound) {
+			continue
+		}
+		if err != nil {
+			return deleted, fmt.Errorf("failed to delete user %s: %w", user.ID, err)
+		}
+		deleted++
+	}
+
+	return deleted, nil
+}
+
+// ExportUserData collects everything stored about a user
+func (u *UserUseCase) ExportUserData(id uuid.UUID) (*UserDataExport, error) {
+	user, err := u.GetUser(id)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &UserDataExport{
+		ExportedAt: time.Now(),
+		User: UserExport{
+			ID:                  user.ID,
+			Email:               user.Email,
+			Handle:              user.Handle,
+			DisplayName:         user.DisplayName,
+			Timezone:            user.Timezone,
+			PreferredJudge:      user.PreferredJudge,
+			CodeforcesHandle:    user.CodeforcesHandle,
+			AtcoderHandle:       user.AtcoderHandle,
+			HasPassword:         user.PasswordHash != "",
+			Roles:               user.Roles,
+			BannedAt:            user.BannedAt,
+			DeletionScheduledAt: user.DeletionScheduledAt,
+			CreatedAt:           user.CreatedAt,
+			UpdatedAt:           user.UpdatedAt,
+		},
+		Modules: make(map[string]any, len(u.dataHooks)),
+	}
+	for _, hook := range u.dataHooks {
+		data, err := hook.ExportUserData(id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to export %s data: %w", hook.Name(), err)
+		}
+		export.Modules[hook.Name()] = data
+	}
+
+	return export, nil
+}
@@ -1,15 +1,30 @@
 package server
 
 import (
+	"context"
+	"expvar"
 	"fmt"
+	"io/fs"
 	"log"
+	"time"
 
 	"github.com/algosim/backend/configs"
 	"github.com/algosim/backend/docs"
 	"github.com/algosim/backend/internal/auth/api/http"
+	"github.com/algosim/backend/internal/auth/domain"
 	"github.com/algosim/backend/internal/auth/infrastructure/db/memory"
+	"github.com/algosim/backend/internal/auth/infrastructure/db/postgres"
+	"github.com/algosim/backend/internal/auth/infrastructure/db/sqlite"
+	"github.com/algosim/backend/internal/auth/infrastructure/events"
+	"github.com/algosim/backend/internal/auth/infrastructure/judge"
+	"github.com/algosim/backend/internal/auth/infrastructure/jwt"
 	"github.com/algosim/backend/internal/auth/infrastructure/oauth"
+	"github.com/algosim/backend/internal/auth/repository"
 	"github.com/algosim/backend/internal/auth/usecase"
+	"github.com/algosim/backend/migrations"
+	"github.com/algosim/backend/pkg/db"
+	"github.com/algosim/backend/pkg/httpclient"
+	"github.com/algosim/backend/pkg/migrate"
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
@@ -30,7 +45,7 @@ func NewServer(config *configs.Config) *Server {
 }
 
 // SetupRoutes configures all routes for the server
-func (s *Server) SetupRoutes() {
+func (s *Server) SetupRoutes() error {
 	// Swagger
 	docs.SwaggerInfo.BasePath = "/api/v1"
 	s.router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
@@ -41,20 +56,210 @@ func (s *Server) SetupRoutes() {
 	})
 
 	// Initialize repositories
-	userRepo := memory.NewUserRepoMemo()
-	tokenRepo := memory.NewTokenRepoMemo()
+	repos, err := s.openRepositories()
+	if err != nil {
+		return err
+	}
+
+	// Initialize OAuth providers, their call metrics are published as the outbound_http expvar
+	outboundMetrics := httpclient.NewMetrics()
+	if expvar.Get("outbound_http") == nil {
+		expvar.Publish("outbound_http", expvar.Func(func() any { return outboundMetrics.Snapshot() }))
+	}
+	providers, err := oauth.NewRegistryFromConfig(s.config, outboundMetrics)
+	if err != nil {
+		return fmt.Errorf("failed to configure oauth providers: %w", err)
+	}
 
-	// Initialize Google OAuth
-	googleOAuth := oauth.NewGoogleOAuth(s.config)
+	// Initialize judge clients
+	codeforces := judge.NewCodeforcesClient(s.config.Judges.CodeforcesAPIURL)
+	atcoder := judge.NewAtCoderClient(s.config.Judges.AtCoderURL)
+
+	// Initialize token signing
+	jwtManager, err := jwt.NewJWTManager(s.config, repos.denylist)
+	if err != nil {
+		return fmt.Errorf("failed to configure token signing: %w", err)
+	}
 
 	// Initialize use cases
-	authUseCase := usecase.NewAuthUseCase(userRepo, tokenRepo, googleOAuth, s.config)
+	publisher := events.NewLogPublisher()
+	authUseCase := usecase.NewAuthUseCase(repos.users, repos.tokens, repos.identities, repos.states, repos.mfa, repos.challenges, providers, publisher, jwtManager, s.config)
+	authUseCase.SetUnitOfWork(repos.uow)
+	adminUseCase := usecase.NewAdminUseCase(repos.users, authUseCase, publisher)
+	deviceUseCase := usecase.NewDeviceUseCase(repos.devices, repos.users, authUseCase, publisher, s.config)
+	userUseCase := usecase.NewUserUseCase(repos.users)
+	handleUseCase := usecase.NewHandleUseCase(repos.users, repos.verifications, codeforces, atcoder, s.config)
+
+	// Modules keeping data about users take part in exports and account deletion
+	userUseCase.RegisterDataHook(authUseCase.SessionDataHook())
+	userUseCase.RegisterDataHook(authUseCase.IdentityDataHook())
+	userUseCase.RegisterDataHook(authUseCase.MFADataHook())
+	userUseCase.RegisterDataHook(handleUseCase.DataHook())
+	userUseCase.RegisterDataHook(deviceUseCase.DataHook())
+	go s.purgeDeletedAccounts(userUseCase)
 
 	// Initialize handlers
-	authHandler := http.NewAuthHandler(authUseCase)
+	authHandler := http.NewAuthHandler(authUseCase, s.config)
+	handleHandler := http.NewHandleHandler(handleUseCase)
+	adminHandler := http.NewAdminHandler(adminUseCase)
+	deviceHandler := http.NewDeviceHandler(deviceUseCase, authUseCase)
+	userHandler := http.NewUserHandler(userUseCase, authUseCase)
+	authMiddleware := http.NewAuthMiddleware(authUseCase)
 
 	// Setup auth routes
-	http.SetupAuthRoutes(s.router, authHandler)
+	http.SetupAuthRoutes(s.router, authHandler, authMiddleware)
+	http.SetupHandleRoutes(s.router, handleHandler, authMiddleware)
+	http.SetupAdminRoutes(s.router, adminHandler, authMiddleware)
+	http.SetupDeviceRoutes(s.router, deviceHandler, authMiddleware)
+	http.SetupUserRoutes(s.router, userHandler, authMiddleware)
+
+	// Metrics of the process and its outbound calls, for admins only
+	s.router.GET("/debug/vars", authMiddleware.RequireAuth(), authMiddleware.RequireRole(domain.RoleAdmin), gin.WrapH(expvar.Handler()))
+
+	return nil
+}
+
+// repositories are the stores of the auth module
+type repositories struct {
+	users         repository.UserRepository
+	tokens        repository.TokenRepository
+	identities    repository.IdentityRepository
+	states        repository.OAuthStateRepository
+	mfa           repository.MFARepository
+	challenges    repository.MFAChallengeRepository
+	devices       repository.DeviceAuthorizationRepository
+	verifications repository.HandleVerificationRepository
+	denylist      repository.AccessTokenDenylist
+	uow           repository.UnitOfWork
+}
+
+// openRepositories returns the repositories of the configured database driver. Every driver
+// stores all of them, none is left in the memory of one instance while the others run elsewhere.
+func (s *Server) openRepositories() (*repositories, error) {
+	ctx := context.Background()
+
+	switch s.config.Database.Driver {
+	case configs.DatabaseDriverPostgres:
+		pool, err := db.OpenPostgres(ctx, db.PostgresConfig{
+			URL:             s.config.Database.URL,
+			MaxConns:        int32(s.config.Database.MaxConns),
+			MinConns:        int32(s.config.Database.MinConns),
+			MaxConnLifetime: time.Duration(s.config.Database.MaxConnLifetime) * time.Second,
+			MaxConnIdleTime: time.Duration(s.config.Database.MaxConnIdleTime) * time.Second,
+			ConnectTimeout:  time.Duration(s.config.Database.ConnectTimeout) * time.Second,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := s.migrate(ctx, migrate.NewPostgresDriver(pool), migrations.Postgres()); err != nil {
+			pool.Close()
+			return nil, err
+		}
+		return &repositories{
+			users:         postgres.NewUserRepoPostgres(pool),
+			tokens:        postgres.NewTokenRepoPostgres(pool),
+			identities:    postgres.NewIdentityRepoPostgres(pool),
+			states:        postgres.NewOAuthStateRepoPostgres(pool),
+			mfa:           postgres.NewMFARepoPostgres(pool),
+			challenges:    postgres.NewMFAChallengeRepoPostgres(pool),
+			devices:       postgres.NewDeviceAuthorizationRepoPostgres(pool),
+			verifications: postgres.NewHandleVerificationRepoPostgres(pool),
+			denylist:      postgres.NewAccessTokenDenylistPostgres(pool),
+			uow:           postgres.NewUnitOfWork(pool),
+		}, nil
+
+	case configs.DatabaseDriverSQLite:
+		conn, err := db.OpenSQLite(ctx, db.SQLiteConfig{Path: s.config.Database.Path})
+		if err != nil {
+			return nil, err
+		}
+		if err := s.migrate(ctx, migrate.NewSQLiteDriver(conn), migrations.SQLite()); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return &repositories{
+			users:         sqlite.NewUserRepoSQLite(conn),
+			tokens:        sqlite.NewTokenRepoSQLite(conn),
+			identities:    sqlite.NewIdentityRepoSQLite(conn),
+			states:        sqlite.NewOAuthStateRepoSQLite(conn),
+			mfa:           sqlite.NewMFARepoSQLite(conn),
+			challenges:    sqlite.NewMFAChallengeRepoSQLite(conn),
+			devices:       sqlite.NewDeviceAuthorizationRepoSQLite(conn),
+			verifications: sqlite.NewHandleVerificationRepoSQLite(conn),
+			denylist:      sqlite.NewAccessTokenDenylistSQLite(conn),
+			uow:           sqlite.NewUnitOfWork(conn),
+		}, nil
+
+	case configs.DatabaseDriverMemory:
+		users := memory.NewUserRepoMemo()
+		tokens := memory.NewTokenRepoMemo()
+		identities := memory.NewIdentityRepoMemo()
+		states := memory.NewOAuthStateRepoMemo()
+		mfa := memory.NewMFARepoMemo()
+		challenges := memory.NewMFAChallengeRepoMemo()
+		devices := memory.NewDeviceAuthorizationRepoMemo()
+		verifications := memory.NewHandleVerificationRepoMemo()
+		users.CascadeDeletes(tokens, identities, states, mfa, challenges, devices, verifications)
+		return &repositories{
+			users:         users,
+			tokens:        tokens,
+			identities:    identities,
+			states:        states,
+			mfa:           mfa,
+			challenges:    challenges,
+			devices:       devices,
+			verifications: verifications,
+			denylist:      memory.NewAccessTokenDenylistMemo(),
+			uow:           memory.NewUnitOfWork(users, tokens, identities, challenges),
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unknown database driver %q", s.config.Database.Driver)
+}
+
+// migrate applies pending migrations, or with auto_migrate off makes sure there are none
+func (s *Server) migrate(ctx context.Context, driver migrate.Driver, fsys fs.FS) error {
+	migrator, err := migrate.New(driver, fsys)
+	if err != nil {
+		return err
+	}
+
+	if !s.config.Database.AutoMigrate {
+		pending, err := migrator.Pending(ctx)
+		if err != nil {
+			return err
+		}
+		if len(pending) > 0 {
+			return fmt.Errorf("database has %d pending migrations, run go run ./cmd/migrate up", len(pending))
+		}
+		return nil
+	}
+
+	applied, err := migrator.Up(ctx)
+	for _, migration := range applied {
+		log.Printf("Applied migration %d_%s", migration.Version, migration.Name)
+	}
+	return err
+}
+
+// purgeDeletedAccounts periodically deletes the accounts whose deletion grace period ended
+func (s *Server) purgeDeletedAccounts(userUseCase *usecase.UserUseCase) {
+	interval := time.Duration(s.config.Account.PurgeInterval) * time.Second
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		deleted, err := userUseCase.PurgeScheduledDeletions(now)
+		if err != nil {
+			log.Printf("Failed to purge deleted accounts: %v", err)
+		}
+		if deleted > 0 {
+			log.Printf("Purged %d deleted accounts", deleted)
+		}
+	}
 }
 
 // Run starts the server
@@ -0,0 +1,31 @@
+// Package migrations embeds the SQL migrations of the server database, one directory per
+// database driver. Every driver has the same versions with the same names, add migrations to all
+// of them with go run ./cmd/migrate create <name>.
+package migrations
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed postgres/*.sql sqlite/*.sql
+var files embed.FS
+
+// Postgres returns the migrations of the postgres driver
+func Postgres() fs.FS {
+	return sub("postgres")
+}
+
+// SQLite returns the migrations of the sqlite driver
+func SQLite() fs.FS {
+	return sub("sqlite")
+}
+
+// sub returns the migrations directory of a driver
+func sub(dir string) fs.FS {
+	fsys, err := fs.Sub(files, dir)
+	if err != nil {
+		panic(err)
+	}
+	return fsys
+}
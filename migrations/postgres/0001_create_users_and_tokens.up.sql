@@ -0,0 +1,42 @@
+CREATE TABLE users (
+    id UUID PRIMARY KEY,
+    email TEXT NOT NULL,
+    handle TEXT NOT NULL DEFAULT '',
+    display_name TEXT NOT NULL DEFAULT '',
+    timezone TEXT NOT NULL DEFAULT '',
+    preferred_judge TEXT NOT NULL DEFAULT '',
+    codeforces_handle TEXT NOT NULL DEFAULT '',
+    atcoder_handle TEXT NOT NULL DEFAULT '',
+    password_hash TEXT NOT NULL DEFAULT '',
+    roles TEXT[] NOT NULL DEFAULT '{}',
+    banned_at TIMESTAMPTZ,
+    deletion_scheduled_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT users_email_key UNIQUE (email)
+);
+
+CREATE UNIQUE INDEX users_handle_key ON users (LOWER(handle)) WHERE handle <> '';
+CREATE INDEX users_roles_idx ON users USING GIN (roles);
+CREATE INDEX users_deletion_scheduled_at_idx ON users (deletion_scheduled_at)
+    WHERE deletion_scheduled_at IS NOT NULL;
+
+CREATE TABLE tokens (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    family_id UUID NOT NULL,
+    refresh_token_hash TEXT NOT NULL,
+    access_token_id TEXT NOT NULL DEFAULT '',
+    access_token_expires_at TIMESTAMPTZ NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    rotated_at TIMESTAMPTZ,
+    user_agent TEXT NOT NULL DEFAULT '',
+    ip_address TEXT NOT NULL DEFAULT '',
+    session_started_at TIMESTAMPTZ NOT NULL,
+    last_used_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT tokens_refresh_token_hash_key UNIQUE (refresh_token_hash)
+);
+
+CREATE INDEX tokens_user_id_idx ON tokens (user_id);
+CREATE INDEX tokens_family_id_idx ON tokens (family_id);
@@ -0,0 +1,3 @@
+-- Fails while several users have no email
+DROP INDEX users_email_key;
+ALTER TABLE users ADD CONSTRAINT users_email_key UNIQUE (email);
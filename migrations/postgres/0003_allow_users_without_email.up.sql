@@ -0,0 +1,3 @@
+-- Providers may share no address, any number of users can have no email
+ALTER TABLE users DROP CONSTRAINT users_email_key;
+CREATE UNIQUE INDEX users_email_key ON users (email) WHERE email <> '';
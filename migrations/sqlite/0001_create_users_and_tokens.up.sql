@@ -0,0 +1,43 @@
+-- IDs are UUID text, times UTC text in the fixed width layout 2006-01-02T15:04:05.000000000Z so
+-- they compare as text like the times they are, lists are JSON arrays.
+CREATE TABLE users (
+    id TEXT PRIMARY KEY,
+    email TEXT NOT NULL,
+    handle TEXT NOT NULL DEFAULT '',
+    display_name TEXT NOT NULL DEFAULT '',
+    timezone TEXT NOT NULL DEFAULT '',
+    preferred_judge TEXT NOT NULL DEFAULT '',
+    codeforces_handle TEXT NOT NULL DEFAULT '',
+    atcoder_handle TEXT NOT NULL DEFAULT '',
+    password_hash TEXT NOT NULL DEFAULT '',
+    roles TEXT NOT NULL DEFAULT '[]',
+    banned_at TEXT,
+    deletion_scheduled_at TEXT,
+    created_at TEXT NOT NULL,
+    updated_at TEXT NOT NULL,
+    CONSTRAINT users_email_key UNIQUE (email)
+);
+
+CREATE UNIQUE INDEX users_handle_key ON users (LOWER(handle)) WHERE handle <> '';
+CREATE INDEX users_deletion_scheduled_at_idx ON users (deletion_scheduled_at)
+    WHERE deletion_scheduled_at IS NOT NULL;
+
+CREATE TABLE tokens (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    family_id TEXT NOT NULL,
+    refresh_token_hash TEXT NOT NULL,
+    access_token_id TEXT NOT NULL DEFAULT '',
+    access_token_expires_at TEXT NOT NULL,
+    expires_at TEXT NOT NULL,
+    rotated_at TEXT,
+    user_agent TEXT NOT NULL DEFAULT '',
+    ip_address TEXT NOT NULL DEFAULT '',
+    session_started_at TEXT NOT NULL,
+    last_used_at TEXT NOT NULL,
+    created_at TEXT NOT NULL,
+    CONSTRAINT tokens_refresh_token_hash_key UNIQUE (refresh_token_hash)
+);
+
+CREATE INDEX tokens_user_id_idx ON tokens (user_id);
+CREATE INDEX tokens_family_id_idx ON tokens (family_id);
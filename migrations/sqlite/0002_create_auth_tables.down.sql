@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS revoked_access_tokens;
+DROP TABLE IF EXISTS handle_verifications;
+DROP TABLE IF EXISTS device_authorizations;
+DROP TABLE IF EXISTS mfa_challenges;
+DROP TABLE IF EXISTS mfa;
+DROP TABLE IF EXISTS oauth_states;
+DROP TABLE IF EXISTS identities;
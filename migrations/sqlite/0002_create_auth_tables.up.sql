@@ -0,0 +1,88 @@
+CREATE TABLE identities (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    provider TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    email TEXT NOT NULL DEFAULT '',
+    email_verified INTEGER NOT NULL DEFAULT 0,
+    created_at TEXT NOT NULL,
+    last_used_at TEXT NOT NULL,
+    CONSTRAINT identities_provider_subject_key UNIQUE (provider, subject)
+);
+
+CREATE INDEX identities_user_id_idx ON identities (user_id);
+
+CREATE TABLE oauth_states (
+    state TEXT PRIMARY KEY,
+    provider TEXT NOT NULL,
+    code_verifier TEXT NOT NULL DEFAULT '',
+    nonce TEXT NOT NULL DEFAULT '',
+    link_user_id TEXT REFERENCES users (id) ON DELETE CASCADE,
+    expires_at TEXT NOT NULL,
+    created_at TEXT NOT NULL,
+    consumed_at TEXT
+);
+
+CREATE INDEX oauth_states_expires_at_idx ON oauth_states (expires_at);
+
+CREATE TABLE mfa (
+    user_id TEXT PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    secret TEXT NOT NULL,
+    last_used_step INTEGER NOT NULL DEFAULT 0,
+    recovery_code_hashes TEXT NOT NULL DEFAULT '[]',
+    failed_attempts INTEGER NOT NULL DEFAULT 0,
+    locked_until TEXT,
+    confirmed_at TEXT,
+    created_at TEXT NOT NULL,
+    updated_at TEXT NOT NULL
+);
+
+CREATE TABLE mfa_challenges (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    token_hash TEXT NOT NULL,
+    expires_at TEXT NOT NULL,
+    created_at TEXT NOT NULL,
+    CONSTRAINT mfa_challenges_token_hash_key UNIQUE (token_hash)
+);
+
+CREATE INDEX mfa_challenges_user_id_idx ON mfa_challenges (user_id);
+CREATE INDEX mfa_challenges_expires_at_idx ON mfa_challenges (expires_at);
+
+CREATE TABLE device_authorizations (
+    id TEXT PRIMARY KEY,
+    device_code_hash TEXT NOT NULL,
+    user_code TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    user_id TEXT REFERENCES users (id) ON DELETE CASCADE,
+    interval_seconds INTEGER NOT NULL,
+    last_poll_at TEXT,
+    user_agent TEXT NOT NULL DEFAULT '',
+    ip_address TEXT NOT NULL DEFAULT '',
+    expires_at TEXT NOT NULL,
+    created_at TEXT NOT NULL,
+    CONSTRAINT device_authorizations_device_code_hash_key UNIQUE (device_code_hash)
+);
+
+CREATE INDEX device_authorizations_user_code_idx ON device_authorizations (user_code);
+CREATE INDEX device_authorizations_expires_at_idx ON device_authorizations (expires_at);
+
+CREATE TABLE handle_verifications (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    judge TEXT NOT NULL,
+    handle TEXT NOT NULL,
+    contest_id INTEGER NOT NULL DEFAULT 0,
+    problem_index TEXT NOT NULL DEFAULT '',
+    token TEXT NOT NULL DEFAULT '',
+    expires_at TEXT NOT NULL,
+    created_at TEXT NOT NULL,
+    CONSTRAINT handle_verifications_user_id_judge_key UNIQUE (user_id, judge)
+);
+
+CREATE TABLE revoked_access_tokens (
+    jti TEXT PRIMARY KEY,
+    expires_at TEXT NOT NULL
+);
+
+CREATE INDEX revoked_access_tokens_expires_at_idx ON revoked_access_tokens (expires_at);
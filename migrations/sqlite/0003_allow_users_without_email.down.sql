@@ -0,0 +1,3 @@
+-- Fails while several users have no email
+DROP INDEX users_email_key;
+CREATE UNIQUE INDEX users_email_key ON users (email);
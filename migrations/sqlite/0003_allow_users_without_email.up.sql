@@ -0,0 +1,34 @@
+-- Providers may share no address, any number of users can have no email.
+--
+-- SQLite cannot drop the unique constraint of a table, so users is rebuilt. Foreign keys are off
+-- while migrating, dropping the old table keeps what the other tables refer to it with.
+CREATE TABLE users_new (
+    id TEXT PRIMARY KEY,
+    email TEXT NOT NULL,
+    handle TEXT NOT NULL DEFAULT '',
+    display_name TEXT NOT NULL DEFAULT '',
+    timezone TEXT NOT NULL DEFAULT '',
+    preferred_judge TEXT NOT NULL DEFAULT '',
+    codeforces_handle TEXT NOT NULL DEFAULT '',
+    atcoder_handle TEXT NOT NULL DEFAULT '',
+    password_hash TEXT NOT NULL DEFAULT '',
+    roles TEXT NOT NULL DEFAULT '[]',
+    banned_at TEXT,
+    deletion_scheduled_at TEXT,
+    created_at TEXT NOT NULL,
+    updated_at TEXT NOT NULL
+);
+
+INSERT INTO users_new (id, email, handle, display_name, timezone, preferred_judge, codeforces_handle,
+    atcoder_handle, password_hash, roles, banned_at, deletion_scheduled_at, created_at, updated_at)
+SELECT id, email, handle, display_name, timezone, preferred_judge, codeforces_handle,
+    atcoder_handle, password_hash, roles, banned_at, deletion_scheduled_at, created_at, updated_at
+FROM users;
+
+DROP TABLE users;
+ALTER TABLE users_new RENAME TO users;
+
+CREATE UNIQUE INDEX users_email_key ON users (email) WHERE email <> '';
+CREATE UNIQUE INDEX users_handle_key ON users (LOWER(handle)) WHERE handle <> '';
+CREATE INDEX users_deletion_scheduled_at_idx ON users (deletion_scheduled_at)
+    WHERE deletion_scheduled_at IS NOT NULL;
@@ -0,0 +1,92 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	// Registers the pure Go sqlite driver with database/sql
+	_ "modernc.org/sqlite"
+)
+
+// defaultBusyTimeout is how long a write waits for another one to finish unless configured
+const defaultBusyTimeout = 5 * time.Second
+
+// SQLiteConfig configures an SQLite database file
+type SQLiteConfig struct {
+	// Path is the database file, it is created with its directory if missing
+	Path string
+	// BusyTimeout is how long a write waits while another connection writes
+	BusyTimeout time.Duration
+}
+
+// OpenSQLite opens the database file in WAL mode, so readers never wait for the writer, with
+// foreign keys enforced. Transactions take the write lock when they begin.
+func OpenSQLite(ctx context.Context, config SQLiteConfig) (*sql.DB, error) {
+	if config.Path == "" {
+		return nil, fmt.Errorf("sqlite path is required")
+	}
+	if err := os.MkdirAll(filepath.Dir(config.Path), 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create sqlite directory: %w", err)
+	}
+
+	busyTimeout := config.BusyTimeout
+	if busyTimeout <= 0 {
+		busyTimeout = defaultBusyTimeout
+	}
+	params := url.Values{}
+	params.Add("_pragma", fmt.Sprintf("busy_timeout(%d)", busyTimeout.Milliseconds()))
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Add("_pragma", "foreign_keys(1)")
+	params.Add("_pragma", "synchronous(NORMAL)")
+	params.Set("_txlock", "immediate")
+
+	db, err := sql.Open("sqlite", config.Path+"?"+params.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite: %w", err)
+	}
+
+	var journalMode string
+	if err := db.QueryRowContext(ctx, `PRAGMA journal_mode`).Scan(&journalMode); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open sqlite: %w", err)
+	}
+	if journalMode != "wal" {
+		db.Close()
+		return nil, fmt.Errorf("failed to enable WAL mode on %s, journal mode is %s", config.Path, journalMode)
+	}
+
+	return db, nil
+}
+
+// BackupSQLite writes a consistent snapshot of db to path while other connections keep reading
+// and writing. The file appears complete or not at all and must not exist yet.
+func BackupSQLite(ctx context.Context, db *sql.DB, path string) error {
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("backup %s already exists", path)
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to check backup path: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	// VACUUM INTO reads within one transaction, writers carry on against the WAL meanwhile
+	partial := path + ".partial"
+	_ = os.Remove(partial)
+	if _, err := db.ExecContext(ctx, `VACUUM INTO ?`, partial); err != nil {
+		_ = os.Remove(partial)
+		return fmt.Errorf("failed to back up sqlite: %w", err)
+	}
+	if err := os.Rename(partial, path); err != nil {
+		_ = os.Remove(partial)
+		return fmt.Errorf("failed to back up sqlite: %w", err)
+	}
+	return nil
+}
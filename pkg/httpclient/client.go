@@ -0,0 +1,203 @@
+// Package httpclient provides the HTTP client for calls to external services. Calls are
+// bounded by a per-attempt timeout and the caller's context, idempotent requests are retried
+// with jittered backoff and a circuit breaker stops calling a target that keeps failing.
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ErrCircuitOpen is returned without calling the target while its circuit breaker is open
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// Defaults for zero Config values
+const (
+	defaultTimeout         = 10 * time.Second
+	defaultRetryBaseDelay  = 100 * time.Millisecond
+	defaultRetryMaxDelay   = 2 * time.Second
+	defaultBreakerCooldown = 30 * time.Second
+)
+
+// Config configures a Client
+type Config struct {
+	// Timeout bounds each attempt including reading the response body
+	Timeout time.Duration
+	// MaxRetries is how many times a failed idempotent request is retried
+	MaxRetries int
+	// RetryBaseDelay and RetryMaxDelay bound the exponential backoff between retries
+	RetryBaseDelay time.Duration
+	RetryMaxDelay  time.Duration
+	// BreakerThreshold is how many consecutive failures open the circuit breaker, zero disables it
+	BreakerThreshold int
+	// BreakerCooldown is how long an open breaker rejects calls before letting one through
+	BreakerCooldown time.Duration
+	// Proxy is the proxy all calls go through, nil means the HTTP_PROXY environment variables
+	Proxy *url.URL
+}
+
+// Client calls one external service
+type Client struct {
+	name     string
+	config   Config
+	http     *http.Client
+	breaker  *breaker
+	recorder Recorder
+}
+
+// New creates a client, name identifies it in metrics. A nil recorder records nothing.
+func New(name string, config Config, recorder Recorder) *Client {
+	if config.Timeout <= 0 {
+		config.Timeout = defaultTimeout
+	}
+	if config.RetryBaseDelay <= 0 {
+		config.RetryBaseDelay = defaultRetryBaseDelay
+	}
+	if config.RetryMaxDelay <= 0 {
+		config.RetryMaxDelay = defaultRetryMaxDelay
+	}
+	if config.BreakerCooldown <= 0 {
+		config.BreakerCooldown = defaultBreakerCooldown
+	}
+	if recorder == nil {
+		recorder = nopRecorder{}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyFromEnvironment
+	if config.Proxy != nil {
+		transport.Proxy = http.ProxyURL(config.Proxy)
+	}
+
+	return &Client{
+		name:     name,
+		config:   config,
+		http:     &http.Client{Timeout: config.Timeout, Transport: transport},
+		breaker:  newBreaker(config.BreakerThreshold, config.BreakerCooldown),
+		recorder: recorder,
+	}
+}
+
+// Do sends the request with ctx. Requests with an idempotent method are retried on network
+// errors and 429 or 5xx answers, the last answer is returned once retries run out.
+func (c *Client) Do(ctx context.Context, req *http.Request) (*http.Response, error) {
+	req = req.WithContext(ctx)
+	retryable := isIdempotent(req.Method) && (req.Body == nil || req.GetBody != nil)
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		resp, err := c.attempt(req)
+		// Once the caller gives up trying again cannot help
+		if !retryable || attempt >= c.config.MaxRetries || ctx.Err() != nil ||
+			!shouldRetry(resp, err) || errors.Is(err, ErrCircuitOpen) {
+			return resp, err
+		}
+
+		// The answer is discarded, free the connection for the next attempt
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(c.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// attempt sends the request once through the circuit breaker and records it
+func (c *Client) attempt(req *http.Request) (*http.Response, error) {
+	if !c.breaker.allow(time.Now()) {
+		err := fmt.Errorf("%s: %w", c.name, ErrCircuitOpen)
+		c.recorder.Observe(Observation{Client: c.name, Method: req.Method, Host: req.URL.Host, Err: err})
+		return nil, err
+	}
+
+	start := time.Now()
+	resp, err := c.http.Do(req)
+	duration := time.Since(start)
+
+	observation := Observation{Client: c.name, Method: req.Method, Host: req.URL.Host, Duration: duration, Err: err}
+	if resp != nil {
+		observation.StatusCode = resp.StatusCode
+	}
+	c.recorder.Observe(observation)
+
+	// A cancelled caller says nothing about the health of the target
+	if req.Context().Err() != nil {
+		c.breaker.release()
+	} else {
+		c.breaker.record(err == nil && resp.StatusCode < http.StatusInternalServerError, time.Now())
+	}
+	return resp, err
+}
+
+// backoff returns the delay before retry attempt+1, a random share of the exponential bound
+func (c *Client) backoff(attempt int) time.Duration {
+	bound := c.config.RetryBaseDelay << attempt
+	if bound <= 0 || bound > c.config.RetryMaxDelay {
+		bound = c.config.RetryMaxDelay
+	}
+	return bound/2 + rand.N(bound/2+1)
+}
+
+// Get sends a GET request
+func (c *Client) Get(ctx context.Context, url string, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	return c.Do(ctx, req)
+}
+
+// PostForm sends a form POST, it is never retried
+func (c *Client) PostForm(ctx context.Context, url string, data url.Values, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(data.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return c.Do(ctx, req)
+}
+
+// isIdempotent reports whether repeating a request with method has no further effect
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// shouldRetry reports whether a failed attempt may succeed when repeated, network errors
+// and timed out attempts included
+func shouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError
+}
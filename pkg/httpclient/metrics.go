@@ -0,0 +1,125 @@
+package httpclient
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// Observation describes one attempt of an outbound call
+type Observation struct {
+	Client string
+	Method string
+	Host   string
+	// StatusCode is zero when no response arrived
+	StatusCode int
+	Duration   time.Duration
+	Err        error
+}
+
+// Recorder records outbound calls
+type Recorder interface {
+	Observe(o Observation)
+}
+
+type nopRecorder struct{}
+
+func (nopRecorder) Observe(Observation) {}
+
+// latencyBuckets are the upper bounds of the latency histogram
+var latencyBuckets = []time.Duration{
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// ClientStats are the totals of one client
+type ClientStats struct {
+	Requests int64 `json:"requests"`
+	// Errors counts attempts that failed without a response or with a 5xx answer
+	Errors int64 `json:"errors"`
+	// Rejected counts calls refused by the open circuit breaker
+	Rejected int64 `json:"rejected"`
+	// StatusCodes counts responses by status code
+	StatusCodes map[int]int64 `json:"status_codes"`
+	// LatencyBuckets counts attempts by the smallest latency bound they stayed within, or +Inf
+	LatencyBuckets map[string]int64 `json:"latency_buckets"`
+	TotalLatencyMs int64            `json:"total_latency_ms"`
+}
+
+// Metrics is an in-memory Recorder keeping totals per client
+type Metrics struct {
+	mu      sync.Mutex
+	clients map[string]*ClientStats
+}
+
+// NewMetrics creates an empty Metrics
+func NewMetrics() *Metrics {
+	return &Metrics{clients: make(map[string]*ClientStats)}
+}
+
+// Observe records one attempt
+func (m *Metrics) Observe(o Observation) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats, exists := m.clients[o.Client]
+	if !exists {
+		stats = &ClientStats{StatusCodes: make(map[int]int64), LatencyBuckets: make(map[string]int64)}
+		m.clients[o.Client] = stats
+	}
+
+	if errors.Is(o.Err, ErrCircuitOpen) {
+		stats.Rejected++
+		return
+	}
+
+	stats.Requests++
+	if o.Err != nil || o.StatusCode >= 500 {
+		stats.Errors++
+	}
+	if o.StatusCode != 0 {
+		stats.StatusCodes[o.StatusCode]++
+	}
+	stats.LatencyBuckets[bucketOf(o.Duration)]++
+	stats.TotalLatencyMs += o.Duration.Milliseconds()
+}
+
+// bucketOf returns the histogram bucket label of a latency
+func bucketOf(d time.Duration) string {
+	for _, bound := range latencyBuckets {
+		if d <= bound {
+			return bound.String()
+		}
+	}
+	return "+Inf"
+}
+
+// Snapshot returns a copy of the totals keyed by client name
+func (m *Metrics) Snapshot() map[string]ClientStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snapshot := make(map[string]ClientStats, len(m.clients))
+	for name, stats := range m.clients {
+		c := *stats
+		c.StatusCodes = make(map[int]int64, len(stats.StatusCodes))
+		for code, n := range stats.StatusCodes {
+			c.StatusCodes[code] = n
+		}
+		c.LatencyBuckets = make(map[string]int64, len(stats.LatencyBuckets))
+		for bucket, n := range stats.LatencyBuckets {
+			c.LatencyBuckets[bucket] = n
+		}
+		snapshot[name] = c
+	}
+	return snapshot
+}
+
+// Ensure Metrics implements Recorder interface
+var _ Recorder = (*Metrics)(nil)
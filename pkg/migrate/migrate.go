@@ -0,0 +1,327 @@
+// Package migrate applies versioned SQL migrations. Migrations are pairs of files named
+// <version>_<name>.up.sql and <version>_<name>.down.sql, applied in version order, each in its
+// own transaction together with its record in the schema_migrations table. The checksum kept
+// with every record catches migrations edited after they were applied.
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+var (
+	// ErrChecksumMismatch is returned when an applied migration was edited since
+	ErrChecksumMismatch = errors.New("migration changed after it was applied")
+	// ErrUnknownMigration is returned when the database has a migration the binary does not know
+	ErrUnknownMigration = errors.New("applied migration not found")
+	// ErrIrreversible is returned when rolling back a migration without a down file
+	ErrIrreversible = errors.New("migration has no down file")
+	// ErrUnknownVersion is returned when asked to go to a version there is no migration for
+	ErrUnknownVersion = errors.New("unknown migration version")
+)
+
+// fileName matches migration file names such as 0001_create_users.up.sql
+var fileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migration is one schema change
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	// Down undoes Up, empty when the migration cannot be rolled back
+	Down string
+	// Checksum is the SHA-256 of Up
+	Checksum string
+}
+
+// AppliedMigration is the record of a migration in the database
+type AppliedMigration struct {
+	Version   int64
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+}
+
+// Direction is whether a migration is applied or rolled back
+type Direction int
+
+const (
+	// DirectionUp applies a migration
+	DirectionUp Direction = iota
+	// DirectionDown rolls a migration back
+	DirectionDown
+)
+
+// Driver runs migrations against one kind of database
+type Driver interface {
+	// Lock waits until no other migrator works on the database and keeps it that way until Unlock
+	Lock(ctx context.Context) error
+	Unlock(ctx context.Context) error
+	// EnsureVersionTable creates schema_migrations unless it exists
+	EnsureVersionTable(ctx context.Context) error
+	// Applied returns the applied migrations in version order
+	Applied(ctx context.Context) ([]AppliedMigration, error)
+	// Apply runs the SQL of migration in direction and records it in one transaction
+	Apply(ctx context.Context, migration Migration, direction Direction) error
+}
+
+// Status describes a migration and whether it is applied
+type Status struct {
+	Migration Migration
+	// Applied is nil for pending migrations
+	Applied *AppliedMigration
+	// Modified is set when the applied checksum differs from the file
+	Modified bool
+}
+
+// Migrator applies a set of migrations with a driver
+type Migrator struct {
+	driver     Driver
+	migrations []Migration
+}
+
+// Load reads the migrations in the root of fsys
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("unexpected file %s in migrations", entry.Name())
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid migration version in %s", entry.Name())
+		}
+
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+
+		migration, exists := byVersion[version]
+		if !exists {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, migration.Name, match[2])
+		}
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", migration.Version, migration.Name)
+		}
+		migration.Checksum = checksum(migration.Up)
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// New creates a migrator for the migrations in the root of fsys
+func New(driver Driver, fsys fs.FS) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{driver: driver, migrations: migrations}, nil
+}
+
+// Migrations returns the known migrations in version order
+func (m *Migrator) Migrations() []Migration {
+	return m.migrations
+}
+
+// Up applies every pending migration and returns them
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var done []Migration
+	err := m.locked(ctx, func(applied map[int64]AppliedMigration) error {
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+			if err := m.apply(ctx, migration, DirectionUp); err != nil {
+				return err
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down rolls back the steps most recent migrations and returns them
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var done []Migration
+	err := m.locked(ctx, func(applied map[int64]AppliedMigration) error {
+		for i := len(m.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+			migration := m.migrations[i]
+			if _, ok := applied[migration.Version]; !ok {
+				continue
+			}
+			if err := m.apply(ctx, migration, DirectionDown); err != nil {
+				return err
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Goto applies or rolls back migrations until version is the latest applied one, 0 rolls back
+// everything. It returns the migrations it applied or rolled back.
+func (m *Migrator) Goto(ctx context.Context, version int64) ([]Migration, error) {
+	if version != 0 && m.find(version) == nil {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+	}
+
+	var done []Migration
+	err := m.locked(ctx, func(applied map[int64]AppliedMigration) error {
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			migration := m.migrations[i]
+			if _, ok := applied[migration.Version]; !ok || migration.Version <= version {
+				continue
+			}
+			if err := m.apply(ctx, migration, DirectionDown); err != nil {
+				return err
+			}
+			done = append(done, migration)
+		}
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; ok || migration.Version > version {
+				continue
+			}
+			if err := m.apply(ctx, migration, DirectionUp); err != nil {
+				return err
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Status lists every known migration with its state. It does not fail on edited migrations
+// but reports them as Modified.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	if err := m.driver.EnsureVersionTable(ctx); err != nil {
+		return nil, err
+	}
+	applied, err := m.driver.Applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]AppliedMigration, len(applied))
+	for _, a := range applied {
+		if m.find(a.Version) == nil {
+			return nil, fmt.Errorf("%w: %d_%s", ErrUnknownMigration, a.Version, a.Name)
+		}
+		byVersion[a.Version] = a
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := Status{Migration: migration}
+		if a, ok := byVersion[migration.Version]; ok {
+			status.Applied = &a
+			status.Modified = a.Checksum != migration.Checksum
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// Pending returns the migrations Up would apply
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var pending []Migration
+	for _, status := range statuses {
+		if status.Applied == nil {
+			pending = append(pending, status.Migration)
+		}
+	}
+	return pending, nil
+}
+
+// locked runs fn holding the migration lock with the verified applied migrations
+func (m *Migrator) locked(ctx context.Context, fn func(applied map[int64]AppliedMigration) error) (err error) {
+	if err := m.driver.Lock(ctx); err != nil {
+		return fmt.Errorf("failed to lock migrations: %w", err)
+	}
+	defer func() {
+		if unlockErr := m.driver.Unlock(context.WithoutCancel(ctx)); unlockErr != nil && err == nil {
+			err = fmt.Errorf("failed to unlock migrations: %w", unlockErr)
+		}
+	}()
+
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+	applied := make(map[int64]AppliedMigration)
+	for _, status := range statuses {
+		if status.Modified {
+			return fmt.Errorf("%w: %d_%s", ErrChecksumMismatch, status.Migration.Version, status.Migration.Name)
+		}
+		if status.Applied != nil {
+			applied[status.Migration.Version] = *status.Applied
+		}
+	}
+
+	return fn(applied)
+}
+
+// apply runs one migration in direction
+func (m *Migrator) apply(ctx context.Context, migration Migration, direction Direction) error {
+	if direction == DirectionDown && migration.Down == "" {
+		return fmt.Errorf("%w: %d_%s", ErrIrreversible, migration.Version, migration.Name)
+	}
+	if err := m.driver.Apply(ctx, migration, direction); err != nil {
+		return fmt.Errorf("migration %d_%s failed: %w", migration.Version, migration.Name, err)
+	}
+	return nil
+}
+
+// find returns the migration of version, nil if there is none
+func (m *Migrator) find(version int64) *Migration {
+	for i := range m.migrations {
+		if m.migrations[i].Version == version {
+			return &m.migrations[i]
+		}
+	}
+	return nil
+}
+
+// checksum returns the hex SHA-256 of sql
+func checksum(sql string) string {
+	sum := sha256.Sum256([]byte(sql))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,124 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// postgresLockKey identifies the migration advisory lock, it is the first 8 bytes of
+// sha256("schema_migrations")
+const postgresLockKey int64 = 0x26bec784c2bd47ab
+
+// PostgresDriver runs migrations on PostgreSQL. The lock is a session advisory lock, so
+// everything runs on the one connection holding it.
+type PostgresDriver struct {
+	pool *pgxpool.Pool
+	conn *pgxpool.Conn
+}
+
+// NewPostgresDriver creates a driver migrating the database of pool
+func NewPostgresDriver(pool *pgxpool.Pool) *PostgresDriver {
+	return &PostgresDriver{pool: pool}
+}
+
+// Lock waits for the migration advisory lock
+func (d *PostgresDriver) Lock(ctx context.Context) error {
+	conn, err := d.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, postgresLockKey); err != nil {
+		conn.Release()
+		return err
+	}
+	d.conn = conn
+	return nil
+}
+
+// Unlock releases the migration advisory lock
+func (d *PostgresDriver) Unlock(ctx context.Context) error {
+	if d.conn == nil {
+		return nil
+	}
+	conn := d.conn
+	d.conn = nil
+
+	_, err := conn.Exec(ctx, `SELECT pg_advisory_unlock($1)`, postgresLockKey)
+	if err != nil {
+		// A connection that may still hold the lock must not go back to the pool
+		conn.Hijack().Close(ctx)
+		return err
+	}
+	conn.Release()
+	return nil
+}
+
+// EnsureVersionTable creates schema_migrations unless it exists
+func (d *PostgresDriver) EnsureVersionTable(ctx context.Context) error {
+	_, err := d.querier().Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT PRIMARY KEY,
+			name TEXT NOT NULL,
+			checksum TEXT NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	return nil
+}
+
+// Applied returns the applied migrations in version order
+func (d *PostgresDriver) Applied(ctx context.Context) ([]AppliedMigration, error) {
+	rows, err := d.querier().Query(ctx, `SELECT version, name, checksum, applied_at FROM schema_migrations ORDER BY version`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (AppliedMigration, error) {
+		var a AppliedMigration
+		err := row.Scan(&a.Version, &a.Name, &a.Checksum, &a.AppliedAt)
+		return a, err
+	})
+}
+
+// Apply runs a migration and records it in one transaction
+func (d *PostgresDriver) Apply(ctx context.Context, migration Migration, direction Direction) error {
+	return pgx.BeginFunc(ctx, d.querier(), func(tx pgx.Tx) error {
+		if direction == DirectionUp {
+			if _, err := tx.Exec(ctx, migration.Up); err != nil {
+				return err
+			}
+			_, err := tx.Exec(ctx, `INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)`,
+				migration.Version, migration.Name, migration.Checksum)
+			return err
+		}
+
+		if _, err := tx.Exec(ctx, migration.Down); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, `DELETE FROM schema_migrations WHERE version = $1`, migration.Version)
+		return err
+	})
+}
+
+// postgresQuerier is implemented by both a pool and a single connection
+type postgresQuerier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// querier returns the connection holding the lock, or the pool outside of Lock and Unlock
+func (d *PostgresDriver) querier() postgresQuerier {
+	if d.conn != nil {
+		return d.conn
+	}
+	return d.pool
+}
+
+// Ensure PostgresDriver implements Driver interface
+var _ Driver = (*PostgresDriver)(nil)
@@ -0,0 +1,187 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// SQLiteDriver runs migrations on SQLite. SQLite has no advisory locks, Lock begins an immediate
+// transaction instead, which holds the write lock of the file until Unlock commits it. Each
+// migration runs in a savepoint of that transaction, so migrations cannot change PRAGMAs such
+// as foreign_keys that only take effect outside of transactions.
+//
+// Foreign keys are off while migrating, so a migration can rebuild a table the way SQLite
+// documents it without the drop of the old table deleting what refers to it. Unlock checks the
+// foreign keys before it commits.
+type SQLiteDriver struct {
+	db   *sql.DB
+	conn *sql.Conn
+}
+
+// NewSQLiteDriver creates a driver migrating the database of db
+func NewSQLiteDriver(db *sql.DB) *SQLiteDriver {
+	return &SQLiteDriver{db: db}
+}
+
+// Lock waits for the write lock of the database file
+func (d *SQLiteDriver) Lock(ctx context.Context) error {
+	conn, err := d.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err := conn.ExecContext(ctx, `PRAGMA foreign_keys = OFF`); err != nil {
+		conn.Close()
+		return err
+	}
+	if _, err := conn.ExecContext(ctx, `BEGIN IMMEDIATE`); err != nil {
+		restoreForeignKeys(ctx, conn)
+		conn.Close()
+		return err
+	}
+	d.conn = conn
+	return nil
+}
+
+// Unlock commits the migrations applied since Lock and releases the write lock
+func (d *SQLiteDriver) Unlock(ctx context.Context) error {
+	if d.conn == nil {
+		return nil
+	}
+	conn := d.conn
+	d.conn = nil
+	defer conn.Close()
+	defer restoreForeignKeys(ctx, conn)
+
+	if err := checkForeignKeys(ctx, conn); err != nil {
+		_, _ = conn.ExecContext(ctx, `ROLLBACK`)
+		return err
+	}
+	if _, err := conn.ExecContext(ctx, `COMMIT`); err != nil {
+		_, _ = conn.ExecContext(ctx, `ROLLBACK`)
+		return err
+	}
+	return nil
+}
+
+// checkForeignKeys fails when a row refers to a row that does not exist
+func checkForeignKeys(ctx context.Context, conn *sql.Conn) error {
+	rows, err := conn.QueryContext(ctx, `PRAGMA foreign_key_check`)
+	if err != nil {
+		return fmt.Errorf("failed to check foreign keys: %w", err)
+	}
+	defer rows.Close()
+
+	if rows.Next() {
+		var table, parent string
+		var rowID sql.NullInt64
+		var index int
+		if err := rows.Scan(&table, &rowID, &parent, &index); err != nil {
+			return fmt.Errorf("failed to check foreign keys: %w", err)
+		}
+		return fmt.Errorf("migrations left rows of %s referring to missing rows of %s", table, parent)
+	}
+	return rows.Err()
+}
+
+// restoreForeignKeys turns foreign keys back on before the connection returns to the pool
+func restoreForeignKeys(ctx context.Context, conn *sql.Conn) {
+	_, _ = conn.ExecContext(ctx, `PRAGMA foreign_keys = ON`)
+}
+
+// EnsureVersionTable creates schema_migrations unless it exists
+func (d *SQLiteDriver) EnsureVersionTable(ctx context.Context) error {
+	_, err := d.querier().ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			checksum TEXT NOT NULL,
+			applied_at TEXT NOT NULL
+		)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	return nil
+}
+
+// Applied returns the applied migrations in version order
+func (d *SQLiteDriver) Applied(ctx context.Context) ([]AppliedMigration, error) {
+	rows, err := d.querier().QueryContext(ctx, `SELECT version, name, checksum, applied_at FROM schema_migrations ORDER BY version`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	var applied []AppliedMigration
+	for rows.Next() {
+		var a AppliedMigration
+		var appliedAt string
+		if err := rows.Scan(&a.Version, &a.Name, &a.Checksum, &appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+		}
+		if a.AppliedAt, err = time.Parse(time.RFC3339Nano, appliedAt); err != nil {
+			return nil, fmt.Errorf("invalid applied_at of migration %d: %w", a.Version, err)
+		}
+		applied = append(applied, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	return applied, nil
+}
+
+// Apply runs a migration and records it in one savepoint of the lock transaction
+func (d *SQLiteDriver) Apply(ctx context.Context, migration Migration, direction Direction) error {
+	if d.conn == nil {
+		return errors.New("sqlite migrations run between Lock and Unlock")
+	}
+	if _, err := d.conn.ExecContext(ctx, `SAVEPOINT migration`); err != nil {
+		return err
+	}
+
+	err := d.apply(ctx, migration, direction)
+	if err != nil {
+		_, _ = d.conn.ExecContext(ctx, `ROLLBACK TO migration`)
+	}
+	if _, releaseErr := d.conn.ExecContext(ctx, `RELEASE migration`); releaseErr != nil && err == nil {
+		err = releaseErr
+	}
+	return err
+}
+
+// apply runs the SQL of migration in direction and updates schema_migrations
+func (d *SQLiteDriver) apply(ctx context.Context, migration Migration, direction Direction) error {
+	if direction == DirectionUp {
+		if _, err := d.conn.ExecContext(ctx, migration.Up); err != nil {
+			return err
+		}
+		_, err := d.conn.ExecContext(ctx, `INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES (?, ?, ?, ?)`,
+			migration.Version, migration.Name, migration.Checksum, time.Now().UTC().Format(time.RFC3339Nano))
+		return err
+	}
+
+	if _, err := d.conn.ExecContext(ctx, migration.Down); err != nil {
+		return err
+	}
+	_, err := d.conn.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = ?`, migration.Version)
+	return err
+}
+
+// sqliteQuerier is implemented by both a database and a single connection
+type sqliteQuerier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// querier returns the connection holding the lock, or the database outside of Lock and Unlock
+func (d *SQLiteDriver) querier() sqliteQuerier {
+	if d.conn != nil {
+		return d.conn
+	}
+	return d.db
+}
+
+// Ensure SQLiteDriver implements Driver interface
+var _ Driver = (*SQLiteDriver)(nil)
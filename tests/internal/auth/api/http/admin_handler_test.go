@@ -0,0 +1,126 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/algosim/backend/configs"
+	authhttp "github.com/algosim/backend/internal/auth/api/http"
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/algosim/backend/internal/auth/infrastructure/db/memory"
+	"github.com/algosim/backend/internal/auth/infrastructure/events"
+	"github.com/algosim/backend/internal/auth/infrastructure/oauth"
+	"github.com/algosim/backend/internal/auth/usecase"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminEndpoints(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	config := &configs.Config{}
+	config.Auth.JWTSecret = "test-secret-key-123"
+	config.Auth.TokenTTL = 3600
+	config.Auth.RefreshTokenSecret = "test-refresh-secret"
+	config.Auth.BootstrapAdminEmail = "root@example.com"
+	config.Password.MinLength = 10
+	config.Password.Argon2Memory = 1024
+	config.Password.Argon2Iterations = 1
+	config.Password.Argon2Parallelism = 1
+	config.Cookie.RefreshTokenMode = configs.RefreshTokenModeJSON
+
+	userRepo := memory.NewUserRepoMemo()
+	publisher := events.NewLogPublisher()
+	authUseCase := usecase.NewAuthUseCase(userRepo, memory.NewTokenRepoMemo(), memory.NewIdentityRepoMemo(), memory.NewOAuthStateRepoMemo(), memory.NewMFARepoMemo(), memory.NewMFAChallengeRepoMemo(), oauth.NewRegistry(), publisher, newJWTManager(t, config), config)
+	m := authhttp.NewAuthMiddleware(authUseCase)
+
+	r := gin.New()
+	authhttp.SetupAuthRoutes(r, authhttp.NewAuthHandler(authUseCase, config), m)
+	authhttp.SetupAdminRoutes(r, authhttp.NewAdminHandler(usecase.NewAdminUseCase(userRepo, authUseCase, publisher)), m)
+	r.GET("/groups", m.RequireAuth(), m.RequirePermission(domain.PermissionGroupsWrite), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	register := func(email string) authhttp.TokenResponse {
+		w := signUp(t, r, `{"email":"`+email+`","password":"correct horse battery staple"}`, nil)
+		var resp authhttp.TokenResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp
+	}
+
+	userID := func(token authhttp.TokenResponse) string {
+		w := doRequest(r, "GET", "/api/v1/auth/validate", "", nil, map[string]string{"Authorization": "Bearer " + token.AccessToken})
+		require.Equal(t, http.StatusOK, w.Code)
+		var user authhttp.UserResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &user))
+		return user.ID.String()
+	}
+
+	root := register("root@example.com")
+	alice := register("alice@example.com")
+	rootBearer := map[string]string{"Authorization": "Bearer " + root.AccessToken}
+	aliceBearer := map[string]string{"Authorization": "Bearer " + alice.AccessToken}
+	aliceRoles := "/api/v1/admin/users/" + userID(alice) + "/roles"
+
+	t.Run("Requires Permission", func(t *testing.T) {
+		w := doRequest(r, "POST", aliceRoles, `{"role":"coach"}`, nil, nil)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+		w = doRequest(r, "POST", aliceRoles, `{"role":"coach"}`, nil, aliceBearer)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		w = doRequest(r, "GET", "/groups", "", nil, aliceBearer)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("Grant And Revoke Role", func(t *testing.T) {
+		w := doRequest(r, "POST", aliceRoles, `{"role":"superuser"}`, nil, rootBearer)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		w = doRequest(r, "POST", "/api/v1/admin/users/not-a-uuid/roles", `{"role":"coach"}`, nil, rootBearer)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = doRequest(r, "POST", aliceRoles, `{"role":"coach"}`, nil, rootBearer)
+		require.Equal(t, http.StatusOK, w.Code)
+		var user authhttp.UserResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &user))
+		assert.ElementsMatch(t, []string{domain.RoleUser, domain.RoleCoach}, user.Roles)
+
+		// A fresh access token carries the new permission
+		w = doRequest(r, "POST", "/api/v1/auth/refresh", `{"refresh_token":"`+alice.RefreshToken+`"}`, nil, nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		var refreshed authhttp.TokenResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &refreshed))
+		coachBearer := map[string]string{"Authorization": "Bearer " + refreshed.AccessToken}
+		w = doRequest(r, "GET", "/groups", "", nil, coachBearer)
+		assert.Equal(t, http.StatusNoContent, w.Code)
+
+		// Revocation applies at once, even to tokens that still claim the role
+		w = doRequest(r, "DELETE", aliceRoles+"/coach", "", nil, rootBearer)
+		require.Equal(t, http.StatusOK, w.Code)
+		w = doRequest(r, "GET", "/groups", "", nil, coachBearer)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		w = doRequest(r, "DELETE", "/api/v1/admin/users/"+userID(root)+"/roles/admin", "", nil, rootBearer)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("Ban And Unban", func(t *testing.T) {
+		bob := register("bob@example.com")
+		bobBan := "/api/v1/admin/users/" + userID(bob) + "/ban"
+
+		w := doRequest(r, "POST", bobBan, "", nil, rootBearer)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		w = doRequest(r, "GET", "/api/v1/auth/validate", "", nil, map[string]string{"Authorization": "Bearer " + bob.AccessToken})
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		w = doRequest(r, "POST", "/api/v1/auth/login", `{"email":"bob@example.com","password":"correct horse battery staple"}`, nil, nil)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		w = doRequest(r, "DELETE", bobBan, "", nil, rootBearer)
+		require.Equal(t, http.StatusOK, w.Code)
+		w = doRequest(r, "POST", "/api/v1/auth/login", `{"email":"bob@example.com","password":"correct horse battery staple"}`, nil, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+}
@@ -0,0 +1,266 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/algosim/backend/configs"
+	authhttp "github.com/algosim/backend/internal/auth/api/http"
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/algosim/backend/internal/auth/infrastructure/db/memory"
+	"github.com/algosim/backend/internal/auth/infrastructure/events"
+	jwtinfra "github.com/algosim/backend/internal/auth/infrastructure/jwt"
+	"github.com/algosim/backend/internal/auth/infrastructure/oauth"
+	"github.com/algosim/backend/internal/auth/usecase"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRouter(t *testing.T, mode string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	config := &configs.Config{}
+	config.Auth.JWTSecret = "test-secret-key-123"
+	config.Auth.TokenTTL = 3600
+	config.Auth.RefreshTokenSecret = "test-refresh-secret"
+	config.Password.MinLength = 10
+	config.Password.Argon2Memory = 1024
+	config.Password.Argon2Iterations = 1
+	config.Password.Argon2Parallelism = 1
+	config.Cookie.Secure = true
+	config.Cookie.SameSite = "strict"
+	config.Cookie.RefreshTokenMode = mode
+	config.MFA.ChallengeTTL = 300
+	config.Device.VerificationURI = "https://algosim.example/device"
+	config.Device.CodeTTL = 600
+
+	userRepo := memory.NewUserRepoMemo()
+	authUseCase := usecase.NewAuthUseCase(userRepo, memory.NewTokenRepoMemo(), memory.NewIdentityRepoMemo(), memory.NewOAuthStateRepoMemo(), memory.NewMFARepoMemo(), memory.NewMFAChallengeRepoMemo(), oauth.NewRegistry(), events.NewLogPublisher(), newJWTManager(t, config), config)
+
+	deviceUseCase := usecase.NewDeviceUseCase(memory.NewDeviceAuthorizationRepoMemo(), userRepo, authUseCase, events.NewLogPublisher(), config)
+
+	r := gin.New()
+	middleware := authhttp.NewAuthMiddleware(authUseCase)
+	authhttp.SetupAuthRoutes(r, authhttp.NewAuthHandler(authUseCase, config), middleware)
+	authhttp.SetupDeviceRoutes(r, authhttp.NewDeviceHandler(deviceUseCase, authUseCase), middleware)
+	return r
+}
+
+func newJWTManager(t *testing.T, config *configs.Config) *jwtinfra.JWTManager {
+	jwtManager, err := jwtinfra.NewJWTManager(config, memory.NewAccessTokenDenylistMemo())
+	require.NoError(t, err)
+	return jwtManager
+}
+
+func doRequest(r *gin.Engine, method, path, body string, cookies []*http.Cookie, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func findCookie(w *httptest.ResponseRecorder, name string) *http.Cookie {
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == name {
+			return cookie
+		}
+	}
+	return nil
+}
+
+// signUp registers an account and returns the response of its first login
+func signUp(t *testing.T, r *gin.Engine, credentials string, headers map[string]string) *httptest.ResponseRecorder {
+	w := doRequest(r, "POST", "/api/v1/auth/register", credentials, nil, headers)
+	require.Equal(t, http.StatusAccepted, w.Code)
+	w = doRequest(r, "POST", "/api/v1/auth/login", credentials, nil, headers)
+	require.Equal(t, http.StatusOK, w.Code)
+	return w
+}
+
+// registerAndLogin creates a password account through the use case and signs it in
+func registerAndLogin(authUseCase *usecase.AuthUseCase, email, password string) (*domain.Token, error) {
+	if err := authUseCase.Register(email, password); err != nil {
+		return nil, err
+	}
+	result, err := authUseCase.Login(email, password, domain.ClientInfo{})
+	if err != nil {
+		return nil, err
+	}
+	return result.Token, nil
+}
+
+func TestRegisterDoesNotRevealAccounts(t *testing.T) {
+	r := newTestRouter(t, configs.RefreshTokenModeJSON)
+
+	fresh := doRequest(r, "POST", "/api/v1/auth/register", `{"email":"alice@example.com","password":"correct horse battery staple"}`, nil, nil)
+	taken := doRequest(r, "POST", "/api/v1/auth/register", `{"email":"alice@example.com","password":"another strong passphrase"}`, nil, nil)
+	assert.Equal(t, http.StatusAccepted, fresh.Code)
+	assert.Equal(t, fresh.Code, taken.Code)
+	assert.Equal(t, fresh.Body.String(), taken.Body.String())
+	assert.Nil(t, findCookie(fresh, "refresh_token"))
+
+	w := doRequest(r, "POST", "/api/v1/auth/register", `{"email":"bob@example.com","password":"short"}`, nil, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestRefreshTokenCookieMode(t *testing.T) {
+	r := newTestRouter(t, configs.RefreshTokenModeCookie)
+
+	w := signUp(t, r, `{"email":"alice@example.com","password":"correct horse battery staple"}`, nil)
+
+	var resp authhttp.TokenResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.NotEmpty(t, resp.AccessToken)
+	assert.Empty(t, resp.RefreshToken)
+	assert.NotEmpty(t, resp.CSRFToken)
+
+	refreshCookie := findCookie(w, "refresh_token")
+	require.NotNil(t, refreshCookie)
+	assert.True(t, refreshCookie.HttpOnly)
+	assert.True(t, refreshCookie.Secure)
+	assert.Equal(t, http.SameSiteStrictMode, refreshCookie.SameSite)
+	assert.Equal(t, "/api/v1/auth", refreshCookie.Path)
+
+	csrfCookie := findCookie(w, "csrf_token")
+	require.NotNil(t, csrfCookie)
+	assert.False(t, csrfCookie.HttpOnly)
+	assert.Equal(t, resp.CSRFToken, csrfCookie.Value)
+
+	cookies := []*http.Cookie{refreshCookie, csrfCookie}
+
+	t.Run("Missing Or Wrong CSRF Token", func(t *testing.T) {
+		w := doRequest(r, "POST", "/api/v1/auth/refresh", "", cookies, nil)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		w = doRequest(r, "POST", "/api/v1/auth/refresh", "", cookies, map[string]string{"X-CSRF-Token": "forged"})
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		// A header without the matching cookie is not enough either
+		w = doRequest(r, "POST", "/api/v1/auth/refresh", "", []*http.Cookie{refreshCookie}, map[string]string{"X-CSRF-Token": resp.CSRFToken})
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("Missing Cookie", func(t *testing.T) {
+		w := doRequest(r, "POST", "/api/v1/auth/refresh", "", []*http.Cookie{csrfCookie}, map[string]string{"X-CSRF-Token": resp.CSRFToken})
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("Refresh And Logout", func(t *testing.T) {
+		w := doRequest(r, "POST", "/api/v1/auth/refresh", "", cookies, map[string]string{"X-CSRF-Token": resp.CSRFToken})
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var refreshed authhttp.TokenResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &refreshed))
+		assert.NotEmpty(t, refreshed.AccessToken)
+		assert.Empty(t, refreshed.RefreshToken)
+
+		newRefreshCookie := findCookie(w, "refresh_token")
+		newCSRFCookie := findCookie(w, "csrf_token")
+		require.NotNil(t, newRefreshCookie)
+		require.NotNil(t, newCSRFCookie)
+		assert.NotEqual(t, refreshCookie.Value, newRefreshCookie.Value)
+
+		w = doRequest(r, "POST", "/api/v1/auth/logout", "", []*http.Cookie{newRefreshCookie, newCSRFCookie}, map[string]string{"X-CSRF-Token": newCSRFCookie.Value})
+		assert.Equal(t, http.StatusOK, w.Code)
+		cleared := findCookie(w, "refresh_token")
+		require.NotNil(t, cleared)
+		assert.Empty(t, cleared.Value)
+		assert.Less(t, cleared.MaxAge, 0)
+	})
+}
+
+func TestRefreshTokenJSONMode(t *testing.T) {
+	r := newTestRouter(t, configs.RefreshTokenModeJSON)
+
+	w := signUp(t, r, `{"email":"alice@example.com","password":"correct horse battery staple"}`, nil)
+	assert.Nil(t, findCookie(w, "refresh_token"))
+
+	var resp authhttp.TokenResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.NotEmpty(t, resp.RefreshToken)
+	assert.Empty(t, resp.CSRFToken)
+
+	w = doRequest(r, "POST", "/api/v1/auth/refresh", "", nil, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = doRequest(r, "POST", "/api/v1/auth/refresh", `{"refresh_token":"`+resp.RefreshToken+`"}`, nil, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var refreshed authhttp.TokenResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &refreshed))
+	assert.NotEmpty(t, refreshed.RefreshToken)
+
+	w = doRequest(r, "POST", "/api/v1/auth/logout", `{"refresh_token":"`+refreshed.RefreshToken+`"}`, nil, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestJWKSEndpoint(t *testing.T) {
+	r := newTestRouter(t, configs.RefreshTokenModeJSON)
+
+	w := doRequest(r, "GET", "/.well-known/jwks.json", "", nil, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"keys":[]}`, w.Body.String())
+	assert.Contains(t, w.Header().Get("Cache-Control"), "max-age")
+}
+
+func TestSessionEndpoints(t *testing.T) {
+	r := newTestRouter(t, configs.RefreshTokenModeJSON)
+
+	register := signUp(t, r, `{"email":"alice@example.com","password":"correct horse battery staple"}`, map[string]string{"User-Agent": "Firefox"})
+	login := doRequest(r, "POST", "/api/v1/auth/login", `{"email":"alice@example.com","password":"correct horse battery staple"}`, nil, map[string]string{"User-Agent": "Safari"})
+	require.Equal(t, http.StatusOK, login.Code)
+
+	var first, second authhttp.TokenResponse
+	require.NoError(t, json.Unmarshal(register.Body.Bytes(), &first))
+	require.NoError(t, json.Unmarshal(login.Body.Bytes(), &second))
+	bearer := map[string]string{"Authorization": "Bearer " + second.AccessToken}
+
+	w := doRequest(r, "GET", "/api/v1/auth/sessions", "", nil, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = doRequest(r, "GET", "/api/v1/auth/sessions", "", nil, bearer)
+	require.Equal(t, http.StatusOK, w.Code)
+	var sessions []authhttp.SessionResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &sessions))
+	require.Len(t, sessions, 2)
+
+	var current, other authhttp.SessionResponse
+	for _, s := range sessions {
+		if s.Current {
+			current = s
+		} else {
+			other = s
+		}
+	}
+	assert.Equal(t, "Safari", current.UserAgent)
+	assert.Equal(t, "Firefox", other.UserAgent)
+
+	w = doRequest(r, "DELETE", "/api/v1/auth/sessions/not-a-uuid", "", nil, bearer)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doRequest(r, "DELETE", "/api/v1/auth/sessions/"+other.ID.String(), "", nil, bearer)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = doRequest(r, "DELETE", "/api/v1/auth/sessions/"+other.ID.String(), "", nil, bearer)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = doRequest(r, "POST", "/api/v1/auth/refresh", `{"refresh_token":"`+first.RefreshToken+`"}`, nil, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = doRequest(r, "POST", "/api/v1/auth/logout-all", "", nil, bearer)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = doRequest(r, "GET", "/api/v1/auth/sessions", "", nil, bearer)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = doRequest(r, "POST", "/api/v1/auth/refresh", `{"refresh_token":"`+second.RefreshToken+`"}`, nil, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
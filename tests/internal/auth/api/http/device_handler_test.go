@@ -0,0 +1,122 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/algosim/backend/configs"
+	authhttp "github.com/algosim/backend/internal/auth/api/http"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeviceEndpoints(t *testing.T) {
+	// Cookie mode, devices still get their refresh token in the body
+	r := newTestRouter(t, configs.RefreshTokenModeCookie)
+
+	w := signUp(t, r, `{"email":"alice@example.com","password":"correct horse battery staple"}`, nil)
+	var registered authhttp.TokenResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &registered))
+	bearer := map[string]string{"Authorization": "Bearer " + registered.AccessToken}
+
+	// pollForm posts a form encoded token request as RFC 8628 clients do
+	pollForm := func(values url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/v1/auth/device/token", strings.NewReader(values.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("User-Agent", "algosim-cli/1.0")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	tokenError := func(w *httptest.ResponseRecorder) string {
+		var resp authhttp.DeviceTokenError
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp.Error
+	}
+	start := func() authhttp.DeviceCodeResponse {
+		w := doRequest(r, "POST", "/api/v1/auth/device/code", "", nil, map[string]string{"User-Agent": "algosim-cli/1.0"})
+		require.Equal(t, http.StatusOK, w.Code)
+		var codes authhttp.DeviceCodeResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &codes))
+		return codes
+	}
+
+	t.Run("Approve", func(t *testing.T) {
+		codes := start()
+		assert.NotEmpty(t, codes.DeviceCode)
+		assert.Equal(t, "https://algosim.example/device", codes.VerificationURI)
+		assert.InDelta(t, 600, codes.ExpiresIn, 5)
+
+		grant := url.Values{"grant_type": {"urn:ietf:params:oauth:grant-type:device_code"}, "device_code": {codes.DeviceCode}}
+		w := pollForm(grant)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, "authorization_pending", tokenError(w))
+
+		userCode := fmt.Sprintf(`{"user_code":%q}`, codes.UserCode)
+		w = doRequest(r, "POST", "/api/v1/auth/device/approve", userCode, nil, nil)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+		w = doRequest(r, "GET", "/api/v1/auth/device?user_code="+url.QueryEscape(codes.UserCode), "", nil, bearer)
+		require.Equal(t, http.StatusOK, w.Code)
+		var device authhttp.DeviceAuthorizationResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &device))
+		assert.Equal(t, "algosim-cli/1.0", device.UserAgent)
+
+		w = doRequest(r, "POST", "/api/v1/auth/device/approve", userCode, nil, bearer)
+		require.Equal(t, http.StatusOK, w.Code)
+		w = doRequest(r, "POST", "/api/v1/auth/device/approve", userCode, nil, bearer)
+		assert.Equal(t, http.StatusConflict, w.Code)
+
+		w = pollForm(grant)
+		require.Equal(t, http.StatusOK, w.Code)
+		var token authhttp.DeviceTokenResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &token))
+		assert.NotEmpty(t, token.AccessToken)
+		assert.NotEmpty(t, token.RefreshToken)
+		assert.Equal(t, "Bearer", token.TokenType)
+		assert.Nil(t, findCookie(w, "refresh_token"))
+
+		// The device session is listed with the other sessions of the user
+		w = doRequest(r, "GET", "/api/v1/auth/sessions", "", nil, bearer)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "algosim-cli/1.0")
+
+		// Refreshing goes through the same endpoint
+		w = pollForm(url.Values{"grant_type": {"refresh_token"}, "refresh_token": {token.RefreshToken}})
+		require.Equal(t, http.StatusOK, w.Code)
+		w = pollForm(url.Values{"grant_type": {"refresh_token"}, "refresh_token": {"unknown"}})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, "invalid_grant", tokenError(w))
+
+		w = pollForm(grant)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, "invalid_grant", tokenError(w))
+	})
+
+	t.Run("Deny", func(t *testing.T) {
+		codes := start()
+
+		w := doRequest(r, "POST", "/api/v1/auth/device/deny", fmt.Sprintf(`{"user_code":%q}`, codes.UserCode), nil, bearer)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		w = pollForm(url.Values{"grant_type": {"urn:ietf:params:oauth:grant-type:device_code"}, "device_code": {codes.DeviceCode}})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, "access_denied", tokenError(w))
+	})
+
+	t.Run("Bad Requests", func(t *testing.T) {
+		w := pollForm(url.Values{"grant_type": {"password"}})
+		assert.Equal(t, "unsupported_grant_type", tokenError(w))
+
+		w = pollForm(url.Values{"grant_type": {"urn:ietf:params:oauth:grant-type:device_code"}})
+		assert.Equal(t, "invalid_request", tokenError(w))
+
+		w = doRequest(r, "GET", "/api/v1/auth/device", "", nil, bearer)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
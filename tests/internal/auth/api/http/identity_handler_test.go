@@ -0,0 +1,115 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/algosim/backend/configs"
+	authhttp "github.com/algosim/backend/internal/auth/api/http"
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/algosim/backend/internal/auth/infrastructure/db/memory"
+	"github.com/algosim/backend/internal/auth/infrastructure/events"
+	"github.com/algosim/backend/internal/auth/infrastructure/oauth"
+	"github.com/algosim/backend/internal/auth/usecase"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubProvider is an OAuth provider whose codes sign in to fixed accounts
+type stubProvider struct {
+	accounts map[string]*oauth.UserInfo
+}
+
+func (p *stubProvider) Name() string { return "stub" }
+
+func (p *stubProvider) GetAuthURL(ctx context.Context, state, codeChallenge, nonce string) (string, error) {
+	return "https://provider.test/authorize?state=" + url.QueryEscape(state), nil
+}
+
+func (p *stubProvider) ExchangeCodeForToken(ctx context.Context, code, codeVerifier string) (*domain.Token, error) {
+	return &domain.Token{AccessToken: code}, nil
+}
+
+func (p *stubProvider) GetUserInfo(ctx context.Context, accessToken string) (*oauth.UserInfo, error) {
+	info, ok := p.accounts[accessToken]
+	if !ok {
+		return nil, domain.ErrInvalidCredentials
+	}
+	return info, nil
+}
+
+func TestIdentityEndpoints(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	config := &configs.Config{}
+	config.Auth.JWTSecret = "test-secret-key-123"
+	config.Auth.TokenTTL = 3600
+	config.Auth.RefreshTokenSecret = "test-refresh-secret"
+	config.OAuth.StateTTL = 600
+	config.Password.MinLength = 10
+	config.Password.Argon2Memory = 1024
+	config.Password.Argon2Iterations = 1
+	config.Password.Argon2Parallelism = 1
+
+	provider := &stubProvider{accounts: map[string]*oauth.UserInfo{
+		"alice-code": {ID: "stub-alice", Email: "alice@stub.test"},
+	}}
+	authUseCase := usecase.NewAuthUseCase(memory.NewUserRepoMemo(), memory.NewTokenRepoMemo(), memory.NewIdentityRepoMemo(), memory.NewOAuthStateRepoMemo(), memory.NewMFARepoMemo(), memory.NewMFAChallengeRepoMemo(), oauth.NewRegistry(provider), events.NewLogPublisher(), newJWTManager(t, config), config)
+
+	r := gin.New()
+	authhttp.SetupAuthRoutes(r, authhttp.NewAuthHandler(authUseCase, config), authhttp.NewAuthMiddleware(authUseCase))
+
+	token, err := registerAndLogin(authUseCase, "alice@example.com", "correct horse battery staple")
+	require.NoError(t, err)
+	bearer := map[string]string{"Authorization": "Bearer " + token.AccessToken}
+
+	w := doRequest(r, "GET", "/api/v1/auth/oauth/link?provider=stub", "", nil, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = doRequest(r, "GET", "/api/v1/auth/oauth/link?provider=facebook", "", nil, bearer)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Start linking and come back through the regular callback
+	w = doRequest(r, "GET", "/api/v1/auth/oauth/link?provider=stub", "", nil, bearer)
+	require.Equal(t, http.StatusOK, w.Code)
+	var link map[string]string
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &link))
+	authURL, err := url.Parse(link["auth_url"])
+	require.NoError(t, err)
+	stateCookie := findCookie(w, "oauth_state")
+	require.NotNil(t, stateCookie)
+
+	w = doRequest(r, "GET", "/api/v1/auth/oauth/stub/callback?code=alice-code&state="+url.QueryEscape(authURL.Query().Get("state")), "", []*http.Cookie{stateCookie}, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var linked authhttp.IdentityResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &linked))
+	assert.Equal(t, "stub", linked.Provider)
+	assert.Equal(t, "alice@stub.test", linked.Email)
+	assert.NotContains(t, w.Body.String(), "access_token")
+
+	w = doRequest(r, "GET", "/api/v1/auth/identities", "", nil, bearer)
+	require.Equal(t, http.StatusOK, w.Code)
+	var identities []authhttp.IdentityResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &identities))
+	require.Len(t, identities, 1)
+	assert.Equal(t, linked.ID, identities[0].ID)
+
+	w = doRequest(r, "DELETE", "/api/v1/auth/identities/not-a-uuid", "", nil, bearer)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = doRequest(r, "DELETE", "/api/v1/auth/identities/"+uuid.NewString(), "", nil, bearer)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// The password still signs in, so the only identity may go
+	w = doRequest(r, "DELETE", "/api/v1/auth/identities/"+linked.ID.String(), "", nil, bearer)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = doRequest(r, "GET", "/api/v1/auth/identities", "", nil, bearer)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `[]`, w.Body.String())
+}
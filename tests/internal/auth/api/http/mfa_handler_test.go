@@ -0,0 +1,88 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/algosim/backend/configs"
+	authhttp "github.com/algosim/backend/internal/auth/api/http"
+	"github.com/algosim/backend/internal/auth/infrastructure/totp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMFAEndpoints(t *testing.T) {
+	r := newTestRouter(t, configs.RefreshTokenModeJSON)
+	const credentials = `{"email":"alice@example.com","password":"correct horse battery staple"}`
+
+	w := signUp(t, r, credentials, nil)
+	var registered authhttp.TokenResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &registered))
+	bearer := map[string]string{"Authorization": "Bearer " + registered.AccessToken}
+
+	w = doRequest(r, "POST", "/api/v1/auth/mfa/enroll", "", nil, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = doRequest(r, "POST", "/api/v1/auth/mfa/enroll", "", nil, bearer)
+	require.Equal(t, http.StatusOK, w.Code)
+	var enrollment authhttp.MFAEnrollmentResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &enrollment))
+	assert.Contains(t, enrollment.ProvisioningURI, "otpauth://totp/")
+
+	step := totp.Step(time.Now())
+	code := func(step int64) string {
+		c, err := totp.Code(enrollment.Secret, step)
+		require.NoError(t, err)
+		return c
+	}
+
+	w = doRequest(r, "POST", "/api/v1/auth/mfa/confirm", `{"code":"000000"}`, nil, bearer)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = doRequest(r, "POST", "/api/v1/auth/mfa/confirm", fmt.Sprintf(`{"code":%q}`, code(step)), nil, bearer)
+	require.Equal(t, http.StatusOK, w.Code)
+	var recovery authhttp.RecoveryCodesResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &recovery))
+	assert.Len(t, recovery.RecoveryCodes, 10)
+
+	w = doRequest(r, "GET", "/api/v1/auth/mfa", "", nil, bearer)
+	require.Equal(t, http.StatusOK, w.Code)
+	var status authhttp.MFAStatusResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+	assert.True(t, status.Enabled)
+	assert.Equal(t, 10, status.RecoveryCodesRemaining)
+
+	t.Run("Login Needs Second Factor", func(t *testing.T) {
+		w := doRequest(r, "POST", "/api/v1/auth/login", credentials, nil, nil)
+		require.Equal(t, http.StatusAccepted, w.Code)
+		var pending authhttp.MFARequiredResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &pending))
+		assert.True(t, pending.MFARequired)
+		require.NotEmpty(t, pending.MFAToken)
+		assert.NotContains(t, w.Body.String(), "access_token")
+
+		w = doRequest(r, "POST", "/api/v1/auth/mfa/verify", fmt.Sprintf(`{"mfa_token":%q,"code":"000000"}`, pending.MFAToken), nil, nil)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+		w = doRequest(r, "POST", "/api/v1/auth/mfa/verify", fmt.Sprintf(`{"mfa_token":%q,"code":%q}`, pending.MFAToken, code(step+1)), nil, nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		var token authhttp.TokenResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &token))
+		assert.NotEmpty(t, token.AccessToken)
+		assert.NotEmpty(t, token.RefreshToken)
+	})
+
+	t.Run("Disable", func(t *testing.T) {
+		w := doRequest(r, "DELETE", "/api/v1/auth/mfa", fmt.Sprintf(`{"code":%q}`, recovery.RecoveryCodes[0]), nil, bearer)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		w = doRequest(r, "POST", "/api/v1/auth/login", credentials, nil, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		w = doRequest(r, "DELETE", "/api/v1/auth/mfa", `{"code":"000000"}`, nil, bearer)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
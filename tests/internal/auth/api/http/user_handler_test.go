@@ -0,0 +1,155 @@
+package tests
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/algosim/backend/configs"
+	authhttp "github.com/algosim/backend/internal/auth/api/http"
+	"github.com/algosim/backend/internal/auth/infrastructure/db/memory"
+	"github.com/algosim/backend/internal/auth/infrastructure/events"
+	"github.com/algosim/backend/internal/auth/infrastructure/oauth"
+	"github.com/algosim/backend/internal/auth/usecase"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserEndpoints(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	config := &configs.Config{}
+	config.Auth.JWTSecret = "test-secret-key-123"
+	config.Auth.TokenTTL = 3600
+	config.Auth.RefreshTokenSecret = "test-refresh-secret"
+	config.Password.MinLength = 10
+	config.Password.Argon2Memory = 1024
+	config.Password.Argon2Iterations = 1
+	config.Password.Argon2Parallelism = 1
+
+	userRepo := memory.NewUserRepoMemo()
+	authUseCase := usecase.NewAuthUseCase(userRepo, memory.NewTokenRepoMemo(), memory.NewIdentityRepoMemo(), memory.NewOAuthStateRepoMemo(), memory.NewMFARepoMemo(), memory.NewMFAChallengeRepoMemo(), oauth.NewRegistry(), events.NewLogPublisher(), newJWTManager(t, config), config)
+
+	r := gin.New()
+	authhttp.SetupUserRoutes(r, authhttp.NewUserHandler(usecase.NewUserUseCase(userRepo), authUseCase), authhttp.NewAuthMiddleware(authUseCase))
+
+	token, err := registerAndLogin(authUseCase, "alice@example.com", "correct horse battery staple")
+	require.NoError(t, err)
+	bearer := map[string]string{"Authorization": "Bearer " + token.AccessToken}
+
+	w := doRequest(r, "GET", "/api/v1/users/me", "", nil, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = doRequest(r, "PATCH", "/api/v1/users/me", `{"handle":"alice","display_name":"Alice","timezone":"Asia/Tokyo","preferred_judge":"codeforces"}`, nil, bearer)
+	require.Equal(t, http.StatusOK, w.Code)
+	var me authhttp.UserResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &me))
+	assert.Equal(t, "alice", me.Handle)
+	assert.Equal(t, "Asia/Tokyo", me.Timezone)
+	assert.Equal(t, "codeforces", me.PreferredJudge)
+
+	w = doRequest(r, "GET", "/api/v1/users/me", "", nil, bearer)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"email":"alice@example.com"`)
+
+	t.Run("Field Errors", func(t *testing.T) {
+		w := doRequest(r, "PATCH", "/api/v1/users/me", `{"timezone":"Mars/Olympus"}`, nil, bearer)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		var resp authhttp.FieldErrorResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, "timezone", resp.Field)
+
+		other, err := registerAndLogin(authUseCase, "bob@example.com", "correct horse battery staple")
+		require.NoError(t, err)
+		w = doRequest(r, "PATCH", "/api/v1/users/me", `{"handle":"Alice"}`, nil, map[string]string{"Authorization": "Bearer " + other.AccessToken})
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Contains(t, w.Body.String(), `"field":"handle"`)
+	})
+
+	t.Run("Public Profile", func(t *testing.T) {
+		w := doRequest(r, "GET", "/api/v1/users/"+me.ID.String(), "", nil, nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		var profile map[string]any
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &profile))
+		assert.Equal(t, "alice", profile["handle"])
+		assert.Equal(t, "Alice", profile["display_name"])
+		assert.NotContains(t, profile, "email")
+		assert.NotContains(t, profile, "timezone")
+		assert.NotContains(t, profile, "roles")
+
+		w = doRequest(r, "GET", "/api/v1/users/"+uuid.NewString(), "", nil, nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		w = doRequest(r, "GET", "/api/v1/users/not-a-uuid", "", nil, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestAccountEndpoints(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	config := &configs.Config{}
+	config.Auth.JWTSecret = "test-secret-key-123"
+	config.Auth.TokenTTL = 3600
+	config.Auth.RefreshTokenSecret = "test-refresh-secret"
+	config.Account.DeletionGracePeriod = 3600
+	config.Password.MinLength = 10
+	config.Password.Argon2Memory = 1024
+	config.Password.Argon2Iterations = 1
+	config.Password.Argon2Parallelism = 1
+
+	userRepo := memory.NewUserRepoMemo()
+	authUseCase := usecase.NewAuthUseCase(userRepo, memory.NewTokenRepoMemo(), memory.NewIdentityRepoMemo(), memory.NewOAuthStateRepoMemo(), memory.NewMFARepoMemo(), memory.NewMFAChallengeRepoMemo(), oauth.NewRegistry(), events.NewLogPublisher(), newJWTManager(t, config), config)
+	userUseCase := usecase.NewUserUseCase(userRepo)
+	userUseCase.RegisterDataHook(authUseCase.SessionDataHook())
+
+	r := gin.New()
+	authhttp.SetupUserRoutes(r, authhttp.NewUserHandler(userUseCase, authUseCase), authhttp.NewAuthMiddleware(authUseCase))
+
+	token, err := registerAndLogin(authUseCase, "alice@example.com", "correct horse battery staple")
+	require.NoError(t, err)
+	bearer := map[string]string{"Authorization": "Bearer " + token.AccessToken}
+
+	t.Run("Export", func(t *testing.T) {
+		w := doRequest(r, "GET", "/api/v1/users/me/export", "", nil, bearer)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Header().Get("Content-Disposition"), ".json")
+		var export usecase.UserDataExport
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &export))
+		assert.Equal(t, "alice@example.com", export.User.Email)
+		assert.Contains(t, export.Modules, "sessions")
+		assert.NotContains(t, w.Body.String(), "argon2")
+
+		w = doRequest(r, "GET", "/api/v1/users/me/export?format=zip", "", nil, bearer)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/zip", w.Header().Get("Content-Type"))
+		archive, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+		require.NoError(t, err)
+		var names []string
+		for _, f := range archive.File {
+			names = append(names, f.Name)
+		}
+		assert.ElementsMatch(t, []string{"user.json", "sessions.json"}, names)
+
+		w = doRequest(r, "GET", "/api/v1/users/me/export?format=xml", "", nil, bearer)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		w := doRequest(r, "DELETE", "/api/v1/users/me", "", nil, bearer)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		w = doRequest(r, "DELETE", "/api/v1/users/me", `{"password":"wrong password"}`, nil, bearer)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		w = doRequest(r, "DELETE", "/api/v1/users/me", `{"password":"correct horse battery staple"}`, nil, bearer)
+		require.Equal(t, http.StatusAccepted, w.Code)
+		assert.Contains(t, w.Body.String(), "deletion_scheduled_at")
+
+		// The access token used is revoked with every other session
+		w = doRequest(r, "GET", "/api/v1/users/me", "", nil, bearer)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
@@ -0,0 +1,34 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/algosim/backend/internal/auth/infrastructure/db/memory"
+	"github.com/algosim/backend/internal/auth/repository/repositorytest"
+)
+
+func TestConformance(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T) repositorytest.Repositories {
+		users := memory.NewUserRepoMemo()
+		tokens := memory.NewTokenRepoMemo()
+		identities := memory.NewIdentityRepoMemo()
+		states := memory.NewOAuthStateRepoMemo()
+		mfa := memory.NewMFARepoMemo()
+		challenges := memory.NewMFAChallengeRepoMemo()
+		devices := memory.NewDeviceAuthorizationRepoMemo()
+		verifications := memory.NewHandleVerificationRepoMemo()
+		users.CascadeDeletes(tokens, identities, states, mfa, challenges, devices, verifications)
+		return repositorytest.Repositories{
+			Users:                users,
+			Tokens:               tokens,
+			Identities:           identities,
+			OAuthStates:          states,
+			MFA:                  mfa,
+			MFAChallenges:        challenges,
+			DeviceAuthorizations: devices,
+			HandleVerifications:  verifications,
+			AccessTokenDenylist:  memory.NewAccessTokenDenylistMemo(),
+			UnitOfWork:           memory.NewUnitOfWork(users, tokens, identities, challenges),
+		}
+	})
+}
@@ -0,0 +1,541 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/algosim/backend/internal/auth/infrastructure/db/postgres"
+	"github.com/algosim/backend/internal/auth/repository/repositorytest"
+	"github.com/algosim/backend/migrations"
+	"github.com/algosim/backend/pkg/db"
+	"github.com/algosim/backend/pkg/migrate"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestPool connects to the database in TEST_POSTGRES_DSN, e.g. started by make test-postgres.
+// Each test gets its own schema, dropped when the test ends.
+func newTestPool(t *testing.T) *pgxpool.Pool {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN not set")
+	}
+	ctx := context.Background()
+
+	admin, err := db.OpenPostgres(ctx, db.PostgresConfig{URL: dsn, ConnectTimeout: 5 * time.Second})
+	require.NoError(t, err)
+	t.Cleanup(admin.Close)
+
+	schema := fmt.Sprintf("test_%d", time.Now().UnixNano())
+	_, err = admin.Exec(ctx, "CREATE SCHEMA "+schema)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_, _ = admin.Exec(context.Background(), "DROP SCHEMA "+schema+" CASCADE")
+	})
+
+	u, err := url.Parse(dsn)
+	require.NoError(t, err)
+	query := u.Query()
+	query.Set("search_path", schema)
+	u.RawQuery = query.Encode()
+
+	pool, err := db.OpenPostgres(ctx, db.PostgresConfig{URL: u.String(), MaxConns: 4})
+	require.NoError(t, err)
+	t.Cleanup(pool.Close)
+
+	migrator, err := migrate.New(migrate.NewPostgresDriver(pool), migrations.Postgres())
+	require.NoError(t, err)
+	_, err = migrator.Up(ctx)
+	require.NoError(t, err)
+	return pool
+}
+
+// newTestUser stores a new user
+func newTestUser(t *testing.T, pool *pgxpool.Pool, email string) *domain.User {
+	user := domain.NewUser(email)
+	require.NoError(t, postgres.NewUserRepoPostgres(pool).Create(user))
+	return user
+}
+
+func newTestToken(userID uuid.UUID) *domain.Token {
+	token := domain.NewToken(userID, "access", "refresh", uuid.NewString(), time.Now().Add(time.Hour))
+	token.AccessTokenID = uuid.NewString()
+	token.AccessTokenExpiresAt = time.Now().Add(15 * time.Minute)
+	token.UserAgent = "Mozilla/5.0"
+	token.IPAddress = "203.0.113.7"
+	return token
+}
+
+func TestConformance(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T) repositorytest.Repositories {
+		pool := newTestPool(t)
+		return repositorytest.Repositories{
+			Users:                postgres.NewUserRepoPostgres(pool),
+			Tokens:               postgres.NewTokenRepoPostgres(pool),
+			Identities:           postgres.NewIdentityRepoPostgres(pool),
+			OAuthStates:          postgres.NewOAuthStateRepoPostgres(pool),
+			MFA:                  postgres.NewMFARepoPostgres(pool),
+			MFAChallenges:        postgres.NewMFAChallengeRepoPostgres(pool),
+			DeviceAuthorizations: postgres.NewDeviceAuthorizationRepoPostgres(pool),
+			HandleVerifications:  postgres.NewHandleVerificationRepoPostgres(pool),
+			AccessTokenDenylist:  postgres.NewAccessTokenDenylistPostgres(pool),
+			UnitOfWork:           postgres.NewUnitOfWork(pool),
+		}
+	})
+}
+
+func TestUserRepoPostgres(t *testing.T) {
+	pool := newTestPool(t)
+	repo := postgres.NewUserRepoPostgres(pool)
+
+	user := domain.NewUser("alice@example.com")
+	user.Handle = "Alice"
+	user.PasswordHash = "$argon2id$hash"
+	require.NoError(t, repo.Create(user))
+
+	t.Run("Find", func(t *testing.T) {
+		found, err := repo.FindByID(user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, user.Email, found.Email)
+		assert.Equal(t, "Alice", found.Handle)
+		assert.Equal(t, user.PasswordHash, found.PasswordHash)
+		assert.Equal(t, []string{domain.RoleUser}, found.Roles)
+		assert.Nil(t, found.BannedAt)
+		assert.WithinDuration(t, user.CreatedAt, found.CreatedAt, time.Millisecond)
+
+		found, err = repo.FindByEmail("alice@example.com")
+		require.NoError(t, err)
+		assert.Equal(t, user.ID, found.ID)
+
+		found, err = repo.FindByHandle("ALICE")
+		require.NoError(t, err)
+		assert.Equal(t, user.ID, found.ID)
+	})
+
+	t.Run("Not Found", func(t *testing.T) {
+		_, err := repo.FindByID(uuid.New())
+		assert.ErrorIs(t, err, domain.ErrUserNotFound)
+		_, err = repo.FindByEmail("nobody@example.com")
+		assert.ErrorIs(t, err, domain.ErrUserNotFound)
+		_, err = repo.FindByHandle("")
+		assert.ErrorIs(t, err, domain.ErrUserNotFound)
+		assert.ErrorIs(t, repo.Update(domain.NewUser("nobody@example.com")), domain.ErrUserNotFound)
+		assert.ErrorIs(t, repo.Delete(uuid.New()), domain.ErrUserNotFound)
+	})
+
+	t.Run("Unique", func(t *testing.T) {
+		assert.ErrorIs(t, repo.Create(domain.NewUser("alice@example.com")), domain.ErrUserAlreadyExists)
+
+		bob := domain.NewUser("bob@example.com")
+		require.NoError(t, repo.Create(bob))
+		bob.Handle = "alice"
+		assert.ErrorIs(t, repo.Update(bob), domain.ErrHandleTaken)
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		now := time.Now()
+		user.AddRole(domain.RoleAdmin)
+		user.BannedAt = &now
+		user.DeletionScheduledAt = &now
+		user.CodeforcesHandle = "tourist"
+		require.NoError(t, repo.Update(user))
+
+		found, err := repo.FindByID(user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, []string{domain.RoleUser, domain.RoleAdmin}, found.Roles)
+		require.NotNil(t, found.BannedAt)
+		assert.WithinDuration(t, now, *found.BannedAt, time.Millisecond)
+		assert.Equal(t, "tourist", found.CodeforcesHandle)
+
+		admins, err := repo.FindByRole(domain.RoleAdmin)
+		require.NoError(t, err)
+		require.Len(t, admins, 1)
+		assert.Equal(t, user.ID, admins[0].ID)
+
+		due, err := repo.FindScheduledForDeletion(now.Add(time.Second))
+		require.NoError(t, err)
+		assert.Len(t, due, 1)
+		due, err = repo.FindScheduledForDeletion(now.Add(-time.Second))
+		require.NoError(t, err)
+		assert.Empty(t, due)
+	})
+
+	t.Run("Delete Cascades To Tokens", func(t *testing.T) {
+		carol := domain.NewUser("carol@example.com")
+		require.NoError(t, repo.Create(carol))
+		tokens := postgres.NewTokenRepoPostgres(pool)
+		token := newTestToken(carol.ID)
+		require.NoError(t, tokens.Create(token))
+
+		require.NoError(t, repo.Delete(carol.ID))
+		_, err := tokens.FindByID(token.ID)
+		assert.ErrorIs(t, err, domain.ErrTokenNotFound)
+	})
+
+	t.Run("Concurrent Creates", func(t *testing.T) {
+		var wg sync.WaitGroup
+		errs := make([]error, 8)
+		for i := range errs {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs[i] = repo.Create(domain.NewUser("race@example.com"))
+			}()
+		}
+		wg.Wait()
+
+		created := 0
+		for _, err := range errs {
+			if err == nil {
+				created++
+			} else {
+				assert.ErrorIs(t, err, domain.ErrUserAlreadyExists)
+			}
+		}
+		assert.Equal(t, 1, created)
+	})
+}
+
+func TestTokenRepoPostgres(t *testing.T) {
+	pool := newTestPool(t)
+	users := postgres.NewUserRepoPostgres(pool)
+	repo := postgres.NewTokenRepoPostgres(pool)
+
+	user := domain.NewUser("alice@example.com")
+	require.NoError(t, users.Create(user))
+
+	token := newTestToken(user.ID)
+	require.NoError(t, repo.Create(token))
+
+	t.Run("Find", func(t *testing.T) {
+		found, err := repo.FindByRefreshTokenHash(token.RefreshTokenHash)
+		require.NoError(t, err)
+		assert.Equal(t, token.ID, found.ID)
+		assert.Equal(t, token.FamilyID, found.FamilyID)
+		assert.Equal(t, token.AccessTokenID, found.AccessTokenID)
+		assert.Equal(t, "Mozilla/5.0", found.UserAgent)
+		assert.Equal(t, "203.0.113.7", found.IPAddress)
+		assert.WithinDuration(t, token.ExpiresAt, found.ExpiresAt, time.Millisecond)
+		// Only the hash of the refresh token is stored
+		assert.Empty(t, found.RefreshToken)
+		assert.Empty(t, found.AccessToken)
+
+		_, err = repo.FindByRefreshTokenHash("unknown")
+		assert.ErrorIs(t, err, domain.ErrTokenNotFound)
+	})
+
+	t.Run("Unique Hash", func(t *testing.T) {
+		duplicate := newTestToken(user.ID)
+		duplicate.RefreshTokenHash = token.RefreshTokenHash
+		assert.Error(t, repo.Create(duplicate))
+	})
+
+	t.Run("Rotate", func(t *testing.T) {
+		now := time.Now()
+		token.RotatedAt = &now
+		require.NoError(t, repo.Update(token))
+
+		found, err := repo.FindByID(token.ID)
+		require.NoError(t, err)
+		require.NotNil(t, found.RotatedAt)
+		assert.True(t, found.IsRotated())
+
+		// The refresh token hash cannot change
+		changed := *token
+		changed.RefreshTokenHash = "other"
+		assert.ErrorIs(t, repo.Update(&changed), domain.ErrTokenNotFound)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		successor := newTestToken(user.ID)
+		successor.FamilyID = token.FamilyID
+		require.NoError(t, repo.Create(successor))
+		other := newTestToken(user.ID)
+		require.NoError(t, repo.Create(other))
+
+		tokens, err := repo.FindByUserID(user.ID)
+		require.NoError(t, err)
+		assert.Len(t, tokens, 3)
+
+		require.NoError(t, repo.DeleteByFamilyID(token.FamilyID))
+		tokens, err = repo.FindByUserID(user.ID)
+		require.NoError(t, err)
+		require.Len(t, tokens, 1)
+		assert.Equal(t, other.ID, tokens[0].ID)
+
+		require.NoError(t, repo.Delete(other.ID))
+		assert.ErrorIs(t, repo.Delete(other.ID), domain.ErrTokenNotFound)
+		require.NoError(t, repo.DeleteByUserID(user.ID))
+	})
+}
+
+func TestIdentityRepoPostgres(t *testing.T) {
+	pool := newTestPool(t)
+	repo := postgres.NewIdentityRepoPostgres(pool)
+	user := newTestUser(t, pool, "alice@example.com")
+
+	first := domain.NewIdentity(user.ID, "github", "1001", "alice@example.com", true)
+	require.NoError(t, repo.Create(first))
+	second := domain.NewIdentity(user.ID, "google", "2002", "alice@gmail.com", false)
+	second.CreatedAt = first.CreatedAt.Add(time.Second)
+	require.NoError(t, repo.Create(second))
+
+	found, err := repo.FindByProviderSubject("github", "1001")
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, found.ID)
+	assert.True(t, found.EmailVerified)
+
+	identities, err := repo.FindByUserID(user.ID)
+	require.NoError(t, err)
+	require.Len(t, identities, 2)
+	assert.Equal(t, first.ID, identities[0].ID, "oldest first")
+
+	assert.ErrorIs(t, repo.Create(domain.NewIdentity(user.ID, "github", "1001", "", false)), domain.ErrIdentityAlreadyLinked)
+
+	second.Email = "alice@example.org"
+	require.NoError(t, repo.Update(second))
+	found, err = repo.FindByID(second.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "alice@example.org", found.Email)
+	assert.False(t, found.EmailVerified)
+
+	require.NoError(t, repo.Delete(first.ID))
+	assert.ErrorIs(t, repo.Delete(first.ID), domain.ErrIdentityNotFound)
+	_, err = repo.FindByProviderSubject("github", "1001")
+	assert.ErrorIs(t, err, domain.ErrIdentityNotFound)
+	assert.ErrorIs(t, repo.Update(first), domain.ErrIdentityNotFound)
+
+	require.NoError(t, repo.DeleteByUserID(user.ID))
+	identities, err = repo.FindByUserID(user.ID)
+	require.NoError(t, err)
+	assert.Empty(t, identities)
+}
+
+func TestOAuthStateRepoPostgres(t *testing.T) {
+	pool := newTestPool(t)
+	repo := postgres.NewOAuthStateRepoPostgres(pool)
+	user := newTestUser(t, pool, "alice@example.com")
+
+	state := domain.NewOAuthState("state-1", "github", "verifier", "nonce", time.Minute)
+	state.LinkUserID = user.ID
+	require.NoError(t, repo.Create(state))
+	assert.Error(t, repo.Create(state))
+
+	consumed, err := repo.Consume("state-1")
+	require.NoError(t, err)
+	assert.Equal(t, "verifier", consumed.CodeVerifier)
+	assert.Equal(t, "nonce", consumed.Nonce)
+	assert.Equal(t, user.ID, consumed.LinkUserID)
+	require.NotNil(t, consumed.ConsumedAt)
+
+	_, err = repo.Consume("state-1")
+	assert.ErrorIs(t, err, domain.ErrOAuthStateReplayed)
+	_, err = repo.Consume("unknown")
+	assert.ErrorIs(t, err, domain.ErrOAuthStateExpired)
+
+	expired := domain.NewOAuthState("state-2", "google", "", "", -time.Second)
+	require.NoError(t, repo.Create(expired))
+	_, err = repo.Consume("state-2")
+	assert.ErrorIs(t, err, domain.ErrOAuthStateExpired)
+
+	t.Run("Concurrent Consume", func(t *testing.T) {
+		require.NoError(t, repo.Create(domain.NewOAuthState("state-3", "github", "", "", time.Minute)))
+
+		var wg sync.WaitGroup
+		errs := make([]error, 8)
+		for i := range errs {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, errs[i] = repo.Consume("state-3")
+			}()
+		}
+		wg.Wait()
+
+		consumed := 0
+		for _, err := range errs {
+			if err == nil {
+				consumed++
+			} else {
+				assert.ErrorIs(t, err, domain.ErrOAuthStateReplayed)
+			}
+		}
+		assert.Equal(t, 1, consumed)
+	})
+}
+
+func TestMFARepoPostgres(t *testing.T) {
+	pool := newTestPool(t)
+	repo := postgres.NewMFARepoPostgres(pool)
+	user := newTestUser(t, pool, "alice@example.com")
+
+	_, err := repo.FindByUserID(user.ID)
+	assert.ErrorIs(t, err, domain.ErrMFANotEnrolled)
+
+	mfa := domain.NewMFA(user.ID, "JBSWY3DPEHPK3PXP")
+	require.NoError(t, repo.Save(mfa))
+	found, err := repo.FindByUserID(user.ID)
+	require.NoError(t, err)
+	assert.False(t, found.IsEnabled())
+	assert.Empty(t, found.RecoveryCodeHashes)
+
+	now := time.Now()
+	mfa.ConfirmedAt = &now
+	mfa.LastUsedStep = 58000000
+	mfa.RecoveryCodeHashes = []string{"hash-1", "hash-2"}
+	mfa.FailedAttempts = 2
+	require.NoError(t, repo.Save(mfa))
+
+	found, err = repo.FindByUserID(user.ID)
+	require.NoError(t, err)
+	assert.True(t, found.IsEnabled())
+	assert.Equal(t, int64(58000000), found.LastUsedStep)
+	assert.Equal(t, []string{"hash-1", "hash-2"}, found.RecoveryCodeHashes)
+	assert.Equal(t, 2, found.FailedAttempts)
+	assert.Nil(t, found.LockedUntil)
+
+	require.NoError(t, repo.DeleteByUserID(user.ID))
+	_, err = repo.FindByUserID(user.ID)
+	assert.ErrorIs(t, err, domain.ErrMFANotEnrolled)
+}
+
+func TestMFAChallengeRepoPostgres(t *testing.T) {
+	pool := newTestPool(t)
+	repo := postgres.NewMFAChallengeRepoPostgres(pool)
+	user := newTestUser(t, pool, "alice@example.com")
+
+	challenge := domain.NewMFAChallenge(user.ID, "token-hash", time.Minute)
+	require.NoError(t, repo.Create(challenge))
+	found, err := repo.FindByTokenHash("token-hash")
+	require.NoError(t, err)
+	assert.Equal(t, challenge.ID, found.ID)
+	assert.Equal(t, user.ID, found.UserID)
+
+	expired := domain.NewMFAChallenge(user.ID, "expired-hash", -time.Second)
+	require.NoError(t, repo.Create(expired))
+	_, err = repo.FindByTokenHash("expired-hash")
+	assert.ErrorIs(t, err, domain.ErrMFAChallengeNotFound)
+
+	require.NoError(t, repo.Delete(challenge.ID))
+	assert.ErrorIs(t, repo.Delete(challenge.ID), domain.ErrMFAChallengeNotFound)
+	_, err = repo.FindByTokenHash("token-hash")
+	assert.ErrorIs(t, err, domain.ErrMFAChallengeNotFound)
+
+	require.NoError(t, repo.Create(domain.NewMFAChallenge(user.ID, "other-hash", time.Minute)))
+	require.NoError(t, repo.DeleteByUserID(user.ID))
+	_, err = repo.FindByTokenHash("other-hash")
+	assert.ErrorIs(t, err, domain.ErrMFAChallengeNotFound)
+}
+
+func TestDeviceAuthorizationRepoPostgres(t *testing.T) {
+	pool := newTestPool(t)
+	repo := postgres.NewDeviceAuthorizationRepoPostgres(pool)
+	user := newTestUser(t, pool, "alice@example.com")
+
+	client := domain.NewClientInfo("algosim-cli/1.0", "203.0.113.7")
+	authorization := domain.NewDeviceAuthorization("device-hash", "BCDFGHJK", 5*time.Second, 10*time.Minute, client)
+	require.NoError(t, repo.Create(authorization))
+	assert.Error(t, repo.Create(domain.NewDeviceAuthorization("other-hash", "BCDFGHJK", 5*time.Second, time.Minute, client)),
+		"user codes are unique among unexpired authorizations")
+
+	found, err := repo.FindByUserCode("BCDFGHJK")
+	require.NoError(t, err)
+	assert.Equal(t, authorization.ID, found.ID)
+	assert.Equal(t, domain.DeviceAuthorizationPending, found.Status)
+	assert.Equal(t, uuid.Nil, found.UserID)
+	assert.Equal(t, 5*time.Second, found.Interval)
+	assert.Equal(t, client, found.Client)
+	assert.Nil(t, found.LastPollAt)
+
+	require.NoError(t, repo.Decide(authorization.ID, domain.DeviceAuthorizationApproved, user.ID))
+	assert.ErrorIs(t, repo.Decide(authorization.ID, domain.DeviceAuthorizationDenied, user.ID), domain.ErrDeviceAuthorizationNotFound,
+		"only pending authorizations are decided")
+	require.NoError(t, repo.UpdatePoll(authorization.ID, time.Now(), 10*time.Second))
+
+	found, err = repo.FindByDeviceCodeHash("device-hash")
+	require.NoError(t, err)
+	assert.Equal(t, domain.DeviceAuthorizationApproved, found.Status)
+	assert.Equal(t, user.ID, found.UserID)
+	assert.Equal(t, 10*time.Second, found.Interval)
+	require.NotNil(t, found.LastPollAt)
+
+	expired := domain.NewDeviceAuthorization("expired-hash", "MNPQRSTV", 5*time.Second, -time.Second, client)
+	require.NoError(t, repo.Create(expired))
+	_, err = repo.FindByUserCode("MNPQRSTV")
+	assert.ErrorIs(t, err, domain.ErrDeviceAuthorizationNotFound)
+	found, err = repo.FindByDeviceCodeHash("expired-hash")
+	require.NoError(t, err, "expired authorizations are still found by device code")
+	assert.True(t, found.IsExpired(time.Now()))
+
+	require.NoError(t, repo.Delete(authorization.ID))
+	assert.ErrorIs(t, repo.Delete(authorization.ID), domain.ErrDeviceAuthorizationNotFound)
+	assert.ErrorIs(t, repo.UpdatePoll(authorization.ID, time.Now(), 10*time.Second), domain.ErrDeviceAuthorizationNotFound)
+	_, err = repo.FindByDeviceCodeHash("device-hash")
+	assert.ErrorIs(t, err, domain.ErrDeviceAuthorizationNotFound)
+}
+
+func TestHandleVerificationRepoPostgres(t *testing.T) {
+	pool := newTestPool(t)
+	repo := postgres.NewHandleVerificationRepoPostgres(pool)
+	user := newTestUser(t, pool, "alice@example.com")
+
+	first := domain.NewHandleVerification(user.ID, domain.JudgeCodeforces, "tourist", time.Hour)
+	first.ContestID = 4
+	first.ProblemIndex = "A"
+	require.NoError(t, repo.Create(first))
+	atcoder := domain.NewHandleVerification(user.ID, domain.JudgeAtCoder, "tourist", time.Hour)
+	atcoder.Token = "algosim-token"
+	require.NoError(t, repo.Create(atcoder))
+
+	// A new verification for the same judge replaces the pending one
+	second := domain.NewHandleVerification(user.ID, domain.JudgeCodeforces, "petr", time.Hour)
+	require.NoError(t, repo.Create(second))
+
+	found, err := repo.FindByUserAndJudge(user.ID, domain.JudgeCodeforces)
+	require.NoError(t, err)
+	assert.Equal(t, second.ID, found.ID)
+	assert.Equal(t, "petr", found.Handle)
+	assert.Zero(t, found.ContestID)
+
+	verifications, err := repo.FindByUserID(user.ID)
+	require.NoError(t, err)
+	assert.Len(t, verifications, 2)
+
+	assert.ErrorIs(t, repo.Delete(first.ID), domain.ErrHandleVerificationNotFound)
+	require.NoError(t, repo.Delete(second.ID))
+	_, err = repo.FindByUserAndJudge(user.ID, domain.JudgeCodeforces)
+	assert.ErrorIs(t, err, domain.ErrHandleVerificationNotFound)
+
+	require.NoError(t, repo.DeleteByUserID(user.ID))
+	verifications, err = repo.FindByUserID(user.ID)
+	require.NoError(t, err)
+	assert.Empty(t, verifications)
+}
+
+func TestAccessTokenDenylistPostgres(t *testing.T) {
+	denylist := postgres.NewAccessTokenDenylistPostgres(newTestPool(t))
+
+	require.NoError(t, denylist.Add("jti-1", time.Now().Add(time.Minute)))
+	require.NoError(t, denylist.Add("jti-1", time.Now().Add(time.Minute)))
+	require.NoError(t, denylist.Add("jti-2", time.Now().Add(-time.Second)))
+
+	revoked, err := denylist.Contains("jti-1")
+	require.NoError(t, err)
+	assert.True(t, revoked)
+
+	revoked, err = denylist.Contains("jti-2")
+	require.NoError(t, err)
+	assert.False(t, revoked, "expired tokens need no revocation")
+
+	revoked, err = denylist.Contains("unknown")
+	require.NoError(t, err)
+	assert.False(t, revoked)
+}
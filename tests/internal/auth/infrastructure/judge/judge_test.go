@@ -0,0 +1,67 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/algosim/backend/internal/auth/infrastructure/judge"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodeforcesClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/user.status", r.URL.Path)
+		if r.URL.Query().Get("handle") != "tourist" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"status":"FAILED","comment":"handle: User with handle nobody not found"}`))
+			return
+		}
+		w.Write([]byte(`{"status":"OK","result":[{"id":1,"contestId":4,"creationTimeSeconds":1700000000,
+			"problem":{"contestId":4,"index":"A","name":"Watermelon"},"verdict":"COMPILATION_ERROR"}]}`))
+	}))
+	defer server.Close()
+
+	client := judge.NewCodeforcesClient(server.URL + "/api")
+
+	submissions, err := client.RecentSubmissions("tourist", 10)
+	require.NoError(t, err)
+	require.Len(t, submissions, 1)
+	assert.Equal(t, 4, submissions[0].ContestID)
+	assert.Equal(t, "A", submissions[0].ProblemIndex)
+	assert.Equal(t, judge.VerdictCompilationError, submissions[0].Verdict)
+	assert.Equal(t, int64(1700000000), submissions[0].CreatedAt.Unix())
+
+	_, err = client.RecentSubmissions("nobody", 10)
+	assert.ErrorIs(t, err, domain.ErrHandleNotFound)
+}
+
+func TestAtCoderClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/users/chokudai":
+			w.Write([]byte(`<table class="dl-table"><tr><th class="no-break">Country/Region</th><td>Japan</td></tr>
+				<tr><th class="no-break">Affiliation</th><td class="break-all">AtCoder &amp; algosim-abc</td></tr></table>`))
+		case "/users/newbie":
+			w.Write([]byte(`<table class="dl-table"><tr><th class="no-break">Country/Region</th><td>Japan</td></tr></table>`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := judge.NewAtCoderClient(server.URL)
+
+	affiliation, err := client.Affiliation("chokudai")
+	require.NoError(t, err)
+	assert.Equal(t, "AtCoder & algosim-abc", affiliation)
+
+	affiliation, err = client.Affiliation("newbie")
+	require.NoError(t, err)
+	assert.Empty(t, affiliation)
+
+	_, err = client.Affiliation("nobody")
+	assert.ErrorIs(t, err, domain.ErrHandleNotFound)
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/algosim/backend/configs"
 	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/algosim/backend/internal/auth/infrastructure/db/memory"
 	jwtinfra "github.com/algosim/backend/internal/auth/infrastructure/jwt"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
@@ -14,17 +15,13 @@ import (
 
 func TestJWTManager(t *testing.T) {
 	// Setup test configuration
-	config := &configs.Config{
-		Auth: struct {
-			JWTSecret string `mapstructure:"jwt_secret" env:"AUTH_JWT_SECRET"`
-			TokenTTL  int    `mapstructure:"token_ttl" env:"AUTH_TOKEN_TTL"`
-		}{
-			JWTSecret: "test-secret-key-123",
-			TokenTTL:  3600, // 1 hour
-		},
-	}
+	config := &configs.Config{}
+	config.Auth.JWTSecret = "test-secret-key-123"
+	config.Auth.TokenTTL = 3600 // 1 hour
+	config.Auth.RefreshTokenSecret = "test-refresh-secret"
 
-	jwtManager := jwtinfra.NewJWTManager(config)
+	jwtManager, err := jwtinfra.NewJWTManager(config, memory.NewAccessTokenDenylistMemo())
+	assert.NoError(t, err)
 
 	// Create test user
 	testUser := &domain.User{
@@ -42,6 +39,24 @@ func TestJWTManager(t *testing.T) {
 		assert.True(t, time.Now().Before(token.ExpiresAt))
 	})
 
+	t.Run("HashRefreshToken", func(t *testing.T) {
+		token, err := jwtManager.GenerateToken(testUser)
+		assert.NoError(t, err)
+		assert.Equal(t, jwtManager.HashRefreshToken(token.RefreshToken), token.RefreshTokenHash)
+		assert.NotEqual(t, token.RefreshToken, token.RefreshTokenHash)
+
+		other, err := jwtManager.GenerateToken(testUser)
+		assert.NoError(t, err)
+		assert.NotEqual(t, token.RefreshToken, other.RefreshToken)
+
+		// The hash is keyed by the configured secret
+		otherConfig := *config
+		otherConfig.Auth.RefreshTokenSecret = "another-refresh-secret"
+		otherManager, err := jwtinfra.NewJWTManager(&otherConfig, memory.NewAccessTokenDenylistMemo())
+		assert.NoError(t, err)
+		assert.NotEqual(t, token.RefreshTokenHash, otherManager.HashRefreshToken(token.RefreshToken))
+	})
+
 	t.Run("ValidateAccessToken", func(t *testing.T) {
 		// Generate a token
 		token, err := jwtManager.GenerateToken(testUser)
@@ -77,6 +92,37 @@ func TestJWTManager(t *testing.T) {
 		assert.Nil(t, claims)
 	})
 
+	t.Run("RevokeAccessToken", func(t *testing.T) {
+		token, err := jwtManager.GenerateToken(testUser)
+		assert.NoError(t, err)
+		other, err := jwtManager.GenerateToken(testUser)
+		assert.NoError(t, err)
+
+		// Every access token carries its own jti
+		claims, err := jwtManager.ValidateAccessToken(token.AccessToken)
+		assert.NoError(t, err)
+		assert.Equal(t, token.AccessTokenID, claims.ID)
+		assert.NotEqual(t, token.AccessTokenID, other.AccessTokenID)
+
+		assert.NoError(t, jwtManager.RevokeAccessToken(token))
+		_, err = jwtManager.ValidateAccessToken(token.AccessToken)
+		assert.ErrorIs(t, err, domain.ErrTokenRevoked)
+		_, err = jwtManager.ValidateAccessToken(other.AccessToken)
+		assert.NoError(t, err)
+
+		// Tokens without a jti cannot be revoked and are refused
+		noJTI := jwt.NewWithClaims(jwt.SigningMethodHS256, jwtinfra.Claims{
+			UserID: testUser.ID,
+			RegisteredClaims: jwt.RegisteredClaims{
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			},
+		})
+		noJTIString, err := noJTI.SignedString([]byte(config.Auth.JWTSecret))
+		assert.NoError(t, err)
+		_, err = jwtManager.ValidateAccessToken(noJTIString)
+		assert.Error(t, err)
+	})
+
 	t.Run("ValidateRefreshToken", func(t *testing.T) {
 		// Generate a token
 		token, err := jwtManager.GenerateToken(testUser)
@@ -0,0 +1,205 @@
+package tests
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/algosim/backend/configs"
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/algosim/backend/internal/auth/infrastructure/db/memory"
+	jwtinfra "github.com/algosim/backend/internal/auth/infrastructure/jwt"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeKeyFile writes a PKCS #8 PEM private key and returns its path
+func writeKeyFile(t *testing.T, dir, name string, key any) string {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	path := filepath.Join(dir, name+".pem")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600))
+	return path
+}
+
+func TestJWTManagerKeySet(t *testing.T) {
+	dir := t.TempDir()
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	edFile := writeKeyFile(t, dir, "ed", edKey)
+	rsaFile := writeKeyFile(t, dir, "rsa", rsaKey)
+
+	// PKCS #1 is accepted too
+	rsaPKCS1File := filepath.Join(dir, "rsa-pkcs1.pem")
+	require.NoError(t, os.WriteFile(rsaPKCS1File, pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(rsaKey),
+	}), 0o600))
+
+	past := time.Now().Add(-time.Hour).Format(time.RFC3339)
+	earlier := time.Now().Add(-2 * time.Hour).Format(time.RFC3339)
+	future := time.Now().Add(time.Hour).Format(time.RFC3339)
+
+	newManager := func(keys ...configs.SigningKeyConfig) *jwtinfra.JWTManager {
+		config := &configs.Config{}
+		config.Auth.JWTSecret = "test-secret-key-123"
+		config.Auth.TokenTTL = 3600
+		config.Auth.SigningKeys = keys
+		m, err := jwtinfra.NewJWTManager(config, memory.NewAccessTokenDenylistMemo())
+		require.NoError(t, err)
+		return m
+	}
+
+	testUser := &domain.User{ID: uuid.New(), Email: "test@example.com"}
+
+	kidOf := func(t *testing.T, tokenString string) (string, string) {
+		token, _, err := jwt.NewParser().ParseUnverified(tokenString, &jwtinfra.Claims{})
+		require.NoError(t, err)
+		kid, _ := token.Header["kid"].(string)
+		return kid, token.Method.Alg()
+	}
+
+	t.Run("Signs With Each Algorithm", func(t *testing.T) {
+		for _, tc := range []configs.SigningKeyConfig{
+			{ID: "ed", Algorithm: jwtinfra.AlgorithmEdDSA, PrivateKeyFile: edFile},
+			{ID: "rsa", Algorithm: jwtinfra.AlgorithmRS256, PrivateKeyFile: rsaFile},
+			{ID: "rsa-pkcs1", Algorithm: jwtinfra.AlgorithmRS256, PrivateKeyFile: rsaPKCS1File},
+		} {
+			m := newManager(tc)
+			token, err := m.GenerateToken(testUser)
+			require.NoError(t, err, tc.ID)
+
+			kid, alg := kidOf(t, token.AccessToken)
+			assert.Equal(t, tc.ID, kid)
+			assert.Equal(t, tc.Algorithm, alg)
+
+			claims, err := m.ValidateAccessToken(token.AccessToken)
+			assert.NoError(t, err, tc.ID)
+			assert.Equal(t, testUser.ID, claims.UserID)
+		}
+	})
+
+	t.Run("Rotation", func(t *testing.T) {
+		old := configs.SigningKeyConfig{ID: "old", Algorithm: jwtinfra.AlgorithmRS256, PrivateKeyFile: rsaFile, ActiveFrom: earlier}
+		oldToken, err := newManager(old).GenerateToken(testUser)
+		require.NoError(t, err)
+
+		// A scheduled key is published but does not sign yet
+		scheduled := newManager(old, configs.SigningKeyConfig{ID: "new", Algorithm: jwtinfra.AlgorithmEdDSA, PrivateKeyFile: edFile, ActiveFrom: future})
+		token, err := scheduled.GenerateToken(testUser)
+		require.NoError(t, err)
+		kid, _ := kidOf(t, token.AccessToken)
+		assert.Equal(t, "old", kid)
+		assert.Len(t, scheduled.JWKS().Keys, 2)
+
+		// Once active the new key signs and the old one still verifies
+		rotated := newManager(old, configs.SigningKeyConfig{ID: "new", Algorithm: jwtinfra.AlgorithmEdDSA, PrivateKeyFile: edFile, ActiveFrom: past})
+		token, err = rotated.GenerateToken(testUser)
+		require.NoError(t, err)
+		kid, _ = kidOf(t, token.AccessToken)
+		assert.Equal(t, "new", kid)
+		_, err = rotated.ValidateAccessToken(oldToken.AccessToken)
+		assert.NoError(t, err)
+
+		// A retired key neither verifies nor is published
+		old.RetireAt = past
+		retired := newManager(old, configs.SigningKeyConfig{ID: "new", Algorithm: jwtinfra.AlgorithmEdDSA, PrivateKeyFile: edFile, ActiveFrom: past})
+		_, err = retired.ValidateAccessToken(oldToken.AccessToken)
+		assert.Error(t, err)
+		jwks := retired.JWKS()
+		require.Len(t, jwks.Keys, 1)
+		assert.Equal(t, "new", jwks.Keys[0].KeyID)
+	})
+
+	t.Run("Rejects Foreign Tokens", func(t *testing.T) {
+		m := newManager(configs.SigningKeyConfig{ID: "rsa", Algorithm: jwtinfra.AlgorithmRS256, PrivateKeyFile: rsaFile})
+		claims := jwtinfra.Claims{
+			UserID: testUser.ID,
+			RegisteredClaims: jwt.RegisteredClaims{
+				ID:        uuid.NewString(),
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			},
+		}
+
+		// HS256 with the shared secret is no longer accepted
+		hs := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		hs.Header["kid"] = "rsa"
+		hsString, err := hs.SignedString([]byte("test-secret-key-123"))
+		require.NoError(t, err)
+		_, err = m.ValidateAccessToken(hsString)
+		assert.Error(t, err)
+
+		// Neither is an HMAC keyed with the public key
+		publicDER, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+		require.NoError(t, err)
+		confused, err := hs.SignedString(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}))
+		require.NoError(t, err)
+		_, err = m.ValidateAccessToken(confused)
+		assert.Error(t, err)
+
+		// Or a token signed by an unknown key
+		unknown := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+		unknown.Header["kid"] = "other"
+		unknownString, err := unknown.SignedString(edKey)
+		require.NoError(t, err)
+		_, err = m.ValidateAccessToken(unknownString)
+		assert.Error(t, err)
+	})
+
+	t.Run("JWKS", func(t *testing.T) {
+		m := newManager(
+			configs.SigningKeyConfig{ID: "ed", Algorithm: jwtinfra.AlgorithmEdDSA, PrivateKeyFile: edFile},
+			configs.SigningKeyConfig{ID: "rsa", Algorithm: jwtinfra.AlgorithmRS256, PrivateKeyFile: rsaFile},
+		)
+		jwks := m.JWKS()
+		require.Len(t, jwks.Keys, 2)
+
+		ed := jwks.Keys[0]
+		assert.Equal(t, "OKP", ed.KeyType)
+		assert.Equal(t, "Ed25519", ed.Curve)
+		assert.Equal(t, base64.RawURLEncoding.EncodeToString(edKey.Public().(ed25519.PublicKey)), ed.X)
+		assert.Equal(t, "sig", ed.Use)
+
+		rsaJWK := jwks.Keys[1]
+		assert.Equal(t, "RSA", rsaJWK.KeyType)
+		assert.Equal(t, jwtinfra.AlgorithmRS256, rsaJWK.Algorithm)
+		assert.Equal(t, base64.RawURLEncoding.EncodeToString(rsaKey.N.Bytes()), rsaJWK.N)
+		assert.Equal(t, "AQAB", rsaJWK.E)
+
+		// HS256 has nothing to publish
+		assert.Empty(t, newManager().JWKS().Keys)
+	})
+
+	t.Run("Invalid Configuration", func(t *testing.T) {
+		for _, keys := range [][]configs.SigningKeyConfig{
+			{{ID: "ed", Algorithm: jwtinfra.AlgorithmRS256, PrivateKeyFile: edFile}},
+			{{ID: "ed", Algorithm: "HS256", PrivateKeyFile: edFile}},
+			{{ID: "", Algorithm: jwtinfra.AlgorithmEdDSA, PrivateKeyFile: edFile}},
+			{{ID: "missing", Algorithm: jwtinfra.AlgorithmEdDSA, PrivateKeyFile: filepath.Join(dir, "missing.pem")}},
+			{{ID: "ed", Algorithm: jwtinfra.AlgorithmEdDSA, PrivateKeyFile: edFile, ActiveFrom: "yesterday"}},
+			{
+				{ID: "dup", Algorithm: jwtinfra.AlgorithmEdDSA, PrivateKeyFile: edFile},
+				{ID: "dup", Algorithm: jwtinfra.AlgorithmRS256, PrivateKeyFile: rsaFile},
+			},
+		} {
+			config := &configs.Config{}
+			config.Auth.SigningKeys = keys
+			_, err := jwtinfra.NewJWTManager(config, memory.NewAccessTokenDenylistMemo())
+			assert.Error(t, err, keys[0].ID)
+		}
+	})
+}
@@ -0,0 +1,137 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/algosim/backend/configs"
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/algosim/backend/internal/auth/infrastructure/oauth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitHubOAuth(t *testing.T) {
+	// Local fake of GitHub's token and user endpoints
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login/oauth/access_token", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Header.Get("Accept") != "application/json" || r.FormValue("code") != "test-code" || r.FormValue("code_verifier") == "" {
+			// GitHub reports grant errors with a 200 status
+			json.NewEncoder(w).Encode(map[string]string{"error": "bad_verification_code"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "github-access-token", "token_type": "bearer"})
+	})
+	mux.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer github-access-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"id": 583231, "login": "octocat", "email": nil})
+	})
+	mux.HandleFunc("/public/user", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"id": 583232, "login": "monalisa", "email": "mona@example.com"})
+	})
+	emails := []map[string]any{
+		{"email": "octocat@users.noreply.github.com", "primary": false, "verified": true},
+		{"email": "octocat@github.com", "primary": true, "verified": true},
+		{"email": "mona@example.com", "primary": false, "verified": false},
+	}
+	mux.HandleFunc("/user/emails", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(emails)
+	})
+	mux.HandleFunc("/public/user/emails", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(emails)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	github := oauth.NewGitHubOAuth(configs.OAuthProviderConfig{
+		Name:        oauth.ProviderGitHub,
+		ClientID:    "test-client-id",
+		RedirectURI: "http://localhost:8080/api/v1/auth/oauth/github/callback",
+		TokenURL:    server.URL + "/login/oauth/access_token",
+		UserInfoURL: server.URL + "/user",
+	}, newTestClient())
+
+	t.Run("GetAuthURL", func(t *testing.T) {
+		rawURL, err := github.GetAuthURL(context.Background(), "test-state", "test-challenge", "test-nonce")
+		require.NoError(t, err)
+		authURL, err := url.Parse(rawURL)
+		require.NoError(t, err)
+		assert.Equal(t, "github.com", authURL.Host)
+		assert.Equal(t, "test-state", authURL.Query().Get("state"))
+		assert.Equal(t, "test-challenge", authURL.Query().Get("code_challenge"))
+		assert.Equal(t, "read:user user:email", authURL.Query().Get("scope"))
+	})
+
+	t.Run("ExchangeCodeForToken", func(t *testing.T) {
+		token, err := github.ExchangeCodeForToken(context.Background(), "test-code", "test-verifier")
+		require.NoError(t, err)
+		assert.Equal(t, "github-access-token", token.AccessToken)
+
+		_, err = github.ExchangeCodeForToken(context.Background(), "wrong-code", "test-verifier")
+		assert.ErrorContains(t, err, "bad_verification_code")
+	})
+
+	t.Run("GetUserInfo", func(t *testing.T) {
+		info, err := github.GetUserInfo(context.Background(), "github-access-token")
+		require.NoError(t, err)
+		assert.Equal(t, "583231", info.ID)
+		assert.Equal(t, "octocat", info.Name)
+		// Private profile email falls back to the primary verified address
+		assert.Equal(t, "octocat@github.com", info.Email)
+		assert.True(t, info.EmailVerified)
+
+		// A public profile email is only verified if the email list says so
+		public := oauth.NewGitHubOAuth(configs.OAuthProviderConfig{
+			Name:        oauth.ProviderGitHub,
+			UserInfoURL: server.URL + "/public/user",
+		}, newTestClient())
+		info, err = public.GetUserInfo(context.Background(), "github-access-token")
+		require.NoError(t, err)
+		assert.Equal(t, "mona@example.com", info.Email)
+		assert.False(t, info.EmailVerified)
+	})
+}
+
+func TestRegistryFromConfig(t *testing.T) {
+	config := &configs.Config{}
+	config.OAuth.Providers = []configs.OAuthProviderConfig{
+		{Name: oauth.ProviderGoogle},
+		{Name: oauth.ProviderGitHub},
+	}
+
+	registry, err := oauth.NewRegistryFromConfig(config, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"github", "google"}, registry.Names())
+
+	provider, err := registry.Get("github")
+	require.NoError(t, err)
+	assert.Equal(t, "github", provider.Name())
+
+	_, err = registry.Get("facebook")
+	assert.ErrorIs(t, err, domain.ErrOAuthProviderNotSupported)
+
+	config.OAuth.Providers = append(config.OAuth.Providers, configs.OAuthProviderConfig{Name: "facebook"})
+	_, err = oauth.NewRegistryFromConfig(config, nil)
+	assert.ErrorIs(t, err, domain.ErrOAuthProviderNotSupported)
+}
+
+func TestRegistryFromConfigProxy(t *testing.T) {
+	config := &configs.Config{}
+	config.OAuth.Providers = []configs.OAuthProviderConfig{{Name: oauth.ProviderGitHub}}
+
+	config.OAuth.HTTP.ProxyURL = "http://proxy.internal:3128"
+	_, err := oauth.NewRegistryFromConfig(config, nil)
+	assert.NoError(t, err)
+
+	config.OAuth.HTTP.ProxyURL = "not a proxy"
+	_, err = oauth.NewRegistryFromConfig(config, nil)
+	assert.Error(t, err)
+}
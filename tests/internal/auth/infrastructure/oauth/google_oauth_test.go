@@ -0,0 +1,122 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/algosim/backend/configs"
+	"github.com/algosim/backend/internal/auth/infrastructure/oauth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGoogleOAuthPKCE(t *testing.T) {
+	verifier, err := oauth.GenerateCodeVerifier()
+	require.NoError(t, err)
+	challenge := oauth.CodeChallengeS256(verifier)
+
+	// Local fake of Google's token endpoint that enforces PKCE like the real one
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if r.PostForm.Get("code") != "test-code" || oauth.CodeChallengeS256(r.PostForm.Get("code_verifier")) != challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"access_token": "google-access-token",
+			"expires_in":   3600,
+			"token_type":   "Bearer",
+		})
+	}))
+	defer tokenServer.Close()
+
+	// Endpoints not configured are discovered from the issuer
+	issuer := newFakeIssuer(t)
+	googleOAuth := oauth.NewGoogleOAuth(configs.OAuthProviderConfig{
+		Name:         oauth.ProviderGoogle,
+		ClientID:     "test-client-id",
+		ClientSecret: "test-client-secret",
+		RedirectURI:  "http://localhost:8080/api/v1/auth/oauth/google/callback",
+		TokenURL:     tokenServer.URL,
+		Issuer:       issuer.server.URL,
+	}, newTestClient())
+
+	t.Run("CodeVerifier", func(t *testing.T) {
+		// RFC 7636 allows 43 to 128 unreserved characters
+		assert.GreaterOrEqual(t, len(verifier), 43)
+		assert.LessOrEqual(t, len(verifier), 128)
+		assert.Regexp(t, `^[A-Za-z0-9._~-]+$`, verifier)
+
+		other, err := oauth.GenerateCodeVerifier()
+		require.NoError(t, err)
+		assert.NotEqual(t, verifier, other)
+
+		// Test vector from RFC 7636 appendix B
+		assert.Equal(t, "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM",
+			oauth.CodeChallengeS256("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"))
+	})
+
+	t.Run("GetAuthURL", func(t *testing.T) {
+		rawURL, err := googleOAuth.GetAuthURL(context.Background(), "test-state", challenge, "test-nonce")
+		require.NoError(t, err)
+		authURL, err := url.Parse(rawURL)
+		require.NoError(t, err)
+
+		query := authURL.Query()
+		assert.Equal(t, "/authorize", authURL.Path)
+		assert.Equal(t, "test-state", query.Get("state"))
+		assert.Equal(t, "test-nonce", query.Get("nonce"))
+		assert.Equal(t, "offline", query.Get("access_type"))
+		assert.Equal(t, challenge, query.Get("code_challenge"))
+		assert.Equal(t, "S256", query.Get("code_challenge_method"))
+		assert.Empty(t, query.Get("code_verifier"))
+	})
+
+	t.Run("ExchangeCodeForToken", func(t *testing.T) {
+		token, err := googleOAuth.ExchangeCodeForToken(context.Background(), "test-code", verifier)
+		require.NoError(t, err)
+		assert.Equal(t, "google-access-token", token.AccessToken)
+
+		// A wrong verifier is rejected by the token endpoint
+		token, err = googleOAuth.ExchangeCodeForToken(context.Background(), "test-code", "wrong-verifier")
+		assert.Error(t, err)
+		assert.Nil(t, token)
+	})
+}
+
+func TestGoogleOAuthUserInfo(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		verified := r.Header.Get("Authorization") == "Bearer verified-token"
+		json.NewEncoder(w).Encode(map[string]any{
+			"sub":            "1234",
+			"email":          "user@gmail.com",
+			"email_verified": verified,
+			"name":           "Test User",
+		})
+	}))
+	defer server.Close()
+
+	googleOAuth := oauth.NewGoogleOAuth(configs.OAuthProviderConfig{
+		Name:        oauth.ProviderGoogle,
+		UserInfoURL: server.URL,
+	}, newTestClient())
+
+	info, err := googleOAuth.GetUserInfo(context.Background(), "verified-token")
+	require.NoError(t, err)
+	assert.Equal(t, "1234", info.ID)
+	assert.Equal(t, "user@gmail.com", info.Email)
+	assert.True(t, info.EmailVerified)
+
+	info, err = googleOAuth.GetUserInfo(context.Background(), "unverified-token")
+	require.NoError(t, err)
+	assert.False(t, info.EmailVerified)
+}
@@ -0,0 +1,363 @@
+package tests
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/algosim/backend/configs"
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/algosim/backend/internal/auth/infrastructure/oauth"
+	"github.com/algosim/backend/pkg/httpclient"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const fakeClientID = "test-client-id"
+
+// newTestClient returns a client without retries for calling local fakes
+func newTestClient() *httpclient.Client {
+	return httpclient.New("test", httpclient.Config{Timeout: 5 * time.Second}, nil)
+}
+
+// fakeIssuer is a local OpenID Connect issuer. Its token endpoint answers each code with
+// the ID token issued for it.
+type fakeIssuer struct {
+	server *httptest.Server
+
+	mu sync.Mutex
+	// signer signs new ID tokens under signerKID
+	signer    crypto.Signer
+	signerKID string
+	method    jwt.SigningMethod
+	// published are the keys served by the JWKS endpoint
+	published    map[string]crypto.PublicKey
+	idTokens     map[string]string
+	jwksRequests int
+	// discoveredIssuer overrides the issuer named in the discovery document
+	discoveredIssuer string
+	// jwksArrived and jwksRelease, when set, hold JWKS requests until the test lets them go
+	jwksArrived chan struct{}
+	jwksRelease chan struct{}
+}
+
+func newFakeIssuer(t *testing.T) *fakeIssuer {
+	f := &fakeIssuer{
+		published: make(map[string]crypto.PublicKey),
+		idTokens:  make(map[string]string),
+	}
+	f.rotate(t, "key-1", newRSAKey(t), jwt.SigningMethodRS256)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		issuer := f.discoveredIssuer
+		f.mu.Unlock()
+		if issuer == "" {
+			issuer = f.server.URL
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"issuer":                 issuer,
+			"authorization_endpoint": f.server.URL + "/authorize",
+			"token_endpoint":         f.server.URL + "/token",
+			"userinfo_endpoint":      f.server.URL + "/userinfo",
+			"jwks_uri":               f.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		arrived, release := f.jwksArrived, f.jwksRelease
+		f.mu.Unlock()
+		if arrived != nil {
+			arrived <- struct{}{}
+			<-release
+		}
+
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.jwksRequests++
+
+		keys := []map[string]string{}
+		for kid, key := range f.published {
+			keys = append(keys, publicJWK(kid, key))
+		}
+		json.NewEncoder(w).Encode(map[string]any{"keys": keys})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.mu.Lock()
+		idToken, ok := f.idTokens[r.PostForm.Get("code")]
+		f.mu.Unlock()
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"access_token": "issuer-access-token",
+			"id_token":     idToken,
+			"expires_in":   3600,
+			"token_type":   "Bearer",
+		})
+	})
+	f.server = httptest.NewServer(mux)
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+func newRSAKey(t *testing.T) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	return key
+}
+
+// rotate publishes a new key and signs with it from now on
+func (f *fakeIssuer) rotate(t *testing.T, kid string, signer crypto.Signer, method jwt.SigningMethod) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.signer, f.signerKID, f.method = signer, kid, method
+	f.published[kid] = signer.Public()
+}
+
+// claims returns valid ID token claims for the nonce
+func (f *fakeIssuer) claims(nonce string) jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss":            f.server.URL,
+		"aud":            fakeClientID,
+		"sub":            "subject-1",
+		"iat":            now.Unix(),
+		"exp":            now.Add(time.Hour).Unix(),
+		"nonce":          nonce,
+		"email":          "user@example.com",
+		"email_verified": true,
+		"name":           "Test User",
+	}
+}
+
+// issue makes code redeem for an ID token with claims, signed by the current key
+func (f *fakeIssuer) issue(t *testing.T, code string, claims jwt.MapClaims) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	token := jwt.NewWithClaims(f.method, claims)
+	token.Header["kid"] = f.signerKID
+	signed, err := token.SignedString(f.signer)
+	require.NoError(t, err)
+	f.idTokens[code] = signed
+}
+
+func (f *fakeIssuer) provider() *oauth.OIDCProvider {
+	return oauth.NewOIDCProvider(configs.OAuthProviderConfig{
+		Name:     "acme",
+		ClientID: fakeClientID,
+		Issuer:   f.server.URL,
+	}, newTestClient())
+}
+
+// publicJWK encodes a public key as an RFC 7517 JSON Web Key
+func publicJWK(kid string, key crypto.PublicKey) map[string]string {
+	encode := base64.RawURLEncoding.EncodeToString
+	switch pub := key.(type) {
+	case *rsa.PublicKey:
+		return map[string]string{"kty": "RSA", "kid": kid, "use": "sig", "n": encode(pub.N.Bytes()), "e": encode(big.NewInt(int64(pub.E)).Bytes())}
+	case *ecdsa.PublicKey:
+		return map[string]string{"kty": "EC", "kid": kid, "use": "sig", "crv": "P-256", "x": encode(pub.X.FillBytes(make([]byte, 32))), "y": encode(pub.Y.FillBytes(make([]byte, 32)))}
+	}
+	return nil
+}
+
+func TestOIDCProvider(t *testing.T) {
+	issuer := newFakeIssuer(t)
+
+	t.Run("Discovery", func(t *testing.T) {
+		config := &configs.Config{}
+		config.OAuth.Providers = []configs.OAuthProviderConfig{{Name: "acme", ClientID: fakeClientID, Issuer: issuer.server.URL + "/"}}
+		registry, err := oauth.NewRegistryFromConfig(config, nil)
+		require.NoError(t, err)
+
+		provider, err := registry.Get("acme")
+		require.NoError(t, err)
+		_, ok := provider.(oauth.IDTokenProvider)
+		assert.True(t, ok)
+
+		rawURL, err := provider.GetAuthURL(context.Background(), "test-state", "test-challenge", "test-nonce")
+		require.NoError(t, err)
+		authURL, err := url.Parse(rawURL)
+		require.NoError(t, err)
+		assert.Equal(t, issuer.server.URL+"/authorize", authURL.Scheme+"://"+authURL.Host+authURL.Path)
+		assert.Equal(t, "test-nonce", authURL.Query().Get("nonce"))
+		assert.Equal(t, "openid email profile", authURL.Query().Get("scope"))
+		assert.Equal(t, "S256", authURL.Query().Get("code_challenge_method"))
+	})
+
+	t.Run("Valid ID Token", func(t *testing.T) {
+		claims := issuer.claims("nonce-1")
+		// Some issuers send the flag as a string
+		claims["email_verified"] = "true"
+		issuer.issue(t, "code-1", claims)
+
+		info, err := issuer.provider().ExchangeCodeForIDToken(context.Background(), "code-1", "verifier", "nonce-1")
+		require.NoError(t, err)
+		assert.Equal(t, "subject-1", info.ID)
+		assert.Equal(t, "user@example.com", info.Email)
+		assert.True(t, info.EmailVerified)
+		assert.Equal(t, "Test User", info.Name)
+	})
+
+	t.Run("Rejected ID Tokens", func(t *testing.T) {
+		provider := issuer.provider()
+
+		for name, mutate := range map[string]func(jwt.MapClaims){
+			"wrong nonce":    func(c jwt.MapClaims) { c["nonce"] = "other" },
+			"no nonce":       func(c jwt.MapClaims) { delete(c, "nonce") },
+			"wrong audience": func(c jwt.MapClaims) { c["aud"] = "other-client" },
+			"wrong issuer":   func(c jwt.MapClaims) { c["iss"] = "https://evil.example" },
+			"expired":        func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() },
+			"no expiry":      func(c jwt.MapClaims) { delete(c, "exp") },
+			"issued later":   func(c jwt.MapClaims) { c["iat"] = time.Now().Add(time.Hour).Unix() },
+			"no subject":     func(c jwt.MapClaims) { delete(c, "sub") },
+			"foreign azp":    func(c jwt.MapClaims) { c["aud"] = []string{fakeClientID, "other-client"}; c["azp"] = "other-client" },
+		} {
+			claims := issuer.claims("nonce-2")
+			mutate(claims)
+			issuer.issue(t, "code-2", claims)
+
+			_, err := provider.ExchangeCodeForIDToken(context.Background(), "code-2", "verifier", "nonce-2")
+			assert.ErrorIs(t, err, domain.ErrInvalidIDToken, name)
+		}
+
+		// Signed by a key the issuer never published
+		unpublished := newRSAKey(t)
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, issuer.claims("nonce-2"))
+		token.Header["kid"] = "key-1"
+		forged, err := token.SignedString(unpublished)
+		require.NoError(t, err)
+		issuer.mu.Lock()
+		issuer.idTokens["code-2"] = forged
+		issuer.mu.Unlock()
+		_, err = provider.ExchangeCodeForIDToken(context.Background(), "code-2", "verifier", "nonce-2")
+		assert.ErrorIs(t, err, domain.ErrInvalidIDToken)
+
+		// HMAC is never accepted
+		hs := jwt.NewWithClaims(jwt.SigningMethodHS256, issuer.claims("nonce-2"))
+		hs.Header["kid"] = "key-1"
+		hsString, err := hs.SignedString([]byte(fakeClientID))
+		require.NoError(t, err)
+		issuer.mu.Lock()
+		issuer.idTokens["code-2"] = hsString
+		issuer.mu.Unlock()
+		_, err = provider.ExchangeCodeForIDToken(context.Background(), "code-2", "verifier", "nonce-2")
+		assert.ErrorIs(t, err, domain.ErrInvalidIDToken)
+	})
+
+	t.Run("Key Rotation", func(t *testing.T) {
+		provider := issuer.provider()
+
+		issuer.issue(t, "code-3", issuer.claims("nonce-3"))
+		_, err := provider.ExchangeCodeForIDToken(context.Background(), "code-3", "verifier", "nonce-3")
+		require.NoError(t, err)
+
+		issuer.mu.Lock()
+		fetched := issuer.jwksRequests
+		issuer.mu.Unlock()
+
+		// Known keys come from the cache
+		_, err = provider.ExchangeCodeForIDToken(context.Background(), "code-3", "verifier", "nonce-3")
+		require.NoError(t, err)
+		issuer.mu.Lock()
+		assert.Equal(t, fetched, issuer.jwksRequests)
+		issuer.mu.Unlock()
+
+		// A new kid refreshes the key set, EC keys work too
+		ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		issuer.rotate(t, "key-2", ecKey, jwt.SigningMethodES256)
+		issuer.issue(t, "code-4", issuer.claims("nonce-4"))
+
+		_, err = provider.ExchangeCodeForIDToken(context.Background(), "code-4", "verifier", "nonce-4")
+		require.NoError(t, err)
+		issuer.mu.Lock()
+		assert.Equal(t, fetched+1, issuer.jwksRequests)
+		issuer.mu.Unlock()
+	})
+
+	t.Run("Slow Key Fetch Does Not Block Logins", func(t *testing.T) {
+		provider := issuer.provider()
+		_, err := provider.GetAuthURL(context.Background(), "test-state", "test-challenge", "test-nonce")
+		require.NoError(t, err)
+
+		issuer.mu.Lock()
+		issuer.jwksArrived, issuer.jwksRelease = make(chan struct{}), make(chan struct{})
+		arrived, release := issuer.jwksArrived, issuer.jwksRelease
+		issuer.mu.Unlock()
+		defer func() {
+			issuer.mu.Lock()
+			issuer.jwksArrived, issuer.jwksRelease = nil, nil
+			issuer.mu.Unlock()
+		}()
+
+		issuer.issue(t, "code-6", issuer.claims("nonce-6"))
+		verified := make(chan error, 1)
+		go func() {
+			_, err := provider.ExchangeCodeForIDToken(context.Background(), "code-6", "verifier", "nonce-6")
+			verified <- err
+		}()
+		<-arrived
+
+		// Another login goes ahead while the key set is on its way
+		started := make(chan error, 1)
+		go func() {
+			_, err := provider.GetAuthURL(context.Background(), "test-state", "test-challenge", "test-nonce")
+			started <- err
+		}()
+		select {
+		case err := <-started:
+			assert.NoError(t, err)
+		case <-time.After(2 * time.Second):
+			t.Error("login waited for the key fetch")
+		}
+
+		close(release)
+		assert.NoError(t, <-verified)
+	})
+
+	t.Run("No ID Token", func(t *testing.T) {
+		issuer.mu.Lock()
+		issuer.idTokens["code-5"] = ""
+		issuer.mu.Unlock()
+
+		_, err := issuer.provider().ExchangeCodeForIDToken(context.Background(), "code-5", "verifier", "nonce-5")
+		assert.ErrorIs(t, err, domain.ErrInvalidIDToken)
+	})
+
+	t.Run("Discovery Issuer Mismatch", func(t *testing.T) {
+		issuer.mu.Lock()
+		issuer.discoveredIssuer = "https://evil.example"
+		issuer.mu.Unlock()
+		defer func() {
+			issuer.mu.Lock()
+			issuer.discoveredIssuer = ""
+			issuer.mu.Unlock()
+		}()
+
+		_, err := issuer.provider().GetAuthURL(context.Background(), "test-state", "test-challenge", "test-nonce")
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,89 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/algosim/backend/configs"
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/algosim/backend/internal/auth/infrastructure/password"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArgon2idHasher(t *testing.T) {
+	config := &configs.Config{}
+	config.Password.Argon2Memory = 1024
+	config.Password.Argon2Iterations = 1
+	config.Password.Argon2Parallelism = 1
+	hasher := password.NewArgon2idHasher(config)
+
+	t.Run("HashAndVerify", func(t *testing.T) {
+		hash, err := hasher.Hash("correct horse battery staple")
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$"))
+		assert.NotContains(t, hash, "correct horse")
+
+		ok, err := hasher.Verify("correct horse battery staple", hash)
+		require.NoError(t, err)
+		assert.True(t, ok)
+
+		ok, err = hasher.Verify("wrong horse battery staple", hash)
+		require.NoError(t, err)
+		assert.False(t, ok)
+
+		// Salted, so the same password hashes differently
+		other, err := hasher.Hash("correct horse battery staple")
+		require.NoError(t, err)
+		assert.NotEqual(t, hash, other)
+	})
+
+	t.Run("CostChange", func(t *testing.T) {
+		hash, err := hasher.Hash("correct horse battery staple")
+		require.NoError(t, err)
+		assert.False(t, hasher.NeedsRehash(hash))
+
+		stronger := *config
+		stronger.Password.Argon2Iterations = 2
+		strongerHasher := password.NewArgon2idHasher(&stronger)
+
+		// Old hashes still verify with the cost they were made with
+		ok, err := strongerHasher.Verify("correct horse battery staple", hash)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.True(t, strongerHasher.NeedsRehash(hash))
+	})
+
+	t.Run("MalformedHash", func(t *testing.T) {
+		for _, encoded := range []string{"", "plain", "$2a$10$bcrypt", "$argon2id$v=19$m=1024,t=0,p=1$c2FsdA$a2V5"} {
+			_, err := hasher.Verify("password", encoded)
+			assert.Error(t, err, encoded)
+		}
+	})
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		valid    bool
+	}{
+		{"Strong", "correct horse battery staple", true},
+		{"TooShort", "Sh0rt!", false},
+		{"TooLong", strings.Repeat("ab1!", 40), false},
+		{"Common", "Password123", false},
+		{"RepeatedCharacters", "aaaaaaaaaaaaaaab", false},
+		{"ContainsEmail", "alice-loves-graphs", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := password.Validate(tt.password, "alice@example.com", 10)
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, domain.ErrWeakPassword)
+			}
+		})
+	}
+}
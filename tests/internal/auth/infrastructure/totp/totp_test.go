@@ -0,0 +1,94 @@
+package tests
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/algosim/backend/internal/auth/infrastructure/totp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rfcSecret is the SHA1 secret "12345678901234567890" of the RFC 6238 test vectors in base32
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCode(t *testing.T) {
+	// The RFC lists eight digit codes, six digit codes are their last six digits
+	for unix, expected := range map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1111111111: "050471",
+		1234567890: "005924",
+		2000000000: "279037",
+	} {
+		code, err := totp.Code(rfcSecret, totp.Step(time.Unix(unix, 0)))
+		require.NoError(t, err)
+		assert.Equal(t, expected, code, unix)
+	}
+
+	_, err := totp.Code("not base32!", 1)
+	assert.Error(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := totp.GenerateSecret()
+	require.NoError(t, err)
+	now := time.Now()
+	step := totp.Step(now)
+
+	code := func(step int64) string {
+		c, err := totp.Code(secret, step)
+		require.NoError(t, err)
+		return c
+	}
+
+	t.Run("Current And Neighbouring Steps", func(t *testing.T) {
+		for _, s := range []int64{step - 1, step, step + 1} {
+			matched, ok := totp.Validate(secret, code(s), now, 0)
+			assert.True(t, ok)
+			assert.Equal(t, s, matched)
+		}
+
+		_, ok := totp.Validate(secret, code(step-2), now, 0)
+		assert.False(t, ok)
+		_, ok = totp.Validate(secret, code(step+2), now, 0)
+		assert.False(t, ok)
+	})
+
+	t.Run("Used Steps Are Refused", func(t *testing.T) {
+		_, ok := totp.Validate(secret, code(step), now, step)
+		assert.False(t, ok)
+		_, ok = totp.Validate(secret, code(step-1), now, step)
+		assert.False(t, ok)
+
+		matched, ok := totp.Validate(secret, code(step+1), now, step)
+		assert.True(t, ok)
+		assert.Equal(t, step+1, matched)
+	})
+
+	t.Run("Malformed Codes", func(t *testing.T) {
+		c := code(step)
+		matched, ok := totp.Validate(secret, c[:3]+" "+c[3:], now, 0)
+		assert.True(t, ok)
+		assert.Equal(t, step, matched)
+
+		for _, malformed := range []string{"", "12345", "1234567", "abcdef"} {
+			_, ok := totp.Validate(secret, malformed, now, 0)
+			assert.False(t, ok, malformed)
+		}
+	})
+}
+
+func TestProvisioningURI(t *testing.T) {
+	uri, err := url.Parse(totp.ProvisioningURI("Algosim", "alice@example.com", rfcSecret))
+	require.NoError(t, err)
+
+	assert.Equal(t, "otpauth", uri.Scheme)
+	assert.Equal(t, "totp", uri.Host)
+	assert.Equal(t, "/Algosim:alice@example.com", uri.Path)
+	assert.Equal(t, rfcSecret, uri.Query().Get("secret"))
+	assert.Equal(t, "Algosim", uri.Query().Get("issuer"))
+	assert.Equal(t, "6", uri.Query().Get("digits"))
+	assert.Equal(t, "30", uri.Query().Get("period"))
+}
@@ -0,0 +1,198 @@
+package usecase
+
+import (
+	"testing"
+	"time"
+
+	"github.com/algosim/backend/configs"
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/algosim/backend/internal/auth/infrastructure/db/memory"
+	"github.com/algosim/backend/internal/auth/infrastructure/events"
+	"github.com/algosim/backend/internal/auth/infrastructure/oauth"
+	"github.com/algosim/backend/internal/auth/usecase"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingHook is a UserDataHook remembering which users it was asked about
+type recordingHook struct {
+	deleted []uuid.UUID
+}
+
+func (h *recordingHook) Name() string { return "recording" }
+
+func (h *recordingHook) ExportUserData(userID uuid.UUID) (any, error) {
+	return map[string]string{"user_id": userID.String()}, nil
+}
+
+func (h *recordingHook) DeleteUserData(userID uuid.UUID) error {
+	h.deleted = append(h.deleted, userID)
+	return nil
+}
+
+func TestAccountDeletion(t *testing.T) {
+	config := &configs.Config{}
+	config.Auth.JWTSecret = "test-secret-key-123"
+	config.Auth.TokenTTL = 3600
+	config.Auth.RefreshTokenSecret = "test-refresh-secret"
+	config.Account.DeletionGracePeriod = 3600
+	config.Account.ReauthWindow = 300
+	config.Password.MinLength = 10
+	config.Password.Argon2Memory = 1024
+	config.Password.Argon2Iterations = 1
+	config.Password.Argon2Parallelism = 1
+
+	userRepo := memory.NewUserRepoMemo()
+	tokenRepo := memory.NewTokenRepoMemo()
+	authUseCase := usecase.NewAuthUseCase(userRepo, tokenRepo, memory.NewIdentityRepoMemo(), memory.NewOAuthStateRepoMemo(), memory.NewMFARepoMemo(), memory.NewMFAChallengeRepoMemo(), oauth.NewRegistry(), events.NewLogPublisher(), newJWTManager(t, config), config)
+	userUseCase := usecase.NewUserUseCase(userRepo)
+	hook := &recordingHook{}
+	userUseCase.RegisterDataHook(authUseCase.SessionDataHook())
+	userUseCase.RegisterDataHook(hook)
+
+	const password = "correct horse battery staple"
+
+	t.Run("Requires Password", func(t *testing.T) {
+		token, err := registerAndLogin(t, authUseCase, "alice@example.com", password, domain.ClientInfo{})
+		require.NoError(t, err)
+
+		_, err = authUseCase.RequestAccountDeletion(token.UserID, token.FamilyID, "")
+		assert.ErrorIs(t, err, domain.ErrReauthenticationRequired)
+		_, err = authUseCase.RequestAccountDeletion(token.UserID, token.FamilyID, "wrong password")
+		assert.ErrorIs(t, err, domain.ErrInvalidCredentials)
+
+		user, err := userUseCase.GetUser(token.UserID)
+		require.NoError(t, err)
+		assert.False(t, user.IsDeletionScheduled())
+	})
+
+	t.Run("Requires Fresh Session Without Password", func(t *testing.T) {
+		user := domain.NewUser("oauth@example.com")
+		require.NoError(t, userRepo.Create(user))
+		token, err := registerAndLogin(t, authUseCase, "other@example.com", password, domain.ClientInfo{})
+		require.NoError(t, err)
+
+		// Another user's session does not count
+		_, err = authUseCase.RequestAccountDeletion(user.ID, token.FamilyID, "")
+		assert.ErrorIs(t, err, domain.ErrReauthenticationRequired)
+
+		session := domain.NewToken(user.ID, "access", "refresh", "oauth-session-hash", time.Now().Add(time.Hour))
+		session.SessionStartedAt = time.Now().Add(-10 * time.Minute)
+		require.NoError(t, tokenRepo.Create(session))
+		_, err = authUseCase.RequestAccountDeletion(user.ID, session.FamilyID, "")
+		assert.ErrorIs(t, err, domain.ErrReauthenticationRequired)
+
+		fresh := domain.NewToken(user.ID, "access", "refresh", "oauth-fresh-hash", time.Now().Add(time.Hour))
+		require.NoError(t, tokenRepo.Create(fresh))
+		_, err = authUseCase.RequestAccountDeletion(user.ID, fresh.FamilyID, "")
+		assert.NoError(t, err)
+	})
+
+	t.Run("Grace Period And Purge", func(t *testing.T) {
+		token, err := registerAndLogin(t, authUseCase, "bob@example.com", password, domain.ClientInfo{})
+		require.NoError(t, err)
+
+		user, err := authUseCase.RequestAccountDeletion(token.UserID, token.FamilyID, password)
+		require.NoError(t, err)
+		require.True(t, user.IsDeletionScheduled())
+		assert.WithinDuration(t, time.Now().Add(time.Hour), *user.DeletionScheduledAt, time.Minute)
+
+		// Every session is signed out at once
+		_, err = authUseCase.ValidateToken(token.AccessToken)
+		assert.Error(t, err)
+		_, err = authUseCase.RefreshToken(token.RefreshToken, domain.ClientInfo{})
+		assert.Error(t, err)
+
+		// Nothing is due before the grace period ends
+		deleted, err := userUseCase.PurgeScheduledDeletions(time.Now())
+		require.NoError(t, err)
+		assert.Zero(t, deleted)
+
+		// Signing in again cancels the deletion
+		_, err = passwordLogin(t, authUseCase, "bob@example.com", password, domain.ClientInfo{})
+		require.NoError(t, err)
+		user, err = userUseCase.GetUser(token.UserID)
+		require.NoError(t, err)
+		assert.False(t, user.IsDeletionScheduled())
+
+		login, err := passwordLogin(t, authUseCase, "bob@example.com", password, domain.ClientInfo{})
+		require.NoError(t, err)
+		_, err = authUseCase.RequestAccountDeletion(token.UserID, login.FamilyID, password)
+		require.NoError(t, err)
+
+		hook.deleted = nil
+		deleted, err = userUseCase.PurgeScheduledDeletions(time.Now().Add(2 * time.Hour))
+		require.NoError(t, err)
+		// The OAuth account scheduled above is due as well
+		assert.Equal(t, 2, deleted)
+		assert.Contains(t, hook.deleted, token.UserID)
+
+		_, err = userUseCase.GetUser(token.UserID)
+		assert.ErrorIs(t, err, domain.ErrUserNotFound)
+	})
+
+	t.Run("DeleteUser Cascades", func(t *testing.T) {
+		token, err := registerAndLogin(t, authUseCase, "carol@example.com", password, domain.ClientInfo{})
+		require.NoError(t, err)
+
+		require.NoError(t, userUseCase.DeleteUser(token.UserID))
+		tokens, err := tokenRepo.FindByUserID(token.UserID)
+		require.NoError(t, err)
+		assert.Empty(t, tokens)
+		assert.Contains(t, hook.deleted, token.UserID)
+	})
+
+	t.Run("Export", func(t *testing.T) {
+		token, err := registerAndLogin(t, authUseCase, "dave@example.com", password, domain.ClientInfo{UserAgent: "Firefox"})
+		require.NoError(t, err)
+
+		export, err := userUseCase.ExportUserData(token.UserID)
+		require.NoError(t, err)
+		assert.Equal(t, "dave@example.com", export.User.Email)
+		assert.True(t, export.User.HasPassword)
+
+		sessions, ok := export.Modules["sessions"].([]usecase.SessionExport)
+		require.True(t, ok)
+		require.Len(t, sessions, 1)
+		assert.Equal(t, "Firefox", sessions[0].UserAgent)
+		assert.Contains(t, export.Modules, "recording")
+
+		_, err = userUseCase.ExportUserData(uuid.New())
+		assert.ErrorIs(t, err, domain.ErrUserNotFound)
+	})
+}
+
+func TestPurgeScheduledDeletionsRaces(t *testing.T) {
+	now := time.Now()
+	due := now.Add(-time.Minute)
+	scheduled := func(email string) *domain.User {
+		user := domain.NewUser(email)
+		user.DeletionScheduledAt = &due
+		return user
+	}
+
+	// The list is stale by the time each account is purged
+	cancelled, purged, lost, deleted := scheduled("alice@example.com"), scheduled("bob@example.com"), scheduled("carol@example.com"), scheduled("dave@example.com")
+	userRepo := new(MockUserRepository)
+	userRepo.On("FindScheduledForDeletion", now).Return([]*domain.User{cancelled, purged, lost, deleted}, nil)
+	userRepo.On("FindByID", cancelled.ID).Return(domain.NewUser("alice@example.com"), nil)
+	userRepo.On("FindByID", purged.ID).Return(nil, domain.ErrUserNotFound)
+	userRepo.On("FindByID", lost.ID).Return(lost, nil)
+	userRepo.On("FindByID", deleted.ID).Return(deleted, nil)
+	// Cancelled after the read, the row stays
+	userRepo.On("DeleteScheduled", lost.ID, now).Return(domain.ErrUserNotFound)
+	userRepo.On("DeleteScheduled", deleted.ID, now).Return(nil)
+
+	userUseCase := usecase.NewUserUseCase(userRepo)
+	hook := &recordingHook{}
+	userUseCase.RegisterDataHook(hook)
+
+	count, err := userUseCase.PurgeScheduledDeletions(now)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, []uuid.UUID{lost.ID, deleted.ID}, hook.deleted)
+	userRepo.AssertExpectations(t)
+	userRepo.AssertNotCalled(t, "Delete", mock.Anything)
+}
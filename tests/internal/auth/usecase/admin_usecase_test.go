@@ -0,0 +1,121 @@
+package usecase
+
+import (
+	"testing"
+
+	"github.com/algosim/backend/configs"
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/algosim/backend/internal/auth/infrastructure/db/memory"
+	"github.com/algosim/backend/internal/auth/infrastructure/events"
+	"github.com/algosim/backend/internal/auth/infrastructure/oauth"
+	"github.com/algosim/backend/internal/auth/usecase"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminUseCase(t *testing.T) {
+	config := &configs.Config{}
+	config.Auth.JWTSecret = "test-secret-key-123"
+	config.Auth.TokenTTL = 3600
+	config.Auth.RefreshTokenSecret = "test-refresh-secret"
+	config.Auth.BootstrapAdminEmail = "root@example.com"
+	config.Password.MinLength = 10
+	config.Password.Argon2Memory = 1024
+	config.Password.Argon2Iterations = 1
+	config.Password.Argon2Parallelism = 1
+
+	userRepo := memory.NewUserRepoMemo()
+	jwtManager := newJWTManager(t, config)
+	publisher := events.NewLogPublisher()
+	authUseCase := usecase.NewAuthUseCase(userRepo, memory.NewTokenRepoMemo(), memory.NewIdentityRepoMemo(), memory.NewOAuthStateRepoMemo(), memory.NewMFARepoMemo(), memory.NewMFAChallengeRepoMemo(), oauth.NewRegistry(), publisher, jwtManager, config)
+	adminUseCase := usecase.NewAdminUseCase(userRepo, authUseCase, publisher)
+
+	const password = "correct horse battery staple"
+
+	root, err := registerAndLogin(t, authUseCase, "root@example.com", password, domain.ClientInfo{})
+	require.NoError(t, err)
+	alice, err := registerAndLogin(t, authUseCase, "alice@example.com", password, domain.ClientInfo{})
+	require.NoError(t, err)
+
+	t.Run("Bootstrap Admin", func(t *testing.T) {
+		claims, err := jwtManager.ValidateAccessToken(root.AccessToken)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{domain.RoleUser, domain.RoleAdmin}, claims.Roles)
+		assert.Contains(t, claims.Permissions, domain.PermissionRolesWrite)
+
+		claims, err = jwtManager.ValidateAccessToken(alice.AccessToken)
+		require.NoError(t, err)
+		assert.Equal(t, []string{domain.RoleUser}, claims.Roles)
+		assert.NotContains(t, claims.Permissions, domain.PermissionRolesWrite)
+	})
+
+	t.Run("GrantRole And RevokeRole", func(t *testing.T) {
+		_, err := adminUseCase.GrantRole(root.UserID, alice.UserID, "superuser")
+		assert.ErrorIs(t, err, domain.ErrInvalidRole)
+		_, err = adminUseCase.GrantRole(root.UserID, uuid.New(), domain.RoleCoach)
+		assert.ErrorIs(t, err, domain.ErrUserNotFound)
+
+		user, err := adminUseCase.GrantRole(root.UserID, alice.UserID, domain.RoleCoach)
+		require.NoError(t, err)
+		assert.True(t, user.HasRole(domain.RoleCoach))
+
+		// The next access token carries the role
+		login, err := passwordLogin(t, authUseCase, "alice@example.com", password, domain.ClientInfo{})
+		require.NoError(t, err)
+		claims, err := jwtManager.ValidateAccessToken(login.AccessToken)
+		require.NoError(t, err)
+		assert.Contains(t, claims.Roles, domain.RoleCoach)
+		assert.Contains(t, claims.Permissions, domain.PermissionGroupsWrite)
+
+		user, err = adminUseCase.RevokeRole(root.UserID, alice.UserID, domain.RoleCoach)
+		require.NoError(t, err)
+		assert.False(t, user.HasRole(domain.RoleCoach))
+
+		_, err = adminUseCase.RevokeRole(root.UserID, root.UserID, domain.RoleAdmin)
+		assert.ErrorIs(t, err, domain.ErrSelfModification)
+	})
+
+	t.Run("Bootstrap Only While No Admin Exists", func(t *testing.T) {
+		_, err := adminUseCase.GrantRole(root.UserID, alice.UserID, domain.RoleAdmin)
+		require.NoError(t, err)
+		_, err = adminUseCase.RevokeRole(alice.UserID, root.UserID, domain.RoleAdmin)
+		require.NoError(t, err)
+
+		// Alice is still an admin, so signing in does not promote root again
+		login, err := passwordLogin(t, authUseCase, "root@example.com", password, domain.ClientInfo{})
+		require.NoError(t, err)
+		claims, err := jwtManager.ValidateAccessToken(login.AccessToken)
+		require.NoError(t, err)
+		assert.NotContains(t, claims.Roles, domain.RoleAdmin)
+
+		_, err = adminUseCase.RevokeRole(root.UserID, alice.UserID, domain.RoleAdmin)
+		require.NoError(t, err)
+	})
+
+	t.Run("BanUser", func(t *testing.T) {
+		bob, err := registerAndLogin(t, authUseCase, "bob@example.com", password, domain.ClientInfo{})
+		require.NoError(t, err)
+
+		_, err = adminUseCase.BanUser(root.UserID, root.UserID)
+		assert.ErrorIs(t, err, domain.ErrSelfModification)
+
+		user, err := adminUseCase.BanUser(root.UserID, bob.UserID)
+		require.NoError(t, err)
+		assert.True(t, user.IsBanned())
+
+		// Existing tokens stop working and no new ones are issued
+		_, err = authUseCase.ValidateToken(bob.AccessToken)
+		assert.Error(t, err)
+		_, err = authUseCase.RefreshToken(bob.RefreshToken, domain.ClientInfo{})
+		assert.Error(t, err)
+		_, err = passwordLogin(t, authUseCase, "bob@example.com", password, domain.ClientInfo{})
+		assert.ErrorIs(t, err, domain.ErrUserBanned)
+
+		user, err = adminUseCase.UnbanUser(root.UserID, bob.UserID)
+		require.NoError(t, err)
+		assert.False(t, user.IsBanned())
+		_, err = passwordLogin(t, authUseCase, "bob@example.com", password, domain.ClientInfo{})
+		assert.NoError(t, err)
+	})
+}
@@ -1,16 +1,21 @@
 package usecase
 
 import (
+	"context"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/algosim/backend/configs"
 	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/algosim/backend/internal/auth/infrastructure/db/memory"
+	jwtinfra "github.com/algosim/backend/internal/auth/infrastructure/jwt"
 	"github.com/algosim/backend/internal/auth/infrastructure/oauth"
 	"github.com/algosim/backend/internal/auth/usecase"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 // MockUserRepository is a mock implementation of UserRepository
@@ -39,14 +44,30 @@ func (m *MockUserRepository) FindByEmail(email string) (*domain.User, error) {
 	return args.Get(0).(*domain.User), args.Error(1)
 }
 
-func (m *MockUserRepository) FindByOAuthProviderID(provider, providerID string) (*domain.User, error) {
-	args := m.Called(provider, providerID)
+func (m *MockUserRepository) FindByHandle(handle string) (*domain.User, error) {
+	args := m.Called(handle)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*domain.User), args.Error(1)
 }
 
+func (m *MockUserRepository) FindByRole(role string) ([]*domain.User, error) {
+	args := m.Called(role)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.User), args.Error(1)
+}
+
+func (m *MockUserRepository) FindScheduledForDeletion(now time.Time) ([]*domain.User, error) {
+	args := m.Called(now)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.User), args.Error(1)
+}
+
 func (m *MockUserRepository) Update(user *domain.User) error {
 	args := m.Called(user)
 	return args.Error(0)
@@ -57,6 +78,11 @@ func (m *MockUserRepository) Delete(id uuid.UUID) error {
 	return args.Error(0)
 }
 
+func (m *MockUserRepository) DeleteScheduled(id uuid.UUID, now time.Time) error {
+	args := m.Called(id, now)
+	return args.Error(0)
+}
+
 // MockTokenRepository is a mock implementation of TokenRepository
 type MockTokenRepository struct {
 	mock.Mock
@@ -83,14 +109,24 @@ func (m *MockTokenRepository) FindByUserID(userID uuid.UUID) ([]*domain.Token, e
 	return args.Get(0).([]*domain.Token), args.Error(1)
 }
 
-func (m *MockTokenRepository) FindByRefreshToken(refreshToken string) (*domain.Token, error) {
-	args := m.Called(refreshToken)
+func (m *MockTokenRepository) FindByRefreshTokenHash(refreshTokenHash string) (*domain.Token, error) {
+	args := m.Called(refreshTokenHash)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*domain.Token), args.Error(1)
 }
 
+func (m *MockTokenRepository) Update(token *domain.Token) error {
+	args := m.Called(token)
+	return args.Error(0)
+}
+
+func (m *MockTokenRepository) DeleteByFamilyID(familyID uuid.UUID) error {
+	args := m.Called(familyID)
+	return args.Error(0)
+}
+
 func (m *MockTokenRepository) Delete(id uuid.UUID) error {
 	args := m.Called(id)
 	return args.Error(0)
@@ -101,67 +137,105 @@ func (m *MockTokenRepository) DeleteByUserID(userID uuid.UUID) error {
 	return args.Error(0)
 }
 
-// MockGoogleOAuth is a mock implementation of Google OAuth
-type MockGoogleOAuth struct {
+// MockOAuthProvider is a mock implementation of an OAuth provider
+type MockOAuthProvider struct {
 	mock.Mock
+	name string
 }
 
-func (m *MockGoogleOAuth) GetAuthURL(state string) string {
-	args := m.Called(state)
-	return args.String(0)
+func (m *MockOAuthProvider) Name() string {
+	return m.name
 }
 
-func (m *MockGoogleOAuth) ExchangeCodeForToken(code string) (*domain.Token, error) {
-	args := m.Called(code)
+func (m *MockOAuthProvider) GetAuthURL(ctx context.Context, state, codeChallenge, nonce string) (string, error) {
+	args := m.Called(state, codeChallenge, nonce)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockOAuthProvider) ExchangeCodeForToken(ctx context.Context, code, codeVerifier string) (*domain.Token, error) {
+	args := m.Called(code, codeVerifier)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*domain.Token), args.Error(1)
 }
 
-func (m *MockGoogleOAuth) GetUserInfo(accessToken string) (*oauth.GoogleUserInfo, error) {
+func (m *MockOAuthProvider) GetUserInfo(ctx context.Context, accessToken string) (*oauth.UserInfo, error) {
 	args := m.Called(accessToken)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
-	return args.Get(0).(*oauth.GoogleUserInfo), args.Error(1)
+	return args.Get(0).(*oauth.UserInfo), args.Error(1)
+}
+
+// MockOIDCProvider is a mock OAuth provider that signs in with ID tokens
+type MockOIDCProvider struct {
+	MockOAuthProvider
+}
+
+func (m *MockOIDCProvider) ExchangeCodeForIDToken(ctx context.Context, code, codeVerifier, nonce string) (*oauth.UserInfo, error) {
+	args := m.Called(code, codeVerifier, nonce)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*oauth.UserInfo), args.Error(1)
+}
+
+// MockPublisher is a mock implementation of events.Publisher
+type MockPublisher struct {
+	mock.Mock
+}
+
+func (m *MockPublisher) Publish(event *domain.SecurityEvent) {
+	m.Called(event)
+}
+
+func newJWTManager(t *testing.T, config *configs.Config) *jwtinfra.JWTManager {
+	jwtManager, err := jwtinfra.NewJWTManager(config, memory.NewAccessTokenDenylistMemo())
+	require.NoError(t, err)
+	return jwtManager
+}
+
+// passwordLogin signs in a password account of a user without MFA
+func passwordLogin(t *testing.T, authUseCase *usecase.AuthUseCase, email, password string, client domain.ClientInfo) (*domain.Token, error) {
+	result, err := authUseCase.Login(email, password, client)
+	if err != nil {
+		return nil, err
+	}
+	require.Nil(t, result.MFA)
+	return result.Token, nil
 }
 
-func (m *MockGoogleOAuth) CreateUserFromGoogleInfo(info *oauth.GoogleUserInfo) *domain.User {
-	args := m.Called(info)
-	return args.Get(0).(*domain.User)
+// registerAndLogin creates a password account and signs it in
+func registerAndLogin(t *testing.T, authUseCase *usecase.AuthUseCase, email, password string, client domain.ClientInfo) (*domain.Token, error) {
+	if err := authUseCase.Register(email, password); err != nil {
+		return nil, err
+	}
+	return passwordLogin(t, authUseCase, email, password, client)
 }
 
 func TestAuthUseCase(t *testing.T) {
 	// Setup test configuration
-	config := &configs.Config{
-		Auth: struct {
-			JWTSecret string `mapstructure:"jwt_secret" env:"AUTH_JWT_SECRET"`
-			TokenTTL  int    `mapstructure:"token_ttl" env:"AUTH_TOKEN_TTL"`
-		}{
-			JWTSecret: "test-secret-key-123",
-			TokenTTL:  3600, // 1 hour
-		},
-		GoogleOAuth: struct {
-			ClientID     string   `mapstructure:"client_id" env:"GOOGLE_OAUTH_CLIENT_ID"`
-			ClientSecret string   `mapstructure:"client_secret" env:"GOOGLE_OAUTH_CLIENT_SECRET"`
-			RedirectURI  string   `mapstructure:"redirect_uri" env:"GOOGLE_OAUTH_REDIRECT_URI"`
-			Scopes       []string `mapstructure:"scopes"`
-		}{
-			ClientID:     "test-client-id",
-			ClientSecret: "test-client-secret",
-			RedirectURI:  "http://localhost:8080/callback",
-		},
-	}
+	config := &configs.Config{}
+	config.Auth.JWTSecret = "test-secret-key-123"
+	config.Auth.TokenTTL = 3600 // 1 hour
+	config.Auth.RefreshTokenSecret = "test-refresh-secret"
+	config.OAuth.StateSecret = "test-state-secret"
+	config.OAuth.StateTTL = 600
 
 	// Create test user
 	testUser := &domain.User{
-		ID:              uuid.New(),
-		Email:           "test@example.com",
-		OAuthProvider:   "google",
-		OAuthProviderID: "test-google-id",
-		CreatedAt:       time.Now(),
-		UpdatedAt:       time.Now(),
+		ID:        uuid.New(),
+		Email:     "test@example.com",
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	// linkedIdentities returns an identity repository where the Google account signs in as testUser
+	linkedIdentities := func(t *testing.T) *memory.IdentityRepoMemo {
+		identityRepo := memory.NewIdentityRepoMemo()
+		require.NoError(t, identityRepo.Create(domain.NewIdentity(testUser.ID, "google", "test-google-id", testUser.Email, true)))
+		return identityRepo
 	}
 
 	// Create test token
@@ -176,39 +250,55 @@ func TestAuthUseCase(t *testing.T) {
 	t.Run("InitiateOAuthLogin", func(t *testing.T) {
 		mockUserRepo := new(MockUserRepository)
 		mockTokenRepo := new(MockTokenRepository)
-		mockGoogleOAuth := new(MockGoogleOAuth)
-		authUseCase := usecase.NewAuthUseCase(mockUserRepo, mockTokenRepo, mockGoogleOAuth, config)
+		mockGoogleOAuth := &MockOAuthProvider{name: "google"}
+		authUseCase := usecase.NewAuthUseCase(mockUserRepo, mockTokenRepo, memory.NewIdentityRepoMemo(), memory.NewOAuthStateRepoMemo(), memory.NewMFARepoMemo(), memory.NewMFAChallengeRepoMemo(), oauth.NewRegistry(mockGoogleOAuth), new(MockPublisher), newJWTManager(t, config), config)
 
-		state := "test-state"
-		expectedURL := "https://accounts.google.com/o/oauth2/v2/auth?client_id=test-client-id&state=test-state"
-		mockGoogleOAuth.On("GetAuthURL", state).Return(expectedURL)
+		expectedURL := "https://accounts.google.com/o/oauth2/v2/auth?client_id=test-client-id"
 
-		authURL := authUseCase.InitiateOAuthLogin(state)
-		assert.Equal(t, expectedURL, authURL)
-		mockGoogleOAuth.AssertExpectations(t)
+		_, err := authUseCase.InitiateOAuthLogin(context.Background(), "facebook")
+		assert.ErrorIs(t, err, domain.ErrOAuthProviderNotSupported)
+		mockGoogleOAuth.On("GetAuthURL", mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(expectedURL, nil)
+
+		login, err := authUseCase.InitiateOAuthLogin(context.Background(), "google")
+		assert.NoError(t, err)
+		assert.Equal(t, expectedURL, login.AuthURL)
+		assert.NotEmpty(t, login.State)
+		assert.NotEmpty(t, login.Binding)
+		assert.True(t, login.ExpiresAt.After(time.Now()))
+		mockGoogleOAuth.AssertCalled(t, "GetAuthURL", login.State, mock.AnythingOfType("string"), mock.AnythingOfType("string"))
+
+		// Every login gets its own state
+		other, err := authUseCase.InitiateOAuthLogin(context.Background(), "google")
+		assert.NoError(t, err)
+		assert.NotEqual(t, login.State, other.State)
 	})
 
 	t.Run("HandleOAuthCallback - New User", func(t *testing.T) {
 		mockUserRepo := new(MockUserRepository)
 		mockTokenRepo := new(MockTokenRepository)
-		mockGoogleOAuth := new(MockGoogleOAuth)
-		authUseCase := usecase.NewAuthUseCase(mockUserRepo, mockTokenRepo, mockGoogleOAuth, config)
+		mockGoogleOAuth := &MockOAuthProvider{name: "google"}
+		authUseCase := usecase.NewAuthUseCase(mockUserRepo, mockTokenRepo, memory.NewIdentityRepoMemo(), memory.NewOAuthStateRepoMemo(), memory.NewMFARepoMemo(), memory.NewMFAChallengeRepoMemo(), oauth.NewRegistry(mockGoogleOAuth), new(MockPublisher), newJWTManager(t, config), config)
 
 		// Setup expectations
-		mockGoogleOAuth.On("ExchangeCodeForToken", "test-code").Return(testToken, nil)
-		mockGoogleOAuth.On("GetUserInfo", testToken.AccessToken).Return(&oauth.GoogleUserInfo{
-			ID:            "test-google-id",
-			Email:         "test@example.com",
-			VerifiedEmail: true,
-			Name:          "Test User",
+		mockGoogleOAuth.On("GetAuthURL", mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return("", nil)
+		mockGoogleOAuth.On("ExchangeCodeForToken", "test-code", mock.AnythingOfType("string")).Return(testToken, nil)
+		mockGoogleOAuth.On("GetUserInfo", testToken.AccessToken).Return(&oauth.UserInfo{
+			ID:    "test-google-id",
+			Email: "test@example.com",
+			Name:  "Test User",
 		}, nil)
-		mockUserRepo.On("FindByOAuthProviderID", "google", "test-google-id").Return(nil, assert.AnError)
+		mockUserRepo.On("FindByEmail", "test@example.com").Return(nil, assert.AnError)
 		mockUserRepo.On("Create", mock.AnythingOfType("*domain.User")).Return(nil)
 		mockTokenRepo.On("Create", mock.AnythingOfType("*domain.Token")).Return(nil)
 
+		login, err := authUseCase.InitiateOAuthLogin(context.Background(), "google")
+		assert.NoError(t, err)
+
 		// Test with mock Google OAuth response
-		token, err := authUseCase.HandleOAuthCallback("test-code")
+		result, err := authUseCase.HandleOAuthCallback(context.Background(), "google", "test-code", login.State, login.Binding, domain.ClientInfo{})
 		assert.NoError(t, err)
+		require.NotNil(t, result)
+		token := result.Token
 		assert.NotNil(t, token)
 		assert.NotEmpty(t, token.AccessToken)
 		assert.NotEmpty(t, token.RefreshToken)
@@ -221,23 +311,28 @@ func TestAuthUseCase(t *testing.T) {
 	t.Run("HandleOAuthCallback - Existing User", func(t *testing.T) {
 		mockUserRepo := new(MockUserRepository)
 		mockTokenRepo := new(MockTokenRepository)
-		mockGoogleOAuth := new(MockGoogleOAuth)
-		authUseCase := usecase.NewAuthUseCase(mockUserRepo, mockTokenRepo, mockGoogleOAuth, config)
+		mockGoogleOAuth := &MockOAuthProvider{name: "google"}
+		authUseCase := usecase.NewAuthUseCase(mockUserRepo, mockTokenRepo, linkedIdentities(t), memory.NewOAuthStateRepoMemo(), memory.NewMFARepoMemo(), memory.NewMFAChallengeRepoMemo(), oauth.NewRegistry(mockGoogleOAuth), new(MockPublisher), newJWTManager(t, config), config)
 
 		// Setup expectations
-		mockGoogleOAuth.On("ExchangeCodeForToken", "test-code").Return(testToken, nil)
-		mockGoogleOAuth.On("GetUserInfo", testToken.AccessToken).Return(&oauth.GoogleUserInfo{
-			ID:            "test-google-id",
-			Email:         "test@example.com",
-			VerifiedEmail: true,
-			Name:          "Test User",
+		mockGoogleOAuth.On("GetAuthURL", mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return("", nil)
+		mockGoogleOAuth.On("ExchangeCodeForToken", "test-code", mock.AnythingOfType("string")).Return(testToken, nil)
+		mockGoogleOAuth.On("GetUserInfo", testToken.AccessToken).Return(&oauth.UserInfo{
+			ID:    "test-google-id",
+			Email: "test@example.com",
+			Name:  "Test User",
 		}, nil)
-		mockUserRepo.On("FindByOAuthProviderID", "google", "test-google-id").Return(testUser, nil)
+		mockUserRepo.On("FindByID", testUser.ID).Return(testUser, nil)
 		mockTokenRepo.On("Create", mock.AnythingOfType("*domain.Token")).Return(nil)
 
+		login, err := authUseCase.InitiateOAuthLogin(context.Background(), "google")
+		assert.NoError(t, err)
+
 		// Test with mock Google OAuth response
-		token, err := authUseCase.HandleOAuthCallback("test-code")
+		result, err := authUseCase.HandleOAuthCallback(context.Background(), "google", "test-code", login.State, login.Binding, domain.ClientInfo{})
 		assert.NoError(t, err)
+		require.NotNil(t, result)
+		token := result.Token
 		assert.NotNil(t, token)
 		assert.NotEmpty(t, token.AccessToken)
 		assert.NotEmpty(t, token.RefreshToken)
@@ -247,20 +342,142 @@ func TestAuthUseCase(t *testing.T) {
 		mockGoogleOAuth.AssertExpectations(t)
 	})
 
+	t.Run("HandleOAuthCallback - PKCE Verifier", func(t *testing.T) {
+		mockUserRepo := new(MockUserRepository)
+		mockTokenRepo := new(MockTokenRepository)
+		mockGoogleOAuth := &MockOAuthProvider{name: "google"}
+		authUseCase := usecase.NewAuthUseCase(mockUserRepo, mockTokenRepo, linkedIdentities(t), memory.NewOAuthStateRepoMemo(), memory.NewMFARepoMemo(), memory.NewMFAChallengeRepoMemo(), oauth.NewRegistry(mockGoogleOAuth), new(MockPublisher), newJWTManager(t, config), config)
+
+		var challenge, verifier string
+		mockGoogleOAuth.On("GetAuthURL", mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("string")).
+			Run(func(args mock.Arguments) { challenge = args.String(1) }).Return("", nil)
+		mockGoogleOAuth.On("ExchangeCodeForToken", "test-code", mock.AnythingOfType("string")).
+			Run(func(args mock.Arguments) { verifier = args.String(1) }).Return(testToken, nil)
+		mockGoogleOAuth.On("GetUserInfo", testToken.AccessToken).Return(&oauth.UserInfo{ID: "test-google-id"}, nil)
+		mockUserRepo.On("FindByID", testUser.ID).Return(testUser, nil)
+		mockTokenRepo.On("Create", mock.AnythingOfType("*domain.Token")).Return(nil)
+
+		login, err := authUseCase.InitiateOAuthLogin(context.Background(), "google")
+		assert.NoError(t, err)
+		_, err = authUseCase.HandleOAuthCallback(context.Background(), "google", "test-code", login.State, login.Binding, domain.ClientInfo{})
+		assert.NoError(t, err)
+
+		// The verifier stays server-side and matches the challenge sent to the provider
+		assert.NotEmpty(t, verifier)
+		assert.Equal(t, oauth.CodeChallengeS256(verifier), challenge)
+		assert.NotContains(t, login.Binding, verifier)
+	})
+
+	t.Run("HandleOAuthCallback - ID Token", func(t *testing.T) {
+		mockUserRepo := new(MockUserRepository)
+		mockTokenRepo := new(MockTokenRepository)
+		mockGoogleOAuth := &MockOIDCProvider{MockOAuthProvider{name: "google"}}
+		authUseCase := usecase.NewAuthUseCase(mockUserRepo, mockTokenRepo, linkedIdentities(t), memory.NewOAuthStateRepoMemo(), memory.NewMFARepoMemo(), memory.NewMFAChallengeRepoMemo(), oauth.NewRegistry(mockGoogleOAuth), new(MockPublisher), newJWTManager(t, config), config)
+
+		var sentNonce, checkedNonce string
+		mockGoogleOAuth.On("GetAuthURL", mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("string")).
+			Run(func(args mock.Arguments) { sentNonce = args.String(2) }).Return("", nil)
+		mockGoogleOAuth.On("ExchangeCodeForIDToken", "test-code", mock.AnythingOfType("string"), mock.AnythingOfType("string")).
+			Run(func(args mock.Arguments) { checkedNonce = args.String(2) }).Return(&oauth.UserInfo{ID: "test-google-id"}, nil)
+		mockUserRepo.On("FindByID", testUser.ID).Return(testUser, nil)
+		mockTokenRepo.On("Create", mock.AnythingOfType("*domain.Token")).Return(nil)
+
+		login, err := authUseCase.InitiateOAuthLogin(context.Background(), "google")
+		require.NoError(t, err)
+		result, err := authUseCase.HandleOAuthCallback(context.Background(), "google", "test-code", login.State, login.Binding, domain.ClientInfo{})
+		require.NoError(t, err)
+		assert.Equal(t, testUser.ID, result.Token.UserID)
+
+		// The ID token is checked against the nonce of the login, no user info request is made
+		assert.NotEmpty(t, sentNonce)
+		assert.Equal(t, sentNonce, checkedNonce)
+		mockGoogleOAuth.AssertNotCalled(t, "ExchangeCodeForToken", mock.Anything, mock.Anything)
+		mockGoogleOAuth.AssertNotCalled(t, "GetUserInfo", mock.Anything)
+
+		// A token failing verification fails the login
+		mockGoogleOAuth.ExpectedCalls = nil
+		mockGoogleOAuth.On("GetAuthURL", mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return("", nil)
+		mockGoogleOAuth.On("ExchangeCodeForIDToken", "test-code", mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil, domain.ErrInvalidIDToken)
+
+		login, err = authUseCase.InitiateOAuthLogin(context.Background(), "google")
+		require.NoError(t, err)
+		_, err = authUseCase.HandleOAuthCallback(context.Background(), "google", "test-code", login.State, login.Binding, domain.ClientInfo{})
+		assert.ErrorIs(t, err, domain.ErrInvalidIDToken)
+	})
+
+	t.Run("HandleOAuthCallback - State Validation", func(t *testing.T) {
+		mockUserRepo := new(MockUserRepository)
+		mockTokenRepo := new(MockTokenRepository)
+		mockGoogleOAuth := &MockOAuthProvider{name: "google"}
+		authUseCase := usecase.NewAuthUseCase(mockUserRepo, mockTokenRepo, linkedIdentities(t), memory.NewOAuthStateRepoMemo(), memory.NewMFARepoMemo(), memory.NewMFAChallengeRepoMemo(), oauth.NewRegistry(mockGoogleOAuth, &MockOAuthProvider{name: "github"}), new(MockPublisher), newJWTManager(t, config), config)
+
+		mockGoogleOAuth.On("GetAuthURL", mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return("", nil)
+		mockGoogleOAuth.On("ExchangeCodeForToken", "test-code", mock.AnythingOfType("string")).Return(testToken, nil)
+		mockGoogleOAuth.On("GetUserInfo", testToken.AccessToken).Return(&oauth.UserInfo{ID: "test-google-id"}, nil)
+		mockUserRepo.On("FindByID", testUser.ID).Return(testUser, nil)
+		mockTokenRepo.On("Create", mock.AnythingOfType("*domain.Token")).Return(nil)
+
+		login, err := authUseCase.InitiateOAuthLogin(context.Background(), "google")
+		assert.NoError(t, err)
+		other, err := authUseCase.InitiateOAuthLogin(context.Background(), "google")
+		assert.NoError(t, err)
+
+		// Missing state or cookie
+		_, err = authUseCase.HandleOAuthCallback(context.Background(), "google", "test-code", "", login.Binding, domain.ClientInfo{})
+		assert.ErrorIs(t, err, domain.ErrOAuthStateMissing)
+		_, err = authUseCase.HandleOAuthCallback(context.Background(), "google", "test-code", login.State, "", domain.ClientInfo{})
+		assert.ErrorIs(t, err, domain.ErrOAuthStateMissing)
+
+		// State bound to another browser or forged binding
+		_, err = authUseCase.HandleOAuthCallback(context.Background(), "google", "test-code", login.State, other.Binding, domain.ClientInfo{})
+		assert.ErrorIs(t, err, domain.ErrOAuthStateMismatch)
+		_, err = authUseCase.HandleOAuthCallback(context.Background(), "google", "test-code", login.State, login.State+".forged", domain.ClientInfo{})
+		assert.ErrorIs(t, err, domain.ErrOAuthStateMismatch)
+
+		// A state issued for Google cannot complete a GitHub login
+		_, err = authUseCase.HandleOAuthCallback(context.Background(), "github", "test-code", other.State, other.Binding, domain.ClientInfo{})
+		assert.ErrorIs(t, err, domain.ErrOAuthStateMismatch)
+		_, err = authUseCase.HandleOAuthCallback(context.Background(), "facebook", "test-code", login.State, login.Binding, domain.ClientInfo{})
+		assert.ErrorIs(t, err, domain.ErrOAuthProviderNotSupported)
+
+		// First use succeeds, the second is a replay
+		_, err = authUseCase.HandleOAuthCallback(context.Background(), "google", "test-code", login.State, login.Binding, domain.ClientInfo{})
+		assert.NoError(t, err)
+		_, err = authUseCase.HandleOAuthCallback(context.Background(), "google", "test-code", login.State, login.Binding, domain.ClientInfo{})
+		assert.ErrorIs(t, err, domain.ErrOAuthStateReplayed)
+
+		mockGoogleOAuth.AssertNumberOfCalls(t, "ExchangeCodeForToken", 1)
+	})
+
+	t.Run("HandleOAuthCallback - Expired State", func(t *testing.T) {
+		expiredConfig := *config
+		expiredConfig.OAuth.StateTTL = -1
+		mockGoogleOAuth := &MockOAuthProvider{name: "google"}
+		authUseCase := usecase.NewAuthUseCase(new(MockUserRepository), new(MockTokenRepository), memory.NewIdentityRepoMemo(), memory.NewOAuthStateRepoMemo(), memory.NewMFARepoMemo(), memory.NewMFAChallengeRepoMemo(), oauth.NewRegistry(mockGoogleOAuth), new(MockPublisher), newJWTManager(t, &expiredConfig), &expiredConfig)
+
+		mockGoogleOAuth.On("GetAuthURL", mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return("", nil)
+
+		login, err := authUseCase.InitiateOAuthLogin(context.Background(), "google")
+		assert.NoError(t, err)
+
+		_, err = authUseCase.HandleOAuthCallback(context.Background(), "google", "test-code", login.State, login.Binding, domain.ClientInfo{})
+		assert.ErrorIs(t, err, domain.ErrOAuthStateExpired)
+		mockGoogleOAuth.AssertNotCalled(t, "ExchangeCodeForToken", mock.Anything, mock.Anything)
+	})
+
 	// t.Run("RefreshToken", func(t *testing.T) {
 	// 	mockUserRepo := new(MockUserRepository)
 	// 	mockTokenRepo := new(MockTokenRepository)
-	// 	googleOAuth := oauth.NewGoogleOAuth(config)
-	// 	authUseCase := usecase.NewAuthUseCase(mockUserRepo, mockTokenRepo, googleOAuth, config)
+	// 	authUseCase := usecase.NewAuthUseCase(mockUserRepo, mockTokenRepo, memory.NewIdentityRepoMemo(), memory.NewOAuthStateRepoMemo(), memory.NewMFARepoMemo(), memory.NewMFAChallengeRepoMemo(), oauth.NewRegistry(), new(MockPublisher), newJWTManager(t, config), config)
 
 	// 	// Setup expectations
-	// 	mockTokenRepo.On("FindByRefreshToken", "test-refresh-token").Return(testToken, nil)
+	// 	mockTokenRepo.On("FindByRefreshTokenHash", mock.AnythingOfType("string")).Return(testToken, nil)
 	// 	mockUserRepo.On("FindByID", testUser.ID).Return(testUser, nil)
 	// 	mockTokenRepo.On("Create", mock.AnythingOfType("*domain.Token")).Return(nil)
 	// 	mockTokenRepo.On("Delete", testToken.ID).Return(nil)
 
 	// 	// Test token refresh
-	// 	newToken, err := authUseCase.RefreshToken("test-refresh-token")
+	// 	newToken, err := authUseCase.RefreshToken("test-refresh-token", domain.ClientInfo{})
 	// 	assert.NoError(t, err)
 	// 	assert.NotNil(t, newToken)
 	// 	assert.NotEmpty(t, newToken.AccessToken)
@@ -273,11 +490,10 @@ func TestAuthUseCase(t *testing.T) {
 	// t.Run("Logout", func(t *testing.T) {
 	// 	mockUserRepo := new(MockUserRepository)
 	// 	mockTokenRepo := new(MockTokenRepository)
-	// 	googleOAuth := oauth.NewGoogleOAuth(config)
-	// 	authUseCase := usecase.NewAuthUseCase(mockUserRepo, mockTokenRepo, googleOAuth, config)
+	// 	authUseCase := usecase.NewAuthUseCase(mockUserRepo, mockTokenRepo, memory.NewIdentityRepoMemo(), memory.NewOAuthStateRepoMemo(), memory.NewMFARepoMemo(), memory.NewMFAChallengeRepoMemo(), oauth.NewRegistry(), new(MockPublisher), newJWTManager(t, config), config)
 
 	// 	// Setup expectations
-	// 	mockTokenRepo.On("FindByRefreshToken", "test-refresh-token").Return(testToken, nil)
+	// 	mockTokenRepo.On("FindByRefreshTokenHash", mock.AnythingOfType("string")).Return(testToken, nil)
 	// 	mockTokenRepo.On("Delete", testToken.ID).Return(nil)
 
 	// 	// Test logout
@@ -290,8 +506,7 @@ func TestAuthUseCase(t *testing.T) {
 	// t.Run("ValidateToken", func(t *testing.T) {
 	// 	mockUserRepo := new(MockUserRepository)
 	// 	mockTokenRepo := new(MockTokenRepository)
-	// 	googleOAuth := oauth.NewGoogleOAuth(config)
-	// 	authUseCase := usecase.NewAuthUseCase(mockUserRepo, mockTokenRepo, googleOAuth, config)
+	// 	authUseCase := usecase.NewAuthUseCase(mockUserRepo, mockTokenRepo, memory.NewIdentityRepoMemo(), memory.NewOAuthStateRepoMemo(), memory.NewMFARepoMemo(), memory.NewMFAChallengeRepoMemo(), oauth.NewRegistry(), new(MockPublisher), newJWTManager(t, config), config)
 
 	// 	// Setup expectations
 	// 	mockUserRepo.On("FindByID", testUser.ID).Return(testUser, nil)
@@ -306,3 +521,295 @@ func TestAuthUseCase(t *testing.T) {
 	// 	mockUserRepo.AssertExpectations(t)
 	// })
 }
+
+func TestAuthUseCasePassword(t *testing.T) {
+	config := &configs.Config{}
+	config.Auth.JWTSecret = "test-secret-key-123"
+	config.Auth.TokenTTL = 3600
+	config.Auth.RefreshTokenSecret = "test-refresh-secret"
+	config.Password.MinLength = 10
+	config.Password.Argon2Memory = 1024
+	config.Password.Argon2Iterations = 1
+	config.Password.Argon2Parallelism = 1
+
+	userRepo := memory.NewUserRepoMemo()
+	tokenRepo := memory.NewTokenRepoMemo()
+	publisher := new(MockPublisher)
+	authUseCase := usecase.NewAuthUseCase(userRepo, tokenRepo, memory.NewIdentityRepoMemo(), memory.NewOAuthStateRepoMemo(), memory.NewMFARepoMemo(), memory.NewMFAChallengeRepoMemo(), oauth.NewRegistry(), publisher, newJWTManager(t, config), config)
+
+	// An OAuth-only account with no password
+	oauthUser := domain.NewUser("oauth@example.com")
+	assert.NoError(t, userRepo.Create(oauthUser))
+
+	t.Run("Register", func(t *testing.T) {
+		require.NoError(t, authUseCase.Register(" Alice@Example.com ", "correct horse battery staple"))
+
+		user, err := userRepo.FindByEmail("alice@example.com")
+		assert.NoError(t, err)
+		assert.NotContains(t, user.PasswordHash, "correct horse")
+		sessions, err := tokenRepo.FindByUserID(user.ID)
+		assert.NoError(t, err)
+		assert.Empty(t, sessions, "the account signs in with Login")
+
+		assert.ErrorIs(t, authUseCase.Register("not-an-email", "correct horse battery staple"), domain.ErrInvalidEmail)
+		assert.ErrorIs(t, authUseCase.Register("bob@example.com", "short"), domain.ErrWeakPassword)
+	})
+
+	t.Run("Register - Taken Email Looks Like A New One", func(t *testing.T) {
+		assert.NoError(t, authUseCase.Register("alice@example.com", "another strong passphrase"))
+		assert.NoError(t, authUseCase.Register("oauth@example.com", "another strong passphrase"))
+
+		// The accounts keep their passwords
+		_, err := passwordLogin(t, authUseCase, "alice@example.com", "another strong passphrase", domain.ClientInfo{})
+		assert.ErrorIs(t, err, domain.ErrInvalidCredentials)
+		_, err = passwordLogin(t, authUseCase, "oauth@example.com", "another strong passphrase", domain.ClientInfo{})
+		assert.ErrorIs(t, err, domain.ErrInvalidCredentials)
+	})
+
+	t.Run("Login", func(t *testing.T) {
+		token, err := passwordLogin(t, authUseCase, "ALICE@example.com", "correct horse battery staple", domain.ClientInfo{})
+		assert.NoError(t, err)
+		assert.NotEmpty(t, token.AccessToken)
+		assert.NotEmpty(t, token.RefreshToken)
+
+		user, err := authUseCase.ValidateToken(token.AccessToken)
+		assert.NoError(t, err)
+		assert.Equal(t, "alice@example.com", user.Email)
+	})
+
+	t.Run("Refresh Tokens Are Hashed At Rest", func(t *testing.T) {
+		token, err := passwordLogin(t, authUseCase, "alice@example.com", "correct horse battery staple", domain.ClientInfo{})
+		assert.NoError(t, err)
+
+		stored, err := tokenRepo.FindByID(token.ID)
+		assert.NoError(t, err)
+		assert.Empty(t, stored.RefreshToken)
+		assert.NotEmpty(t, stored.RefreshTokenHash)
+		assert.NotEqual(t, token.RefreshToken, stored.RefreshTokenHash)
+
+		refreshed, err := authUseCase.RefreshToken(token.RefreshToken, domain.ClientInfo{})
+		assert.NoError(t, err)
+		assert.NotEqual(t, token.RefreshToken, refreshed.RefreshToken)
+
+		// The hash alone does not redeem the token
+		_, err = authUseCase.RefreshToken(refreshed.RefreshTokenHash, domain.ClientInfo{})
+		assert.Error(t, err)
+
+		assert.NoError(t, authUseCase.Logout(refreshed.RefreshToken))
+		_, err = authUseCase.RefreshToken(refreshed.RefreshToken, domain.ClientInfo{})
+		assert.Error(t, err)
+	})
+
+	t.Run("Login - Failures Look Alike", func(t *testing.T) {
+		for _, tc := range []struct{ email, password string }{
+			{"alice@example.com", "wrong horse battery staple"},
+			{"nobody@example.com", "correct horse battery staple"},
+			{"oauth@example.com", "correct horse battery staple"},
+			{"not-an-email", "correct horse battery staple"},
+		} {
+			token, err := passwordLogin(t, authUseCase, tc.email, tc.password, domain.ClientInfo{})
+			assert.Nil(t, token)
+			assert.Equal(t, domain.ErrInvalidCredentials, err, tc.email)
+		}
+	})
+
+	t.Run("Login - Multi-byte Password", func(t *testing.T) {
+		// 96 characters in 192 bytes, the length limit counts characters on both paths
+		multiByte := strings.Repeat("пароль", 16)
+		require.NoError(t, authUseCase.Register("dora@example.com", multiByte))
+
+		token, err := passwordLogin(t, authUseCase, "dora@example.com", multiByte, domain.ClientInfo{})
+		assert.NoError(t, err)
+		assert.NotNil(t, token)
+	})
+}
+
+func TestAuthUseCaseRefreshTokenFamilies(t *testing.T) {
+	config := &configs.Config{}
+	config.Auth.JWTSecret = "test-secret-key-123"
+	config.Auth.TokenTTL = 3600
+	config.Auth.RefreshTokenSecret = "test-refresh-secret"
+	config.Password.MinLength = 10
+	config.Password.Argon2Memory = 1024
+	config.Password.Argon2Iterations = 1
+	config.Password.Argon2Parallelism = 1
+
+	userRepo := memory.NewUserRepoMemo()
+	tokenRepo := memory.NewTokenRepoMemo()
+	publisher := new(MockPublisher)
+	authUseCase := usecase.NewAuthUseCase(userRepo, tokenRepo, memory.NewIdentityRepoMemo(), memory.NewOAuthStateRepoMemo(), memory.NewMFARepoMemo(), memory.NewMFAChallengeRepoMemo(), oauth.NewRegistry(), publisher, newJWTManager(t, config), config)
+
+	graceConfig := *config
+	graceConfig.Auth.RefreshGracePeriod = 60
+	graceUseCase := usecase.NewAuthUseCase(userRepo, tokenRepo, memory.NewIdentityRepoMemo(), memory.NewOAuthStateRepoMemo(), memory.NewMFARepoMemo(), memory.NewMFAChallengeRepoMemo(), oauth.NewRegistry(), publisher, newJWTManager(t, &graceConfig), &graceConfig)
+
+	_, err := registerAndLogin(t, authUseCase, "alice@example.com", "correct horse battery staple", domain.ClientInfo{})
+	assert.NoError(t, err)
+
+	t.Run("Rotation Keeps The Family", func(t *testing.T) {
+		login, err := passwordLogin(t, authUseCase, "alice@example.com", "correct horse battery staple", domain.ClientInfo{})
+		assert.NoError(t, err)
+		assert.Equal(t, login.ID, login.FamilyID)
+
+		refreshed, err := authUseCase.RefreshToken(login.RefreshToken, domain.ClientInfo{})
+		assert.NoError(t, err)
+		assert.Equal(t, login.FamilyID, refreshed.FamilyID)
+		assert.NotEqual(t, login.ID, refreshed.ID)
+
+		old, err := tokenRepo.FindByID(login.ID)
+		assert.NoError(t, err)
+		assert.True(t, old.IsRotated())
+
+		// Another login starts its own family
+		other, err := passwordLogin(t, authUseCase, "alice@example.com", "correct horse battery staple", domain.ClientInfo{})
+		assert.NoError(t, err)
+		assert.NotEqual(t, login.FamilyID, other.FamilyID)
+	})
+
+	t.Run("Reuse Revokes The Family", func(t *testing.T) {
+		login, err := passwordLogin(t, authUseCase, "alice@example.com", "correct horse battery staple", domain.ClientInfo{})
+		assert.NoError(t, err)
+		bystander, err := passwordLogin(t, authUseCase, "alice@example.com", "correct horse battery staple", domain.ClientInfo{})
+		assert.NoError(t, err)
+
+		refreshed, err := authUseCase.RefreshToken(login.RefreshToken, domain.ClientInfo{})
+		assert.NoError(t, err)
+		latest, err := authUseCase.RefreshToken(refreshed.RefreshToken, domain.ClientInfo{})
+		assert.NoError(t, err)
+
+		publisher.On("Publish", mock.MatchedBy(func(event *domain.SecurityEvent) bool {
+			return event.Type == domain.SecurityEventRefreshTokenReuse &&
+				event.UserID == login.UserID &&
+				event.FamilyID == login.FamilyID
+		})).Return().Once()
+
+		_, err = authUseCase.RefreshToken(login.RefreshToken, domain.ClientInfo{})
+		assert.ErrorIs(t, err, domain.ErrRefreshTokenReused)
+		publisher.AssertExpectations(t)
+
+		// Every token of the family is gone, other logins are untouched
+		_, err = authUseCase.RefreshToken(latest.RefreshToken, domain.ClientInfo{})
+		assert.Error(t, err)
+		_, err = tokenRepo.FindByID(refreshed.ID)
+		assert.Error(t, err)
+		_, err = authUseCase.RefreshToken(bystander.RefreshToken, domain.ClientInfo{})
+		assert.NoError(t, err)
+	})
+
+	t.Run("Grace Period Tolerates Concurrent Refreshes", func(t *testing.T) {
+		login, err := passwordLogin(t, graceUseCase, "alice@example.com", "correct horse battery staple", domain.ClientInfo{})
+		assert.NoError(t, err)
+
+		first, err := graceUseCase.RefreshToken(login.RefreshToken, domain.ClientInfo{})
+		assert.NoError(t, err)
+		second, err := graceUseCase.RefreshToken(login.RefreshToken, domain.ClientInfo{})
+		assert.NoError(t, err)
+		assert.Equal(t, login.FamilyID, second.FamilyID)
+		assert.NotEqual(t, first.RefreshToken, second.RefreshToken)
+
+		_, err = graceUseCase.RefreshToken(first.RefreshToken, domain.ClientInfo{})
+		assert.NoError(t, err)
+	})
+
+	t.Run("Logout Revokes The Family", func(t *testing.T) {
+		login, err := passwordLogin(t, authUseCase, "alice@example.com", "correct horse battery staple", domain.ClientInfo{})
+		assert.NoError(t, err)
+		refreshed, err := authUseCase.RefreshToken(login.RefreshToken, domain.ClientInfo{})
+		assert.NoError(t, err)
+
+		assert.NoError(t, authUseCase.Logout(refreshed.RefreshToken))
+		_, err = tokenRepo.FindByID(login.ID)
+		assert.Error(t, err)
+
+		// Access tokens of the whole family stop working at once
+		_, err = authUseCase.ValidateToken(login.AccessToken)
+		assert.ErrorIs(t, err, domain.ErrTokenRevoked)
+		_, err = authUseCase.ValidateToken(refreshed.AccessToken)
+		assert.ErrorIs(t, err, domain.ErrTokenRevoked)
+	})
+}
+
+func TestAuthUseCaseSessions(t *testing.T) {
+	config := &configs.Config{}
+	config.Auth.JWTSecret = "test-secret-key-123"
+	config.Auth.TokenTTL = 3600
+	config.Auth.RefreshTokenSecret = "test-refresh-secret"
+	config.Password.MinLength = 10
+	config.Password.Argon2Memory = 1024
+	config.Password.Argon2Iterations = 1
+	config.Password.Argon2Parallelism = 1
+
+	tokenRepo := memory.NewTokenRepoMemo()
+	jwtManager := newJWTManager(t, config)
+	authUseCase := usecase.NewAuthUseCase(memory.NewUserRepoMemo(), tokenRepo, memory.NewIdentityRepoMemo(), memory.NewOAuthStateRepoMemo(), memory.NewMFARepoMemo(), memory.NewMFAChallengeRepoMemo(), oauth.NewRegistry(), new(MockPublisher), jwtManager, config)
+
+	laptop := domain.NewClientInfo("Firefox", "192.0.2.1")
+	phone := domain.NewClientInfo("Safari", "198.51.100.7")
+
+	alice, err := registerAndLogin(t, authUseCase, "alice@example.com", "correct horse battery staple", laptop)
+	require.NoError(t, err)
+	bob, err := registerAndLogin(t, authUseCase, "bob@example.com", "correct horse battery staple", laptop)
+	require.NoError(t, err)
+
+	t.Run("Sessions Record Client Metadata", func(t *testing.T) {
+		onPhone, err := passwordLogin(t, authUseCase, "alice@example.com", "correct horse battery staple", phone)
+		require.NoError(t, err)
+
+		// Rotation keeps the session and refreshes its metadata
+		moved := domain.NewClientInfo("Safari", "203.0.113.9")
+		rotated, err := authUseCase.RefreshToken(onPhone.RefreshToken, moved)
+		require.NoError(t, err)
+		assert.Equal(t, onPhone.SessionStartedAt, rotated.SessionStartedAt)
+
+		claims, err := jwtManager.ValidateAccessToken(rotated.AccessToken)
+		require.NoError(t, err)
+		assert.Equal(t, onPhone.FamilyID, claims.SessionID)
+
+		sessions, err := authUseCase.ListSessions(alice.UserID)
+		require.NoError(t, err)
+		require.Len(t, sessions, 2)
+
+		// Most recently used first
+		assert.Equal(t, onPhone.FamilyID, sessions[0].ID)
+		assert.Equal(t, "Safari", sessions[0].UserAgent)
+		assert.Equal(t, "203.0.113.9", sessions[0].IPAddress)
+		assert.Equal(t, alice.FamilyID, sessions[1].ID)
+		assert.Equal(t, "Firefox", sessions[1].UserAgent)
+		assert.Equal(t, "192.0.2.1", sessions[1].IPAddress)
+	})
+
+	t.Run("RevokeSession", func(t *testing.T) {
+		// Another user's session looks missing
+		assert.ErrorIs(t, authUseCase.RevokeSession(bob.UserID, alice.FamilyID), domain.ErrSessionNotFound)
+		assert.ErrorIs(t, authUseCase.RevokeSession(alice.UserID, uuid.New()), domain.ErrSessionNotFound)
+
+		assert.NoError(t, authUseCase.RevokeSession(alice.UserID, alice.FamilyID))
+		_, err := authUseCase.RefreshToken(alice.RefreshToken, laptop)
+		assert.Error(t, err)
+		_, err = authUseCase.ValidateToken(alice.AccessToken)
+		assert.ErrorIs(t, err, domain.ErrTokenRevoked)
+
+		sessions, err := authUseCase.ListSessions(alice.UserID)
+		require.NoError(t, err)
+		assert.Len(t, sessions, 1)
+	})
+
+	t.Run("LogoutAll", func(t *testing.T) {
+		onPhone, err := passwordLogin(t, authUseCase, "alice@example.com", "correct horse battery staple", phone)
+		require.NoError(t, err)
+		assert.NoError(t, authUseCase.LogoutAll(alice.UserID))
+		_, err = authUseCase.ValidateToken(onPhone.AccessToken)
+		assert.ErrorIs(t, err, domain.ErrTokenRevoked)
+		_, err = authUseCase.ValidateToken(bob.AccessToken)
+		assert.NoError(t, err)
+
+		sessions, err := authUseCase.ListSessions(alice.UserID)
+		require.NoError(t, err)
+		assert.Empty(t, sessions)
+
+		// Other users keep their sessions
+		sessions, err = authUseCase.ListSessions(bob.UserID)
+		require.NoError(t, err)
+		assert.Len(t, sessions, 1)
+	})
+}
@@ -0,0 +1,286 @@
+package usecase
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/algosim/backend/configs"
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/algosim/backend/internal/auth/infrastructure/db/memory"
+	"github.com/algosim/backend/internal/auth/infrastructure/events"
+	"github.com/algosim/backend/internal/auth/infrastructure/oauth"
+	"github.com/algosim/backend/internal/auth/usecase"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeviceAuthorization(t *testing.T) {
+	config := &configs.Config{}
+	config.Auth.JWTSecret = "test-secret-key-123"
+	config.Auth.TokenTTL = 3600
+	config.Auth.RefreshTokenSecret = "test-refresh-secret"
+	config.Device.VerificationURI = "https://algosim.example/device"
+	config.Device.CodeTTL = 600
+	config.Password.MinLength = 10
+	config.Password.Argon2Memory = 1024
+	config.Password.Argon2Iterations = 1
+	config.Password.Argon2Parallelism = 1
+
+	userRepo := memory.NewUserRepoMemo()
+	publisher := events.NewLogPublisher()
+	authUseCase := usecase.NewAuthUseCase(userRepo, memory.NewTokenRepoMemo(), memory.NewIdentityRepoMemo(), memory.NewOAuthStateRepoMemo(), memory.NewMFARepoMemo(), memory.NewMFAChallengeRepoMemo(), oauth.NewRegistry(), publisher, newJWTManager(t, config), config)
+	adminUseCase := usecase.NewAdminUseCase(userRepo, authUseCase, publisher)
+
+	// newDeviceUseCase creates a use case polling every interval with codes valid for ttl
+	newDeviceUseCase := func(interval, ttl time.Duration) *usecase.DeviceUseCase {
+		deviceConfig := *config
+		deviceConfig.Device.Interval = int(interval.Seconds())
+		deviceConfig.Device.CodeTTL = int(ttl.Seconds())
+		return usecase.NewDeviceUseCase(memory.NewDeviceAuthorizationRepoMemo(), userRepo, authUseCase, publisher, &deviceConfig)
+	}
+
+	register := func(email string) *domain.User {
+		_, err := registerAndLogin(t, authUseCase, email, "correct horse battery staple", domain.ClientInfo{})
+		require.NoError(t, err)
+		user, err := userRepo.FindByEmail(email)
+		require.NoError(t, err)
+		return user
+	}
+
+	cli := domain.NewClientInfo("algosim-cli/1.0", "203.0.113.7")
+
+	t.Run("approved device gets a session", func(t *testing.T) {
+		deviceUseCase := newDeviceUseCase(0, 10*time.Minute)
+		user := register("alice@example.com")
+
+		codes, err := deviceUseCase.StartDeviceAuthorization(cli)
+		require.NoError(t, err)
+		assert.Regexp(t, `^[B-Z]{4}-[B-Z]{4}$`, codes.UserCode)
+		assert.Equal(t, "https://algosim.example/device", codes.VerificationURI)
+		assert.Equal(t, "https://algosim.example/device?user_code="+codes.UserCode, codes.VerificationURIComplete)
+		assert.WithinDuration(t, time.Now().Add(10*time.Minute), codes.ExpiresAt, time.Minute)
+
+		_, err = deviceUseCase.PollDeviceToken(codes.DeviceCode, cli)
+		assert.ErrorIs(t, err, domain.ErrDeviceAuthorizationPending)
+
+		// Users may type the code in lower case and without the dash
+		typed := strings.ToLower(strings.ReplaceAll(codes.UserCode, "-", ""))
+		authorization, err := deviceUseCase.GetDeviceAuthorization(typed)
+		require.NoError(t, err)
+		assert.Equal(t, "algosim-cli/1.0", authorization.Client.UserAgent)
+		require.NoError(t, deviceUseCase.ApproveDevice(user.ID, typed))
+
+		token, err := deviceUseCase.PollDeviceToken(codes.DeviceCode, cli)
+		require.NoError(t, err)
+		assert.NotEmpty(t, token.AccessToken)
+		assert.NotEmpty(t, token.RefreshToken)
+
+		sessions, err := authUseCase.ListSessions(user.ID)
+		require.NoError(t, err)
+		var userAgents []string
+		for _, s := range sessions {
+			userAgents = append(userAgents, s.UserAgent)
+		}
+		assert.Contains(t, userAgents, "algosim-cli/1.0")
+
+		// The device session refreshes like any other
+		_, err = authUseCase.RefreshToken(token.RefreshToken, cli)
+		require.NoError(t, err)
+
+		// The device code is used up
+		_, err = deviceUseCase.PollDeviceToken(codes.DeviceCode, cli)
+		assert.ErrorIs(t, err, domain.ErrDeviceAuthorizationNotFound)
+	})
+
+	t.Run("denied device", func(t *testing.T) {
+		deviceUseCase := newDeviceUseCase(0, 10*time.Minute)
+		user := register("bob@example.com")
+
+		codes, err := deviceUseCase.StartDeviceAuthorization(cli)
+		require.NoError(t, err)
+		require.NoError(t, deviceUseCase.DenyDevice(user.ID, codes.UserCode))
+
+		// A decision is final
+		assert.ErrorIs(t, deviceUseCase.ApproveDevice(user.ID, codes.UserCode), domain.ErrDeviceAuthorizationDecided)
+
+		_, err = deviceUseCase.PollDeviceToken(codes.DeviceCode, cli)
+		assert.ErrorIs(t, err, domain.ErrDeviceAuthorizationDenied)
+		_, err = deviceUseCase.PollDeviceToken(codes.DeviceCode, cli)
+		assert.ErrorIs(t, err, domain.ErrDeviceAuthorizationNotFound)
+	})
+
+	t.Run("approval is redeemed once across instances", func(t *testing.T) {
+		// Instances share nothing but the store
+		deviceRepo := memory.NewDeviceAuthorizationRepoMemo()
+		instances := []*usecase.DeviceUseCase{
+			usecase.NewDeviceUseCase(deviceRepo, userRepo, authUseCase, publisher, config),
+			usecase.NewDeviceUseCase(deviceRepo, userRepo, authUseCase, publisher, config),
+		}
+		user := register("dave@example.com")
+
+		codes, err := instances[0].StartDeviceAuthorization(cli)
+		require.NoError(t, err)
+		require.NoError(t, instances[1].ApproveDevice(user.ID, codes.UserCode))
+
+		errs := make([]error, 8)
+		var wg sync.WaitGroup
+		for i := range errs {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, errs[i] = instances[i%2].PollDeviceToken(codes.DeviceCode, cli)
+			}()
+		}
+		wg.Wait()
+
+		redeemed := 0
+		for _, err := range errs {
+			if err == nil {
+				redeemed++
+			} else {
+				assert.ErrorIs(t, err, domain.ErrDeviceAuthorizationNotFound)
+			}
+		}
+		assert.Equal(t, 1, redeemed)
+	})
+
+	t.Run("polls keep approvals made on another instance", func(t *testing.T) {
+		deviceRepo := memory.NewDeviceAuthorizationRepoMemo()
+		polling := usecase.NewDeviceUseCase(deviceRepo, userRepo, authUseCase, publisher, config)
+		deciding := usecase.NewDeviceUseCase(deviceRepo, userRepo, authUseCase, publisher, config)
+		user := register("erin@example.com")
+
+		codes, err := polling.StartDeviceAuthorization(cli)
+		require.NoError(t, err)
+
+		// The device polls while the user approves, a denial racing the approval loses or wins whole
+		polls := make([]error, 8)
+		decisions := make([]error, 8)
+		redeemed := make(chan struct{}, len(polls)+1)
+		var wg sync.WaitGroup
+		for i := range polls {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				var token *domain.Token
+				token, polls[i] = polling.PollDeviceToken(codes.DeviceCode, cli)
+				if token != nil {
+					redeemed <- struct{}{}
+				}
+			}()
+			go func() {
+				defer wg.Done()
+				if i%2 == 0 {
+					decisions[i] = deciding.ApproveDevice(user.ID, codes.UserCode)
+				} else {
+					decisions[i] = deciding.DenyDevice(user.ID, codes.UserCode)
+				}
+			}()
+		}
+		wg.Wait()
+
+		decided := 0
+		for _, err := range decisions {
+			if err == nil {
+				decided++
+			} else if !errors.Is(err, domain.ErrDeviceAuthorizationNotFound) {
+				// Not found once the device redeemed the decision
+				assert.ErrorIs(t, err, domain.ErrDeviceAuthorizationDecided)
+			}
+		}
+		assert.Equal(t, 1, decided, "the first decision counts")
+
+		// Whatever was decided reaches the device once, at the latest on its next poll
+		token, err := polling.PollDeviceToken(codes.DeviceCode, cli)
+		if token != nil {
+			redeemed <- struct{}{}
+		}
+		answered := len(redeemed)
+		for _, err := range append(polls, err) {
+			if errors.Is(err, domain.ErrDeviceAuthorizationDenied) {
+				answered++
+			}
+		}
+		assert.Equal(t, 1, answered)
+	})
+
+	t.Run("decided devices are exported and deleted with the user", func(t *testing.T) {
+		deviceUseCase := newDeviceUseCase(0, 10*time.Minute)
+		userUseCase := usecase.NewUserUseCase(userRepo)
+		userUseCase.RegisterDataHook(deviceUseCase.DataHook())
+		user := register("frank@example.com")
+
+		codes, err := deviceUseCase.StartDeviceAuthorization(cli)
+		require.NoError(t, err)
+		require.NoError(t, deviceUseCase.ApproveDevice(user.ID, codes.UserCode))
+
+		export, err := userUseCase.ExportUserData(user.ID)
+		require.NoError(t, err)
+		devices, ok := export.Modules["device_authorizations"].([]usecase.DeviceAuthorizationExport)
+		require.True(t, ok)
+		require.Len(t, devices, 1)
+		assert.Equal(t, "approved", devices[0].Status)
+		assert.Equal(t, "algosim-cli/1.0", devices[0].UserAgent)
+
+		// The approval does not outlive the account
+		require.NoError(t, userUseCase.DeleteUser(user.ID))
+		_, err = deviceUseCase.PollDeviceToken(codes.DeviceCode, cli)
+		assert.ErrorIs(t, err, domain.ErrDeviceAuthorizationNotFound)
+	})
+
+	t.Run("polling too fast slows the device down", func(t *testing.T) {
+		deviceUseCase := newDeviceUseCase(5*time.Second, 10*time.Minute)
+
+		codes, err := deviceUseCase.StartDeviceAuthorization(cli)
+		require.NoError(t, err)
+		assert.Equal(t, 5*time.Second, codes.Interval)
+
+		_, err = deviceUseCase.PollDeviceToken(codes.DeviceCode, cli)
+		assert.ErrorIs(t, err, domain.ErrDeviceAuthorizationPending)
+		_, err = deviceUseCase.PollDeviceToken(codes.DeviceCode, cli)
+		assert.ErrorIs(t, err, domain.ErrDeviceSlowDown)
+
+		authorization, err := deviceUseCase.GetDeviceAuthorization(codes.UserCode)
+		require.NoError(t, err)
+		assert.Equal(t, 10*time.Second, authorization.Interval)
+	})
+
+	t.Run("expired codes", func(t *testing.T) {
+		deviceUseCase := newDeviceUseCase(0, 0)
+		user := register("carol@example.com")
+
+		codes, err := deviceUseCase.StartDeviceAuthorization(cli)
+		require.NoError(t, err)
+
+		assert.ErrorIs(t, deviceUseCase.ApproveDevice(user.ID, codes.UserCode), domain.ErrDeviceAuthorizationNotFound)
+		_, err = deviceUseCase.PollDeviceToken(codes.DeviceCode, cli)
+		assert.ErrorIs(t, err, domain.ErrDeviceAuthorizationExpired)
+	})
+
+	t.Run("banned user", func(t *testing.T) {
+		deviceUseCase := newDeviceUseCase(0, 10*time.Minute)
+		admin := register("admin@example.com")
+		user := register("mallory@example.com")
+
+		codes, err := deviceUseCase.StartDeviceAuthorization(cli)
+		require.NoError(t, err)
+		require.NoError(t, deviceUseCase.ApproveDevice(user.ID, codes.UserCode))
+		_, err = adminUseCase.BanUser(admin.ID, user.ID)
+		require.NoError(t, err)
+
+		_, err = deviceUseCase.PollDeviceToken(codes.DeviceCode, cli)
+		assert.ErrorIs(t, err, domain.ErrUserBanned)
+	})
+
+	t.Run("unknown codes", func(t *testing.T) {
+		deviceUseCase := newDeviceUseCase(0, 10*time.Minute)
+
+		_, err := deviceUseCase.PollDeviceToken("unknown", cli)
+		assert.ErrorIs(t, err, domain.ErrDeviceAuthorizationNotFound)
+		_, err = deviceUseCase.GetDeviceAuthorization("BCDF-GHJK")
+		assert.ErrorIs(t, err, domain.ErrDeviceAuthorizationNotFound)
+	})
+}
@@ -0,0 +1,126 @@
+package usecase
+
+import (
+	"testing"
+	"time"
+
+	"github.com/algosim/backend/configs"
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/algosim/backend/internal/auth/infrastructure/db/memory"
+	"github.com/algosim/backend/internal/auth/infrastructure/judge"
+	"github.com/algosim/backend/internal/auth/usecase"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleUseCase(t *testing.T) {
+	config := &configs.Config{}
+	config.Judges.VerificationTTL = 300
+
+	setup := func(t *testing.T) (*usecase.HandleUseCase, *judge.Fake, *domain.User) {
+		userRepo := memory.NewUserRepoMemo()
+		user := domain.NewUser("test@example.com")
+		require.NoError(t, userRepo.Create(user))
+
+		fake := judge.NewFake()
+		handleUseCase := usecase.NewHandleUseCase(userRepo, memory.NewHandleVerificationRepoMemo(), fake, fake, config)
+		return handleUseCase, fake, user
+	}
+
+	t.Run("ClaimHandle - Validation", func(t *testing.T) {
+		handleUseCase, _, user := setup(t)
+
+		_, err := handleUseCase.ClaimHandle(user.ID, "topcoder", "tourist")
+		assert.ErrorIs(t, err, domain.ErrJudgeNotSupported)
+		_, err = handleUseCase.ClaimHandle(user.ID, domain.JudgeCodeforces, "a b")
+		assert.ErrorIs(t, err, domain.ErrInvalidHandle)
+		_, err = handleUseCase.ClaimHandle(user.ID, domain.JudgeAtCoder, "tourist.dot")
+		assert.ErrorIs(t, err, domain.ErrInvalidHandle)
+	})
+
+	t.Run("Codeforces", func(t *testing.T) {
+		handleUseCase, fake, user := setup(t)
+		fake.AddHandle("tourist")
+
+		verification, err := handleUseCase.ClaimHandle(user.ID, domain.JudgeCodeforces, "tourist")
+		require.NoError(t, err)
+		assert.NotZero(t, verification.ContestID)
+		assert.NotEmpty(t, verification.ProblemIndex)
+		assert.WithinDuration(t, time.Now().Add(5*time.Minute), verification.ExpiresAt, time.Second)
+
+		// Nothing submitted yet, the handle stays unlinked
+		_, err = handleUseCase.VerifyHandle(user.ID, domain.JudgeCodeforces)
+		assert.ErrorIs(t, err, domain.ErrHandleVerificationIncomplete)
+		assert.Empty(t, user.VerifiedHandles())
+
+		// Accepted or old submissions do not count
+		fake.AddSubmission("tourist", judge.Submission{
+			ContestID: verification.ContestID, ProblemIndex: verification.ProblemIndex,
+			Verdict: "OK", CreatedAt: time.Now(),
+		})
+		fake.AddSubmission("tourist", judge.Submission{
+			ContestID: verification.ContestID, ProblemIndex: verification.ProblemIndex,
+			Verdict: judge.VerdictCompilationError, CreatedAt: time.Now().Add(-time.Hour),
+		})
+		_, err = handleUseCase.VerifyHandle(user.ID, domain.JudgeCodeforces)
+		assert.ErrorIs(t, err, domain.ErrHandleVerificationIncomplete)
+
+		fake.AddSubmission("tourist", judge.Submission{
+			ContestID: verification.ContestID, ProblemIndex: verification.ProblemIndex,
+			Verdict: judge.VerdictCompilationError, CreatedAt: time.Now(),
+		})
+		updated, err := handleUseCase.VerifyHandle(user.ID, domain.JudgeCodeforces)
+		require.NoError(t, err)
+		assert.Equal(t, "tourist", updated.CodeforcesHandle)
+		assert.Equal(t, map[string]string{domain.JudgeCodeforces: "tourist"}, updated.VerifiedHandles())
+
+		// The task is single-use
+		_, err = handleUseCase.VerifyHandle(user.ID, domain.JudgeCodeforces)
+		assert.ErrorIs(t, err, domain.ErrHandleVerificationNotFound)
+	})
+
+	t.Run("AtCoder", func(t *testing.T) {
+		handleUseCase, fake, user := setup(t)
+		fake.SetAffiliation("chokudai", "AtCoder Inc.")
+
+		verification, err := handleUseCase.ClaimHandle(user.ID, domain.JudgeAtCoder, "chokudai")
+		require.NoError(t, err)
+		assert.NotEmpty(t, verification.Token)
+
+		_, err = handleUseCase.VerifyHandle(user.ID, domain.JudgeAtCoder)
+		assert.ErrorIs(t, err, domain.ErrHandleVerificationIncomplete)
+
+		fake.SetAffiliation("chokudai", "AtCoder Inc. "+verification.Token)
+		updated, err := handleUseCase.VerifyHandle(user.ID, domain.JudgeAtCoder)
+		require.NoError(t, err)
+		assert.Equal(t, "chokudai", updated.AtcoderHandle)
+	})
+
+	t.Run("Unknown Handle", func(t *testing.T) {
+		handleUseCase, _, user := setup(t)
+
+		_, err := handleUseCase.ClaimHandle(user.ID, domain.JudgeAtCoder, "nobody")
+		require.NoError(t, err)
+		_, err = handleUseCase.VerifyHandle(user.ID, domain.JudgeAtCoder)
+		assert.ErrorIs(t, err, domain.ErrHandleNotFound)
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		expiredConfig := *config
+		expiredConfig.Judges.VerificationTTL = -1
+		userRepo := memory.NewUserRepoMemo()
+		user := domain.NewUser("test@example.com")
+		require.NoError(t, userRepo.Create(user))
+		fake := judge.NewFake()
+		handleUseCase := usecase.NewHandleUseCase(userRepo, memory.NewHandleVerificationRepoMemo(), fake, fake, &expiredConfig)
+
+		fake.SetAffiliation("chokudai", "")
+		verification, err := handleUseCase.ClaimHandle(user.ID, domain.JudgeAtCoder, "chokudai")
+		require.NoError(t, err)
+		fake.SetAffiliation("chokudai", verification.Token)
+
+		_, err = handleUseCase.VerifyHandle(user.ID, domain.JudgeAtCoder)
+		assert.ErrorIs(t, err, domain.ErrHandleVerificationExpired)
+		assert.Empty(t, user.AtcoderHandle)
+	})
+}
@@ -0,0 +1,210 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+
+	"github.com/algosim/backend/configs"
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/algosim/backend/internal/auth/infrastructure/db/memory"
+	"github.com/algosim/backend/internal/auth/infrastructure/events"
+	"github.com/algosim/backend/internal/auth/infrastructure/oauth"
+	"github.com/algosim/backend/internal/auth/usecase"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdentities(t *testing.T) {
+	config := &configs.Config{}
+	config.Auth.JWTSecret = "test-secret-key-123"
+	config.Auth.TokenTTL = 3600
+	config.Auth.RefreshTokenSecret = "test-refresh-secret"
+	config.OAuth.StateTTL = 600
+	config.Password.MinLength = 10
+	config.Password.Argon2Memory = 1024
+	config.Password.Argon2Iterations = 1
+	config.Password.Argon2Parallelism = 1
+
+	google := &MockOAuthProvider{name: "google"}
+	github := &MockOAuthProvider{name: "github"}
+	for _, provider := range []*MockOAuthProvider{google, github} {
+		provider.On("GetAuthURL", mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return("", nil)
+	}
+
+	userRepo := memory.NewUserRepoMemo()
+	identityRepo := memory.NewIdentityRepoMemo()
+	authUseCase := usecase.NewAuthUseCase(userRepo, memory.NewTokenRepoMemo(), identityRepo, memory.NewOAuthStateRepoMemo(), memory.NewMFARepoMemo(), memory.NewMFAChallengeRepoMemo(), oauth.NewRegistry(google, github), events.NewLogPublisher(), newJWTManager(t, config), config)
+
+	// providerAccount makes code sign in to the provider account described by info
+	providerAccount := func(provider *MockOAuthProvider, code string, info *oauth.UserInfo) {
+		accessToken := &domain.Token{AccessToken: "provider-" + code}
+		provider.On("ExchangeCodeForToken", code, mock.AnythingOfType("string")).Return(accessToken, nil)
+		provider.On("GetUserInfo", accessToken.AccessToken).Return(info, nil)
+	}
+
+	callback := func(login *usecase.OAuthLogin, provider, code string) (*usecase.OAuthCallbackResult, error) {
+		return authUseCase.HandleOAuthCallback(context.Background(), provider, code, login.State, login.Binding, domain.ClientInfo{})
+	}
+
+	signIn := func(provider, code string) (*usecase.OAuthCallbackResult, error) {
+		login, err := authUseCase.InitiateOAuthLogin(context.Background(), provider)
+		require.NoError(t, err)
+		return callback(login, provider, code)
+	}
+
+	const password = "correct horse battery staple"
+
+	t.Run("Verified Email Links To Existing Account", func(t *testing.T) {
+		providerAccount(github, "alice-github", &oauth.UserInfo{ID: "gh-alice", Email: "alice@example.com", EmailVerified: true})
+		first, err := signIn("github", "alice-github")
+		require.NoError(t, err)
+
+		providerAccount(google, "alice-google", &oauth.UserInfo{ID: "g-alice", Email: "Alice@example.com", EmailVerified: true})
+		result, err := signIn("google", "alice-google")
+		require.NoError(t, err)
+		assert.Equal(t, first.Token.UserID, result.Token.UserID)
+		assert.Nil(t, result.LinkedIdentity)
+
+		identities, err := authUseCase.ListIdentities(first.Token.UserID)
+		require.NoError(t, err)
+		require.Len(t, identities, 2)
+
+		// Later sign-ins find the identity
+		result, err = signIn("google", "alice-google")
+		require.NoError(t, err)
+		assert.Equal(t, first.Token.UserID, result.Token.UserID)
+	})
+
+	t.Run("Unverified Account Is Not Taken Over", func(t *testing.T) {
+		// Anyone can register an address they do not own, its owner must not inherit the account
+		registered, err := registerAndLogin(t, authUseCase, "frank@example.com", password, domain.ClientInfo{})
+		require.NoError(t, err)
+		providerAccount(github, "mallory-github", &oauth.UserInfo{ID: "gh-mallory", Email: "judy@example.com"})
+		_, err = signIn("github", "mallory-github")
+		require.NoError(t, err)
+
+		providerAccount(google, "frank-google", &oauth.UserInfo{ID: "g-frank", Email: "frank@example.com", EmailVerified: true})
+		_, err = signIn("google", "frank-google")
+		assert.ErrorIs(t, err, domain.ErrAccountEmailNotVerified)
+		providerAccount(google, "judy-google", &oauth.UserInfo{ID: "g-judy", Email: "judy@example.com", EmailVerified: true})
+		_, err = signIn("google", "judy-google")
+		assert.ErrorIs(t, err, domain.ErrAccountEmailNotVerified)
+
+		_, err = identityRepo.FindByProviderSubject("google", "g-frank")
+		assert.ErrorIs(t, err, domain.ErrIdentityNotFound)
+
+		// Signed in with the password, the owner links the provider account
+		login, err := authUseCase.InitiateOAuthLink(context.Background(), registered.UserID, "google")
+		require.NoError(t, err)
+		result, err := callback(login, "google", "frank-google")
+		require.NoError(t, err)
+		require.NotNil(t, result.LinkedIdentity)
+		assert.Equal(t, registered.UserID, result.LinkedIdentity.UserID)
+	})
+
+	t.Run("Unverified Email Is Refused", func(t *testing.T) {
+		_, err := registerAndLogin(t, authUseCase, "bob@example.com", password, domain.ClientInfo{})
+		require.NoError(t, err)
+
+		providerAccount(github, "bob-github", &oauth.UserInfo{ID: "gh-bob", Email: "bob@example.com"})
+		_, err = signIn("github", "bob-github")
+		assert.ErrorIs(t, err, domain.ErrEmailNotVerified)
+
+		_, err = identityRepo.FindByProviderSubject("github", "gh-bob")
+		assert.ErrorIs(t, err, domain.ErrIdentityNotFound)
+	})
+
+	t.Run("New Account", func(t *testing.T) {
+		providerAccount(github, "carol-github", &oauth.UserInfo{ID: "gh-carol", Email: "carol@example.com", EmailVerified: true})
+		result, err := signIn("github", "carol-github")
+		require.NoError(t, err)
+
+		user, err := userRepo.FindByID(result.Token.UserID)
+		require.NoError(t, err)
+		assert.Equal(t, "carol@example.com", user.Email)
+		assert.Empty(t, user.PasswordHash)
+	})
+
+	t.Run("New Accounts Without Email", func(t *testing.T) {
+		providerAccount(github, "grace-github", &oauth.UserInfo{ID: "gh-grace"})
+		providerAccount(github, "heidi-github", &oauth.UserInfo{ID: "gh-heidi"})
+		grace, err := signIn("github", "grace-github")
+		require.NoError(t, err)
+		heidi, err := signIn("github", "heidi-github")
+		require.NoError(t, err)
+		assert.NotEqual(t, grace.Token.UserID, heidi.Token.UserID)
+	})
+
+	t.Run("Explicit Link And Unlink", func(t *testing.T) {
+		providerAccount(google, "dave-google", &oauth.UserInfo{ID: "g-dave", Email: "dave@gmail.com", EmailVerified: true})
+		session, err := signIn("google", "dave-google")
+		require.NoError(t, err)
+		daveID := session.Token.UserID
+
+		// Only identity of an account without a password
+		identities, err := authUseCase.ListIdentities(daveID)
+		require.NoError(t, err)
+		require.Len(t, identities, 1)
+		googleIdentity := identities[0]
+		assert.ErrorIs(t, authUseCase.UnlinkIdentity(daveID, googleIdentity.ID), domain.ErrLastSignInMethod)
+
+		// A different address is fine, the user proves ownership by signing in
+		providerAccount(github, "dave-github", &oauth.UserInfo{ID: "gh-dave", Email: "dave@example.org"})
+		login, err := authUseCase.InitiateOAuthLink(context.Background(), daveID, "github")
+		require.NoError(t, err)
+		result, err := callback(login, "github", "dave-github")
+		require.NoError(t, err)
+		assert.Nil(t, result.Token)
+		require.NotNil(t, result.LinkedIdentity)
+		assert.Equal(t, daveID, result.LinkedIdentity.UserID)
+		assert.Equal(t, "github", result.LinkedIdentity.Provider)
+
+		// Both providers now sign in to the same account
+		result, err = signIn("github", "dave-github")
+		require.NoError(t, err)
+		assert.Equal(t, daveID, result.Token.UserID)
+
+		// Another user's identity looks missing
+		assert.ErrorIs(t, authUseCase.UnlinkIdentity(uuid.New(), googleIdentity.ID), domain.ErrIdentityNotFound)
+
+		require.NoError(t, authUseCase.UnlinkIdentity(daveID, googleIdentity.ID))
+		identities, err = authUseCase.ListIdentities(daveID)
+		require.NoError(t, err)
+		require.Len(t, identities, 1)
+		assert.Equal(t, "github", identities[0].Provider)
+	})
+
+	t.Run("Identity Of Another User", func(t *testing.T) {
+		registered, err := registerAndLogin(t, authUseCase, "erin@example.com", password, domain.ClientInfo{})
+		require.NoError(t, err)
+
+		// The Carol GitHub account already belongs to Carol
+		login, err := authUseCase.InitiateOAuthLink(context.Background(), registered.UserID, "github")
+		require.NoError(t, err)
+		_, err = callback(login, "github", "carol-github")
+		assert.ErrorIs(t, err, domain.ErrIdentityAlreadyLinked)
+	})
+
+	t.Run("Data Hook", func(t *testing.T) {
+		providerAccount(google, "frank-google", &oauth.UserInfo{ID: "g-frank", Email: "frank@example.com", EmailVerified: true})
+		result, err := signIn("google", "frank-google")
+		require.NoError(t, err)
+		frankID := result.Token.UserID
+
+		hook := authUseCase.IdentityDataHook()
+		assert.Equal(t, "identities", hook.Name())
+
+		data, err := hook.ExportUserData(frankID)
+		require.NoError(t, err)
+		exported, ok := data.([]usecase.IdentityExport)
+		require.True(t, ok)
+		require.Len(t, exported, 1)
+		assert.Equal(t, "g-frank", exported[0].Subject)
+
+		require.NoError(t, hook.DeleteUserData(frankID))
+		_, err = identityRepo.FindByProviderSubject("google", "g-frank")
+		assert.ErrorIs(t, err, domain.ErrIdentityNotFound)
+	})
+}
@@ -0,0 +1,259 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/algosim/backend/configs"
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/algosim/backend/internal/auth/infrastructure/db/memory"
+	"github.com/algosim/backend/internal/auth/infrastructure/events"
+	"github.com/algosim/backend/internal/auth/infrastructure/oauth"
+	"github.com/algosim/backend/internal/auth/infrastructure/totp"
+	"github.com/algosim/backend/internal/auth/usecase"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMFA(t *testing.T) {
+	config := &configs.Config{}
+	config.Auth.JWTSecret = "test-secret-key-123"
+	config.Auth.TokenTTL = 3600
+	config.Auth.RefreshTokenSecret = "test-refresh-secret"
+	config.OAuth.StateTTL = 600
+	config.MFA.Issuer = "Algosim"
+	config.MFA.ChallengeTTL = 300
+	config.MFA.MaxAttempts = 3
+	config.MFA.Lockout = 900
+	config.Password.MinLength = 10
+	config.Password.Argon2Memory = 1024
+	config.Password.Argon2Iterations = 1
+	config.Password.Argon2Parallelism = 1
+
+	google := &MockOAuthProvider{name: "google"}
+	google.On("GetAuthURL", mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return("", nil)
+
+	userRepo := memory.NewUserRepoMemo()
+	tokenRepo := memory.NewTokenRepoMemo()
+	identityRepo := memory.NewIdentityRepoMemo()
+	stateRepo := memory.NewOAuthStateRepoMemo()
+	mfaRepo := memory.NewMFARepoMemo()
+	challengeRepo := memory.NewMFAChallengeRepoMemo()
+	publisher := events.NewLogPublisher()
+	authUseCase := usecase.NewAuthUseCase(userRepo, tokenRepo, identityRepo, stateRepo, mfaRepo, challengeRepo, oauth.NewRegistry(google), publisher, newJWTManager(t, config), config)
+	adminUseCase := usecase.NewAdminUseCase(userRepo, authUseCase, publisher)
+
+	const password = "correct horse battery staple"
+
+	// Codes are taken from fixed steps, a step boundary passing during the test stays within the skew
+	step := totp.Step(time.Now())
+	codeAt := func(secret string, step int64) string {
+		code, err := totp.Code(secret, step)
+		require.NoError(t, err)
+		return code
+	}
+
+	// enable enrolls and confirms an authenticator, returning its secret and recovery codes
+	enable := func(email string) (string, []string) {
+		user, err := userRepo.FindByEmail(email)
+		require.NoError(t, err)
+		enrollment, err := authUseCase.EnrollMFA(user.ID)
+		require.NoError(t, err)
+		recoveryCodes, err := authUseCase.ConfirmMFA(user.ID, codeAt(enrollment.Secret, step))
+		require.NoError(t, err)
+		return enrollment.Secret, recoveryCodes
+	}
+
+	// pendingLogin signs in with the password and returns the MFA challenge
+	pendingLogin := func(email string) *usecase.MFAPending {
+		result, err := authUseCase.Login(email, password, domain.ClientInfo{})
+		require.NoError(t, err)
+		require.Nil(t, result.Token)
+		require.NotNil(t, result.MFA)
+		return result.MFA
+	}
+
+	alice, err := registerAndLogin(t, authUseCase, "alice@example.com", password, domain.ClientInfo{})
+	require.NoError(t, err)
+
+	var aliceSecret string
+	var aliceRecoveryCodes []string
+
+	t.Run("Enroll And Confirm", func(t *testing.T) {
+		_, err := authUseCase.ConfirmMFA(alice.UserID, "123456")
+		assert.ErrorIs(t, err, domain.ErrMFANotEnrolled)
+
+		enrollment, err := authUseCase.EnrollMFA(alice.UserID)
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(enrollment.ProvisioningURI, "otpauth://totp/Algosim:alice@example.com?"))
+		assert.Contains(t, enrollment.ProvisioningURI, "secret="+enrollment.Secret)
+
+		// Enrolled but unconfirmed does not guard sign-in yet
+		status, err := authUseCase.GetMFAStatus(alice.UserID)
+		require.NoError(t, err)
+		assert.False(t, status.Enabled)
+		_, err = passwordLogin(t, authUseCase, "alice@example.com", password, domain.ClientInfo{})
+		require.NoError(t, err)
+
+		_, err = authUseCase.ConfirmMFA(alice.UserID, "000000")
+		assert.ErrorIs(t, err, domain.ErrInvalidMFACode)
+
+		aliceRecoveryCodes, err = authUseCase.ConfirmMFA(alice.UserID, codeAt(enrollment.Secret, step))
+		require.NoError(t, err)
+		aliceSecret = enrollment.Secret
+		assert.Len(t, aliceRecoveryCodes, 10)
+		assert.Regexp(t, `^[a-z2-7]{4}-[a-z2-7]{4}-[a-z2-7]{4}$`, aliceRecoveryCodes[0])
+
+		status, err = authUseCase.GetMFAStatus(alice.UserID)
+		require.NoError(t, err)
+		assert.True(t, status.Enabled)
+		assert.Equal(t, 10, status.RecoveryCodesRemaining)
+
+		_, err = authUseCase.EnrollMFA(alice.UserID)
+		assert.ErrorIs(t, err, domain.ErrMFAAlreadyEnabled)
+		_, err = authUseCase.ConfirmMFA(alice.UserID, codeAt(enrollment.Secret, step+1))
+		assert.ErrorIs(t, err, domain.ErrMFAAlreadyEnabled)
+	})
+
+	t.Run("Login Requires Second Factor", func(t *testing.T) {
+		pending := pendingLogin("alice@example.com")
+		assert.NotEmpty(t, pending.Token)
+		assert.True(t, pending.ExpiresAt.After(time.Now()))
+
+		// The code that confirmed the authenticator cannot be replayed
+		_, err := authUseCase.VerifyMFA(pending.Token, codeAt(aliceSecret, step), domain.ClientInfo{})
+		assert.ErrorIs(t, err, domain.ErrInvalidMFACode)
+		_, err = authUseCase.VerifyMFA("forged", codeAt(aliceSecret, step+1), domain.ClientInfo{})
+		assert.ErrorIs(t, err, domain.ErrMFAChallengeNotFound)
+
+		token, err := authUseCase.VerifyMFA(pending.Token, codeAt(aliceSecret, step+1), domain.ClientInfo{})
+		require.NoError(t, err)
+		user, err := authUseCase.ValidateToken(token.AccessToken)
+		require.NoError(t, err)
+		assert.Equal(t, alice.UserID, user.ID)
+
+		// The pending token is single-use
+		_, err = authUseCase.VerifyMFA(pending.Token, aliceRecoveryCodes[0], domain.ClientInfo{})
+		assert.ErrorIs(t, err, domain.ErrMFAChallengeNotFound)
+	})
+
+	t.Run("Recovery Code", func(t *testing.T) {
+		code := strings.ToUpper(aliceRecoveryCodes[0])
+		_, err := authUseCase.VerifyMFA(pendingLogin("alice@example.com").Token, code, domain.ClientInfo{})
+		require.NoError(t, err)
+
+		_, err = authUseCase.VerifyMFA(pendingLogin("alice@example.com").Token, code, domain.ClientInfo{})
+		assert.ErrorIs(t, err, domain.ErrInvalidMFACode)
+
+		status, err := authUseCase.GetMFAStatus(alice.UserID)
+		require.NoError(t, err)
+		assert.Equal(t, 9, status.RecoveryCodesRemaining)
+	})
+
+	t.Run("Instances Accept A Code Once", func(t *testing.T) {
+		// Another instance shares the stores but not the lock
+		other := usecase.NewAuthUseCase(userRepo, tokenRepo, identityRepo, stateRepo, mfaRepo, challengeRepo, oauth.NewRegistry(google), publisher, newJWTManager(t, config), config)
+		instances := []*usecase.AuthUseCase{authUseCase, other}
+
+		// Fewer than the attempts that lock, as the losers count as wrong codes
+		pending := make([]*usecase.MFAPending, config.MFA.MaxAttempts)
+		for i := range pending {
+			pending[i] = pendingLogin("alice@example.com")
+		}
+		errs := make([]error, len(pending))
+		var wg sync.WaitGroup
+		for i := range pending {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, errs[i] = instances[i%2].VerifyMFA(pending[i].Token, aliceRecoveryCodes[2], domain.ClientInfo{})
+			}()
+		}
+		wg.Wait()
+
+		accepted := 0
+		for _, err := range errs {
+			if err == nil {
+				accepted++
+			} else {
+				assert.ErrorIs(t, err, domain.ErrInvalidMFACode)
+			}
+		}
+		assert.Equal(t, 1, accepted)
+
+		status, err := authUseCase.GetMFAStatus(alice.UserID)
+		require.NoError(t, err)
+		assert.Equal(t, 8, status.RecoveryCodesRemaining)
+	})
+
+	t.Run("OAuth Login Requires Second Factor", func(t *testing.T) {
+		accessToken := &domain.Token{AccessToken: "provider-access-token"}
+		google.On("ExchangeCodeForToken", "google-code", mock.AnythingOfType("string")).Return(accessToken, nil)
+		google.On("GetUserInfo", accessToken.AccessToken).Return(&oauth.UserInfo{ID: "google-alice", Email: "alice@example.com", EmailVerified: true}, nil)
+
+		// A password account links the provider account explicitly
+		link, err := authUseCase.InitiateOAuthLink(context.Background(), alice.UserID, "google")
+		require.NoError(t, err)
+		_, err = authUseCase.HandleOAuthCallback(context.Background(), "google", "google-code", link.State, link.Binding, domain.ClientInfo{})
+		require.NoError(t, err)
+
+		login, err := authUseCase.InitiateOAuthLogin(context.Background(), "google")
+		require.NoError(t, err)
+		result, err := authUseCase.HandleOAuthCallback(context.Background(), "google", "google-code", login.State, login.Binding, domain.ClientInfo{})
+		require.NoError(t, err)
+		assert.Nil(t, result.Token)
+		require.NotNil(t, result.MFA)
+
+		_, err = authUseCase.VerifyMFA(result.MFA.Token, aliceRecoveryCodes[1], domain.ClientInfo{})
+		require.NoError(t, err)
+	})
+
+	t.Run("Lockout And Admin Reset", func(t *testing.T) {
+		root, err := registerAndLogin(t, authUseCase, "root@example.com", password, domain.ClientInfo{})
+		require.NoError(t, err)
+		bob, err := registerAndLogin(t, authUseCase, "bob@example.com", password, domain.ClientInfo{})
+		require.NoError(t, err)
+		_, recoveryCodes := enable("bob@example.com")
+
+		pending := pendingLogin("bob@example.com")
+		for range config.MFA.MaxAttempts {
+			_, err := authUseCase.VerifyMFA(pending.Token, "000000", domain.ClientInfo{})
+			assert.ErrorIs(t, err, domain.ErrInvalidMFACode)
+		}
+
+		// Locked, even a valid code is refused
+		_, err = authUseCase.VerifyMFA(pending.Token, recoveryCodes[0], domain.ClientInfo{})
+		assert.ErrorIs(t, err, domain.ErrMFALocked)
+		err = authUseCase.DisableMFA(bob.UserID, recoveryCodes[0])
+		assert.ErrorIs(t, err, domain.ErrMFALocked)
+
+		_, err = adminUseCase.ResetMFA(root.UserID, bob.UserID)
+		require.NoError(t, err)
+
+		// Pending sign-ins are dropped, the next one needs the password alone
+		_, err = authUseCase.VerifyMFA(pending.Token, recoveryCodes[0], domain.ClientInfo{})
+		assert.ErrorIs(t, err, domain.ErrMFAChallengeNotFound)
+		_, err = passwordLogin(t, authUseCase, "bob@example.com", password, domain.ClientInfo{})
+		require.NoError(t, err)
+	})
+
+	t.Run("Disable", func(t *testing.T) {
+		carol, err := registerAndLogin(t, authUseCase, "carol@example.com", password, domain.ClientInfo{})
+		require.NoError(t, err)
+		secret, _ := enable("carol@example.com")
+
+		err = authUseCase.DisableMFA(carol.UserID, "000000")
+		assert.ErrorIs(t, err, domain.ErrInvalidMFACode)
+
+		require.NoError(t, authUseCase.DisableMFA(carol.UserID, codeAt(secret, step+1)))
+		_, err = passwordLogin(t, authUseCase, "carol@example.com", password, domain.ClientInfo{})
+		require.NoError(t, err)
+
+		err = authUseCase.DisableMFA(carol.UserID, codeAt(secret, step+1))
+		assert.ErrorIs(t, err, domain.ErrMFANotEnrolled)
+	})
+}
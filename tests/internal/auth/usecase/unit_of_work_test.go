@@ -0,0 +1,165 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/algosim/backend/configs"
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/algosim/backend/internal/auth/infrastructure/db/memory"
+	"github.com/algosim/backend/internal/auth/infrastructure/oauth"
+	"github.com/algosim/backend/internal/auth/repository"
+	"github.com/algosim/backend/internal/auth/usecase"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// errStoreFailed is returned by token writes made to fail
+var errStoreFailed = errors.New("store failed")
+
+// failingTokens fails the token write named failOn
+type failingTokens struct {
+	repository.TokenRepository
+	failOn string
+}
+
+func (r failingTokens) Create(token *domain.Token) error {
+	if r.failOn == "Create" {
+		return errStoreFailed
+	}
+	return r.TokenRepository.Create(token)
+}
+
+func (r failingTokens) Update(token *domain.Token) error {
+	if r.failOn == "Update" {
+		return errStoreFailed
+	}
+	return r.TokenRepository.Update(token)
+}
+
+// failingUnitOfWork runs units whose token write named failOn fails, none fail while it is ""
+type failingUnitOfWork struct {
+	repository.UnitOfWork
+	failOn string
+}
+
+func (w *failingUnitOfWork) Do(fn func(tx repository.Repositories) error) error {
+	return w.UnitOfWork.Do(func(tx repository.Repositories) error {
+		if w.failOn != "" {
+			tx.Tokens = failingTokens{tx.Tokens, w.failOn}
+		}
+		return fn(tx)
+	})
+}
+
+func TestAuthUseCaseUnitOfWork(t *testing.T) {
+	config := &configs.Config{}
+	config.Auth.JWTSecret = "test-secret-key-123"
+	config.Auth.TokenTTL = 3600
+	config.Auth.RefreshTokenSecret = "test-refresh-secret"
+	config.Auth.BootstrapAdminEmail = "alice@example.com"
+	config.OAuth.StateSecret = "test-state-secret"
+	config.OAuth.StateTTL = 600
+	config.Password.MinLength = 10
+	config.Password.Argon2Memory = 1024
+	config.Password.Argon2Iterations = 1
+	config.Password.Argon2Parallelism = 1
+
+	userRepo := memory.NewUserRepoMemo()
+	tokenRepo := memory.NewTokenRepoMemo()
+	identityRepo := memory.NewIdentityRepoMemo()
+	challengeRepo := memory.NewMFAChallengeRepoMemo()
+	uow := &failingUnitOfWork{UnitOfWork: memory.NewUnitOfWork(userRepo, tokenRepo, identityRepo, challengeRepo)}
+
+	testToken := &domain.Token{AccessToken: "test-access-token"}
+	mockGoogleOAuth := &MockOAuthProvider{name: "google"}
+	mockGoogleOAuth.On("GetAuthURL", mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return("", nil)
+	mockGoogleOAuth.On("ExchangeCodeForToken", "test-code", mock.AnythingOfType("string")).Return(testToken, nil)
+	mockGoogleOAuth.On("GetUserInfo", testToken.AccessToken).Return(&oauth.UserInfo{
+		ID:            "test-google-id",
+		Email:         "bob@example.com",
+		EmailVerified: true,
+	}, nil)
+
+	// Events of units that roll back must not be published, the publisher expects none yet
+	publisher := new(MockPublisher)
+	authUseCase := usecase.NewAuthUseCase(userRepo, tokenRepo, identityRepo, memory.NewOAuthStateRepoMemo(), memory.NewMFARepoMemo(), challengeRepo, oauth.NewRegistry(mockGoogleOAuth), publisher, newJWTManager(t, config), config)
+	authUseCase.SetUnitOfWork(uow)
+
+	t.Run("Login Keeps No Admin Bootstrap Without A Session", func(t *testing.T) {
+		require.NoError(t, authUseCase.Register("alice@example.com", "correct horse battery staple"))
+
+		uow.failOn = "Create"
+		_, err := passwordLogin(t, authUseCase, "alice@example.com", "correct horse battery staple", domain.ClientInfo{})
+		assert.ErrorIs(t, err, errStoreFailed)
+
+		user, err := userRepo.FindByEmail("alice@example.com")
+		require.NoError(t, err)
+		assert.False(t, user.HasRole(domain.RoleAdmin))
+
+		// The bootstrap happens with the first session that is stored
+		uow.failOn = ""
+		publisher.On("Publish", mock.MatchedBy(func(event *domain.SecurityEvent) bool {
+			return event.Type == domain.SecurityEventAdminBootstrapped
+		})).Return().Once()
+		token, err := passwordLogin(t, authUseCase, "alice@example.com", "correct horse battery staple", domain.ClientInfo{})
+		require.NoError(t, err)
+		user, err = userRepo.FindByID(token.UserID)
+		require.NoError(t, err)
+		assert.True(t, user.HasRole(domain.RoleAdmin))
+		publisher.AssertExpectations(t)
+	})
+
+	t.Run("OAuth Callback Keeps No User Without A Session", func(t *testing.T) {
+		callback := func() (*usecase.OAuthCallbackResult, error) {
+			login, err := authUseCase.InitiateOAuthLogin(context.Background(), "google")
+			require.NoError(t, err)
+			return authUseCase.HandleOAuthCallback(context.Background(), "google", "test-code", login.State, login.Binding, domain.ClientInfo{})
+		}
+
+		uow.failOn = "Create"
+		_, err := callback()
+		assert.ErrorIs(t, err, errStoreFailed)
+
+		_, err = userRepo.FindByEmail("bob@example.com")
+		assert.ErrorIs(t, err, domain.ErrUserNotFound)
+		_, err = identityRepo.FindByProviderSubject("google", "test-google-id")
+		assert.ErrorIs(t, err, domain.ErrIdentityNotFound)
+
+		uow.failOn = ""
+		result, err := callback()
+		require.NoError(t, err)
+		require.NotNil(t, result.Token)
+		identity, err := identityRepo.FindByProviderSubject("google", "test-google-id")
+		require.NoError(t, err)
+		assert.Equal(t, result.Token.UserID, identity.UserID)
+	})
+
+	t.Run("Refresh Keeps No Token Without Rotating The Old One", func(t *testing.T) {
+		uow.failOn = ""
+		token, err := passwordLogin(t, authUseCase, "alice@example.com", "correct horse battery staple", domain.ClientInfo{})
+		require.NoError(t, err)
+		before, err := tokenRepo.FindByUserID(token.UserID)
+		require.NoError(t, err)
+
+		uow.failOn = "Update"
+		_, err = authUseCase.RefreshToken(token.RefreshToken, domain.ClientInfo{})
+		assert.ErrorIs(t, err, errStoreFailed)
+
+		after, err := tokenRepo.FindByUserID(token.UserID)
+		require.NoError(t, err)
+		assert.Len(t, after, len(before), "the new token is not kept")
+		stored, err := tokenRepo.FindByID(token.ID)
+		require.NoError(t, err)
+		assert.False(t, stored.IsRotated())
+
+		// Without a grace period a rotated token would count as reused by now
+		uow.failOn = ""
+		time.Sleep(time.Millisecond)
+		_, err = authUseCase.RefreshToken(token.RefreshToken, domain.ClientInfo{})
+		require.NoError(t, err)
+	})
+}
@@ -0,0 +1,94 @@
+package usecase
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/algosim/backend/internal/auth/domain"
+	"github.com/algosim/backend/internal/auth/infrastructure/db/memory"
+	"github.com/algosim/backend/internal/auth/usecase"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserUseCaseUpdateProfile(t *testing.T) {
+	userRepo := memory.NewUserRepoMemo()
+	userUseCase := usecase.NewUserUseCase(userRepo)
+
+	alice, err := userUseCase.CreateUser("alice@example.com")
+	require.NoError(t, err)
+	bob, err := userUseCase.CreateUser("bob@example.com")
+	require.NoError(t, err)
+
+	ptr := func(s string) *string { return &s }
+
+	t.Run("Applies Fields And Bumps UpdatedAt", func(t *testing.T) {
+		before := alice.UpdatedAt
+		time.Sleep(time.Millisecond)
+
+		user, err := userUseCase.UpdateProfile(alice.ID, domain.ProfileUpdate{
+			Handle:         ptr("alice_42"),
+			DisplayName:    ptr("  Alice Liddell "),
+			Timezone:       ptr("Europe/Berlin"),
+			PreferredJudge: ptr(domain.JudgeAtCoder),
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "alice_42", user.Handle)
+		assert.Equal(t, "Alice Liddell", user.DisplayName)
+		assert.Equal(t, "Europe/Berlin", user.Timezone)
+		assert.Equal(t, domain.JudgeAtCoder, user.PreferredJudge)
+		assert.True(t, user.UpdatedAt.After(before))
+
+		// Omitted fields stay, empty strings clear
+		user, err = userUseCase.UpdateProfile(alice.ID, domain.ProfileUpdate{DisplayName: ptr("")})
+		require.NoError(t, err)
+		assert.Empty(t, user.DisplayName)
+		assert.Equal(t, "alice_42", user.Handle)
+	})
+
+	t.Run("Field Validation", func(t *testing.T) {
+		for _, tc := range []struct {
+			name   string
+			update domain.ProfileUpdate
+			field  string
+			err    error
+		}{
+			{"Short Handle", domain.ProfileUpdate{Handle: ptr("al")}, "handle", domain.ErrInvalidHandle},
+			{"Handle With Spaces", domain.ProfileUpdate{Handle: ptr("bob builder")}, "handle", domain.ErrInvalidHandle},
+			{"Taken Handle", domain.ProfileUpdate{Handle: ptr("ALICE_42")}, "handle", domain.ErrHandleTaken},
+			{"Long Display Name", domain.ProfileUpdate{DisplayName: ptr(strings.Repeat("é", 65))}, "display_name", domain.ErrInvalidDisplayName},
+			{"Control Characters", domain.ProfileUpdate{DisplayName: ptr("Bob\nBuilder")}, "display_name", domain.ErrInvalidDisplayName},
+			{"Unknown Timezone", domain.ProfileUpdate{Timezone: ptr("Mars/Olympus")}, "timezone", domain.ErrInvalidTimezone},
+			{"Local Timezone", domain.ProfileUpdate{Timezone: ptr("Local")}, "timezone", domain.ErrInvalidTimezone},
+			{"Unknown Judge", domain.ProfileUpdate{PreferredJudge: ptr("leetcode")}, "preferred_judge", domain.ErrJudgeNotSupported},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				_, err := userUseCase.UpdateProfile(bob.ID, tc.update)
+				var fieldErr *domain.FieldError
+				require.ErrorAs(t, err, &fieldErr)
+				assert.Equal(t, tc.field, fieldErr.Field)
+				assert.ErrorIs(t, err, tc.err)
+			})
+		}
+
+		// A bad field leaves the valid ones unapplied
+		_, err := userUseCase.UpdateProfile(bob.ID, domain.ProfileUpdate{Handle: ptr("bob"), Timezone: ptr("Nowhere")})
+		assert.Error(t, err)
+		user, err := userUseCase.GetUser(bob.ID)
+		require.NoError(t, err)
+		assert.Empty(t, user.Handle)
+
+		// Keeping your own handle is fine
+		_, err = userUseCase.UpdateProfile(alice.ID, domain.ProfileUpdate{Handle: ptr("Alice_42")})
+		assert.NoError(t, err)
+	})
+
+	t.Run("Unknown User", func(t *testing.T) {
+		_, err := userUseCase.UpdateProfile(uuid.New(), domain.ProfileUpdate{})
+		assert.ErrorIs(t, err, domain.ErrUserNotFound)
+		_, err = userUseCase.GetUser(uuid.New())
+		assert.ErrorIs(t, err, domain.ErrUserNotFound)
+	})
+}
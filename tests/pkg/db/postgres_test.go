@@ -0,0 +1,30 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/algosim/backend/pkg/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenPostgres(t *testing.T) {
+	t.Run("Invalid URL", func(t *testing.T) {
+		_, err := db.OpenPostgres(context.Background(), db.PostgresConfig{URL: "postgres://%zz"})
+		assert.ErrorContains(t, err, "invalid postgres url")
+	})
+
+	t.Run("Unreachable", func(t *testing.T) {
+		// Nothing listens on port 1, the connect timeout bounds the attempt
+		start := time.Now()
+		_, err := db.OpenPostgres(context.Background(), db.PostgresConfig{
+			URL:            "postgres://algosim@127.0.0.1:1/algosim?sslmode=disable",
+			ConnectTimeout: time.Second,
+		})
+		require.Error(t, err)
+		assert.ErrorContains(t, err, "failed to reach postgres")
+		assert.Less(t, time.Since(start), 5*time.Second)
+	})
+}
@@ -0,0 +1,99 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/algosim/backend/pkg/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenSQLite(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Path Required", func(t *testing.T) {
+		_, err := db.OpenSQLite(ctx, db.SQLiteConfig{})
+		assert.Error(t, err)
+	})
+
+	t.Run("Creates Directory With WAL And Foreign Keys", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "nested", "algosim.db")
+		conn, err := db.OpenSQLite(ctx, db.SQLiteConfig{Path: path})
+		require.NoError(t, err)
+		defer conn.Close()
+		assert.FileExists(t, path)
+
+		var journalMode string
+		require.NoError(t, conn.QueryRowContext(ctx, `PRAGMA journal_mode`).Scan(&journalMode))
+		assert.Equal(t, "wal", journalMode)
+
+		var foreignKeys int
+		require.NoError(t, conn.QueryRowContext(ctx, `PRAGMA foreign_keys`).Scan(&foreignKeys))
+		assert.Equal(t, 1, foreignKeys)
+	})
+}
+
+func TestBackupSQLite(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	conn, err := db.OpenSQLite(ctx, db.SQLiteConfig{Path: filepath.Join(dir, "algosim.db")})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.ExecContext(ctx, `CREATE TABLE entries (id INTEGER PRIMARY KEY, batch INTEGER NOT NULL)`)
+	require.NoError(t, err)
+
+	// Writers insert batches of ten rows in transactions while the backup runs
+	var wg sync.WaitGroup
+	for batch := range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 20 {
+				tx, err := conn.BeginTx(ctx, nil)
+				if !assert.NoError(t, err) {
+					return
+				}
+				for range 10 {
+					_, err = tx.ExecContext(ctx, `INSERT INTO entries (batch) VALUES (?)`, batch)
+					assert.NoError(t, err)
+				}
+				assert.NoError(t, tx.Commit())
+			}
+		}()
+	}
+
+	path := filepath.Join(dir, "backups", "snapshot.db")
+	require.NoError(t, db.BackupSQLite(ctx, conn, path))
+	wg.Wait()
+
+	t.Run("Consistent Snapshot", func(t *testing.T) {
+		snapshot, err := sql.Open("sqlite", path)
+		require.NoError(t, err)
+		defer snapshot.Close()
+
+		var count int
+		require.NoError(t, snapshot.QueryRowContext(ctx, `SELECT COUNT(*) FROM entries`).Scan(&count))
+		assert.Zero(t, count%10, "the snapshot holds whole transactions only")
+
+		var integrity string
+		require.NoError(t, snapshot.QueryRowContext(ctx, `PRAGMA integrity_check`).Scan(&integrity))
+		assert.Equal(t, "ok", integrity)
+	})
+
+	t.Run("Existing Target", func(t *testing.T) {
+		before, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.ErrorContains(t, db.BackupSQLite(ctx, conn, path), fmt.Sprintf("backup %s already exists", path))
+
+		after, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, before, after, "the existing file is left alone")
+	})
+}
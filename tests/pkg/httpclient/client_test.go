@@ -0,0 +1,223 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/algosim/backend/pkg/httpclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingServer answers with the status returned by status for each request
+func countingServer(t *testing.T, status func(n int32) int) (*httptest.Server, *atomic.Int32) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status(calls.Add(1)))
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func fastConfig() httpclient.Config {
+	return httpclient.Config{
+		Timeout:        time.Second,
+		MaxRetries:     2,
+		RetryBaseDelay: time.Millisecond,
+		RetryMaxDelay:  5 * time.Millisecond,
+	}
+}
+
+func TestClient(t *testing.T) {
+	t.Run("Retries Idempotent Requests", func(t *testing.T) {
+		server, calls := countingServer(t, func(n int32) int {
+			if n < 3 {
+				return http.StatusServiceUnavailable
+			}
+			return http.StatusOK
+		})
+		metrics := httpclient.NewMetrics()
+		client := httpclient.New("test", fastConfig(), metrics)
+
+		resp, err := client.Get(context.Background(), server.URL, nil)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, int32(3), calls.Load())
+
+		stats := metrics.Snapshot()["test"]
+		assert.Equal(t, int64(3), stats.Requests)
+		assert.Equal(t, int64(2), stats.Errors)
+		assert.Equal(t, int64(2), stats.StatusCodes[http.StatusServiceUnavailable])
+		assert.Equal(t, int64(1), stats.StatusCodes[http.StatusOK])
+	})
+
+	t.Run("Returns Last Answer When Retries Run Out", func(t *testing.T) {
+		server, calls := countingServer(t, func(int32) int { return http.StatusBadGateway })
+		client := httpclient.New("test", fastConfig(), nil)
+
+		resp, err := client.Get(context.Background(), server.URL, nil)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+		assert.Equal(t, int32(3), calls.Load())
+	})
+
+	t.Run("Does Not Retry POST", func(t *testing.T) {
+		server, calls := countingServer(t, func(int32) int { return http.StatusServiceUnavailable })
+		client := httpclient.New("test", fastConfig(), nil)
+
+		resp, err := client.PostForm(context.Background(), server.URL, url.Values{"code": {"abc"}}, nil)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("Does Not Retry Client Errors", func(t *testing.T) {
+		server, calls := countingServer(t, func(int32) int { return http.StatusBadRequest })
+		client := httpclient.New("test", fastConfig(), nil)
+
+		resp, err := client.Get(context.Background(), server.URL, nil)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("Attempt Timeout", func(t *testing.T) {
+		var calls atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if calls.Add(1) == 1 {
+				<-r.Context().Done()
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+		t.Cleanup(server.Close)
+
+		config := fastConfig()
+		config.Timeout = 50 * time.Millisecond
+		client := httpclient.New("test", config, nil)
+
+		resp, err := client.Get(context.Background(), server.URL, nil)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("Context Deadline", func(t *testing.T) {
+		var hanging atomic.Bool
+		hanging.Store(true)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if hanging.Load() {
+				<-r.Context().Done()
+			}
+		}))
+		t.Cleanup(server.Close)
+
+		config := fastConfig()
+		config.Timeout = 5 * time.Second
+		config.BreakerThreshold = 1
+		client := httpclient.New("test", config, nil)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		_, err := client.Get(ctx, server.URL, nil)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), time.Second)
+
+		// The caller giving up is not held against the target
+		hanging.Store(false)
+		resp, err := client.Get(context.Background(), server.URL, nil)
+		require.NoError(t, err)
+		resp.Body.Close()
+	})
+
+	t.Run("Circuit Breaker", func(t *testing.T) {
+		var healthy atomic.Bool
+		server, calls := countingServer(t, func(int32) int {
+			if healthy.Load() {
+				return http.StatusOK
+			}
+			return http.StatusInternalServerError
+		})
+
+		config := fastConfig()
+		config.MaxRetries = 0
+		config.BreakerThreshold = 2
+		config.BreakerCooldown = 50 * time.Millisecond
+		metrics := httpclient.NewMetrics()
+		client := httpclient.New("test", config, metrics)
+
+		for range 2 {
+			resp, err := client.Get(context.Background(), server.URL, nil)
+			require.NoError(t, err)
+			resp.Body.Close()
+		}
+
+		// Open: calls are refused without reaching the target
+		_, err := client.Get(context.Background(), server.URL, nil)
+		assert.ErrorIs(t, err, httpclient.ErrCircuitOpen)
+		assert.Equal(t, int32(2), calls.Load())
+		assert.Equal(t, int64(1), metrics.Snapshot()["test"].Rejected)
+
+		// After the cooldown a successful probe closes it again
+		healthy.Store(true)
+		time.Sleep(60 * time.Millisecond)
+		for range 2 {
+			resp, err := client.Get(context.Background(), server.URL, nil)
+			require.NoError(t, err)
+			resp.Body.Close()
+		}
+		assert.Equal(t, int32(4), calls.Load())
+	})
+
+	t.Run("Failed Probe Reopens Breaker", func(t *testing.T) {
+		server, calls := countingServer(t, func(int32) int { return http.StatusInternalServerError })
+
+		config := fastConfig()
+		config.MaxRetries = 0
+		config.BreakerThreshold = 1
+		config.BreakerCooldown = 50 * time.Millisecond
+		client := httpclient.New("test", config, nil)
+
+		resp, err := client.Get(context.Background(), server.URL, nil)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		time.Sleep(60 * time.Millisecond)
+		resp, err = client.Get(context.Background(), server.URL, nil)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		_, err = client.Get(context.Background(), server.URL, nil)
+		assert.ErrorIs(t, err, httpclient.ErrCircuitOpen)
+		assert.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("Proxy", func(t *testing.T) {
+		var proxied atomic.Int32
+		proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			proxied.Add(1)
+			assert.Equal(t, "example.invalid", r.URL.Host)
+			w.WriteHeader(http.StatusOK)
+		}))
+		t.Cleanup(proxy.Close)
+
+		proxyURL, err := url.Parse(proxy.URL)
+		require.NoError(t, err)
+		config := fastConfig()
+		config.Proxy = proxyURL
+		client := httpclient.New("test", config, nil)
+
+		resp, err := client.Get(context.Background(), "http://example.invalid/userinfo", nil)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, int32(1), proxied.Load())
+	})
+}
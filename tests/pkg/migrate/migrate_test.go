@@ -0,0 +1,308 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/algosim/backend/migrations"
+	"github.com/algosim/backend/pkg/migrate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDriver records migrations in memory
+type fakeDriver struct {
+	applied map[int64]migrate.AppliedMigration
+	// ran lists the migrations applied (positive) and rolled back (negative) in order
+	ran     []int64
+	locks   int
+	unlocks int
+	failOn  int64
+}
+
+func newFakeDriver() *fakeDriver {
+	return &fakeDriver{applied: make(map[int64]migrate.AppliedMigration)}
+}
+
+func (d *fakeDriver) Lock(ctx context.Context) error {
+	d.locks++
+	return nil
+}
+
+func (d *fakeDriver) Unlock(ctx context.Context) error {
+	d.unlocks++
+	return nil
+}
+
+func (d *fakeDriver) EnsureVersionTable(ctx context.Context) error {
+	return nil
+}
+
+func (d *fakeDriver) Applied(ctx context.Context) ([]migrate.AppliedMigration, error) {
+	applied := make([]migrate.AppliedMigration, 0, len(d.applied))
+	for _, a := range d.applied {
+		applied = append(applied, a)
+	}
+	sort.Slice(applied, func(i, j int) bool { return applied[i].Version < applied[j].Version })
+	return applied, nil
+}
+
+func (d *fakeDriver) Apply(ctx context.Context, migration migrate.Migration, direction migrate.Direction) error {
+	if migration.Version == d.failOn {
+		return errors.New("syntax error")
+	}
+	if direction == migrate.DirectionUp {
+		d.applied[migration.Version] = migrate.AppliedMigration{
+			Version:   migration.Version,
+			Name:      migration.Name,
+			Checksum:  migration.Checksum,
+			AppliedAt: time.Now(),
+		}
+		d.ran = append(d.ran, migration.Version)
+	} else {
+		delete(d.applied, migration.Version)
+		d.ran = append(d.ran, -migration.Version)
+	}
+	return nil
+}
+
+func testMigrations() fstest.MapFS {
+	return fstest.MapFS{
+		"0001_create_users.up.sql":    {Data: []byte("CREATE TABLE users (id INT);")},
+		"0001_create_users.down.sql":  {Data: []byte("DROP TABLE users;")},
+		"0002_add_email.up.sql":       {Data: []byte("ALTER TABLE users ADD email TEXT;")},
+		"0002_add_email.down.sql":     {Data: []byte("ALTER TABLE users DROP email;")},
+		"0003_create_tokens.up.sql":   {Data: []byte("CREATE TABLE tokens (id INT);")},
+		"0003_create_tokens.down.sql": {Data: []byte("DROP TABLE tokens;")},
+	}
+}
+
+func versions(done []migrate.Migration) []int64 {
+	result := make([]int64, 0, len(done))
+	for _, migration := range done {
+		result = append(result, migration.Version)
+	}
+	return result
+}
+
+func TestLoad(t *testing.T) {
+	t.Run("Ordered", func(t *testing.T) {
+		loaded, err := migrate.Load(testMigrations())
+		require.NoError(t, err)
+		assert.Equal(t, []int64{1, 2, 3}, versions(loaded))
+		assert.Equal(t, "create_users", loaded[0].Name)
+		assert.Equal(t, "DROP TABLE users;", loaded[0].Down)
+		assert.Len(t, loaded[0].Checksum, 64)
+	})
+
+	tests := []struct {
+		name  string
+		files fstest.MapFS
+		err   string
+	}{
+		{
+			name:  "Unexpected file",
+			files: fstest.MapFS{"README.md": {Data: []byte("notes")}},
+			err:   "unexpected file",
+		},
+		{
+			name: "Two names",
+			files: fstest.MapFS{
+				"0001_create_users.up.sql":  {Data: []byte("SELECT 1;")},
+				"0001_create_people.up.sql": {Data: []byte("SELECT 1;")},
+			},
+			err: "has two names",
+		},
+		{
+			name:  "Missing up file",
+			files: fstest.MapFS{"0001_create_users.down.sql": {Data: []byte("SELECT 1;")}},
+			err:   "has no up file",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := migrate.Load(tt.files)
+			assert.ErrorContains(t, err, tt.err)
+		})
+	}
+}
+
+func TestMigrator(t *testing.T) {
+	ctx := context.Background()
+
+	newMigrator := func(t *testing.T, files fstest.MapFS) (*migrate.Migrator, *fakeDriver) {
+		driver := newFakeDriver()
+		migrator, err := migrate.New(driver, files)
+		require.NoError(t, err)
+		return migrator, driver
+	}
+
+	t.Run("Up", func(t *testing.T) {
+		migrator, driver := newMigrator(t, testMigrations())
+
+		done, err := migrator.Up(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []int64{1, 2, 3}, versions(done))
+
+		// A second run has nothing left to do
+		done, err = migrator.Up(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, done)
+		assert.Equal(t, []int64{1, 2, 3}, driver.ran)
+		assert.Equal(t, 2, driver.locks)
+		assert.Equal(t, 2, driver.unlocks)
+	})
+
+	t.Run("Down", func(t *testing.T) {
+		migrator, driver := newMigrator(t, testMigrations())
+		_, err := migrator.Up(ctx)
+		require.NoError(t, err)
+
+		done, err := migrator.Down(ctx, 2)
+		require.NoError(t, err)
+		assert.Equal(t, []int64{3, 2}, versions(done))
+		assert.Equal(t, []int64{1, 2, 3, -3, -2}, driver.ran)
+
+		pending, err := migrator.Pending(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []int64{2, 3}, versions(pending))
+	})
+
+	t.Run("Goto", func(t *testing.T) {
+		migrator, driver := newMigrator(t, testMigrations())
+
+		done, err := migrator.Goto(ctx, 2)
+		require.NoError(t, err)
+		assert.Equal(t, []int64{1, 2}, versions(done))
+
+		done, err = migrator.Goto(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, []int64{2}, versions(done))
+
+		done, err = migrator.Goto(ctx, 0)
+		require.NoError(t, err)
+		assert.Equal(t, []int64{1}, versions(done))
+		assert.Empty(t, driver.applied)
+
+		_, err = migrator.Goto(ctx, 7)
+		assert.ErrorIs(t, err, migrate.ErrUnknownVersion)
+	})
+
+	t.Run("Status", func(t *testing.T) {
+		migrator, _ := newMigrator(t, testMigrations())
+		_, err := migrator.Goto(ctx, 1)
+		require.NoError(t, err)
+
+		statuses, err := migrator.Status(ctx)
+		require.NoError(t, err)
+		require.Len(t, statuses, 3)
+		require.NotNil(t, statuses[0].Applied)
+		assert.False(t, statuses[0].Modified)
+		assert.Nil(t, statuses[1].Applied)
+		assert.Nil(t, statuses[2].Applied)
+	})
+
+	t.Run("Checksum mismatch", func(t *testing.T) {
+		migrator, driver := newMigrator(t, testMigrations())
+		_, err := migrator.Goto(ctx, 1)
+		require.NoError(t, err)
+
+		// The up file of an applied migration is edited afterwards
+		files := testMigrations()
+		files["0001_create_users.up.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE users (id BIGINT);")}
+		edited, err := migrate.New(driver, files)
+		require.NoError(t, err)
+
+		statuses, err := edited.Status(ctx)
+		require.NoError(t, err)
+		assert.True(t, statuses[0].Modified)
+
+		_, err = edited.Up(ctx)
+		assert.ErrorIs(t, err, migrate.ErrChecksumMismatch)
+		assert.Equal(t, []int64{1}, driver.ran)
+		assert.Equal(t, driver.locks, driver.unlocks)
+	})
+
+	t.Run("Unknown applied migration", func(t *testing.T) {
+		migrator, driver := newMigrator(t, testMigrations())
+		driver.applied[9] = migrate.AppliedMigration{Version: 9, Name: "from_a_newer_build"}
+
+		_, err := migrator.Status(ctx)
+		assert.ErrorIs(t, err, migrate.ErrUnknownMigration)
+		_, err = migrator.Up(ctx)
+		assert.ErrorIs(t, err, migrate.ErrUnknownMigration)
+	})
+
+	t.Run("Irreversible", func(t *testing.T) {
+		files := testMigrations()
+		delete(files, "0003_create_tokens.down.sql")
+		migrator, driver := newMigrator(t, files)
+		_, err := migrator.Up(ctx)
+		require.NoError(t, err)
+
+		_, err = migrator.Down(ctx, 1)
+		assert.ErrorIs(t, err, migrate.ErrIrreversible)
+		assert.Len(t, driver.applied, 3)
+	})
+
+	t.Run("Failed migration stops", func(t *testing.T) {
+		migrator, driver := newMigrator(t, testMigrations())
+		driver.failOn = 2
+
+		done, err := migrator.Up(ctx)
+		assert.ErrorContains(t, err, "migration 2_add_email failed")
+		assert.Equal(t, []int64{1}, versions(done))
+		assert.Equal(t, driver.locks, driver.unlocks)
+	})
+}
+
+func TestCreate(t *testing.T) {
+	dir := t.TempDir()
+
+	upPath, downPath, err := migrate.Create(dir, "Create Users")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "0001_create_users.up.sql"), upPath)
+	assert.Equal(t, filepath.Join(dir, "0001_create_users.down.sql"), downPath)
+
+	upPath, _, err = migrate.Create(dir, "add-email")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "0002_add_email.up.sql"), upPath)
+
+	loaded, err := migrate.Load(os.DirFS(dir))
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1, 2}, versions(loaded))
+
+	_, _, err = migrate.Create(dir, "!!!")
+	assert.Error(t, err)
+}
+
+func TestEmbeddedPostgresMigrations(t *testing.T) {
+	loaded, err := migrate.Load(migrations.Postgres())
+	require.NoError(t, err)
+	require.NotEmpty(t, loaded)
+	for i, migration := range loaded {
+		assert.Equal(t, int64(i+1), migration.Version, "versions have no gaps")
+		assert.NotEmpty(t, migration.Down, "%d_%s can be rolled back", migration.Version, migration.Name)
+	}
+}
+
+func TestEmbeddedSQLiteMigrations(t *testing.T) {
+	postgres, err := migrate.Load(migrations.Postgres())
+	require.NoError(t, err)
+	sqlite, err := migrate.Load(migrations.SQLite())
+	require.NoError(t, err)
+
+	// Both drivers share one migration history
+	require.Len(t, sqlite, len(postgres))
+	for i, migration := range sqlite {
+		assert.Equal(t, postgres[i].Version, migration.Version)
+		assert.Equal(t, postgres[i].Name, migration.Name)
+		assert.NotEmpty(t, migration.Down, "%d_%s can be rolled back", migration.Version, migration.Name)
+	}
+}
@@ -0,0 +1,124 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/algosim/backend/migrations"
+	"github.com/algosim/backend/pkg/db"
+	"github.com/algosim/backend/pkg/migrate"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestPool connects to an empty schema of the database in TEST_POSTGRES_DSN
+func newTestPool(t *testing.T) *pgxpool.Pool {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN not set")
+	}
+	ctx := context.Background()
+
+	admin, err := db.OpenPostgres(ctx, db.PostgresConfig{URL: dsn, ConnectTimeout: 5 * time.Second})
+	require.NoError(t, err)
+	t.Cleanup(admin.Close)
+
+	schema := fmt.Sprintf("test_migrate_%d", time.Now().UnixNano())
+	_, err = admin.Exec(ctx, "CREATE SCHEMA "+schema)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_, _ = admin.Exec(context.Background(), "DROP SCHEMA "+schema+" CASCADE")
+	})
+
+	u, err := url.Parse(dsn)
+	require.NoError(t, err)
+	query := u.Query()
+	query.Set("search_path", schema)
+	u.RawQuery = query.Encode()
+
+	pool, err := db.OpenPostgres(ctx, db.PostgresConfig{URL: u.String(), MaxConns: 8})
+	require.NoError(t, err)
+	t.Cleanup(pool.Close)
+	return pool
+}
+
+func TestPostgresDriver(t *testing.T) {
+	pool := newTestPool(t)
+	ctx := context.Background()
+
+	newMigrator := func(t *testing.T) *migrate.Migrator {
+		migrator, err := migrate.New(migrate.NewPostgresDriver(pool), migrations.Postgres())
+		require.NoError(t, err)
+		return migrator
+	}
+
+	t.Run("Concurrent up applies once", func(t *testing.T) {
+		const migrators = 4
+		applied := make([]int, migrators)
+		errs := make([]error, migrators)
+
+		var wg sync.WaitGroup
+		for i := 0; i < migrators; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				done, err := newMigrator(t).Up(ctx)
+				applied[i], errs[i] = len(done), err
+			}(i)
+		}
+		wg.Wait()
+
+		total := 0
+		for i := 0; i < migrators; i++ {
+			require.NoError(t, errs[i])
+			total += applied[i]
+		}
+		assert.Equal(t, len(newMigrator(t).Migrations()), total)
+
+		pending, err := newMigrator(t).Pending(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, pending)
+	})
+
+	t.Run("Down and up again", func(t *testing.T) {
+		migrator := newMigrator(t)
+
+		_, err := migrator.Goto(ctx, 0)
+		require.NoError(t, err)
+		var exists bool
+		require.NoError(t, pool.QueryRow(ctx, `SELECT to_regclass('users') IS NOT NULL`).Scan(&exists))
+		assert.False(t, exists)
+
+		_, err = migrator.Up(ctx)
+		require.NoError(t, err)
+		require.NoError(t, pool.QueryRow(ctx, `SELECT to_regclass('users') IS NOT NULL`).Scan(&exists))
+		assert.True(t, exists)
+	})
+
+	t.Run("Failed migration rolls back", func(t *testing.T) {
+		driver := migrate.NewPostgresDriver(pool)
+		broken := migrate.Migration{
+			Version:  999,
+			Name:     "broken",
+			Up:       `CREATE TABLE half_done (id INT); SELECT * FROM does_not_exist;`,
+			Checksum: "x",
+		}
+		require.NoError(t, driver.EnsureVersionTable(ctx))
+		assert.Error(t, driver.Apply(ctx, broken, migrate.DirectionUp))
+
+		var exists bool
+		require.NoError(t, pool.QueryRow(ctx, `SELECT to_regclass('half_done') IS NOT NULL`).Scan(&exists))
+		assert.False(t, exists)
+		applied, err := driver.Applied(ctx)
+		require.NoError(t, err)
+		for _, a := range applied {
+			assert.NotEqual(t, int64(999), a.Version)
+		}
+	})
+}
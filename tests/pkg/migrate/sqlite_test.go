@@ -0,0 +1,168 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/algosim/backend/migrations"
+	"github.com/algosim/backend/pkg/db"
+	"github.com/algosim/backend/pkg/migrate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// openTestSQLite opens the database file at path
+func openTestSQLite(t *testing.T, path string) *sql.DB {
+	conn, err := db.OpenSQLite(context.Background(), db.SQLiteConfig{Path: path})
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// tableExists reports whether the SQLite database has a table named name
+func tableExists(t *testing.T, conn *sql.DB, name string) bool {
+	var exists bool
+	require.NoError(t, conn.QueryRowContext(context.Background(),
+		`SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = ?)`, name).Scan(&exists))
+	return exists
+}
+
+func TestSQLiteDriver(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "algosim.db")
+	conn := openTestSQLite(t, path)
+	ctx := context.Background()
+
+	newMigrator := func(t *testing.T, conn *sql.DB) *migrate.Migrator {
+		migrator, err := migrate.New(migrate.NewSQLiteDriver(conn), migrations.SQLite())
+		require.NoError(t, err)
+		return migrator
+	}
+
+	t.Run("Concurrent up applies once", func(t *testing.T) {
+		// Separate handles on the file, like two processes starting at once
+		const migrators = 4
+		applied := make([]int, migrators)
+		errs := make([]error, migrators)
+
+		var wg sync.WaitGroup
+		for i := 0; i < migrators; i++ {
+			migrator := newMigrator(t, openTestSQLite(t, path))
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				done, err := migrator.Up(ctx)
+				applied[i], errs[i] = len(done), err
+			}(i)
+		}
+		wg.Wait()
+
+		total := 0
+		for i := 0; i < migrators; i++ {
+			require.NoError(t, errs[i])
+			total += applied[i]
+		}
+		assert.Equal(t, len(newMigrator(t, conn).Migrations()), total)
+
+		pending, err := newMigrator(t, conn).Pending(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, pending)
+	})
+
+	t.Run("Down and up again", func(t *testing.T) {
+		migrator := newMigrator(t, conn)
+
+		_, err := migrator.Goto(ctx, 0)
+		require.NoError(t, err)
+		assert.False(t, tableExists(t, conn, "users"))
+		assert.False(t, tableExists(t, conn, "identities"))
+
+		_, err = migrator.Up(ctx)
+		require.NoError(t, err)
+		assert.True(t, tableExists(t, conn, "users"))
+		assert.True(t, tableExists(t, conn, "identities"))
+
+		statuses, err := migrator.Status(ctx)
+		require.NoError(t, err)
+		for _, status := range statuses {
+			assert.NotNil(t, status.Applied, "%d_%s is applied", status.Migration.Version, status.Migration.Name)
+			assert.False(t, status.Modified)
+		}
+	})
+
+	t.Run("Failed migration rolls back", func(t *testing.T) {
+		driver := migrate.NewSQLiteDriver(conn)
+		broken := migrate.Migration{
+			Version:  999,
+			Name:     "broken",
+			Up:       `CREATE TABLE half_done (id INTEGER); SELECT * FROM does_not_exist;`,
+			Checksum: "x",
+		}
+		require.NoError(t, driver.Lock(ctx))
+		require.NoError(t, driver.EnsureVersionTable(ctx))
+		assert.Error(t, driver.Apply(ctx, broken, migrate.DirectionUp))
+		require.NoError(t, driver.Unlock(ctx))
+
+		assert.False(t, tableExists(t, conn, "half_done"))
+		applied, err := driver.Applied(ctx)
+		require.NoError(t, err)
+		for _, a := range applied {
+			assert.NotEqual(t, int64(999), a.Version)
+		}
+	})
+
+	t.Run("Rebuilding users keeps what refers to them", func(t *testing.T) {
+		migrator := newMigrator(t, conn)
+		_, err := migrator.Goto(ctx, 2)
+		require.NoError(t, err)
+
+		const now = "2026-01-02T03:04:05.000000000Z"
+		_, err = conn.ExecContext(ctx, `INSERT INTO users (id, email, created_at, updated_at) VALUES ('u1', 'alice@example.com', ?, ?)`, now, now)
+		require.NoError(t, err)
+		_, err = conn.ExecContext(ctx, `
+			INSERT INTO tokens (id, user_id, family_id, refresh_token_hash, access_token_expires_at, expires_at,
+				session_started_at, last_used_at, created_at)
+			VALUES ('t1', 'u1', 'f1', 'hash', ?, ?, ?, ?, ?)`, now, now, now, now, now)
+		require.NoError(t, err)
+
+		_, err = migrator.Up(ctx)
+		require.NoError(t, err)
+
+		var tokens int
+		require.NoError(t, conn.QueryRowContext(ctx, `SELECT COUNT(*) FROM tokens WHERE user_id = 'u1'`).Scan(&tokens))
+		assert.Equal(t, 1, tokens)
+
+		// Foreign keys are on again, deleting the user deletes their tokens
+		_, err = conn.ExecContext(ctx, `DELETE FROM users WHERE id = 'u1'`)
+		require.NoError(t, err)
+		require.NoError(t, conn.QueryRowContext(ctx, `SELECT COUNT(*) FROM tokens`).Scan(&tokens))
+		assert.Zero(t, tokens)
+	})
+
+	t.Run("Migrations must keep foreign keys", func(t *testing.T) {
+		driver := migrate.NewSQLiteDriver(conn)
+		orphan := migrate.Migration{
+			Version: 997,
+			Name:    "orphan",
+			Up: `INSERT INTO identities (id, user_id, provider, subject, created_at, last_used_at)
+				VALUES ('i1', 'nobody', 'github', '1', '', '')`,
+			Checksum: "x",
+		}
+		require.NoError(t, driver.Lock(ctx))
+		require.NoError(t, driver.Apply(ctx, orphan, migrate.DirectionUp))
+		assert.Error(t, driver.Unlock(ctx))
+
+		var identities int
+		require.NoError(t, conn.QueryRowContext(ctx, `SELECT COUNT(*) FROM identities`).Scan(&identities))
+		assert.Zero(t, identities)
+	})
+
+	t.Run("Apply requires the lock", func(t *testing.T) {
+		driver := migrate.NewSQLiteDriver(conn)
+		migration := migrate.Migration{Version: 998, Name: "unlocked", Up: `CREATE TABLE unlocked (id INTEGER)`}
+		assert.Error(t, driver.Apply(ctx, migration, migrate.DirectionUp))
+		assert.False(t, tableExists(t, conn, "unlocked"))
+	})
+}